@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"github.com/mehrbod2002/fxtrader/internal/api"
+	"github.com/mehrbod2002/fxtrader/internal/clock"
 	"github.com/mehrbod2002/fxtrader/internal/config"
+	"github.com/mehrbod2002/fxtrader/internal/jobs"
 	"github.com/mehrbod2002/fxtrader/internal/middleware"
 	"github.com/mehrbod2002/fxtrader/internal/repository"
 	"github.com/mehrbod2002/fxtrader/internal/service"
@@ -25,6 +27,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
 
 	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(cfg.MongoURI))
 	if err != nil {
@@ -57,66 +62,135 @@ func main() {
 	alertRepo := repository.NewAlertRepository(client, "fxtrader", "alerts")
 	copyTradeRepo := repository.NewCopyTradeRepository(client, "fxtrader", "copy_trades")
 	leaderRequestRepo := repository.NewLeaderRequestRepository(client, "fxtrader", "leader_requests")
+	currencyRateRepo := repository.NewCurrencyRateRepository(client, "fxtrader", "currency_rates")
+	deadLetterRepo := repository.NewDeadLetterRepository(client, "fxtrader", "mt5_dead_letters")
+	webhookRepo := repository.NewWebhookRepository(client, "fxtrader", "webhook_deliveries")
+	discrepancyRepo := repository.NewBalanceDiscrepancyRepository(client, "fxtrader", "balance_discrepancies")
+	jobLeaseRepo := repository.NewJobLeaseRepository(client, "fxtrader", "job_leases")
 
 	if err := config.EnsureAdminUser(adminRepo, cfg.AdminUser, cfg.AdminPass); err != nil {
 		log.Fatalf("Failed to ensure admin user: %v", err)
 	}
+	if n, err := repository.NormalizeAccountTypes(client.Database("fxtrader"), "users_user_accounts", "trades_fxtrader", "copy_trades"); err != nil {
+		log.Printf("Failed to normalize account types: %v", err)
+	} else if n > 0 {
+		log.Printf("Normalized the account type of %d records", n)
+	}
+	if n, err := repository.BackfillTradeOpenTimes(client.Database("fxtrader"), "trades_fxtrader"); err != nil {
+		log.Printf("Failed to backfill trade open times: %v", err)
+	} else if n > 0 {
+		log.Printf("Backfilled the open time of %d trades", n)
+	}
 
-	logService := service.NewLogService(logRepo)
+	clk := clock.New(cfg.Timezone)
+	logService := service.NewLogService(logRepo, cfg)
+	webhookService := service.NewWebhookService(webhookRepo, logService, cfg)
 	userService := service.NewUserService(userRepo)
 	accountService := service.NewAccountService(accountRepo)
-	transferService := service.NewTransferService(userRepo, accountRepo)
+	transactor := repository.NewTransactor(client)
+	transferService := service.NewTransferService(userRepo, accountRepo, transactionRepo, transactor)
 	symbolService := service.NewSymbolService(symbolRepo)
 	ruleService := service.NewRuleService(ruleRepo)
-	transactionService := service.NewTransactionService(transactionRepo, logService, userRepo)
-	alertService := service.NewAlertService(alertRepo, symbolRepo, logService)
-	socketServer, err := socket.NewWebSocketServer(cfg.ListenPort, accountRepo)
+	var rateProvider service.RateProvider
+	if cfg.CurrencyRatesURL != "" {
+		rateProvider = service.NewHTTPRateProvider(cfg.CurrencyRatesURL)
+	}
+	currencyService := service.NewCurrencyService(currencyRateRepo, rateProvider, cfg.BaseCurrency, cfg.CurrencyRateTTL)
+	transactionService := service.NewTransactionService(transactionRepo, logService, userRepo, currencyService, hub, webhookService, transactor)
+	alertService := service.NewAlertService(alertRepo, symbolRepo, logService, clk, cfg)
+	socketServer, err := socket.NewWebSocketServer(cfg.ListenPort, accountRepo, cfg.WSCompression)
 	if err != nil {
 		log.Fatalf("Failed to initialize WebSocket server: %v", err)
 	}
+	if cfg.TLSEnabled() {
+		socketServer.UseTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+	}
+	mt5Allowlist, err := socket.NewAllowlist(cfg.MT5AllowedOrigins, cfg.MT5AllowedIPs)
+	if err != nil {
+		log.Fatalf("Failed to parse MT5 allowlist: %v", err)
+	}
+	if mt5Allowlist.Empty() {
+		log.Printf("MT5_ALLOWED_IPS and MT5_ALLOWED_ORIGINS are unset; accepting MT5 connections from any host")
+	}
+	socketServer.SetAllowlist(mt5Allowlist)
+	socketServer.SetDeadLetterRepository(deadLetterRepo)
 
-	tradeService, err := service.NewTradeService(tradeRepo, symbolRepo, userRepo, accountRepo, logService, hub, socketServer, nil)
+	tradeService, err := service.NewTradeService(tradeRepo, symbolRepo, userRepo, accountRepo, logService, hub, socketServer, nil, webhookService, transactor, currencyService, clk, cfg)
 	if err != nil {
 		log.Fatalf("Failed to initialize trade service: %v", err)
 	}
 
-	copyTradeService := service.NewCopyTradeService(copyTradeRepo, tradeService, userService, accountService, logService)
-
-	copyTradeService.SetTradeService(tradeService)
+	var telegramService service.TelegramService
+	if cfg.BotToken != "" {
+		telegramService, err = service.NewTelegramService(cfg.BotToken, userService, logService)
+		if err != nil {
+			log.Printf("Telegram notifications disabled: %v", err)
+		}
+	}
 
-	// telegramService, err := service.NewTelegramService(cfg.BotToken, userService, logService)
-	// if err != nil {
-	// 	log.Fatalf("Failed to initialize telegram service: %v", err)
-	// }
+	copyTradeService := service.NewCopyTradeService(copyTradeRepo, symbolRepo, tradeService, userService, accountService, logService, telegramService, cfg)
 
-	priceService := service.NewPriceService(priceRepo, hub, alertService)
-	leaderRequestService := service.NewLeaderRequestService(leaderRequestRepo, userService, logService)
+	copyTradeService.SetTradeService(tradeService)
+	tradeService.SetTradeMirror(copyTradeService)
+
+	priceService := service.NewPriceService(priceRepo, hub, alertService, clk, cfg)
+	priceService.SetTradeService(tradeService)
+	announcementService := service.NewAnnouncementService(hub, userService, telegramService, logService)
+	adminService := service.NewAdminService(accountRepo, tradeRepo, tradeService, logService)
+	reconciliationService := service.NewReconciliationService(accountRepo, discrepancyRepo, tradeService, logService, cfg)
+	leaderRequestService := service.NewLeaderRequestService(leaderRequestRepo, userService, tradeRepo, copyTradeRepo, logService, telegramService, cfg)
+	ws.SetCompression(cfg.WSCompression)
 	wsHandler := ws.NewWebSocketHandler(hub, tradeService, userRepo)
 
 	if err := socketServer.Start(tradeService); err != nil {
 		log.Fatalf("Failed to start WebSocket server: %v", err)
 	}
 
-	go func() {
-		ticker := time.NewTicker(1 * time.Minute)
-		defer ticker.Stop()
-		for range ticker.C {
-			if err := alertService.ProcessTimeBasedAlerts(); err != nil {
-				log.Printf("Error processing time-based alerts: %v", err)
-			}
-		}
-	}()
+	scheduler := jobs.NewScheduler(jobLeaseRepo)
+	scheduler.Register(jobs.Job{
+		Name:     "time-based-alerts",
+		Interval: time.Minute,
+		Run: func(ctx context.Context) error {
+			return alertService.ProcessTimeBasedAlerts()
+		},
+	})
+	scheduler.Register(jobs.Job{
+		Name:     "requote-expiry",
+		Interval: 5 * time.Second,
+		Run:      tradeService.ExpireRequotes,
+	})
+	scheduler.Register(jobs.Job{
+		Name:     "pending-order-expiry",
+		Interval: 30 * time.Second,
+		Run:      tradeService.ExpirePendingOrders,
+	})
+	if cfg.ReconcileInterval > 0 {
+		scheduler.Register(jobs.Job{
+			Name:     "balance-reconciliation",
+			Interval: cfg.ReconcileInterval,
+			Run: func(ctx context.Context) error {
+				_, err := reconciliationService.ReconcileBalances(ctx)
+				return err
+			},
+		})
+	}
+	scheduler.Start(context.Background())
 
 	r := gin.New()
-	r.Use(gin.Recovery())
+	r.Use(middleware.RecoveryMiddleware(logService))
 	r.Use(middleware.LoggerMiddleware())
 
-	api.SetupRoutes(r, cfg, alertService, copyTradeService, priceService, adminRepo, userService, symbolService, logService, ruleService, tradeService, transactionService, wsHandler, hub, leaderRequestService, accountService, transferService, accountRepo, userRepo)
+	api.SetupRoutes(r, cfg, alertService, copyTradeService, priceService, adminRepo, userService, symbolService, logService, ruleService, tradeService, transactionService, wsHandler, hub, leaderRequestService, accountService, transferService, accountRepo, userRepo, currencyService, deadLetterRepo, announcementService, adminService, webhookService, reconciliationService)
 
 	addr := fmt.Sprintf("%s:%d", cfg.Address, cfg.Port)
-	log.Printf("Starting server on http://%s", addr)
-
-	if err := r.Run(addr); err != nil {
+	if cfg.TLSEnabled() {
+		log.Printf("Starting server on https://%s", addr)
+		err = r.RunTLS(addr, cfg.TLSCertFile, cfg.TLSKeyFile)
+	} else {
+		log.Printf("Starting server on http://%s", addr)
+		err = r.Run(addr)
+	}
+	if err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
@@ -2,6 +2,7 @@ package interfaces
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -10,11 +11,22 @@ import (
 
 type TradeService interface {
 	PlaceTrade(userID, accountID, symbol, accountType string, tradeType models.TradeType, orderType string, leverage int, volume, entryPrice, stopLoss, takeProfit float64, expiration *time.Time) (*models.TradeHistory, TradeResponse, error)
-	CloseTrade(tradeID, userID, accountType, accountID string) (TradeResponse, error)
+	ConfirmRequote(ctx context.Context, tradeID, userID string) (*models.TradeHistory, TradeResponse, error)
+	ExpireRequotes(ctx context.Context) error
+	ExpirePendingOrders(ctx context.Context) error
+	CloseTrade(tradeID, userID string) (TradeResponse, error)
+	CancelPendingOrder(tradeID, userID string) (TradeResponse, error)
 	StreamTrades(userID, accountType string) (chan models.OrderStreamResponse, error)
-	GetTrade(id string) (*models.TradeHistory, error)
-	GetTradesByUserID(userID string) ([]*models.TradeHistory, error)
-	GetAllTrades() ([]*models.TradeHistory, error)
+	StopStream(userID, accountType string) error
+	TouchStream(userID, accountType string)
+	GetTrade(ctx context.Context, id string) (*models.TradeHistory, error)
+	GetTradesByUserID(ctx context.Context, userID, accountType string) ([]*models.TradeHistory, error)
+	GetTradesUpdatedSince(ctx context.Context, userID string, since time.Time) ([]*models.TradeHistory, error)
+	GetAllTrades(ctx context.Context, accountType string) ([]*models.TradeHistory, error)
+	GetSettlementReport(ctx context.Context, from, to time.Time, accountType string) (*models.SettlementReport, error)
+	ExportTradeHistory(ctx context.Context, userID string, from, to time.Time, emit func([]*models.TradeHistory) error) error
+	ActivatePendingOrders(price *models.PriceData) error
+	EvaluateRiskLimits(price *models.PriceData) error
 	HandleTradeResponse(response TradeResponse) error
 	HandleCloseTradeResponse(response TradeResponse) error
 	HandleOrderStreamResponse(response models.OrderStreamResponse) error
@@ -22,11 +34,66 @@ type TradeService interface {
 	HandleBalanceRequest(request map[string]interface{}) error
 	HandleBalanceResponse(request BalanceResponse) error
 	RequestBalance(userID, accountID, accountType string) (float64, error)
+	CachedBalance(userID, accountID, accountType string) (float64, time.Time, error)
+	AccountBalance(ctx context.Context, userID, accountID string, refresh bool) (*AccountBalance, error)
 	RegisterMT5Connection(conn *websocket.Conn)
 	ModifyTrade(ctx context.Context, userID, tradeID, accountType, accountID string, entryPrice, volume float64) (TradeResponse, error)
 	RegisterWallet(userID, accountID, walletID string) error // New method for wallet registration
+	InFlightTradeCount() int
+	PlaceTradeBatch(userID string, orders []TradeOrder, failFast bool) ([]BatchTradeResult, error)
+	CloseTradesBySymbol(userID, accountType, accountID, symbol string) (BulkCloseResult, error)
+	CloseTradesByDirection(userID, accountType, accountID string, tradeType models.TradeType) (BulkCloseResult, error)
+	CloseTradesByGroup(userID, accountType, accountID, symbol string, tradeType models.TradeType) (BulkCloseResult, error)
+	SetTradeMirror(mirror TradeMirror)
+	MT5Metrics() MT5Metrics
 }
 
+// TradeMirror copies a newly executed trade to the accounts following its owner.
+type TradeMirror interface {
+	MirrorTrade(leaderTrade *models.TradeHistory, accountType string) error
+}
+
+// EventPublisher forwards trade and account events to external subscribers.
+type EventPublisher interface {
+	Publish(event string, data interface{})
+}
+
+// MT5Transport delivers requests to the MT5 bridge. Replies arrive
+// asynchronously through the TradeService Handle* callbacks.
+type MT5Transport interface {
+	SendTradeRequest(request map[string]interface{}) error
+	SendCloseTradeRequest(request map[string]interface{}) error
+	SendOrderStreamRequest(request map[string]interface{}) error
+	SendBalanceRequest(request map[string]interface{}) error
+}
+
+// TradeOrder carries the parameters of a single order; AccountID is the account name.
+type TradeOrder struct {
+	AccountID   string
+	Symbol      string
+	AccountType string
+	TradeType   models.TradeType
+	OrderType   string
+	Leverage    int
+	Volume      float64
+	EntryPrice  float64
+	StopLoss    float64
+	TakeProfit  float64
+	Expiration  *time.Time
+}
+
+type BatchTradeResult struct {
+	Index int                  `json:"index"`
+	Trade *models.TradeHistory `json:"trade,omitempty"`
+	Error string               `json:"error,omitempty"`
+}
+
+// ErrUnknownTrade is returned when MT5 answers for a trade ID the platform
+// has no record of, a sign the two have drifted apart.
+var ErrUnknownTrade = errors.New("MT5 response for a trade unknown to the platform")
+
+// TradeResponse is MT5's answer to a trade or close request. Price is only
+// set on a requote, with the price MT5 is now offering.
 type TradeResponse struct {
 	TradeID        string  `json:"trade_id"`
 	UserID         string  `json:"user_id"`
@@ -39,6 +106,9 @@ type TradeResponse struct {
 	Status         string  `json:"status"`
 	ClosePrice     float64 `json:"close_price"`
 	CloseReason    string  `json:"close_reason"`
+	Commission     float64 `json:"commission"`
+	Swap           float64 `json:"swap"`
+	Price          float64 `json:"price,omitempty"`
 }
 
 type BalanceResponse struct {
@@ -50,3 +120,67 @@ type BalanceResponse struct {
 	Error       string  `json:"error,omitempty"`
 	Timestamp   float64 `json:"timestamp"`
 }
+
+// AccountBalance is an account's stored balance and, when a refresh was
+// asked for, the balance MT5 reported and when it was fetched. Cached is set
+// when that MT5 balance was reused from a recent refresh.
+type AccountBalance struct {
+	AccountID       string     `json:"account_id"`
+	AccountType     string     `json:"account_type"`
+	Currency        string     `json:"currency,omitempty"`
+	Balance         float64    `json:"balance"`
+	BrokerBalance   *float64   `json:"broker_balance,omitempty"`
+	BrokerCheckedAt *time.Time `json:"broker_checked_at,omitempty"`
+	Cached          bool       `json:"cached,omitempty"`
+}
+
+type CloseResult struct {
+	TradeID string `json:"trade_id"`
+	Status  string `json:"status,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type BulkCloseResult struct {
+	Requested int           `json:"requested"`
+	Closed    int           `json:"closed"`
+	Failed    int           `json:"failed"`
+	Results   []CloseResult `json:"results"`
+}
+
+// MT5RequestStats covers one kind of request correlated with an MT5 reply.
+// Latencies are over answered requests only.
+type MT5RequestStats struct {
+	Requests     int64   `json:"requests"`
+	Timeouts     int64   `json:"timeouts"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	MaxLatencyMs float64 `json:"max_latency_ms"`
+}
+
+// MT5Metrics reports MT5 response times since startup by request kind, the
+// timeout rate over the recent alerting window and the circuit breaker.
+type MT5Metrics struct {
+	ByKind               map[string]MT5RequestStats `json:"by_kind"`
+	WindowRequests       int                        `json:"window_requests"`
+	WindowTimeouts       int                        `json:"window_timeouts"`
+	WindowTimeoutPercent float64                    `json:"window_timeout_percent"`
+	Degraded             bool                       `json:"degraded"`
+	Circuit              MT5Circuit                 `json:"circuit"`
+}
+
+// MT5 circuit breaker states.
+const (
+	MT5CircuitClosed   = "closed"
+	MT5CircuitOpen     = "open"
+	MT5CircuitHalfOpen = "half_open"
+	MT5CircuitDisabled = "disabled"
+)
+
+// MT5Circuit is the state of the breaker in front of MT5. While it is open,
+// requests fail at once instead of waiting out a timeout; RetryAt is when the
+// next request will be let through to test the bridge.
+type MT5Circuit struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	RetryAt             *time.Time `json:"retry_at,omitempty"`
+}
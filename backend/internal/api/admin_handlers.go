@@ -1,6 +1,7 @@
 package api
 
 import (
+	"log"
 	"net/http"
 	"strconv"
 
@@ -11,20 +12,23 @@ import (
 	"github.com/mehrbod2002/fxtrader/internal/config"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AdminHandler struct {
-	adminRepo   repository.AdminRepository
-	cfg         *config.Config
-	userService service.UserService
+	adminRepo    repository.AdminRepository
+	cfg          *config.Config
+	userService  service.UserService
+	adminService service.AdminService
 }
 
-func NewAdminHandler(adminRepo repository.AdminRepository, cfg *config.Config, userService service.UserService) *AdminHandler {
+func NewAdminHandler(adminRepo repository.AdminRepository, cfg *config.Config, userService service.UserService, adminService service.AdminService) *AdminHandler {
 	return &AdminHandler{
-		adminRepo:   adminRepo,
-		cfg:         cfg,
-		userService: userService,
+		adminRepo:    adminRepo,
+		cfg:          cfg,
+		userService:  userService,
+		adminService: adminService,
 	}
 }
 
@@ -53,7 +57,7 @@ func (h *AdminHandler) UpdateUserActivation(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.GetUser(req.UserID)
+	user, err := h.userService.GetUser(c.Request.Context(), req.UserID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
@@ -63,7 +67,7 @@ func (h *AdminHandler) UpdateUserActivation(c *gin.Context) {
 		return
 	}
 
-	err = h.userService.ActiveUser(user.ID, req.IsActive)
+	err = h.userService.ActiveUser(c.Request.Context(), user.ID, req.IsActive)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user status"})
 		return
@@ -100,7 +104,7 @@ func (h *AdminHandler) AdminLogin(c *gin.Context) {
 		return
 	}
 
-	admin, err := h.adminRepo.GetAdminByUsername(req.Username)
+	admin, err := h.adminRepo.GetAdminByUsername(c.Request.Context(), req.Username)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve admin"})
 		return
@@ -169,7 +173,7 @@ func (h *AdminHandler) GetUserReferrals(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.GetUser(userIDStr)
+	user, err := h.userService.GetUser(c.Request.Context(), userIDStr)
 	if err != nil || user == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
@@ -186,7 +190,7 @@ func (h *AdminHandler) GetUserReferrals(c *gin.Context) {
 		return
 	}
 
-	referredUsers, _, err := h.userService.GetUsersReferredBy(user.ReferralCode, page, limit)
+	referredUsers, _, err := h.userService.GetUsersReferredBy(c.Request.Context(), user.ReferralCode, page, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch referred users"})
 		return
@@ -239,7 +243,7 @@ func (h *AdminHandler) GetAllReferrals(c *gin.Context) {
 		return
 	}
 
-	users, total, err := h.userService.GetAllReferrals(page, limit)
+	users, total, err := h.userService.GetAllReferrals(c.Request.Context(), page, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch referrals"})
 		return
@@ -247,7 +251,7 @@ func (h *AdminHandler) GetAllReferrals(c *gin.Context) {
 
 	responseUsers := make([]UserReferralResponse, len(users))
 	for i, user := range users {
-		referredUsers, _, err := h.userService.GetUsersReferredBy(user.ReferralCode, 1, 1000)
+		referredUsers, _, err := h.userService.GetUsersReferredBy(c.Request.Context(), user.ReferralCode, 1, 1000)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch referred users"})
 			return
@@ -277,3 +281,143 @@ func (h *AdminHandler) GetAllReferrals(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+type LiquidateAccountRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+// @Summary Liquidate an account
+// @Description Disables the account, cancels its pending orders and closes its open positions (admin only). The owner's user ID must be given to confirm the target.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param id path string true "Account ID"
+// @Param request body LiquidateAccountRequest true "Account owner and liquidation reason"
+// @Success 200 {object} service.LiquidationReport
+// @Failure 400 {object} map[string]string "Invalid JSON or ID"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Account not found"
+// @Failure 500 {object} map[string]string "Failed to liquidate account"
+// @Router /admin/accounts/{id}/liquidate [post]
+func (h *AdminHandler) LiquidateAccount(c *gin.Context) {
+	accountID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	var req LiquidateAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	report, err := h.adminService.LiquidateAccount(c.Request.Context(), accountID, userID, req.Reason)
+	if err != nil {
+		log.Printf("error: %v", err)
+		respondError(c, err, "Failed to liquidate account")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+type AccountLeverageRequest struct {
+	UserID      string `json:"user_id" binding:"required"`
+	MaxLeverage int    `json:"max_leverage"`
+}
+
+// @Summary Override an account's leverage limit
+// @Description Sets the highest leverage the account may use on any symbol, replacing the symbols' own limits (admin only). Symbol margin rates still apply. Zero removes the override.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param id path string true "Account ID"
+// @Param request body AccountLeverageRequest true "Account owner and leverage limit"
+// @Success 200 {object} map[string]string "Account leverage updated"
+// @Failure 400 {object} map[string]string "Invalid JSON, ID or leverage"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Account not found"
+// @Failure 500 {object} map[string]string "Failed to update account leverage"
+// @Router /admin/accounts/{id}/leverage [put]
+func (h *AdminHandler) SetAccountLeverage(c *gin.Context) {
+	accountID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	var req AccountLeverageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	if err := h.adminService.SetAccountMaxLeverage(c.Request.Context(), accountID, userID, req.MaxLeverage); err != nil {
+		log.Printf("error: %v", err)
+		respondError(c, err, "Failed to update account leverage")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account leverage updated"})
+}
+
+type AccountTierRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Tier   string `json:"tier" binding:"required"`
+}
+
+// @Summary Assign an account tier
+// @Description Moves the account to the BASIC, PRO or VIP tier, which sets the leverage range and commission its new orders get on each symbol (admin only)
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param id path string true "Account ID"
+// @Param request body AccountTierRequest true "Account owner and tier"
+// @Success 200 {object} map[string]string "Account tier updated"
+// @Failure 400 {object} map[string]string "Invalid JSON, ID or tier"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Account not found"
+// @Failure 500 {object} map[string]string "Failed to update account tier"
+// @Router /admin/accounts/{id}/tier [put]
+func (h *AdminHandler) SetAccountTier(c *gin.Context) {
+	accountID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	var req AccountTierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(req.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	tier, err := h.adminService.SetAccountTier(c.Request.Context(), accountID, userID, req.Tier)
+	if err != nil {
+		log.Printf("error: %v", err)
+		respondError(c, err, "Failed to update account tier")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account tier updated", "tier": string(tier)})
+}
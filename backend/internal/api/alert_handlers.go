@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
@@ -30,6 +31,7 @@ func NewAlertHandler(alertService service.AlertService, logService service.LogSe
 // @Success 201 {object} map[string]string "Alert created"
 // @Failure 400 {object} map[string]string "Invalid JSON or parameters"
 // @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 409 {object} map[string]interface{} "Pending alert limit reached"
 // @Failure 500 {object} map[string]string "Failed to create alert"
 // @Router /alerts [post]
 func (h *AlertHandler) CreateAlert(c *gin.Context) {
@@ -47,7 +49,12 @@ func (h *AlertHandler) CreateAlert(c *gin.Context) {
 		NotificationMethod: req.NotificationMethod,
 	}
 
-	if err := h.alertService.CreateAlert(userID, alert); err != nil {
+	if err := h.alertService.CreateAlert(c.Request.Context(), userID, alert); err != nil {
+		if errors.Is(err, service.ErrAlertLimitReached) {
+			quota, _ := h.alertService.GetAlertQuota(c.Request.Context(), userID)
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "quota": quota})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -75,7 +82,7 @@ func (h *AlertHandler) CreateAlert(c *gin.Context) {
 // @Router /alerts [get]
 func (h *AlertHandler) GetUserAlerts(c *gin.Context) {
 	userID := c.GetString("user_id")
-	alerts, err := h.alertService.GetAlertsByUserID(userID)
+	alerts, err := h.alertService.GetAlertsByUserID(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -92,6 +99,25 @@ func (h *AlertHandler) GetUserAlerts(c *gin.Context) {
 	c.JSON(http.StatusOK, alerts)
 }
 
+// @Summary Get alert quota
+// @Description Returns how many pending alerts the user has and the configured cap (0 means unlimited)
+// @Tags Alerts
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} service.AlertQuota
+// @Failure 500 {object} map[string]string "Failed to retrieve alert quota"
+// @Router /alerts/quota [get]
+func (h *AlertHandler) GetAlertQuota(c *gin.Context) {
+	userID := c.GetString("user_id")
+	quota, err := h.alertService.GetAlertQuota(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve alert quota"})
+		return
+	}
+
+	c.JSON(http.StatusOK, quota)
+}
+
 // @Summary Get alert by ID
 // @Description Retrieves details of a specific alert
 // @Tags Alerts
@@ -100,17 +126,15 @@ func (h *AlertHandler) GetUserAlerts(c *gin.Context) {
 // @Param id path string true "Alert ID"
 // @Success 200 {object} models.Alert
 // @Failure 400 {object} map[string]string "Invalid alert ID"
+// @Failure 403 {object} map[string]string "Alert belongs to another user"
 // @Failure 404 {object} map[string]string "Alert not found"
 // @Router /alerts/{id} [get]
 func (h *AlertHandler) GetAlert(c *gin.Context) {
 	alertID := c.Param("id")
-	alert, err := h.alertService.GetAlert(alertID)
+	userID := c.GetString("user_id")
+	alert, err := h.alertService.GetAlert(c.Request.Context(), alertID, userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
-		return
-	}
-	if alert == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+		respondAlertError(c, err)
 		return
 	}
 
@@ -124,6 +148,102 @@ func (h *AlertHandler) GetAlert(c *gin.Context) {
 	c.JSON(http.StatusOK, alert)
 }
 
+// @Summary Update an alert
+// @Description Replaces the symbol, condition and notification method of a pending alert owned by the user
+// @Tags Alerts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Alert ID"
+// @Param alert body AlertRequest true "Alert data"
+// @Success 200 {object} models.Alert
+// @Failure 400 {object} map[string]string "Invalid JSON or parameters"
+// @Failure 403 {object} map[string]string "Alert belongs to another user"
+// @Failure 404 {object} map[string]string "Alert not found"
+// @Failure 409 {object} map[string]string "Alert is no longer pending"
+// @Router /alerts/{id} [put]
+func (h *AlertHandler) UpdateAlert(c *gin.Context) {
+	var req AlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return
+	}
+
+	alertID := c.Param("id")
+	userID := c.GetString("user_id")
+	alert := &models.Alert{
+		SymbolName:         req.SymbolName,
+		AlertType:          req.AlertType,
+		Condition:          req.Condition,
+		NotificationMethod: req.NotificationMethod,
+	}
+	if err := h.alertService.UpdateAlert(c.Request.Context(), alertID, userID, alert); err != nil {
+		respondAlertError(c, err)
+		return
+	}
+
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+	metadata := map[string]interface{}{
+		"alert_id":    alertID,
+		"symbol_name": alert.SymbolName,
+		"alert_type":  alert.AlertType,
+	}
+	if err := h.logService.LogAction(userObjID, "UpdateAlert", "Alert updated", c.ClientIP(), metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+// @Summary Delete an alert
+// @Description Deletes a pending or triggered alert owned by the user
+// @Tags Alerts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Alert ID"
+// @Success 200 {object} map[string]string "Alert deleted"
+// @Failure 400 {object} map[string]string "Invalid alert ID"
+// @Failure 403 {object} map[string]string "Alert belongs to another user"
+// @Failure 404 {object} map[string]string "Alert not found"
+// @Router /alerts/{id} [delete]
+func (h *AlertHandler) DeleteAlert(c *gin.Context) {
+	alertID := c.Param("id")
+	userID := c.GetString("user_id")
+	if err := h.alertService.DeleteAlert(c.Request.Context(), alertID, userID); err != nil {
+		respondAlertError(c, err)
+		return
+	}
+
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+	metadata := map[string]interface{}{
+		"alert_id": alertID,
+	}
+	if err := h.logService.LogAction(userObjID, "DeleteAlert", "Alert deleted", c.ClientIP(), metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "Alert deleted"})
+}
+
+// respondAlertError maps the alert service's ownership errors to statuses;
+// anything else is a validation failure.
+func respondAlertError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidAlertID):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
+	case errors.Is(err, service.ErrAlertNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+	case errors.Is(err, service.ErrAlertForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden (alert belongs to another user)"})
+	case errors.Is(err, service.ErrAlertNotEditable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// AlertRequest.SymbolName is resolved like a trade's: broker or display name,
+// stored as the broker name that prices are published under.
 type AlertRequest struct {
 	SymbolName         string                `json:"symbol_name" binding:"required"`
 	AlertType          models.AlertType      `json:"alert_type" binding:"required,oneof=PRICE TIME"`
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+	"github.com/mehrbod2002/fxtrader/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AnnouncementHandler struct {
+	announcementService service.AnnouncementService
+	logService          service.LogService
+}
+
+func NewAnnouncementHandler(announcementService service.AnnouncementService, logService service.LogService) *AnnouncementHandler {
+	return &AnnouncementHandler{announcementService: announcementService, logService: logService}
+}
+
+type BroadcastRequest struct {
+	Message     string                      `json:"message" binding:"required"`
+	Severity    models.AnnouncementSeverity `json:"severity"`
+	Symbol      string                      `json:"symbol"`
+	ViaTelegram bool                        `json:"via_telegram"`
+}
+
+// @Summary Broadcast an announcement
+// @Description Pushes a platform message to all connected WebSocket/SSE clients, or only those subscribed to symbol when given, and optionally to every user over Telegram
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param announcement body BroadcastRequest true "Announcement"
+// @Success 200 {object} models.Announcement
+// @Failure 400 {object} map[string]string "Invalid JSON or parameters"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /admin/broadcast [post]
+func (h *AnnouncementHandler) Broadcast(c *gin.Context) {
+	var req BroadcastRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return
+	}
+
+	notice := &models.Announcement{
+		Message:  req.Message,
+		Severity: req.Severity,
+		Symbol:   req.Symbol,
+	}
+	if err := h.announcementService.Broadcast(notice, req.ViaTelegram); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminID, _ := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	metadata := map[string]interface{}{
+		"severity":     notice.Severity,
+		"symbol":       notice.Symbol,
+		"via_telegram": req.ViaTelegram,
+		"message":      notice.Message,
+	}
+	if err := h.logService.LogAction(adminID, "BroadcastAnnouncement", "Announcement broadcast", c.ClientIP(), metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+
+	c.JSON(http.StatusOK, notice)
+}
@@ -1,9 +1,11 @@
 package api
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
+	"github.com/mehrbod2002/fxtrader/internal/models"
 	"github.com/mehrbod2002/fxtrader/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -29,6 +31,7 @@ func NewCopyTradeHandler(copyTradeService service.CopyTradeService, logService s
 // @Success 201 {object} map[string]string "Subscription created"
 // @Failure 400 {object} map[string]string "Invalid JSON or parameters"
 // @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 409 {object} map[string]string "Leader has reached the follower limit"
 // @Failure 500 {object} map[string]string "Failed to create subscription"
 // @Router /copy-trades [post]
 func (h *CopyTradeHandler) CreateSubscription(c *gin.Context) {
@@ -39,8 +42,12 @@ func (h *CopyTradeHandler) CreateSubscription(c *gin.Context) {
 	}
 
 	followerID := c.GetString("user_id")
-	subscription, err := h.copyTradeService.CreateSubscription(followerID, req.LeaderID, req.AllocatedAmount, req.AccountType)
+	subscription, err := h.copyTradeService.CreateSubscription(c.Request.Context(), followerID, req.LeaderID, req.AllocatedAmount, req.AccountType, req.CopySettings)
 	if err != nil {
+		if errors.Is(err, service.ErrLeaderAtCapacity) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -67,7 +74,7 @@ func (h *CopyTradeHandler) CreateSubscription(c *gin.Context) {
 // @Failure 500 {object} map[string]string "Failed to retrieve subscriptions"
 // @Router /copy-trades-all [get]
 func (h *CopyTradeHandler) GetAllUserSubscriptions(c *gin.Context) {
-	subscriptions, err := h.copyTradeService.GetAllSubscriptions()
+	subscriptions, err := h.copyTradeService.GetAllSubscriptions(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -87,7 +94,7 @@ func (h *CopyTradeHandler) GetAllUserSubscriptions(c *gin.Context) {
 // @Router /copy-trades [get]
 func (h *CopyTradeHandler) GetUserSubscriptions(c *gin.Context) {
 	followerID := c.GetString("user_id")
-	subscriptions, err := h.copyTradeService.GetSubscriptionsByFollowerID(followerID)
+	subscriptions, err := h.copyTradeService.GetSubscriptionsByFollowerID(c.Request.Context(), followerID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -116,9 +123,9 @@ func (h *CopyTradeHandler) GetUserSubscriptions(c *gin.Context) {
 // @Router /copy-trades/{id} [get]
 func (h *CopyTradeHandler) GetSubscription(c *gin.Context) {
 	subscriptionID := c.Param("id")
-	subscription, err := h.copyTradeService.GetSubscription(subscriptionID)
+	subscription, err := h.copyTradeService.GetSubscription(c.Request.Context(), subscriptionID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		respondError(c, err, "Failed to retrieve subscription")
 		return
 	}
 	if subscription == nil {
@@ -136,8 +143,167 @@ func (h *CopyTradeHandler) GetSubscription(c *gin.Context) {
 	c.JSON(http.StatusOK, subscription)
 }
 
+// @Summary Get copy trade history
+// @Description Lists the trades mirrored under a subscription with both legs' status and P/L
+// @Tags CopyTrading
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Subscription ID"
+// @Success 200 {array} models.CopyTradeHistoryEntry
+// @Failure 400 {object} map[string]string "Invalid subscription ID"
+// @Failure 403 {object} map[string]string "Forbidden (subscription belongs to another user)"
+// @Failure 404 {object} map[string]string "Subscription not found"
+// @Failure 500 {object} map[string]string "Failed to retrieve copy trade history"
+// @Router /copy-trades/{id}/history [get]
+func (h *CopyTradeHandler) GetCopyTradeHistory(c *gin.Context) {
+	subscriptionID := c.Param("id")
+	userID := c.GetString("user_id")
+
+	history, err := h.copyTradeService.GetCopyTradeHistory(c.Request.Context(), subscriptionID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSubscriptionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		case errors.Is(err, service.ErrSubscriptionForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden (subscription belongs to another user)"})
+		case errors.Is(err, service.ErrInvalidSubscriptionID):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve copy trade history"})
+		}
+		return
+	}
+
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+	metadata := map[string]interface{}{
+		"subscription_id": subscriptionID,
+		"count":           len(history),
+	}
+	if err := h.logService.LogAction(userObjID, "GetCopyTradeHistory", "Copy trade history retrieved", c.ClientIP(), metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+type NotificationPreferenceRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
 type CopyTradeRequest struct {
 	LeaderID        string  `json:"leader_id" binding:"required"`
 	AccountType     string  `json:"account_type" binding:"required"`
 	AllocatedAmount float64 `json:"allocated_amount" binding:"required,gt=0"`
+	models.CopySettings
+}
+
+// @Summary Set copy trade notification preference
+// @Description Opts the authenticated user in or out of Telegram notifications about copy trading
+// @Tags CopyTrading
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param preference body NotificationPreferenceRequest true "Notification preference"
+// @Success 200 {object} map[string]interface{} "Preference updated"
+// @Failure 400 {object} map[string]string "Invalid JSON"
+// @Failure 500 {object} map[string]string "Failed to update preference"
+// @Router /copy-trades/notifications [put]
+func (h *CopyTradeHandler) SetNotificationPreference(c *gin.Context) {
+	var req NotificationPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	if err := h.copyTradeService.SetNotificationPreference(c.Request.Context(), userID, *req.Enabled); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update preference"})
+		return
+	}
+
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+	metadata := map[string]interface{}{
+		"user_id": userID,
+		"enabled": *req.Enabled,
+	}
+	if err := h.logService.LogAction(userObjID, "SetCopyTradeNotifications", "Copy trade notification preference updated", c.ClientIP(), metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "Preference updated", "enabled": *req.Enabled})
+}
+
+// @Summary Set copy trade follower limits
+// @Description Lets an approved leader set the minimum allocation and maximum number of active followers for new subscriptions. Zero disables a limit.
+// @Tags CopyTrading
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param limits body models.LeaderLimits true "Follower limits"
+// @Success 200 {object} models.LeaderLimits
+// @Failure 400 {object} map[string]string "Invalid JSON or limits"
+// @Failure 403 {object} map[string]string "Not an approved leader"
+// @Failure 500 {object} map[string]string "Failed to update limits"
+// @Router /copy-trades/leader-limits [put]
+func (h *CopyTradeHandler) SetLeaderLimits(c *gin.Context) {
+	var limits models.LeaderLimits
+	if err := c.ShouldBindJSON(&limits); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	if err := h.copyTradeService.SetLeaderLimits(c.Request.Context(), userID, limits); err != nil {
+		respondError(c, err, "Failed to update limits")
+		return
+	}
+
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+	metadata := map[string]interface{}{
+		"min_allocation": limits.MinAllocation,
+		"max_followers":  limits.MaxFollowers,
+	}
+	if err := h.logService.LogAction(userObjID, "SetLeaderLimits", "Copy trade follower limits updated", c.ClientIP(), metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+
+	c.JSON(http.StatusOK, limits)
+}
+
+type CancelSubscriptionRequest struct {
+	CloseTrades bool `json:"close_trades"`
+}
+
+// @Summary Cancel a copy trade subscription
+// @Description Stops copying the leader. With close_trades the follower's copied trades that are still open are closed and pending ones cancelled; otherwise they are kept.
+// @Tags CopyTrading
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Subscription ID"
+// @Param request body CancelSubscriptionRequest false "Whether to close copied trades"
+// @Success 200 {object} map[string]interface{} "Subscription cancelled, with the close results"
+// @Failure 400 {object} map[string]string "Invalid JSON or subscription ID"
+// @Failure 403 {object} map[string]string "Subscription belongs to another user"
+// @Failure 404 {object} map[string]string "Subscription not found"
+// @Failure 409 {object} map[string]string "Subscription already cancelled"
+// @Failure 500 {object} map[string]string "Failed to cancel subscription"
+// @Router /copy-trades/{id}/cancel [post]
+func (h *CopyTradeHandler) CancelSubscription(c *gin.Context) {
+	var req CancelSubscriptionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+			return
+		}
+	}
+
+	userID := c.GetString("user_id")
+	result, err := h.copyTradeService.CancelSubscription(c.Request.Context(), c.Param("id"), userID, req.CloseTrades)
+	if err != nil {
+		respondError(c, err, "Failed to cancel subscription")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "Subscription cancelled", "close_trades": req.CloseTrades, "trades": result})
 }
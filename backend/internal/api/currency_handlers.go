@@ -0,0 +1,81 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/mehrbod2002/fxtrader/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type CurrencyHandler struct {
+	currencyService service.CurrencyService
+	logService      service.LogService
+}
+
+func NewCurrencyHandler(currencyService service.CurrencyService, logService service.LogService) *CurrencyHandler {
+	return &CurrencyHandler{currencyService: currencyService, logService: logService}
+}
+
+type ExchangeRateRequest struct {
+	From string  `json:"from" binding:"required,len=3"`
+	To   string  `json:"to" binding:"required,len=3"`
+	Rate float64 `json:"rate" binding:"required,gt=0"`
+}
+
+// @Summary Get exchange rate overrides
+// @Description Retrieves the admin-set exchange rates used for currency conversion (admin only)
+// @Tags Currency
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {array} models.ExchangeRate
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Failed to retrieve exchange rates"
+// @Router /admin/currency-rates [get]
+func (h *CurrencyHandler) GetRates(c *gin.Context) {
+	rates, err := h.currencyService.GetRates(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve exchange rates"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"base_currency": h.currencyService.BaseCurrency(), "rates": rates})
+}
+
+// @Summary Set an exchange rate override
+// @Description Sets the conversion rate for a currency pair, overriding the configured provider (admin only)
+// @Tags Currency
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param rate body ExchangeRateRequest true "Exchange rate data"
+// @Success 200 {object} map[string]string "Exchange rate updated"
+// @Failure 400 {object} map[string]string "Invalid JSON or parameters"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /admin/currency-rates [put]
+func (h *CurrencyHandler) SetRate(c *gin.Context) {
+	var req ExchangeRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return
+	}
+
+	adminID := c.GetString("user_id")
+	if err := h.currencyService.SetRate(c.Request.Context(), req.From, req.To, req.Rate, adminID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminObjID, _ := primitive.ObjectIDFromHex(adminID)
+	metadata := map[string]interface{}{
+		"from": req.From,
+		"to":   req.To,
+		"rate": req.Rate,
+	}
+	if err := h.logService.LogAction(adminObjID, "SetExchangeRate", "Exchange rate override updated", c.ClientIP(), metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "Exchange rate updated"})
+}
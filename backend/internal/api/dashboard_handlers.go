@@ -0,0 +1,187 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/mehrbod2002/fxtrader/interfaces"
+	"github.com/mehrbod2002/fxtrader/internal/models"
+	"github.com/mehrbod2002/fxtrader/internal/service"
+	"github.com/mehrbod2002/fxtrader/internal/ws"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const dashboardRecentTransactions = 10
+
+type DashboardHandler struct {
+	userService        service.UserService
+	accountService     service.AccountService
+	tradeService       interfaces.TradeService
+	transactionService service.TransactionService
+	hub                *ws.Hub
+	logService         service.LogService
+}
+
+func NewDashboardHandler(
+	userService service.UserService,
+	accountService service.AccountService,
+	tradeService interfaces.TradeService,
+	transactionService service.TransactionService,
+	hub *ws.Hub,
+	logService service.LogService,
+) *DashboardHandler {
+	return &DashboardHandler{
+		userService:        userService,
+		accountService:     accountService,
+		tradeService:       tradeService,
+		transactionService: transactionService,
+		hub:                hub,
+		logService:         logService,
+	}
+}
+
+// DashboardResponse bundles what the app needs on launch. A section that
+// failed to load is left empty and its error is reported under Errors, so
+// one slow or broken dependency doesn't blank the whole screen.
+type DashboardResponse struct {
+	Profile            *models.User          `json:"profile"`
+	Accounts           []DashboardAccount    `json:"accounts"`
+	OpenPositions      []DashboardPosition   `json:"open_positions"`
+	RecentTransactions []*models.Transaction `json:"recent_transactions"`
+	Prices             []*models.PriceData   `json:"prices"`
+	Errors             map[string]string     `json:"errors,omitempty"`
+}
+
+// DashboardAccount adds live figures to an account. UsedMargin is the margin
+// held by open positions, which is already deducted from Balance.
+type DashboardAccount struct {
+	*models.Account
+	UsedMargin     float64 `json:"used_margin"`
+	FloatingProfit float64 `json:"floating_profit"`
+	Equity         float64 `json:"equity"`
+}
+
+// DashboardPosition is an open trade marked to the last known price. Current
+// price and floating profit are zero when no tick has been seen for the symbol.
+type DashboardPosition struct {
+	*models.TradeHistory
+	CurrentPrice   float64 `json:"current_price"`
+	FloatingProfit float64 `json:"floating_profit"`
+}
+
+// @Summary User dashboard
+// @Description Returns profile, accounts with equity, open positions with floating P/L, recent transactions and watchlist prices in one call
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param symbols query string false "Comma-separated watchlist symbols; defaults to the symbols of open positions"
+// @Success 200 {object} DashboardResponse
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /dashboard [get]
+func (h *DashboardHandler) GetDashboard(c *gin.Context) {
+	userID := c.GetString("user_id")
+	response := DashboardResponse{Errors: make(map[string]string)}
+
+	profile, err := h.userService.GetUser(c.Request.Context(), userID)
+	if err != nil || profile == nil {
+		response.Errors["profile"] = "Failed to retrieve profile"
+	}
+	response.Profile = profile
+
+	var positions []DashboardPosition
+	trades, err := h.tradeService.GetTradesByUserID(c.Request.Context(), userID, "")
+	if err != nil {
+		response.Errors["open_positions"] = "Failed to retrieve open positions"
+	}
+	for _, trade := range trades {
+		if trade.Status != string(models.TradeStatusOpen) {
+			continue
+		}
+		positions = append(positions, h.markPosition(trade))
+	}
+	response.OpenPositions = positions
+
+	accounts, err := h.accountService.GetAccountsByUserID(c.Request.Context(), userID)
+	if err != nil {
+		response.Errors["accounts"] = "Failed to retrieve accounts"
+	}
+	for _, account := range accounts {
+		entry := DashboardAccount{Account: account}
+		for _, position := range positions {
+			if position.AccountID != account.ID {
+				continue
+			}
+			entry.UsedMargin += position.Margin()
+			entry.FloatingProfit += position.FloatingProfit
+		}
+		entry.Equity = account.Balance + entry.UsedMargin + entry.FloatingProfit
+		response.Accounts = append(response.Accounts, entry)
+	}
+
+	transactions, err := h.transactionService.GetTransactionsByUserID(c.Request.Context(), userID)
+	if err != nil {
+		response.Errors["recent_transactions"] = "Failed to retrieve transactions"
+	}
+	if len(transactions) > dashboardRecentTransactions {
+		transactions = transactions[:dashboardRecentTransactions]
+	}
+	response.RecentTransactions = transactions
+
+	for _, symbol := range h.watchlist(c.Query("symbols"), positions) {
+		if price, ok := h.hub.LastPrice(symbol); ok {
+			response.Prices = append(response.Prices, price)
+		}
+	}
+
+	if len(response.Errors) == 0 {
+		response.Errors = nil
+	}
+
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+	metadata := map[string]interface{}{
+		"user_id":        userID,
+		"open_positions": len(positions),
+		"failed":         len(response.Errors),
+	}
+	if err := h.logService.LogAction(userObjID, "GetDashboard", "Dashboard retrieved", c.ClientIP(), metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// markPosition values a trade at the side of the book it would close on.
+func (h *DashboardHandler) markPosition(trade *models.TradeHistory) DashboardPosition {
+	position := DashboardPosition{TradeHistory: trade}
+	price, ok := h.hub.LastPrice(trade.Symbol)
+	if !ok {
+		return position
+	}
+	position.CurrentPrice, position.FloatingProfit = trade.MarkToMarket(price)
+	return position
+}
+
+func (h *DashboardHandler) watchlist(raw string, positions []DashboardPosition) []string {
+	seen := make(map[string]bool)
+	var symbols []string
+	add := func(symbol string) {
+		symbol = strings.TrimSpace(symbol)
+		if symbol != "" && !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	if raw != "" {
+		for _, symbol := range strings.Split(raw, ",") {
+			add(symbol)
+		}
+		return symbols
+	}
+	for _, position := range positions {
+		add(position.Symbol)
+	}
+	return symbols
+}
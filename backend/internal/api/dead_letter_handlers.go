@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+	"github.com/mehrbod2002/fxtrader/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeadLetterHandler struct {
+	deadLetterRepo repository.DeadLetterRepository
+}
+
+func NewDeadLetterHandler(deadLetterRepo repository.DeadLetterRepository) *DeadLetterHandler {
+	return &DeadLetterHandler{deadLetterRepo: deadLetterRepo}
+}
+
+type PaginatedDeadLettersResponse struct {
+	DeadLetters []*models.DeadLetter `json:"dead_letters"`
+	Total       int64                `json:"total"`
+	Page        int64                `json:"page"`
+	Limit       int64                `json:"limit"`
+	TotalPages  int64                `json:"total_pages"`
+}
+
+// @Summary Get MT5 dead letters
+// @Description Lists MT5 bridge messages that failed processing, newest first, optionally only those with a given reason such as ORPHAN_RESPONSE (admin only)
+// @Tags Admin
+// @Produce json
+// @Security BasicAuth
+// @Param reason query string false "Only dead letters with this reason, e.g. ORPHAN_RESPONSE"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Number of entries per page (default 50)"
+// @Success 200 {object} PaginatedDeadLettersResponse
+// @Failure 400 {object} map[string]string "Invalid pagination parameters"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Failed to retrieve dead letters"
+// @Router /admin/mt5/deadletters [get]
+func (h *DeadLetterHandler) GetDeadLetters(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit value"})
+		return
+	}
+
+	reason := strings.ToUpper(strings.TrimSpace(c.Query("reason")))
+	letters, total, err := h.deadLetterRepo.GetDeadLetters(c.Request.Context(), reason, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve dead letters"})
+		return
+	}
+
+	c.JSON(http.StatusOK, PaginatedDeadLettersResponse{
+		DeadLetters: letters,
+		Total:       total,
+		Page:        int64(page),
+		Limit:       int64(limit),
+		TotalPages:  (total + int64(limit) - 1) / int64(limit),
+	})
+}
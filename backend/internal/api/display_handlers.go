@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/mehrbod2002/fxtrader/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DisplayHandler struct {
+	symbolService   service.SymbolService
+	currencyService service.CurrencyService
+	moneyDecimals   int
+}
+
+func NewDisplayHandler(symbolService service.SymbolService, currencyService service.CurrencyService, moneyDecimals int) *DisplayHandler {
+	return &DisplayHandler{symbolService: symbolService, currencyService: currencyService, moneyDecimals: moneyDecimals}
+}
+
+// DisplayConfig tells clients how to render money and prices. Balances and
+// P/L are in Currency with MoneyDecimals places; a symbol's prices use the
+// digits listed for it, and symbols without configured digits are omitted.
+type DisplayConfig struct {
+	Currency      string         `json:"currency"`
+	MoneyDecimals int            `json:"money_decimals"`
+	SymbolDigits  map[string]int `json:"symbol_digits"`
+}
+
+// @Summary Get display configuration
+// @Description Returns the account currency, the decimals used for money amounts and the price digits of each symbol
+// @Tags Config
+// @Produce json
+// @Success 200 {object} DisplayConfig
+// @Failure 500 {object} map[string]string "Failed to retrieve display configuration"
+// @Router /config/display [get]
+func (h *DisplayHandler) GetDisplayConfig(c *gin.Context) {
+	symbols, err := h.symbolService.GetAllSymbols(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve display configuration"})
+		return
+	}
+
+	digits := make(map[string]int, len(symbols))
+	for _, symbol := range symbols {
+		if symbol.Digits > 0 {
+			digits[symbol.SymbolName] = symbol.Digits
+		}
+	}
+
+	c.JSON(http.StatusOK, DisplayConfig{
+		Currency:      h.currencyService.BaseCurrency(),
+		MoneyDecimals: h.moneyDecimals,
+		SymbolDigits:  digits,
+	})
+}
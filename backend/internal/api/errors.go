@@ -0,0 +1,42 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/mehrbod2002/fxtrader/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusForError maps a service error to the HTTP status of its kind. Errors
+// the service layer did not classify are internal failures.
+func statusForError(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidInput):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrForbidden), errors.Is(err, service.ErrInsufficientBalance):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrTimeout):
+		return http.StatusRequestTimeout
+	case errors.Is(err, service.ErrUnavailable):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// respondError writes err with the status of its kind. A classified error's
+// message is meant for the client; an internal one is replaced by fallback.
+func respondError(c *gin.Context, err error, fallback string) {
+	status := statusForError(err)
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		message = fallback
+	}
+	c.JSON(status, gin.H{"error": message})
+}
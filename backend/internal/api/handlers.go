@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
@@ -25,9 +26,12 @@ func NewPriceHandler(priceService service.PriceService, logService service.LogSe
 // @Tags Prices
 // @Accept json
 // @Produce json
+// @Param X-API-Key header string true "Price feed API key"
 // @Param priceData body models.PriceData true "Price data"
 // @Success 200 {object} map[string]string "Price received"
-// @Failure 400 {object} map[string]string "Invalid JSON"
+// @Failure 400 {object} map[string]string "Invalid JSON or bid/ask"
+// @Failure 401 {object} map[string]string "Invalid or missing API key"
+// @Failure 422 {object} map[string]string "Price rejected as an outlier"
 // @Failure 500 {object} map[string]string "Failed to process price"
 // @Router /prices [post]
 func (h *PriceHandler) HandlePrice(c *gin.Context) {
@@ -38,6 +42,14 @@ func (h *PriceHandler) HandlePrice(c *gin.Context) {
 	}
 
 	if err := h.priceService.ProcessPrice(&priceData); err != nil {
+		if errors.Is(err, service.ErrInvalidPrice) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrPriceOutlier) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process price"})
 		return
 	}
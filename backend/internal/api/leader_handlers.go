@@ -1,9 +1,12 @@
 package api
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 
+	"github.com/mehrbod2002/fxtrader/internal/models"
 	"github.com/mehrbod2002/fxtrader/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -37,6 +40,8 @@ type ManageLeaderRequest struct {
 // @Success 201 {object} map[string]string "Leader request created"
 // @Failure 400 {object} map[string]string "Invalid JSON or parameters"
 // @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Eligibility criteria not met"
+// @Failure 409 {object} map[string]string "A leader request is already pending"
 // @Failure 500 {object} map[string]string "Failed to create leader request"
 // @Router /leader-requests [post]
 func (h *LeaderRequestHandler) CreateLeaderRequest(c *gin.Context) {
@@ -47,8 +52,17 @@ func (h *LeaderRequestHandler) CreateLeaderRequest(c *gin.Context) {
 	}
 
 	userID := c.GetString("user_id")
-	request, err := h.leaderRequestService.CreateLeaderRequest(userID, req.Reason)
+	request, err := h.leaderRequestService.CreateLeaderRequest(c.Request.Context(), userID, req.Reason)
 	if err != nil {
+		var ineligible *service.LeaderEligibilityError
+		if errors.As(err, &ineligible) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not eligible to become a leader", "unmet_criteria": ineligible.Unmet})
+			return
+		}
+		if errors.Is(err, service.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -64,6 +78,29 @@ func (h *LeaderRequestHandler) CreateLeaderRequest(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{"status": "Leader request created", "request_id": request.ID.Hex()})
 }
 
+// @Summary Get my leader requests
+// @Description Retrieves the caller's leader requests, newest first, with their status and the admin's reason
+// @Tags CopyTrading
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.LeaderRequest
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Failed to retrieve leader requests"
+// @Router /leader-requests/me [get]
+func (h *LeaderRequestHandler) GetMyLeaderRequests(c *gin.Context) {
+	userID := c.GetString("user_id")
+	requests, err := h.leaderRequestService.GetUserLeaderRequests(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve leader requests"})
+		return
+	}
+	if requests == nil {
+		requests = []*models.LeaderRequest{}
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
 // @Summary Approve a leader request
 // @Description Allows an admin to approve a leader request
 // @Tags CopyTrading
@@ -90,7 +127,7 @@ func (h *LeaderRequestHandler) ApproveLeaderRequest(c *gin.Context) {
 	}
 
 	requestID := c.Param("id")
-	err := h.leaderRequestService.ApproveLeaderRequest(requestID, req.AdminReason)
+	err := h.leaderRequestService.ApproveLeaderRequest(c.Request.Context(), requestID, req.AdminReason)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -132,7 +169,7 @@ func (h *LeaderRequestHandler) DenyLeaderRequest(c *gin.Context) {
 	}
 
 	requestID := c.Param("id")
-	err := h.leaderRequestService.DenyLeaderRequest(requestID, req.AdminReason)
+	err := h.leaderRequestService.DenyLeaderRequest(c.Request.Context(), requestID, req.AdminReason)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -163,7 +200,7 @@ func (h *LeaderRequestHandler) GetPendingLeaderRequests(c *gin.Context) {
 		return
 	}
 
-	requests, err := h.leaderRequestService.GetPendingLeaderRequests()
+	requests, err := h.leaderRequestService.GetPendingLeaderRequests(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -184,11 +221,25 @@ func (h *LeaderRequestHandler) GetPendingLeaderRequests(c *gin.Context) {
 // @Tags CopyTrading
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {array} models.User
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Number of entries per page (default 50)"
+// @Success 200 {object} PaginatedUsersResponse
+// @Failure 400 {object} map[string]string "Invalid pagination parameters"
 // @Failure 500 {object} map[string]string "Failed to retrieve leaders"
 // @Router /copy-trade-leaders [get]
 func (h *LeaderRequestHandler) GetApprovedLeaders(c *gin.Context) {
-	leaders, err := h.leaderRequestService.GetApprovedLeaders()
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit value"})
+		return
+	}
+
+	leaders, total, err := h.leaderRequestService.GetApprovedLeaders(c.Request.Context(), page, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -196,10 +247,50 @@ func (h *LeaderRequestHandler) GetApprovedLeaders(c *gin.Context) {
 
 	metadata := map[string]interface{}{
 		"count": len(leaders),
+		"total": total,
 	}
 	if err := h.logService.LogAction(primitive.ObjectID{}, "GetApprovedLeaders", "Approved leaders retrieved", c.ClientIP(), metadata); err != nil {
 		log.Printf("error: %v", err)
 	}
 
-	c.JSON(http.StatusOK, leaders)
+	c.JSON(http.StatusOK, PaginatedUsersResponse{
+		Users:      leaders,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: (total + limit - 1) / limit,
+	})
+}
+
+// @Summary Revoke a copy trade leader
+// @Description Allows an admin to withdraw a user's leader status; all subscriptions following them are paused
+// @Tags CopyTrading
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Leader user ID"
+// @Param request body ManageLeaderRequest true "Admin reason"
+// @Success 200 {object} map[string]string "Leader revoked"
+// @Failure 400 {object} map[string]string "Invalid JSON or parameters"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /admin/copy-trade-leaders/{id}/revoke [post]
+func (h *LeaderRequestHandler) RevokeLeader(c *gin.Context) {
+	if !c.GetBool("is_admin") {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	var req ManageLeaderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return
+	}
+
+	userID := c.Param("id")
+	if err := h.leaderRequestService.RevokeLeader(c.Request.Context(), userID, req.AdminReason); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "Leader revoked"})
 }
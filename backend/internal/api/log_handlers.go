@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/mehrbod2002/fxtrader/internal/models"
 	"github.com/mehrbod2002/fxtrader/internal/service"
 
 	"github.com/gin-gonic/gin"
@@ -17,6 +18,24 @@ func NewLogHandler(logService service.LogService) *LogHandler {
 	return &LogHandler{logService: logService}
 }
 
+type PaginatedLogsResponse struct {
+	Logs       []*models.LogEntry `json:"logs"`
+	Total      int64              `json:"total"`
+	Page       int64              `json:"page"`
+	Limit      int64              `json:"limit"`
+	TotalPages int64              `json:"total_pages"`
+}
+
+func newPaginatedLogsResponse(logs []*models.LogEntry, total int64, page, limit int) PaginatedLogsResponse {
+	return PaginatedLogsResponse{
+		Logs:       logs,
+		Total:      total,
+		Page:       int64(page),
+		Limit:      int64(limit),
+		TotalPages: (total + int64(limit) - 1) / int64(limit),
+	}
+}
+
 // @Summary Get all logs
 // @Description Retrieves a paginated list of all system logs (admin only)
 // @Tags Logs
@@ -24,7 +43,7 @@ func NewLogHandler(logService service.LogService) *LogHandler {
 // @Security BasicAuth
 // @Param page query int false "Page number (default 1)"
 // @Param limit query int false "Number of logs per page (default 100)"
-// @Success 200 {array} models.LogEntry
+// @Success 200 {object} PaginatedLogsResponse
 // @Failure 400 {object} map[string]string "Invalid pagination parameters"
 // @Failure 401 {object} map[string]string "Unauthorized"
 // @Failure 500 {object} map[string]string "Failed to retrieve logs"
@@ -41,12 +60,12 @@ func (h *LogHandler) GetAllLogs(c *gin.Context) {
 		return
 	}
 
-	logs, err := h.logService.GetAllLogs(page, limit)
+	logs, total, err := h.logService.GetAllLogs(c.Request.Context(), page, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve logs"})
 		return
 	}
-	c.JSON(http.StatusOK, logs)
+	c.JSON(http.StatusOK, newPaginatedLogsResponse(logs, total, page, limit))
 }
 
 // @Summary Get logs by user ID
@@ -57,7 +76,7 @@ func (h *LogHandler) GetAllLogs(c *gin.Context) {
 // @Param user_id path string true "User ID"
 // @Param page query int false "Page number (default 1)"
 // @Param limit query int false "Number of logs per page (default 100)"
-// @Success 200 {array} models.LogEntry
+// @Success 200 {object} PaginatedLogsResponse
 // @Failure 400 {object} map[string]string "Invalid user ID or pagination parameters"
 // @Failure 401 {object} map[string]string "Unauthorized"
 // @Router /admin/logs/user/{user_id} [get]
@@ -74,10 +93,10 @@ func (h *LogHandler) GetLogsByUser(c *gin.Context) {
 		return
 	}
 
-	logs, err := h.logService.GetLogsByUserID(userID, page, limit)
+	logs, total, err := h.logService.GetLogsByUserID(c.Request.Context(), userID, page, limit)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
-	c.JSON(http.StatusOK, logs)
+	c.JSON(http.StatusOK, newPaginatedLogsResponse(logs, total, page, limit))
 }
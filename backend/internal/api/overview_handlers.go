@@ -54,20 +54,19 @@ func (h *OverviewHandler) GetOverview(c *gin.Context) {
 		return
 	}
 
-	users, err := h.userService.GetAllUsers()
+	userCount, err := h.userService.CountUsers(c.Request.Context(), models.UserFilter{})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user data"})
 		return
 	}
-	userCount := len(users)
 
-	trades, err := h.tradeService.GetAllTrades()
+	trades, err := h.tradeService.GetAllTrades(c.Request.Context(), "")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve trade data"})
 		return
 	}
 
-	allSymbols, err := h.symbolService.GetAllSymbols()
+	allSymbols, err := h.symbolService.GetAllSymbols(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve symbbols"})
 		return
@@ -76,14 +75,29 @@ func (h *OverviewHandler) GetOverview(c *gin.Context) {
 	totalTrades := len(trades)
 	pendingTrades := 0
 	symbolCounts := make(map[string]int)
+	byAccountType := map[string]*AccountTypeStats{models.AccountTypeDemo: {}, models.AccountTypeReal: {}}
 	for _, trade := range trades {
 		if trade.Status == string(models.TradeStatusPending) {
 			pendingTrades++
 		}
 		symbolCounts[trade.Symbol]++
+
+		accountType := models.NormalizeAccountType(trade.AccountType)
+		stats, ok := byAccountType[accountType]
+		if !ok {
+			stats = &AccountTypeStats{}
+			byAccountType[accountType] = stats
+		}
+		stats.TradeCount++
+		if trade.Status == string(models.TradeStatusPending) {
+			stats.PendingTrades++
+		}
+		if trade.Status == string(models.TradeStatusOpen) || trade.Status == string(models.TradeStatusClosed) {
+			stats.Volume += trade.Volume
+		}
 	}
 
-	transactions, err := h.transactionService.GetAllTransactions()
+	transactions, err := h.transactionService.GetAllTransactions(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve transaction data"})
 		return
@@ -121,6 +135,7 @@ func (h *OverviewHandler) GetOverview(c *gin.Context) {
 		PendingTransactions: pendingTransactions,
 		TopSymbols:          topSymbols,
 		Symbols:             len(allSymbols),
+		ByAccountType:       byAccountType,
 	}
 
 	adminID := c.GetString("user_id")
@@ -141,13 +156,24 @@ func (h *OverviewHandler) GetOverview(c *gin.Context) {
 }
 
 type OverviewResponse struct {
-	UserCount           int           `json:"user_count"`
+	UserCount           int64         `json:"user_count"`
 	TotalTrades         int           `json:"total_trades"`
 	PendingTrades       int           `json:"pending_trades"`
 	TotalTransactions   int           `json:"total_transactions"`
 	PendingTransactions int           `json:"pending_transactions"`
 	TopSymbols          []SymbolUsage `json:"top_symbols"`
 	Symbols             int           `json:"symbols"`
+	// ByAccountType splits trade activity into demo and real so virtual
+	// trading doesn't inflate the platform's real figures.
+	ByAccountType map[string]*AccountTypeStats `json:"by_account_type"`
+}
+
+// AccountTypeStats counts the trades of one account type. Volume only
+// includes trades that actually executed.
+type AccountTypeStats struct {
+	TradeCount    int     `json:"trade_count"`
+	PendingTrades int     `json:"pending_trades"`
+	Volume        float64 `json:"volume"`
 }
 
 type SymbolUsage struct {
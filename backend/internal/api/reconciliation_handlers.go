@@ -0,0 +1,134 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+	"github.com/mehrbod2002/fxtrader/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReconciliationHandler struct {
+	reconciliationService service.ReconciliationService
+}
+
+func NewReconciliationHandler(reconciliationService service.ReconciliationService) *ReconciliationHandler {
+	return &ReconciliationHandler{reconciliationService: reconciliationService}
+}
+
+type PaginatedBalanceDiscrepanciesResponse struct {
+	Discrepancies []*models.BalanceDiscrepancy `json:"discrepancies"`
+	Total         int64                        `json:"total"`
+	Page          int64                        `json:"page"`
+	Limit         int64                        `json:"limit"`
+	TotalPages    int64                        `json:"total_pages"`
+}
+
+type ReviewDiscrepancyRequest struct {
+	Note string `json:"note" binding:"required"`
+}
+
+// @Summary Reconcile balances against MT5
+// @Description Requests the MT5 balance of every account with a registered wallet and records the accounts that differ from the platform. Balances are not changed (admin only).
+// @Tags Admin
+// @Produce json
+// @Security BasicAuth
+// @Success 200 {object} service.BalanceReconciliationReport
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 409 {object} map[string]string "A reconciliation is already running"
+// @Failure 500 {object} map[string]string "Failed to reconcile balances"
+// @Router /admin/reconciliation/balances [post]
+func (h *ReconciliationHandler) ReconcileBalances(c *gin.Context) {
+	report, err := h.reconciliationService.ReconcileBalances(c.Request.Context())
+	if err != nil {
+		log.Printf("error: %v", err)
+		respondError(c, err, "Failed to reconcile balances")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Get balance discrepancies
+// @Description Lists balance discrepancies found by reconciliation, newest first (admin only)
+// @Tags Admin
+// @Produce json
+// @Security BasicAuth
+// @Param flagged query bool false "Only discrepancies above the review threshold"
+// @Param open query bool false "Only discrepancies not yet reviewed"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Number of entries per page (default 50)"
+// @Success 200 {object} PaginatedBalanceDiscrepanciesResponse
+// @Failure 400 {object} map[string]string "Invalid filter or pagination parameters"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Failed to retrieve balance discrepancies"
+// @Router /admin/reconciliation/discrepancies [get]
+func (h *ReconciliationHandler) GetDiscrepancies(c *gin.Context) {
+	flagged, err := strconv.ParseBool(c.DefaultQuery("flagged", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flagged value"})
+		return
+	}
+	open, err := strconv.ParseBool(c.DefaultQuery("open", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid open value"})
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit value"})
+		return
+	}
+
+	discrepancies, total, err := h.reconciliationService.GetDiscrepancies(c.Request.Context(), flagged, open, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve balance discrepancies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, PaginatedBalanceDiscrepanciesResponse{
+		Discrepancies: discrepancies,
+		Total:         total,
+		Page:          int64(page),
+		Limit:         int64(limit),
+		TotalPages:    (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// @Summary Review a balance discrepancy
+// @Description Marks a discrepancy as reviewed with a note. Any correction to the balance is made separately (admin only).
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param id path string true "Discrepancy ID"
+// @Param request body ReviewDiscrepancyRequest true "Review note"
+// @Success 200 {object} models.BalanceDiscrepancy
+// @Failure 400 {object} map[string]string "Invalid JSON, ID or note"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Discrepancy not found"
+// @Failure 500 {object} map[string]string "Failed to review discrepancy"
+// @Router /admin/reconciliation/discrepancies/{id}/review [post]
+func (h *ReconciliationHandler) ReviewDiscrepancy(c *gin.Context) {
+	var req ReviewDiscrepancyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return
+	}
+
+	discrepancy, err := h.reconciliationService.ReviewDiscrepancy(c.Request.Context(), c.Param("id"), req.Note)
+	if err != nil {
+		respondError(c, err, "Failed to review discrepancy")
+		return
+	}
+
+	c.JSON(http.StatusOK, discrepancy)
+}
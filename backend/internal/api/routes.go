@@ -38,9 +38,31 @@ func SetupRoutes(
 	transferService service.TransferService,
 	accountRepository repository.AccountRepository,
 	userRepository repository.UserRepository,
+	currencyService service.CurrencyService,
+	deadLetterRepository repository.DeadLetterRepository,
+	announcementService service.AnnouncementService,
+	adminService service.AdminService,
+	webhookService service.WebhookService,
+	reconciliationService service.ReconciliationService,
 ) {
 	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{"status": "healthy"})
+		// The server itself is up either way; an open MT5 circuit means trading
+		// requests are being refused until the bridge answers again.
+		circuit := tradeService.MT5Metrics().Circuit
+		status := "healthy"
+		if circuit.State == interfaces.MT5CircuitOpen {
+			status = "degraded"
+		}
+		c.JSON(200, gin.H{"status": status, "in_flight_trades": tradeService.InFlightTradeCount(), "mt5_circuit": circuit})
+	})
+
+	r.GET("/metrics", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"in_flight_trades":     tradeService.InFlightTradeCount(),
+			"max_in_flight_trades": cfg.MaxInFlightTrades,
+			"ws_clients":           hub.GetClientCount(),
+			"mt5":                  tradeService.MT5Metrics(),
+		})
 	})
 
 	r.Use(cors.New(cors.Config{
@@ -56,13 +78,20 @@ func SetupRoutes(
 	symbolHandler := NewSymbolHandler(symbolService, logService)
 	logHandler := NewLogHandler(logService)
 	overviewHandler := NewOverviewHandler(userService, tradeService, transactionService, symbolService, logService)
+	dashboardHandler := NewDashboardHandler(userService, accountService, tradeService, transactionService, hub, logService)
 	ruleHandler := NewRuleHandler(ruleService)
 	tradeHandler := NewTradeHandler(tradeService, logService, hub)
 	transactionHandler := NewTransactionHandler(transactionService, logService, userRepository)
-	adminHandler := NewAdminHandler(adminRepo, cfg, userService)
+	adminHandler := NewAdminHandler(adminRepo, cfg, userService, adminService)
 	alertHandler := NewAlertHandler(alertService, logService)
 	copyTradeHandler := NewCopyTradeHandler(copyTradeService, logService)
 	leaderRequestHandler := NewLeaderRequestHandler(leaderRequestService, logService)
+	currencyHandler := NewCurrencyHandler(currencyService, logService)
+	displayHandler := NewDisplayHandler(symbolService, currencyService, cfg.MoneyDecimals)
+	deadLetterHandler := NewDeadLetterHandler(deadLetterRepository)
+	webhookHandler := NewWebhookHandler(webhookService)
+	reconciliationHandler := NewReconciliationHandler(reconciliationService)
+	announcementHandler := NewAnnouncementHandler(announcementService, logService)
 
 	wd, err := os.Getwd()
 	if err != nil {
@@ -92,38 +121,57 @@ func SetupRoutes(
 
 	v1 := r.Group("/api/v1")
 	{
-		v1.POST("/prices", priceHandler.HandlePrice)
+		v1.POST("/prices", middleware.APIKeyMiddleware(cfg.PriceFeedAPIKey), priceHandler.HandlePrice)
+		v1.GET("/prices/stream", wsHandler.HandlePriceStream)
 		v1.POST("/users/signup", userHandler.SignupUser)
 		v1.GET("/users/me/:id", userHandler.GetMe)
 		v1.POST("/users/login", userHandler.Login)
 		v1.GET("/users/:id", middleware.UserAuthMiddleware(userService), userHandler.GetUser)
 		v1.GET("/symbols", symbolHandler.GetAllSymbols)
+		v1.GET("/symbols/search", symbolHandler.SearchSymbols)
 		v1.GET("/symbols/:id", symbolHandler.GetSymbol)
 		v1.GET("/rules", ruleHandler.GetAllRules)
+		v1.GET("/config/display", displayHandler.GetDisplayConfig)
 		v1.POST("/admin/login", adminHandler.AdminLogin)
 		v1.POST("/leader-requests", middleware.UserAuthMiddleware(userService), leaderRequestHandler.CreateLeaderRequest)
+		v1.GET("/leader-requests/me", middleware.UserAuthMiddleware(userService), leaderRequestHandler.GetMyLeaderRequests)
 		v1.GET("/copy-trade-leaders", middleware.UserAuthMiddleware(userService), leaderRequestHandler.GetApprovedLeaders)
 		v1.GET("/referrals", middleware.UserAuthMiddleware(userService), adminHandler.GetUserReferrals)
 
 		user := v1.Group("/").Use(middleware.UserAuthMiddleware(userService))
 		{
+			user.GET("/dashboard", dashboardHandler.GetDashboard)
 			user.POST("/trades", tradeHandler.PlaceTrade)
+			user.POST("/trades/batch", tradeHandler.PlaceTradeBatch)
 			user.GET("/trades", tradeHandler.GetUserTrades)
+			user.GET("/trades/since", tradeHandler.GetTradesSince)
+			user.GET("/trades/export", tradeHandler.ExportTrades)
 			user.GET("/trades/:id", tradeHandler.GetTrade)
 			user.PUT("/trades/:id/close", tradeHandler.CloseTrade)
+			user.POST("/trades/:id/confirm", tradeHandler.ConfirmTrade)
+			user.POST("/trades/close-group", tradeHandler.CloseTradeGroup)
 			user.GET("/trades/stream", tradeHandler.StreamTrades)
 			user.PUT("/trades/:id/modify", tradeHandler.ModifyTrade)
 			user.POST("/transactions", transactionHandler.CreateTransaction)
 			user.GET("/transactions", transactionHandler.GetUserTransactions)
 			user.POST("/alerts", alertHandler.CreateAlert)
 			user.GET("/alerts", alertHandler.GetUserAlerts)
+			user.GET("/alerts/quota", alertHandler.GetAlertQuota)
 			user.GET("/alerts/:id", alertHandler.GetAlert)
+			user.PUT("/alerts/:id", alertHandler.UpdateAlert)
+			user.DELETE("/alerts/:id", alertHandler.DeleteAlert)
 			user.POST("/copy-trades", copyTradeHandler.CreateSubscription)
 			user.GET("/copy-trades", copyTradeHandler.GetUserSubscriptions)
 			user.GET("/copy-trades/:id", copyTradeHandler.GetSubscription)
+			user.GET("/copy-trades/:id/history", copyTradeHandler.GetCopyTradeHistory)
+			user.POST("/copy-trades/:id/cancel", copyTradeHandler.CancelSubscription)
+			user.PUT("/copy-trades/notifications", copyTradeHandler.SetNotificationPreference)
+			user.PUT("/copy-trades/leader-limits", copyTradeHandler.SetLeaderLimits)
 			user.POST("/accounts", userHandler.CreateAccount)
 			user.GET("/accounts", userHandler.GetUserAccounts)
 			user.DELETE("/accounts/:id", userHandler.DeleteAccount)
+			user.GET("/accounts/:id/balance", tradeHandler.GetAccountBalance)
+			user.PUT("/accounts/:id/risk-limits", userHandler.SetRiskLimits)
 			user.POST("/accounts/transfer", userHandler.TransferBalance)
 		}
 
@@ -133,6 +181,8 @@ func SetupRoutes(
 			admin.POST("/symbols", symbolHandler.CreateSymbol)
 			admin.PUT("/symbols/:id", symbolHandler.UpdateSymbol)
 			admin.DELETE("/symbols/:id", symbolHandler.DeleteSymbol)
+			admin.PUT("/symbols/:id/news-halt", symbolHandler.SetNewsHalt)
+			admin.DELETE("/symbols/:id/news-halt", symbolHandler.ClearNewsHalt)
 			admin.GET("/logs", logHandler.GetAllLogs)
 			admin.GET("/overview", overviewHandler.GetOverview)
 			admin.GET("/logs/user/:user_id", logHandler.GetLogsByUser)
@@ -145,8 +195,12 @@ func SetupRoutes(
 			admin.GET("/users/:id", userHandler.GetMe)
 			admin.PUT("/users/edit", userHandler.EditUser)
 			admin.PUT("/users/activation", adminHandler.UpdateUserActivation)
+			admin.POST("/accounts/:id/liquidate", adminHandler.LiquidateAccount)
+			admin.PUT("/accounts/:id/leverage", adminHandler.SetAccountLeverage)
+			admin.PUT("/accounts/:id/tier", adminHandler.SetAccountTier)
 			admin.GET("/trades", tradeHandler.GetAllTrades)
 			admin.GET("/trades/:id", tradeHandler.GetTrade)
+			admin.GET("/settlement", tradeHandler.GetSettlement)
 			admin.GET("/transactions", transactionHandler.GetAllTransactions)
 			admin.GET("/transactions/id/:user_id", transactionHandler.GetTransactionByID)
 			admin.GET("/transactions/user/:user_id", transactionHandler.GetTransactionsByUser)
@@ -157,8 +211,18 @@ func SetupRoutes(
 			admin.POST("/leader-requests/:id/deny", leaderRequestHandler.DenyLeaderRequest)
 			admin.GET("/leader-requests", leaderRequestHandler.GetPendingLeaderRequests)
 			admin.GET("/copy-trade-leaders", leaderRequestHandler.GetApprovedLeaders)
+			admin.POST("/copy-trade-leaders/:id/revoke", leaderRequestHandler.RevokeLeader)
 			admin.GET("/copy-trades-all", copyTradeHandler.GetAllUserSubscriptions)
 			admin.GET("/referrals", adminHandler.GetAllReferrals)
+			admin.GET("/currency-rates", currencyHandler.GetRates)
+			admin.PUT("/currency-rates", currencyHandler.SetRate)
+			admin.GET("/mt5/deadletters", deadLetterHandler.GetDeadLetters)
+			admin.GET("/webhooks/deliveries", webhookHandler.GetDeliveries)
+			admin.POST("/webhooks/deliveries/:id/retry", webhookHandler.RetryDelivery)
+			admin.POST("/reconciliation/balances", reconciliationHandler.ReconcileBalances)
+			admin.GET("/reconciliation/discrepancies", reconciliationHandler.GetDiscrepancies)
+			admin.POST("/reconciliation/discrepancies/:id/review", reconciliationHandler.ReviewDiscrepancy)
+			admin.POST("/broadcast", announcementHandler.Broadcast)
 		}
 	}
 
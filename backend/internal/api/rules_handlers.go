@@ -42,7 +42,7 @@ func (h *RuleHandler) CreateRule(c *gin.Context) {
 		return
 	}
 
-	if err := h.ruleService.CreateRule(&rule); err != nil {
+	if err := h.ruleService.CreateRule(c.Request.Context(), &rule); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rule"})
 		return
 	}
@@ -63,7 +63,7 @@ func (h *RuleHandler) CreateRule(c *gin.Context) {
 // @Router /admin/rules/{id} [get]
 func (h *RuleHandler) GetRule(c *gin.Context) {
 	id := c.Param("id")
-	rule, err := h.ruleService.GetRule(id)
+	rule, err := h.ruleService.GetRule(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rule ID"})
 		return
@@ -83,7 +83,7 @@ func (h *RuleHandler) GetRule(c *gin.Context) {
 // @Failure 500 {object} map[string]string "Failed to retrieve rules"
 // @Router /rules [get]
 func (h *RuleHandler) GetAllRules(c *gin.Context) {
-	rules, err := h.ruleService.GetAllRules()
+	rules, err := h.ruleService.GetAllRules(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve rules"})
 		return
@@ -117,7 +117,7 @@ func (h *RuleHandler) UpdateRule(c *gin.Context) {
 		return
 	}
 
-	if err := h.ruleService.UpdateRule(id, &rule); err != nil {
+	if err := h.ruleService.UpdateRule(c.Request.Context(), id, &rule); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rule"})
 		return
 	}
@@ -136,7 +136,7 @@ func (h *RuleHandler) UpdateRule(c *gin.Context) {
 // @Router /admin/rules/{id} [delete]
 func (h *RuleHandler) DeleteRule(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.ruleService.DeleteRule(id); err != nil {
+	if err := h.ruleService.DeleteRule(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rule"})
 		return
 	}
@@ -1,9 +1,11 @@
 package api
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"regexp"
+	"strconv"
 
 	"github.com/mehrbod2002/fxtrader/internal/models"
 
@@ -40,8 +42,24 @@ func (h *SymbolHandler) CreateSymbol(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
 		return
 	}
+	if err := symbol.ValidateCommissionTiers(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := symbol.ValidateMargin(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := symbol.ValidateTierTerms(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := symbol.ValidateProfitCurrency(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := h.symbolService.CreateSymbol(&symbol); err != nil {
+	if err := h.symbolService.CreateSymbol(c.Request.Context(), &symbol); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create symbol"})
 		return
 	}
@@ -76,7 +94,7 @@ func (h *SymbolHandler) CreateSymbol(c *gin.Context) {
 // @Router /symbols/{id} [get]
 func (h *SymbolHandler) GetSymbol(c *gin.Context) {
 	id := c.Param("id")
-	symbol, err := h.symbolService.GetSymbol(id)
+	symbol, err := h.symbolService.GetSymbol(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid symbol ID"})
 		return
@@ -105,7 +123,7 @@ func (h *SymbolHandler) GetSymbol(c *gin.Context) {
 // @Failure 500 {object} map[string]string "Failed to retrieve symbols"
 // @Router /symbols [get]
 func (h *SymbolHandler) GetAllSymbols(c *gin.Context) {
-	symbols, err := h.symbolService.GetAllSymbols()
+	symbols, err := h.symbolService.GetAllSymbols(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve symbols"})
 		return
@@ -118,6 +136,35 @@ func (h *SymbolHandler) GetAllSymbols(c *gin.Context) {
 	c.JSON(http.StatusOK, symbols)
 }
 
+// @Summary Search symbols
+// @Description Finds symbols whose name or display name contains the query, ignoring case; prefix matches come first
+// @Tags Symbols
+// @Produce json
+// @Param q query string true "Search text"
+// @Param limit query int false "Maximum results (1-50)" default(20)
+// @Success 200 {array} models.Symbol
+// @Failure 400 {object} map[string]string "Invalid query or limit"
+// @Failure 500 {object} map[string]string "Failed to search symbols"
+// @Router /symbols/search [get]
+func (h *SymbolHandler) SearchSymbols(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	symbols, err := h.symbolService.SearchSymbols(c.Request.Context(), c.Query("q"), limit)
+	if err != nil {
+		respondError(c, err, "Failed to search symbols")
+		return
+	}
+	if symbols == nil {
+		symbols = []*models.Symbol{}
+	}
+
+	c.JSON(http.StatusOK, symbols)
+}
+
 // @Summary Update a symbol
 // @Description Updates the details of an existing trading symbol (admin only)
 // @Tags Symbols
@@ -138,8 +185,24 @@ func (h *SymbolHandler) UpdateSymbol(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
 		return
 	}
+	if err := symbol.ValidateCommissionTiers(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := symbol.ValidateMargin(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := symbol.ValidateTierTerms(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := symbol.ValidateProfitCurrency(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := h.symbolService.UpdateSymbol(id, &symbol); err != nil {
+	if err := h.symbolService.UpdateSymbol(c.Request.Context(), id, &symbol); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update symbol"})
 		return
 	}
@@ -166,7 +229,7 @@ func (h *SymbolHandler) UpdateSymbol(c *gin.Context) {
 // @Router /admin/symbols/{id} [delete]
 func (h *SymbolHandler) DeleteSymbol(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.symbolService.DeleteSymbol(id); err != nil {
+	if err := h.symbolService.DeleteSymbol(c.Request.Context(), id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete symbol"})
 		return
 	}
@@ -180,3 +243,76 @@ func (h *SymbolHandler) DeleteSymbol(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"status": "Symbol deleted"})
 }
+
+// @Summary Schedule a news halt
+// @Description Sets a window around a news event during which the symbol's spread is multiplied and, optionally, MARKET orders are rejected (admin only)
+// @Tags Symbols
+// @Accept json
+// @Produce json
+// @Security BasicAuth
+// @Param id path string true "Symbol ID"
+// @Param halt body models.NewsHalt true "News halt window"
+// @Success 200 {object} map[string]string "News halt set"
+// @Failure 400 {object} map[string]string "Invalid JSON or window"
+// @Failure 404 {object} map[string]string "Symbol not found"
+// @Router /admin/symbols/{id}/news-halt [put]
+func (h *SymbolHandler) SetNewsHalt(c *gin.Context) {
+	id := c.Param("id")
+	var halt models.NewsHalt
+	if err := c.ShouldBindJSON(&halt); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return
+	}
+
+	if err := h.symbolService.SetNewsHalt(c.Request.Context(), id, &halt); err != nil {
+		if errors.Is(err, service.ErrSymbolNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Symbol not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	metadata := map[string]interface{}{
+		"symbol_id":         id,
+		"start":             halt.Start,
+		"end":               halt.End,
+		"spread_multiplier": halt.SpreadMultiplier,
+		"block_market":      halt.BlockMarket,
+	}
+	if err := h.logService.LogAction(primitive.ObjectID{}, "SetNewsHalt", "Symbol news halt scheduled", c.ClientIP(), metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "News halt set"})
+}
+
+// @Summary Clear a news halt
+// @Description Removes the symbol's news halt window (admin only)
+// @Tags Symbols
+// @Produce json
+// @Security BasicAuth
+// @Param id path string true "Symbol ID"
+// @Success 200 {object} map[string]string "News halt cleared"
+// @Failure 404 {object} map[string]string "Symbol not found"
+// @Router /admin/symbols/{id}/news-halt [delete]
+func (h *SymbolHandler) ClearNewsHalt(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.symbolService.SetNewsHalt(c.Request.Context(), id, nil); err != nil {
+		if errors.Is(err, service.ErrSymbolNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Symbol not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	metadata := map[string]interface{}{
+		"symbol_id": id,
+	}
+	if err := h.logService.LogAction(primitive.ObjectID{}, "ClearNewsHalt", "Symbol news halt cleared", c.ClientIP(), metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "News halt cleared"})
+}
@@ -1,8 +1,12 @@
 package api
 
 import (
+	"encoding/csv"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -92,8 +96,11 @@ func (h *TradeHandler) RegisterWallet(c *gin.Context) {
 // @Success 201 {object} map[string]interface{} "Trade placed"
 // @Failure 400 {object} map[string]string "Invalid JSON or parameters"
 // @Failure 401 {object} map[string]string "Unauthorized"
-// @Failure 403 {object} map[string]string "Invalid account"
+// @Failure 403 {object} map[string]interface{} "Insufficient balance, account disabled or risk-blocked, or trading paused after a losing streak (remaining_seconds)"
+// @Failure 404 {object} map[string]string "Account or symbol not found"
+// @Failure 409 {object} map[string]interface{} "Open trade limit reached for the symbol, or the MARKET order was requoted (requote, trade_id, price, expires_at)"
 // @Failure 500 {object} map[string]string "Server error"
+// @Failure 503 {object} map[string]string "Too many trades awaiting execution, no price yet, or MT5 unavailable"
 // @Router /trades [post]
 func (h *TradeHandler) PlaceTrade(c *gin.Context) {
 	var req TradeRequest
@@ -116,14 +123,40 @@ func (h *TradeHandler) PlaceTrade(c *gin.Context) {
 	userID := c.GetString("user_id")
 	trade, tradeResponse, err := h.tradeService.PlaceTrade(userID, req.AccountID, req.SymbolName, req.AccountType, req.TradeType, req.OrderType, req.Leverage, req.Volume, req.EntryPrice, req.StopLoss, req.TakeProfit, req.Expiration)
 	if err != nil {
+		if errors.Is(err, service.ErrTooManyInFlightTrades) || errors.Is(err, service.ErrUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		var limitErr *service.PositionLimitError
+		if errors.As(err, &limitErr) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "symbol": limitErr.Symbol, "limit": limitErr.Limit})
+			return
+		}
+		var cooldownErr *service.CooldownError
+		if errors.As(err, &cooldownErr) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "remaining_seconds": int(cooldownErr.Remaining.Seconds())})
+			return
+		}
+		var stopsErr *service.StopsLevelError
+		if errors.As(err, &stopsErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "field": stopsErr.Field, "min_distance": stopsErr.MinDistance})
+			return
+		}
+		var requoteErr *service.RequoteError
+		if errors.As(err, &requoteErr) {
+			respondRequote(c, requoteErr)
+			return
+		}
+		if status := statusForError(err); status != http.StatusInternalServerError {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		// Anything unclassified is MT5 turning the order down.
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	executionType := models.ExecutionTypeUserToUser
-	if req.OrderType == "MARKET" {
-		executionType = models.ExecutionTypePlatform
-	}
+	executionType := trade.ExecutionType
 
 	metadata := map[string]interface{}{
 		"user_id":        userID,
@@ -150,6 +183,140 @@ func (h *TradeHandler) PlaceTrade(c *gin.Context) {
 	})
 }
 
+// @Summary Place a batch of trades
+// @Description Validates a set of orders together, checks the combined margin per account and submits them. With fail_fast any invalid order rejects the whole batch; otherwise invalid orders are skipped and reported.
+// @Tags Trades
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param batch body BatchTradeRequest true "Batch of trade orders"
+// @Success 200 {object} map[string]interface{} "Per-order results"
+// @Failure 400 {object} map[string]interface{} "Invalid JSON or batch rejected"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 503 {object} map[string]string "Too many trades awaiting execution, or MT5 unavailable"
+// @Router /trades/batch [post]
+func (h *TradeHandler) PlaceTradeBatch(c *gin.Context) {
+	var req BatchTradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return
+	}
+
+	orders := make([]interfaces.TradeOrder, len(req.Orders))
+	for i, o := range req.Orders {
+		orders[i] = interfaces.TradeOrder{
+			AccountID:   o.AccountID,
+			Symbol:      o.SymbolName,
+			AccountType: o.AccountType,
+			TradeType:   o.TradeType,
+			OrderType:   o.OrderType,
+			Leverage:    o.Leverage,
+			Volume:      o.Volume,
+			EntryPrice:  o.EntryPrice,
+			StopLoss:    o.StopLoss,
+			TakeProfit:  o.TakeProfit,
+			Expiration:  o.Expiration,
+		}
+	}
+
+	userID := c.GetString("user_id")
+	results, err := h.tradeService.PlaceTradeBatch(userID, orders, req.FailFast)
+	if err != nil {
+		if errors.Is(err, service.ErrTooManyInFlightTrades) || errors.Is(err, service.ErrUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		status := statusForError(err)
+		if status == http.StatusInternalServerError {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error(), "results": results})
+		return
+	}
+
+	placed := 0
+	for _, r := range results {
+		if r.Trade != nil {
+			placed++
+		}
+	}
+
+	metadata := map[string]interface{}{
+		"user_id":   userID,
+		"orders":    len(orders),
+		"placed":    placed,
+		"fail_fast": req.FailFast,
+	}
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+	if err := h.logService.LogAction(userObjID, "PlaceTradeBatch", "Batch trade orders placed", c.ClientIP(), metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "Batch processed",
+		"placed":  placed,
+		"results": results,
+	})
+}
+
+// @Summary Close a group of trades
+// @Description Closes all open trades on an account matching a symbol and/or direction. A given account_type must match the account.
+// @Tags Trades
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param group body CloseGroupRequest true "Account and filters"
+// @Success 200 {object} interfaces.BulkCloseResult
+// @Failure 400 {object} map[string]string "Invalid JSON or parameters"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Account not found"
+// @Failure 500 {object} map[string]string "Server error"
+// @Router /trades/close-group [post]
+func (h *TradeHandler) CloseTradeGroup(c *gin.Context) {
+	var req CloseGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return
+	}
+	if req.Symbol == "" && req.TradeType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "symbol or trade_type is required"})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	var result interfaces.BulkCloseResult
+	var err error
+	switch {
+	case req.TradeType == "":
+		result, err = h.tradeService.CloseTradesBySymbol(userID, req.AccountType, req.AccountID, req.Symbol)
+	case req.Symbol == "":
+		result, err = h.tradeService.CloseTradesByDirection(userID, req.AccountType, req.AccountID, req.TradeType)
+	default:
+		result, err = h.tradeService.CloseTradesByGroup(userID, req.AccountType, req.AccountID, req.Symbol, req.TradeType)
+	}
+	if err != nil {
+		respondError(c, err, "Failed to close trades")
+		return
+	}
+
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+	metadata := map[string]interface{}{
+		"user_id":      userID,
+		"account_id":   req.AccountID,
+		"account_type": req.AccountType,
+		"symbol":       req.Symbol,
+		"trade_type":   req.TradeType,
+		"requested":    result.Requested,
+		"closed":       result.Closed,
+		"failed":       result.Failed,
+	}
+	if err := h.logService.LogAction(userObjID, "CloseTradeGroup", "Trade group close requested", c.ClientIP(), metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // @Summary Close a trade
 // @Description Allows an authenticated user to close an open trade
 // @Tags Trades
@@ -162,29 +329,85 @@ func (h *TradeHandler) PlaceTrade(c *gin.Context) {
 // @Failure 401 {object} map[string]string "Unauthorized"
 // @Failure 403 {object} map[string]string "Forbidden (trade belongs to another user or account)"
 // @Failure 404 {object} map[string]string "Trade not found"
+// @Failure 408 {object} map[string]string "MT5 did not answer in time"
 // @Failure 500 {object} map[string]string "Server error"
+// @Failure 503 {object} map[string]string "MT5 unavailable"
 // @Router /trades/{id}/close [put]
 func (h *TradeHandler) CloseTrade(c *gin.Context) {
 	tradeID := c.Param("id")
 	userID := c.GetString("user_id")
-	trade, err := h.tradeService.GetTrade(tradeID)
 
+	closeResponse, err := h.tradeService.CloseTrade(tradeID, userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trade ID"})
+		respondError(c, err, "Failed to close trade")
 		return
 	}
-	if trade == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Trade not found"})
-		return
+
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+	metadata := map[string]interface{}{
+		"user_id":    userID,
+		"account_id": closeResponse.AccountID,
+		"trade_id":   tradeID,
 	}
-	if trade.UserID.Hex() != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden (trade belongs to another user)"})
-		return
+	if err := h.logService.LogAction(userObjID, "CloseTrade", "Trade close requested", c.ClientIP(), metadata); err != nil {
+		log.Printf("error: %v", err)
 	}
 
-	closeResponse, err := h.tradeService.CloseTrade(tradeID, userID, trade.AccountType, trade.AccountID.Hex())
+	c.JSON(http.StatusOK, gin.H{
+		"status":       "Trade closed",
+		"trade_id":     tradeID,
+		"account_id":   closeResponse.AccountID,
+		"mt5_response": closeResponse,
+	})
+}
+
+// respondRequote tells the client which price MT5 offered and how long it has
+// to confirm it.
+func respondRequote(c *gin.Context, err *service.RequoteError) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error":      err.Error(),
+		"requote":    true,
+		"trade_id":   err.TradeID,
+		"price":      err.Price,
+		"expires_at": err.ExpiresAt,
+	})
+}
+
+// @Summary Confirm a requoted trade
+// @Description Accepts the price MT5 offered for a requoted MARKET order and resubmits it. Requotes that are not confirmed before expires_at are cancelled and their margin and commission refunded.
+// @Tags Trades
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Trade ID"
+// @Success 200 {object} map[string]interface{} "Trade placed"
+// @Failure 400 {object} map[string]string "Invalid trade ID or rejected by MT5"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden (trade belongs to another user)"
+// @Failure 404 {object} map[string]string "Trade not found"
+// @Failure 409 {object} map[string]interface{} "Trade is not awaiting confirmation, the requote expired, or MT5 requoted again"
+// @Failure 503 {object} map[string]string "Too many trades awaiting execution, or MT5 unavailable"
+// @Router /trades/{id}/confirm [post]
+func (h *TradeHandler) ConfirmTrade(c *gin.Context) {
+	tradeID := c.Param("id")
+	userID := c.GetString("user_id")
+
+	trade, tradeResponse, err := h.tradeService.ConfirmRequote(c.Request.Context(), tradeID, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, service.ErrTooManyInFlightTrades) || errors.Is(err, service.ErrUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		var requoteErr *service.RequoteError
+		if errors.As(err, &requoteErr) {
+			respondRequote(c, requoteErr)
+			return
+		}
+		if status := statusForError(err); status != http.StatusInternalServerError {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		// As with PlaceTrade, anything else is MT5 turning the order down.
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -194,15 +417,16 @@ func (h *TradeHandler) CloseTrade(c *gin.Context) {
 		"account_id": trade.AccountID.Hex(),
 		"trade_id":   tradeID,
 	}
-	if err := h.logService.LogAction(userObjID, "CloseTrade", "Trade close requested", c.ClientIP(), metadata); err != nil {
+	if err := h.logService.LogAction(userObjID, "ConfirmTrade", "Requoted trade confirmed", c.ClientIP(), metadata); err != nil {
 		log.Printf("error: %v", err)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"status":       "Trade closed",
-		"trade_id":     tradeID,
-		"account_id":   trade.AccountID.Hex(),
-		"mt5_response": closeResponse,
+		"status":         "Trade placed",
+		"trade_id":       trade.ID.Hex(),
+		"trade_status":   trade.Status,
+		"mt5_response":   tradeResponse,
+		"execution_type": trade.ExecutionType,
 	})
 }
 
@@ -217,14 +441,14 @@ func (h *TradeHandler) CloseTrade(c *gin.Context) {
 // @Router /trades/stream [get]
 func (h *TradeHandler) StreamTrades(c *gin.Context) {
 	userID := c.GetString("user_id")
-	accountType := c.GetString("account_type")
+	accountType := models.NormalizeAccountType(c.GetString("account_type"))
 
 	userObjID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
 		return
 	}
-	if accountType != "DEMO" && accountType != "REAL" {
+	if !models.IsTradingAccountType(accountType) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account type"})
 		return
 	}
@@ -269,13 +493,14 @@ func (h *TradeHandler) StreamTrades(c *gin.Context) {
 // @Tags Trades
 // @Produce json
 // @Security BearerAuth
+// @Param account_type query string false "demo or real; omit for both"
 // @Success 200 {array} models.TradeHistory
 // @Failure 401 {object} map[string]string "Unauthorized"
 // @Failure 500 {object} map[string]string "Server error"
 // @Router /trades [get]
 func (h *TradeHandler) GetUserTrades(c *gin.Context) {
 	userID := c.GetString("user_id")
-	trades, err := h.tradeService.GetTradesByUserID(userID)
+	trades, err := h.tradeService.GetTradesByUserID(c.Request.Context(), userID, c.Query("account_type"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve trades"})
 		return
@@ -293,13 +518,218 @@ func (h *TradeHandler) GetUserTrades(c *gin.Context) {
 	c.JSON(http.StatusOK, trades)
 }
 
+// @Summary Get trades updated since a timestamp
+// @Description Returns the authenticated user's trades changed after the given time, so reconnecting clients can catch up on missed updates
+// @Tags Trades
+// @Produce json
+// @Security BearerAuth
+// @Param timestamp query string true "Unix seconds or RFC3339 time"
+// @Success 200 {array} models.TradeHistory
+// @Failure 400 {object} map[string]string "Invalid timestamp"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Server error"
+// @Router /trades/since [get]
+func (h *TradeHandler) GetTradesSince(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	since, err := parseTimestamp(c.Query("timestamp"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timestamp"})
+		return
+	}
+
+	trades, err := h.tradeService.GetTradesUpdatedSince(c.Request.Context(), userID, since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve trades"})
+		return
+	}
+	if trades == nil {
+		trades = []*models.TradeHistory{}
+	}
+
+	c.JSON(http.StatusOK, trades)
+}
+
+// tradeExportColumns is the header row of a trade history export.
+var tradeExportColumns = []string{
+	"trade_id", "account_id", "account_type", "symbol", "trade_type", "order_type", "status",
+	"volume", "leverage", "entry_price", "close_price", "stop_loss", "take_profit",
+	"profit", "commission", "swap", "open_time", "close_time", "close_reason",
+}
+
+func tradeExportRow(trade *models.TradeHistory) []string {
+	price := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	closeTime := ""
+	if trade.CloseTime != nil {
+		closeTime = trade.CloseTime.UTC().Format(time.RFC3339)
+	}
+	return []string{
+		trade.ID.Hex(), trade.AccountID.Hex(), trade.AccountType, trade.Symbol,
+		string(trade.TradeType), trade.OrderType, trade.Status,
+		price(trade.Volume), strconv.Itoa(trade.Leverage), price(trade.EntryPrice), price(trade.ClosePrice),
+		price(trade.StopLoss), price(trade.TakeProfit),
+		price(trade.Profit), price(trade.Commission), price(trade.Swap),
+		trade.OpenTime.UTC().Format(time.RFC3339), closeTime, string(trade.CloseReason),
+	}
+}
+
+// @Summary Export trade history
+// @Description Downloads the authenticated user's open and closed trades opened in the window as CSV
+// @Tags Trades
+// @Produce text/csv
+// @Security BearerAuth
+// @Param format query string false "Export format; only csv is supported"
+// @Param from query string false "Unix seconds or RFC3339 time; defaults to the first trade"
+// @Param to query string false "Unix seconds or RFC3339 time; defaults to now"
+// @Success 200 {file} file "CSV attachment"
+// @Failure 400 {object} map[string]string "Invalid parameters"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Server error"
+// @Router /trades/export [get]
+func (h *TradeHandler) ExportTrades(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	if format := c.DefaultQuery("format", "csv"); format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported export format"})
+		return
+	}
+	from := time.Unix(0, 0)
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := parseTimestamp(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from timestamp"})
+			return
+		}
+		from = parsed
+	}
+	to := time.Now()
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := parseTimestamp(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to timestamp"})
+			return
+		}
+		to = parsed
+	}
+
+	// Headers go out with the first page, so a failure before any rows still
+	// gets a JSON error instead of an empty attachment.
+	writer := csv.NewWriter(c.Writer)
+	started := false
+	start := func() error {
+		if started {
+			return nil
+		}
+		started = true
+		filename := fmt.Sprintf("trades-%s.csv", time.Now().UTC().Format("20060102-150405"))
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		return writer.Write(tradeExportColumns)
+	}
+
+	rows := 0
+	err := h.tradeService.ExportTradeHistory(c.Request.Context(), userID, from, to, func(page []*models.TradeHistory) error {
+		if err := start(); err != nil {
+			return err
+		}
+		for _, trade := range page {
+			if err := writer.Write(tradeExportRow(trade)); err != nil {
+				return err
+			}
+		}
+		rows += len(page)
+		writer.Flush()
+		return writer.Error()
+	})
+	if err != nil {
+		if !started {
+			respondError(c, err, "Failed to export trades")
+			return
+		}
+		log.Printf("Trade export for %s aborted after %d rows: %v", userID, rows, err)
+		return
+	}
+	if err := start(); err != nil {
+		log.Printf("Trade export for %s failed: %v", userID, err)
+		return
+	}
+	writer.Flush()
+
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+	metadata := map[string]interface{}{
+		"from": from,
+		"to":   to,
+		"rows": rows,
+	}
+	if err := h.logService.LogAction(userObjID, "ExportTrades", "Trade history exported", c.ClientIP(), metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+}
+
+// parseTimestamp accepts either unix seconds or an RFC3339 string.
+func parseTimestamp(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, errors.New("timestamp is required")
+	}
+	if secs, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+// LiveTrade is an open trade with the latest quote for its symbol. Distances
+// to the stop loss and take profit are in price units from CurrentPrice and
+// positive while the level has not been reached; they are omitted when the
+// level is not set.
+type LiveTrade struct {
+	*models.TradeHistory
+	Bid                float64  `json:"bid"`
+	Ask                float64  `json:"ask"`
+	QuotedAt           int64    `json:"quoted_at"`
+	CurrentPrice       float64  `json:"current_price"`
+	FloatingProfit     float64  `json:"floating_profit"`
+	StopLossDistance   *float64 `json:"stop_loss_distance,omitempty"`
+	TakeProfitDistance *float64 `json:"take_profit_distance,omitempty"`
+}
+
+// liveTrade marks an open trade to the last tick. It reports false for other
+// statuses and when no tick has been seen for the symbol.
+func (h *TradeHandler) liveTrade(trade *models.TradeHistory) (*LiveTrade, bool) {
+	if trade.Status != string(models.TradeStatusOpen) {
+		return nil, false
+	}
+	price, ok := h.hub.LastPrice(trade.Symbol)
+	if !ok {
+		return nil, false
+	}
+
+	live := &LiveTrade{TradeHistory: trade, Bid: price.Bid, Ask: price.Ask, QuotedAt: price.Timestamp}
+	live.CurrentPrice, live.FloatingProfit = trade.MarkToMarket(price)
+	// A BUY stops out below the price and takes profit above it; a SELL the
+	// other way round.
+	direction := 1.0
+	if trade.TradeType == models.TradeTypeSell {
+		direction = -1
+	}
+	if trade.StopLoss > 0 {
+		distance := (live.CurrentPrice - trade.StopLoss) * direction
+		live.StopLossDistance = &distance
+	}
+	if trade.TakeProfit > 0 {
+		distance := (trade.TakeProfit - live.CurrentPrice) * direction
+		live.TakeProfitDistance = &distance
+	}
+	return live, true
+}
+
 // @Summary Get trade by ID
-// @Description Retrieves details of a specific trade by its ID (user or admin)
+// @Description Retrieves details of a specific trade by its ID (user or admin). With live=true an open trade also carries the latest bid/ask, its floating P/L and the distance to its stop loss and take profit; trades that are not open, or whose symbol has no quote yet, are returned as stored.
 // @Tags Trades
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Trade ID"
-// @Success 200 {object} models.TradeHistory
+// @Param live query bool false "Include the live quote and floating P/L"
+// @Success 200 {object} LiveTrade
 // @Failure 400 {object} map[string]string "Invalid trade ID"
 // @Failure 401 {object} map[string]string "Unauthorized"
 // @Failure 403 {object} map[string]string "Forbidden (trade belongs to another user)"
@@ -309,7 +739,7 @@ func (h *TradeHandler) GetTrade(c *gin.Context) {
 	tradeID := c.Param("id")
 	userID := c.GetString("user_id")
 
-	trade, err := h.tradeService.GetTrade(tradeID)
+	trade, err := h.tradeService.GetTrade(c.Request.Context(), tradeID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trade ID"})
 		return
@@ -318,6 +748,11 @@ func (h *TradeHandler) GetTrade(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Trade not found"})
 		return
 	}
+	// Only the admin route may read other users' trades.
+	if !c.GetBool("is_admin") && trade.UserID.Hex() != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden (trade belongs to another user)"})
+		return
+	}
 
 	userObjID, _ := primitive.ObjectIDFromHex(userID)
 	metadata := map[string]interface{}{
@@ -329,9 +764,46 @@ func (h *TradeHandler) GetTrade(c *gin.Context) {
 		log.Printf("error: %v", err)
 	}
 
+	if live, _ := strconv.ParseBool(c.Query("live")); live {
+		if enriched, ok := h.liveTrade(trade); ok {
+			c.JSON(http.StatusOK, enriched)
+			return
+		}
+	}
 	c.JSON(http.StatusOK, trade)
 }
 
+// @Summary Get account balance
+// @Description Returns the account's stored balance at once. With refresh=true the balance MT5 reports is fetched too and returned alongside; an MT5 balance fetched within the last few seconds is reused rather than asked for again. The stored balance is never replaced by the MT5 one.
+// @Tags Accounts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Account ID"
+// @Param refresh query bool false "Also fetch the balance from MT5"
+// @Success 200 {object} interfaces.AccountBalance
+// @Failure 400 {object} map[string]string "Invalid account ID or refresh value"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Account not found"
+// @Failure 408 {object} map[string]string "MT5 did not answer in time"
+// @Failure 500 {object} map[string]string "Failed to get account balance"
+// @Failure 503 {object} map[string]string "MT5 unavailable"
+// @Router /accounts/{id}/balance [get]
+func (h *TradeHandler) GetAccountBalance(c *gin.Context) {
+	refresh, err := strconv.ParseBool(c.DefaultQuery("refresh", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid refresh value"})
+		return
+	}
+
+	balance, err := h.tradeService.AccountBalance(c.Request.Context(), c.GetString("user_id"), c.Param("id"), refresh)
+	if err != nil {
+		respondError(c, err, "Failed to get account balance")
+		return
+	}
+
+	c.JSON(http.StatusOK, balance)
+}
+
 // @Summary Handle trade response from MT5
 // @Description Processes trade response from MT5 EA
 // @Tags Trades
@@ -362,13 +834,14 @@ func (h *TradeHandler) HandleTradeResponse(c *gin.Context) {
 // @Tags Trades
 // @Produce json
 // @Security BearerAuth
+// @Param account_type query string false "demo or real; omit for both"
 // @Success 200 {array} models.TradeHistory
 // @Failure 401 {object} map[string]string "Unauthorized"
 // @Failure 403 {object} map[string]string "Forbidden (non-admin)"
 // @Failure 500 {object} map[string]string "Server error"
 // @Router /admin/trades [get]
 func (h *TradeHandler) GetAllTrades(c *gin.Context) {
-	trades, err := h.tradeService.GetAllTrades()
+	trades, err := h.tradeService.GetAllTrades(c.Request.Context(), c.Query("account_type"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve trades"})
 		return
@@ -387,8 +860,67 @@ func (h *TradeHandler) GetAllTrades(c *gin.Context) {
 	c.JSON(http.StatusOK, trades)
 }
 
+// @Summary Settlement report
+// @Description Sums net P/L, commission and swap of trades closed in a window, per user and per symbol (admin only). Defaults to the last 24 hours.
+// @Tags Trades
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "Window start, unix seconds or RFC3339"
+// @Param to query string false "Window end, unix seconds or RFC3339"
+// @Param account_type query string false "DEMO or REAL"
+// @Success 200 {object} models.SettlementReport
+// @Failure 400 {object} map[string]string "Invalid parameters"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Server error"
+// @Router /admin/settlement [get]
+func (h *TradeHandler) GetSettlement(c *gin.Context) {
+	to := time.Now()
+	if raw := c.Query("to"); raw != "" {
+		parsed, err := parseTimestamp(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to timestamp"})
+			return
+		}
+		to = parsed
+	}
+	from := to.Add(-24 * time.Hour)
+	if raw := c.Query("from"); raw != "" {
+		parsed, err := parseTimestamp(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from timestamp"})
+			return
+		}
+		from = parsed
+	}
+	accountType := models.NormalizeAccountType(c.Query("account_type"))
+	if accountType != "" && !models.IsTradingAccountType(accountType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account type"})
+		return
+	}
+
+	report, err := h.tradeService.GetSettlementReport(c.Request.Context(), from, to, accountType)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("user_id")
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+	metadata := map[string]interface{}{
+		"admin_id":     userID,
+		"from":         from,
+		"to":           to,
+		"account_type": accountType,
+	}
+	if err := h.logService.LogAction(userObjID, "GetSettlement", "Settlement report generated", c.ClientIP(), metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
 // @Summary Modify a pending trade
-// @Description Modify the entry price and/or volume of a pending trade
+// @Description Modify the entry price and/or volume of a pending trade. Extra margin for the new terms is reserved from the account balance up front; margin freed by the change is refunded once MT5 confirms it.
 // @Tags Trades
 // @Accept json
 // @Produce json
@@ -398,8 +930,10 @@ func (h *TradeHandler) GetAllTrades(c *gin.Context) {
 // @Success 200 {object} interfaces.TradeResponse
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string "Insufficient balance for the additional margin"
 // @Failure 500 {object} map[string]string
 // @Failure 408 {object} map[string]string
+// @Failure 503 {object} map[string]string "MT5 unavailable"
 // @Router /trades/{id}/modify [put]
 func (h *TradeHandler) ModifyTrade(c *gin.Context) {
 	tradeID := c.Param("id")
@@ -413,11 +947,7 @@ func (h *TradeHandler) ModifyTrade(c *gin.Context) {
 
 	response, err := h.tradeService.ModifyTrade(c.Request.Context(), userID, tradeID, req.AccountType, req.AccountID, req.EntryPrice, req.Volume)
 	if err != nil {
-		if err.Error() == "timeout waiting for modify response" {
-			c.JSON(http.StatusRequestTimeout, gin.H{"error": err.Error()})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
+		respondError(c, err, "Failed to modify trade")
 		return
 	}
 
@@ -436,6 +966,8 @@ type ModifyTradeRequest struct {
 	AccountID   string  `json:"account_id" binding:"required"`
 }
 
+// TradeRequest.SymbolName may be a symbol's broker name or its display name;
+// the trade is recorded under the broker name either way.
 type TradeRequest struct {
 	SymbolName  string           `json:"symbol_name" binding:"required"`
 	TradeType   models.TradeType `json:"trade_type" binding:"required,oneof=BUY SELL"`
@@ -449,3 +981,15 @@ type TradeRequest struct {
 	AccountType string           `json:"account_type" binding:"required"`
 	AccountID   string           `json:"account_id" binding:"required"`
 }
+
+type BatchTradeRequest struct {
+	Orders   []TradeRequest `json:"orders" binding:"required,min=1,max=20,dive"`
+	FailFast bool           `json:"fail_fast"`
+}
+
+type CloseGroupRequest struct {
+	AccountID   string           `json:"account_id" binding:"required"`
+	AccountType string           `json:"account_type"`
+	Symbol      string           `json:"symbol"`
+	TradeType   models.TradeType `json:"trade_type" binding:"omitempty,oneof=BUY SELL"`
+}
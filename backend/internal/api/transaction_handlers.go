@@ -43,16 +43,17 @@ func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 
 	userID := c.GetString("user_id")
 	userObjID, _ := primitive.ObjectIDFromHex(userID)
-	user, _ := h.accountRepo.GetUserByID(userObjID)
+	user, _ := h.accountRepo.GetUserByID(c.Request.Context(), userObjID)
 	transaction := &models.Transaction{
 		TransactionType: req.TransactionType,
 		PaymentMethod:   req.PaymentMethod,
 		Amount:          req.Amount,
+		Currency:        req.Currency,
 		TelegramID:      user.TelegramID,
 		ReceiptImage:    req.ReceiptImage,
 	}
 
-	if err := h.transactionService.CreateTransaction(userID, transaction); err != nil {
+	if err := h.transactionService.CreateTransaction(c.Request.Context(), userID, transaction); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -71,7 +72,7 @@ func (h *TransactionHandler) CreateTransaction(c *gin.Context) {
 // @Router /transactions [get]
 func (h *TransactionHandler) GetUserTransactions(c *gin.Context) {
 	userID := c.GetString("user_id")
-	transactions, err := h.transactionService.GetTransactionsByUserID(userID)
+	transactions, err := h.transactionService.GetTransactionsByUserID(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -89,7 +90,7 @@ func (h *TransactionHandler) GetUserTransactions(c *gin.Context) {
 // @Failure 500 {object} map[string]string "Failed to retrieve transactions"
 // @Router /admin/transactions [get]
 func (h *TransactionHandler) GetAllTransactions(c *gin.Context) {
-	transactions, err := h.transactionService.GetAllTransactions()
+	transactions, err := h.transactionService.GetAllTransactions(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve transactions"})
 		return
@@ -110,7 +111,7 @@ func (h *TransactionHandler) GetAllTransactions(c *gin.Context) {
 // @Router /admin/transactions/user/{user_id} [get]
 func (h *TransactionHandler) GetTransactionsByUser(c *gin.Context) {
 	userID := c.Param("user_id")
-	transactions, err := h.transactionService.GetTransactionsByUserID(userID)
+	transactions, err := h.transactionService.GetTransactionsByUserID(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -132,7 +133,7 @@ func (h *TransactionHandler) GetTransactionsByUser(c *gin.Context) {
 // @Router /api/v1/transactions/{id} [get]
 func (h *TransactionHandler) GetTransactionByID(c *gin.Context) {
 	transactionID := c.Param("id")
-	transaction, err := h.transactionService.GetTransactionByID(transactionID)
+	transaction, err := h.transactionService.GetTransactionByID(c.Request.Context(), transactionID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transaction ID"})
 		return
@@ -176,12 +177,12 @@ func (h *TransactionHandler) ApproveTransaction(c *gin.Context) {
 		return
 	}
 
-	if err := h.transactionService.ApproveTransaction(id, req.Reason, req.AdminComment); err != nil {
+	if err := h.transactionService.ApproveTransaction(c.Request.Context(), id, req.Reason, req.AdminComment); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	transaction, _ := h.transactionService.GetTransactionByID(id)
+	transaction, _ := h.transactionService.GetTransactionByID(c.Request.Context(), id)
 	status := "Transaction approved"
 	if transaction != nil {
 		switch transaction.TransactionType {
@@ -227,12 +228,12 @@ func (h *TransactionHandler) DenyTransaction(c *gin.Context) {
 		return
 	}
 
-	if err := h.transactionService.DenyTransaction(id, req.Reason, req.AdminComment); err != nil {
+	if err := h.transactionService.DenyTransaction(c.Request.Context(), id, req.Reason, req.AdminComment); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	transaction, _ := h.transactionService.GetTransactionByID(id)
+	transaction, _ := h.transactionService.GetTransactionByID(c.Request.Context(), id)
 	status := "Transaction denied"
 	if transaction != nil {
 		switch transaction.TransactionType {
@@ -261,6 +262,7 @@ type TransactionRequest struct {
 	TransactionType models.TransactionType `json:"transaction_type" binding:"required,oneof=DEPOSIT WITHDRAWAL"`
 	PaymentMethod   models.PaymentMethod   `json:"payment_method" binding:"required,oneof=CARD_TO_CARD DEPOSIT_RECEIPT"`
 	Amount          float64                `json:"amount" binding:"required,gt=0"`
+	Currency        string                 `json:"currency,omitempty" binding:"omitempty,len=3"`
 	ReceiptImage    string                 `json:"receipt_image,omitempty"`
 }
 
@@ -1,10 +1,11 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"strings"
+	"strconv"
 	"time"
 
 	"github.com/mehrbod2002/fxtrader/internal/config"
@@ -78,7 +79,7 @@ func (h *UserHandler) SignupUser(c *gin.Context) {
 		return
 	}
 
-	existingUser, err := h.userService.GetUserByTelegramID(req.TelegramID)
+	existingUser, err := h.userService.GetUserByTelegramID(c.Request.Context(), req.TelegramID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing user"})
 		return
@@ -110,7 +111,7 @@ func (h *UserHandler) SignupUser(c *gin.Context) {
 
 	var referredBy primitive.ObjectID
 	if req.ReferralCode != "" {
-		referrer, err := h.userService.GetUserByReferralCode(req.ReferralCode)
+		referrer, err := h.userService.GetUserByReferralCode(c.Request.Context(), req.ReferralCode)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate referral code"})
 			return
@@ -126,7 +127,7 @@ func (h *UserHandler) SignupUser(c *gin.Context) {
 	user.ReferralCode = fmt.Sprintf("%s-%x", user.Username, timestamp)[0:12]
 	user.ReferredBy = referredBy
 
-	if err := h.userService.SignupUser(user); err != nil {
+	if err := h.userService.SignupUser(c.Request.Context(), user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
@@ -177,7 +178,8 @@ func (h *UserHandler) CreateAccount(c *gin.Context) {
 		return
 	}
 
-	if req.AccountType != "demo" && req.AccountType != "real" {
+	req.AccountType = models.NormalizeAccountType(req.AccountType)
+	if !models.IsTradingAccountType(req.AccountType) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account type"})
 		return
 	}
@@ -188,7 +190,7 @@ func (h *UserHandler) CreateAccount(c *gin.Context) {
 		AccountType: req.AccountType,
 	}
 
-	if err := h.accountService.CreateAccount(account); err != nil {
+	if err := h.accountService.CreateAccount(c.Request.Context(), account); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create account"})
 		return
 	}
@@ -239,9 +241,9 @@ func (h *UserHandler) DeleteAccount(c *gin.Context) {
 		return
 	}
 
-	err = h.accountService.DeleteAccount(accountObjID, userObjID)
+	err = h.accountService.DeleteAccount(c.Request.Context(), accountObjID, userObjID)
 	if err != nil {
-		if err.Error() == "account not found" {
+		if errors.Is(err, service.ErrAccountNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
 			return
 		}
@@ -278,7 +280,7 @@ func (h *UserHandler) EditUser(c *gin.Context) {
 		return
 	}
 
-	existingUser, err := h.userService.GetUserByTelegramID(user.TelegramID)
+	existingUser, err := h.userService.GetUserByTelegramID(c.Request.Context(), user.TelegramID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing user"})
 		return
@@ -290,7 +292,7 @@ func (h *UserHandler) EditUser(c *gin.Context) {
 
 	user.ID = existingUser.ID
 
-	if err := h.userService.EditUser(&user); err != nil {
+	if err := h.userService.EditUser(c.Request.Context(), &user); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit user"})
 		return
 	}
@@ -328,7 +330,7 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.GetUserByTelegramID(req.TelegramID)
+	user, err := h.userService.GetUserByTelegramID(c.Request.Context(), req.TelegramID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
 		return
@@ -362,7 +364,7 @@ func (h *UserHandler) Login(c *gin.Context) {
 // @Router /users/{id} [get]
 func (h *UserHandler) GetUser(c *gin.Context) {
 	id := c.Param("id")
-	user, err := h.userService.GetUserByTelegramID(id)
+	user, err := h.userService.GetUserByTelegramID(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
@@ -382,15 +384,56 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
+type PaginatedUsersResponse struct {
+	Users      []*models.User `json:"users"`
+	Total      int64          `json:"total"`
+	Page       int64          `json:"page"`
+	Limit      int64          `json:"limit"`
+	TotalPages int64          `json:"total_pages"`
+}
+
 // @Summary Get all users
-// @Description Retrieves a list of all users
-// @Tags Users
+// @Description Retrieves users, newest first unless sorted otherwise, optionally searched and filtered by active and leader status (admin only)
+// @Tags Admin
 // @Produce json
-// @Success 200 {array} models.User
+// @Security BasicAuth
+// @Param q query string false "Prefix of the full name, username, phone number, Telegram ID or national ID"
+// @Param sort query string false "registration_date or balance, prefixed with - for descending"
+// @Param active query bool false "Only active (true) or inactive (false) users"
+// @Param leader query bool false "Only copy trade leaders (true) or non-leaders (false)"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Number of entries per page (default 50)"
+// @Success 200 {object} PaginatedUsersResponse
+// @Failure 400 {object} map[string]string "Invalid filter, sort or pagination parameters"
 // @Failure 500 {object} map[string]string "Server error"
-// @Router /users [get]
+// @Router /admin/users [get]
 func (h *UserHandler) GetAllUsers(c *gin.Context) {
-	users, err := h.userService.GetAllUsers()
+	var filter models.UserFilter
+	var err error
+	if filter.IsActive, err = optionalBoolQuery(c, "active"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid active value"})
+		return
+	}
+	if filter.IsLeader, err = optionalBoolQuery(c, "leader"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid leader value"})
+		return
+	}
+	if filter.Sort, err = models.ParseUserSort(c.Query("sort")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit value"})
+		return
+	}
+
+	users, total, err := h.userService.SearchUsers(c.Request.Context(), c.Query("q"), filter, page, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
 		return
@@ -398,12 +441,34 @@ func (h *UserHandler) GetAllUsers(c *gin.Context) {
 
 	metadata := map[string]interface{}{
 		"count": len(users),
+		"total": total,
+		"page":  page,
 	}
 	if err := h.logService.LogAction(primitive.ObjectID{}, "GetAllUsers", "Retrieved all users", c.ClientIP(), metadata); err != nil {
 		log.Printf("error: %v", err)
 	}
 
-	c.JSON(http.StatusOK, users)
+	c.JSON(http.StatusOK, PaginatedUsersResponse{
+		Users:      users,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: (total + limit - 1) / limit,
+	})
+}
+
+// optionalBoolQuery parses a boolean query parameter, returning nil when it
+// is absent.
+func optionalBoolQuery(c *gin.Context, name string) (*bool, error) {
+	raw, ok := c.GetQuery(name)
+	if !ok {
+		return nil, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
 }
 
 // @Summary Get current user
@@ -422,7 +487,7 @@ func (h *UserHandler) GetMe(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.GetUserByTelegramID(userID)
+	user, err := h.userService.GetUserByTelegramID(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
 		return
@@ -442,6 +507,61 @@ func (h *UserHandler) GetMe(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
+// @Summary Set account risk limits
+// @Description Sets the account's daily loss limit and profit target. When daily realized plus floating P/L crosses either, all positions are closed and, with block_after_trigger, new trades are refused until UTC midnight. loss_streak_limit losing closes within loss_streak_window_minutes pause trading for loss_cooldown_minutes. Zero disables a limit.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path string true "Account ID"
+// @Param limits body models.AccountRiskLimits true "Risk limits"
+// @Success 200 {object} map[string]string "Risk limits updated"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Account not found"
+// @Router /accounts/{id}/risk-limits [put]
+func (h *UserHandler) SetRiskLimits(c *gin.Context) {
+	userObjID, err := primitive.ObjectIDFromHex(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	accountID := c.Param("id")
+	accountObjID, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account ID"})
+		return
+	}
+
+	var limits models.AccountRiskLimits
+	if err := c.ShouldBindJSON(&limits); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
+		return
+	}
+
+	if err := h.accountService.SetRiskLimits(c.Request.Context(), accountObjID, userObjID, limits); err != nil {
+		if errors.Is(err, service.ErrAccountNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	metadata := map[string]interface{}{
+		"account_id":          accountID,
+		"daily_loss_limit":    limits.DailyLossLimit,
+		"daily_profit_target": limits.DailyProfitTarget,
+		"block_after_trigger": limits.BlockAfterTrigger,
+		"loss_streak_limit":   limits.LossStreakLimit,
+		"loss_cooldown":       limits.LossCooldownMinutes,
+	}
+	if err := h.logService.LogAction(userObjID, "SetRiskLimits", "Account risk limits updated", c.ClientIP(), metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "Risk limits updated"})
+}
+
 // @Summary Transfer balance between accounts
 // @Description Transfers balance between accounts (main, demo, or real) owned by the same user
 // @Tags Users
@@ -474,50 +594,39 @@ func (h *UserHandler) TransferBalance(c *gin.Context) {
 		return
 	}
 
+	req.SourceType = models.NormalizeAccountType(req.SourceType)
+	req.DestType = models.NormalizeAccountType(req.DestType)
 	if req.SourceID == req.DestID && req.SourceType == req.DestType {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Source and destination cannot be the same"})
 		return
 	}
 
-	if req.SourceType != "main" && req.SourceType != "demo" && req.SourceType != "real" {
+	if req.SourceType != models.AccountTypeMain && !models.IsTradingAccountType(req.SourceType) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid source account type"})
 		return
 	}
 
-	if req.DestType != "main" && req.DestType != "demo" && req.DestType != "real" {
+	if req.DestType != models.AccountTypeMain && !models.IsTradingAccountType(req.DestType) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid destination account type"})
 		return
 	}
 
-	err = h.transferService.TransferBalance(userObjID, req.SourceID, req.DestID, req.Amount, req.SourceType, req.DestType)
+	err = h.transferService.TransferBalance(c.Request.Context(), userObjID, req.SourceID, req.DestID, req.Amount, req.SourceType, req.DestType)
 	if err != nil {
-		switch {
-		case strings.Contains(err.Error(), "insufficient balance"):
-			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient balance in source account"})
-		case strings.Contains(err.Error(), "not found"):
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		case strings.Contains(err.Error(), "invalid") || strings.Contains(err.Error(), "mismatch"):
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		case strings.Contains(err.Error(), "cannot transfer between demo and real"):
-			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-		case strings.Contains(err.Error(), "same user"):
-			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-		default:
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Transfer failed: %v", err)})
-		}
+		respondError(c, err, "Transfer failed")
 		return
 	}
 
 	var sourceBal float64
-	if req.SourceType == "main" {
-		user, err := h.userService.GetUser(userID.(string))
+	if req.SourceType == models.AccountTypeMain {
+		user, err := h.userService.GetUser(c.Request.Context(), userID.(string))
 		if err != nil || user == nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated source balance"})
 			return
 		}
 		sourceBal = user.Balance
 	} else {
-		acc, err := h.accountRepository.GetAccountByName(req.SourceID, userObjID)
+		acc, err := h.accountRepository.GetAccountByName(c.Request.Context(), req.SourceID, userObjID)
 		if err != nil || acc == nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated source balance"})
 			return
@@ -526,15 +635,15 @@ func (h *UserHandler) TransferBalance(c *gin.Context) {
 	}
 
 	var destBal float64
-	if req.DestType == "main" {
-		user, err := h.userService.GetUser(userID.(string))
+	if req.DestType == models.AccountTypeMain {
+		user, err := h.userService.GetUser(c.Request.Context(), userID.(string))
 		if err != nil || user == nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated destination balance"})
 			return
 		}
 		destBal = user.Balance
 	} else {
-		acc, err := h.accountRepository.GetAccountByName(req.DestID, userObjID)
+		acc, err := h.accountRepository.GetAccountByName(c.Request.Context(), req.DestID, userObjID)
 		if err != nil || acc == nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated destination balance"})
 			return
@@ -575,7 +684,7 @@ func (h *UserHandler) GetUserAccounts(c *gin.Context) {
 		return
 	}
 
-	accounts, err := h.accountService.GetAccountsByUserID(userID.(string))
+	accounts, err := h.accountService.GetAccountsByUserID(c.Request.Context(), userID.(string))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve accounts"})
 		return
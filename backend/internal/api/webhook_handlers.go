@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+	"github.com/mehrbod2002/fxtrader/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	webhookService service.WebhookService
+}
+
+func NewWebhookHandler(webhookService service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{webhookService: webhookService}
+}
+
+type PaginatedWebhookDeliveriesResponse struct {
+	Deliveries []*models.WebhookDelivery `json:"deliveries"`
+	Total      int64                     `json:"total"`
+	Page       int64                     `json:"page"`
+	Limit      int64                     `json:"limit"`
+	TotalPages int64                     `json:"total_pages"`
+}
+
+// @Summary Get webhook deliveries
+// @Description Lists outbound webhook deliveries, newest first, optionally filtered by status (admin only)
+// @Tags Admin
+// @Produce json
+// @Security BasicAuth
+// @Param status query string false "PENDING, DELIVERED or FAILED"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Number of entries per page (default 50)"
+// @Success 200 {object} PaginatedWebhookDeliveriesResponse
+// @Failure 400 {object} map[string]string "Invalid status or pagination parameters"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Failed to retrieve webhook deliveries"
+// @Router /admin/webhooks/deliveries [get]
+func (h *WebhookHandler) GetDeliveries(c *gin.Context) {
+	status := models.WebhookDeliveryStatus(strings.ToUpper(c.Query("status")))
+	switch status {
+	case "", models.WebhookDeliveryPending, models.WebhookDeliveryDelivered, models.WebhookDeliveryFailed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit value"})
+		return
+	}
+
+	deliveries, total, err := h.webhookService.GetDeliveries(c.Request.Context(), status, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve webhook deliveries"})
+		return
+	}
+
+	c.JSON(http.StatusOK, PaginatedWebhookDeliveriesResponse{
+		Deliveries: deliveries,
+		Total:      total,
+		Page:       int64(page),
+		Limit:      int64(limit),
+		TotalPages: (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// @Summary Retry a failed webhook delivery
+// @Description Queues a failed delivery again with a fresh set of attempts (admin only)
+// @Tags Admin
+// @Produce json
+// @Security BasicAuth
+// @Param id path string true "Delivery ID"
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 400 {object} map[string]string "Invalid delivery ID"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Delivery not found"
+// @Failure 409 {object} map[string]string "Delivery has not failed"
+// @Failure 500 {object} map[string]string "Failed to retry delivery"
+// @Router /admin/webhooks/deliveries/{id}/retry [post]
+func (h *WebhookHandler) RetryDelivery(c *gin.Context) {
+	delivery, err := h.webhookService.RetryDelivery(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err, "Failed to retry delivery")
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
@@ -0,0 +1,57 @@
+// Package clock lets services read the current time through an interface, so
+// time-dependent logic can be driven by a controllable clock and all of it
+// reports times in the one configured timezone.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct {
+	loc *time.Location
+}
+
+// New returns the wall clock in loc. A nil loc means UTC.
+func New(loc *time.Location) Clock {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return systemClock{loc: loc}
+}
+
+func (c systemClock) Now() time.Time {
+	return time.Now().In(c.loc)
+}
+
+// Manual is a clock that only moves when told to.
+type Manual struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewManual(now time.Time) *Manual {
+	return &Manual{now: now}
+}
+
+func (m *Manual) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+func (m *Manual) Set(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = now
+}
+
+func (m *Manual) Advance(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = m.now.Add(d)
+}
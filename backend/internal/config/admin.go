@@ -13,10 +13,10 @@ import (
 )
 
 func EnsureAdminUser(adminRepo repository.AdminRepository, adminUser, adminPass string) error {
-	_, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	user, err := adminRepo.GetAdminByUsername(adminUser)
+	user, err := adminRepo.GetAdminByUsername(ctx, adminUser)
 	if err == nil && user != nil {
 		return nil
 	}
@@ -34,7 +34,7 @@ func EnsureAdminUser(adminRepo repository.AdminRepository, adminUser, adminPass
 		RegistrationDate: time.Now().Format(time.RFC3339),
 	}
 
-	err = adminRepo.SaveAdmin(admin)
+	err = adminRepo.SaveAdmin(ctx, admin)
 	if err != nil {
 		return err
 	}
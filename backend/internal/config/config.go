@@ -2,8 +2,14 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -19,6 +25,73 @@ type Config struct {
 	MT5Port    int
 	ListenPort int
 	BotToken   string
+
+	BaseCurrency     string
+	CurrencyRatesURL string
+	CurrencyRateTTL  time.Duration
+	MoneyDecimals    int
+	Timezone         *time.Location
+
+	MaxInFlightTrades      int
+	MaxOpenTradesPerSymbol int
+
+	// Demo accounts pay DemoCommissionRate times the commission and trade
+	// on DemoSpreadRate times the spread; real accounts pay the full amounts.
+	DemoCommissionRate float64
+	DemoSpreadRate     float64
+
+	WSCompression bool
+
+	TLSCertFile string
+	TLSKeyFile  string
+
+	MT5AllowedOrigins []string
+	MT5AllowedIPs     []string
+
+	LogRetryQueueSize int
+	LogFallbackPath   string
+
+	LogRetention          time.Duration
+	LogHighValueRetention time.Duration
+	LogHighValueActions   []string
+
+	LeaderMinClosedTrades int
+	LeaderMinVolume       float64
+	LeaderMinAccountAge   time.Duration
+
+	CopyTradeMaxMirrorFailures int
+	CopyTradeMirrorWorkers     int
+
+	PriceFeedAPIKey string
+
+	PriceOutlierPercent  float64
+	PriceOutlierRecovery time.Duration
+
+	MaxPendingAlertsPerUser int
+
+	MT5TimeoutAlertPercent    float64
+	MT5TimeoutAlertWindow     time.Duration
+	MT5TimeoutAlertMinSamples int
+
+	MT5BreakerThreshold int
+	MT5BreakerCooldown  time.Duration
+
+	MT5ResponseBuffer        int
+	TradeResponseBuffer      int
+	TradeResponseSendTimeout time.Duration
+	RequoteTTL               time.Duration
+	StreamLifetime           time.Duration
+	BalanceRefreshTTL        time.Duration
+	StreamIdleTimeout        time.Duration
+
+	ReconcileInterval      time.Duration
+	ReconcileTolerance     float64
+	ReconcileFlagThreshold float64
+
+	WebhookURLs        []string
+	WebhookSecret      string
+	WebhookMaxAttempts int
+	WebhookQueueSize   int
 }
 
 func Load() (*Config, error) {
@@ -83,6 +156,361 @@ func Load() (*Config, error) {
 		return nil, errors.New("invalid LISTEN_PORT value")
 	}
 
+	baseCurrency := os.Getenv("BASE_CURRENCY")
+	if baseCurrency == "" {
+		baseCurrency = "USD"
+	}
+
+	currencyRatesURL := os.Getenv("CURRENCY_RATES_URL")
+
+	currencyRateTTLStr := os.Getenv("CURRENCY_RATE_TTL_SECONDS")
+	if currencyRateTTLStr == "" {
+		currencyRateTTLStr = "300"
+	}
+	currencyRateTTL, err := strconv.Atoi(currencyRateTTLStr)
+	if err != nil {
+		return nil, errors.New("invalid CURRENCY_RATE_TTL_SECONDS value")
+	}
+
+	moneyDecimalsStr := os.Getenv("MONEY_DECIMALS")
+	if moneyDecimalsStr == "" {
+		moneyDecimalsStr = "2"
+	}
+	moneyDecimals, err := strconv.Atoi(moneyDecimalsStr)
+	if err != nil {
+		return nil, errors.New("invalid MONEY_DECIMALS value")
+	}
+
+	maxInFlightTradesStr := os.Getenv("MAX_IN_FLIGHT_TRADES")
+	if maxInFlightTradesStr == "" {
+		maxInFlightTradesStr = "500"
+	}
+	maxInFlightTrades, err := strconv.Atoi(maxInFlightTradesStr)
+	if err != nil {
+		return nil, errors.New("invalid MAX_IN_FLIGHT_TRADES value")
+	}
+
+	maxOpenTradesPerSymbolStr := os.Getenv("MAX_OPEN_TRADES_PER_SYMBOL")
+	if maxOpenTradesPerSymbolStr == "" {
+		maxOpenTradesPerSymbolStr = "0"
+	}
+	maxOpenTradesPerSymbol, err := strconv.Atoi(maxOpenTradesPerSymbolStr)
+	if err != nil {
+		return nil, errors.New("invalid MAX_OPEN_TRADES_PER_SYMBOL value")
+	}
+
+	demoCommissionRateStr := os.Getenv("DEMO_COMMISSION_RATE")
+	if demoCommissionRateStr == "" {
+		demoCommissionRateStr = "1"
+	}
+	demoCommissionRate, err := strconv.ParseFloat(demoCommissionRateStr, 64)
+	if err != nil {
+		return nil, errors.New("invalid DEMO_COMMISSION_RATE value")
+	}
+
+	demoSpreadRateStr := os.Getenv("DEMO_SPREAD_RATE")
+	if demoSpreadRateStr == "" {
+		demoSpreadRateStr = "1"
+	}
+	demoSpreadRate, err := strconv.ParseFloat(demoSpreadRateStr, 64)
+	if err != nil {
+		return nil, errors.New("invalid DEMO_SPREAD_RATE value")
+	}
+
+	wsCompressionStr := os.Getenv("WS_COMPRESSION")
+	if wsCompressionStr == "" {
+		wsCompressionStr = "true"
+	}
+	wsCompression, err := strconv.ParseBool(wsCompressionStr)
+	if err != nil {
+		return nil, errors.New("invalid WS_COMPRESSION value")
+	}
+
+	tlsCertFile := os.Getenv("TLS_CERT_FILE")
+	tlsKeyFile := os.Getenv("TLS_KEY_FILE")
+
+	mt5AllowedOrigins := splitList(os.Getenv("MT5_ALLOWED_ORIGINS"))
+	mt5AllowedIPs := splitList(os.Getenv("MT5_ALLOWED_IPS"))
+
+	logRetryQueueSizeStr := os.Getenv("LOG_RETRY_QUEUE_SIZE")
+	if logRetryQueueSizeStr == "" {
+		logRetryQueueSizeStr = "1000"
+	}
+	logRetryQueueSize, err := strconv.Atoi(logRetryQueueSizeStr)
+	if err != nil {
+		return nil, errors.New("invalid LOG_RETRY_QUEUE_SIZE value")
+	}
+
+	logFallbackPath := os.Getenv("LOG_FALLBACK_PATH")
+	if logFallbackPath == "" {
+		logFallbackPath = "audit_fallback.jsonl"
+	}
+
+	logRetentionDaysStr := os.Getenv("LOG_RETENTION_DAYS")
+	if logRetentionDaysStr == "" {
+		logRetentionDaysStr = "90"
+	}
+	logRetentionDays, err := strconv.Atoi(logRetentionDaysStr)
+	if err != nil {
+		return nil, errors.New("invalid LOG_RETENTION_DAYS value")
+	}
+
+	logHighValueRetentionDaysStr := os.Getenv("LOG_HIGH_VALUE_RETENTION_DAYS")
+	if logHighValueRetentionDaysStr == "" {
+		logHighValueRetentionDaysStr = "0"
+	}
+	logHighValueRetentionDays, err := strconv.Atoi(logHighValueRetentionDaysStr)
+	if err != nil {
+		return nil, errors.New("invalid LOG_HIGH_VALUE_RETENTION_DAYS value")
+	}
+
+	logHighValueActions := splitList(os.Getenv("LOG_HIGH_VALUE_ACTIONS"))
+	if len(logHighValueActions) == 0 {
+		logHighValueActions = []string{
+			"PlaceTrade", "PlaceTradeBatch", "TradeResponse", "CloseTrade", "CloseTradeGroup", "ModifyTrade", "InternalFill",
+			"ApproveTransaction", "DenyTransaction", "CreateTransaction", "TransferBalance", "SetExchangeRate",
+		}
+	}
+
+	leaderMinClosedTradesStr := os.Getenv("LEADER_MIN_CLOSED_TRADES")
+	if leaderMinClosedTradesStr == "" {
+		leaderMinClosedTradesStr = "0"
+	}
+	leaderMinClosedTrades, err := strconv.Atoi(leaderMinClosedTradesStr)
+	if err != nil {
+		return nil, errors.New("invalid LEADER_MIN_CLOSED_TRADES value")
+	}
+
+	leaderMinVolumeStr := os.Getenv("LEADER_MIN_VOLUME")
+	if leaderMinVolumeStr == "" {
+		leaderMinVolumeStr = "0"
+	}
+	leaderMinVolume, err := strconv.ParseFloat(leaderMinVolumeStr, 64)
+	if err != nil {
+		return nil, errors.New("invalid LEADER_MIN_VOLUME value")
+	}
+
+	leaderMinAccountAgeDaysStr := os.Getenv("LEADER_MIN_ACCOUNT_AGE_DAYS")
+	if leaderMinAccountAgeDaysStr == "" {
+		leaderMinAccountAgeDaysStr = "0"
+	}
+	leaderMinAccountAgeDays, err := strconv.Atoi(leaderMinAccountAgeDaysStr)
+	if err != nil {
+		return nil, errors.New("invalid LEADER_MIN_ACCOUNT_AGE_DAYS value")
+	}
+
+	copyTradeMaxMirrorFailuresStr := os.Getenv("COPY_TRADE_MAX_MIRROR_FAILURES")
+	if copyTradeMaxMirrorFailuresStr == "" {
+		copyTradeMaxMirrorFailuresStr = "3"
+	}
+	copyTradeMaxMirrorFailures, err := strconv.Atoi(copyTradeMaxMirrorFailuresStr)
+	if err != nil {
+		return nil, errors.New("invalid COPY_TRADE_MAX_MIRROR_FAILURES value")
+	}
+
+	copyTradeMirrorWorkersStr := os.Getenv("COPY_TRADE_MIRROR_WORKERS")
+	if copyTradeMirrorWorkersStr == "" {
+		copyTradeMirrorWorkersStr = "8"
+	}
+	copyTradeMirrorWorkers, err := strconv.Atoi(copyTradeMirrorWorkersStr)
+	if err != nil {
+		return nil, errors.New("invalid COPY_TRADE_MIRROR_WORKERS value")
+	}
+
+	priceFeedAPIKey := os.Getenv("PRICE_FEED_API_KEY")
+
+	priceOutlierPercentStr := os.Getenv("PRICE_OUTLIER_PERCENT")
+	if priceOutlierPercentStr == "" {
+		priceOutlierPercentStr = "10"
+	}
+	priceOutlierPercent, err := strconv.ParseFloat(priceOutlierPercentStr, 64)
+	if err != nil {
+		return nil, errors.New("invalid PRICE_OUTLIER_PERCENT value")
+	}
+
+	priceOutlierRecoveryStr := os.Getenv("PRICE_OUTLIER_RECOVERY_SECONDS")
+	if priceOutlierRecoveryStr == "" {
+		priceOutlierRecoveryStr = "30"
+	}
+	priceOutlierRecovery, err := strconv.Atoi(priceOutlierRecoveryStr)
+	if err != nil {
+		return nil, errors.New("invalid PRICE_OUTLIER_RECOVERY_SECONDS value")
+	}
+
+	maxPendingAlertsStr := os.Getenv("MAX_PENDING_ALERTS_PER_USER")
+	if maxPendingAlertsStr == "" {
+		maxPendingAlertsStr = "50"
+	}
+	maxPendingAlerts, err := strconv.Atoi(maxPendingAlertsStr)
+	if err != nil {
+		return nil, errors.New("invalid MAX_PENDING_ALERTS_PER_USER value")
+	}
+
+	mt5TimeoutAlertPercentStr := os.Getenv("MT5_TIMEOUT_ALERT_PERCENT")
+	if mt5TimeoutAlertPercentStr == "" {
+		mt5TimeoutAlertPercentStr = "20"
+	}
+	mt5TimeoutAlertPercent, err := strconv.ParseFloat(mt5TimeoutAlertPercentStr, 64)
+	if err != nil {
+		return nil, errors.New("invalid MT5_TIMEOUT_ALERT_PERCENT value")
+	}
+
+	mt5TimeoutAlertWindowStr := os.Getenv("MT5_TIMEOUT_ALERT_WINDOW_SECONDS")
+	if mt5TimeoutAlertWindowStr == "" {
+		mt5TimeoutAlertWindowStr = "300"
+	}
+	mt5TimeoutAlertWindow, err := strconv.Atoi(mt5TimeoutAlertWindowStr)
+	if err != nil {
+		return nil, errors.New("invalid MT5_TIMEOUT_ALERT_WINDOW_SECONDS value")
+	}
+
+	mt5TimeoutAlertMinSamplesStr := os.Getenv("MT5_TIMEOUT_ALERT_MIN_SAMPLES")
+	if mt5TimeoutAlertMinSamplesStr == "" {
+		mt5TimeoutAlertMinSamplesStr = "10"
+	}
+	mt5TimeoutAlertMinSamples, err := strconv.Atoi(mt5TimeoutAlertMinSamplesStr)
+	if err != nil {
+		return nil, errors.New("invalid MT5_TIMEOUT_ALERT_MIN_SAMPLES value")
+	}
+
+	mt5BreakerThresholdStr := os.Getenv("MT5_BREAKER_THRESHOLD")
+	if mt5BreakerThresholdStr == "" {
+		mt5BreakerThresholdStr = "5"
+	}
+	mt5BreakerThreshold, err := strconv.Atoi(mt5BreakerThresholdStr)
+	if err != nil {
+		return nil, errors.New("invalid MT5_BREAKER_THRESHOLD value")
+	}
+
+	mt5BreakerCooldownStr := os.Getenv("MT5_BREAKER_COOLDOWN_SECONDS")
+	if mt5BreakerCooldownStr == "" {
+		mt5BreakerCooldownStr = "30"
+	}
+	mt5BreakerCooldown, err := strconv.Atoi(mt5BreakerCooldownStr)
+	if err != nil {
+		return nil, errors.New("invalid MT5_BREAKER_COOLDOWN_SECONDS value")
+	}
+
+	mt5ResponseBufferStr := os.Getenv("MT5_RESPONSE_BUFFER")
+	if mt5ResponseBufferStr == "" {
+		mt5ResponseBufferStr = "100"
+	}
+	mt5ResponseBuffer, err := strconv.Atoi(mt5ResponseBufferStr)
+	if err != nil {
+		return nil, errors.New("invalid MT5_RESPONSE_BUFFER value")
+	}
+
+	tradeResponseBufferStr := os.Getenv("TRADE_RESPONSE_BUFFER")
+	if tradeResponseBufferStr == "" {
+		tradeResponseBufferStr = "1"
+	}
+	tradeResponseBuffer, err := strconv.Atoi(tradeResponseBufferStr)
+	if err != nil {
+		return nil, errors.New("invalid TRADE_RESPONSE_BUFFER value")
+	}
+
+	tradeResponseSendTimeoutStr := os.Getenv("TRADE_RESPONSE_SEND_TIMEOUT_MS")
+	if tradeResponseSendTimeoutStr == "" {
+		tradeResponseSendTimeoutStr = "500"
+	}
+	tradeResponseSendTimeout, err := strconv.Atoi(tradeResponseSendTimeoutStr)
+	if err != nil {
+		return nil, errors.New("invalid TRADE_RESPONSE_SEND_TIMEOUT_MS value")
+	}
+
+	requoteTTLStr := os.Getenv("REQUOTE_TTL_SECONDS")
+	if requoteTTLStr == "" {
+		requoteTTLStr = "10"
+	}
+	requoteTTL, err := strconv.Atoi(requoteTTLStr)
+	if err != nil {
+		return nil, errors.New("invalid REQUOTE_TTL_SECONDS value")
+	}
+
+	streamLifetimeStr := os.Getenv("STREAM_LIFETIME_MINUTES")
+	if streamLifetimeStr == "" {
+		streamLifetimeStr = "1440"
+	}
+	streamLifetime, err := strconv.Atoi(streamLifetimeStr)
+	if err != nil {
+		return nil, errors.New("invalid STREAM_LIFETIME_MINUTES value")
+	}
+
+	streamIdleTimeoutStr := os.Getenv("STREAM_IDLE_TIMEOUT_SECONDS")
+	if streamIdleTimeoutStr == "" {
+		streamIdleTimeoutStr = "300"
+	}
+	streamIdleTimeout, err := strconv.Atoi(streamIdleTimeoutStr)
+	if err != nil {
+		return nil, errors.New("invalid STREAM_IDLE_TIMEOUT_SECONDS value")
+	}
+
+	balanceRefreshTTLStr := os.Getenv("BALANCE_REFRESH_TTL_SECONDS")
+	if balanceRefreshTTLStr == "" {
+		balanceRefreshTTLStr = "5"
+	}
+	balanceRefreshTTL, err := strconv.Atoi(balanceRefreshTTLStr)
+	if err != nil {
+		return nil, errors.New("invalid BALANCE_REFRESH_TTL_SECONDS value")
+	}
+
+	reconcileIntervalStr := os.Getenv("RECONCILE_INTERVAL_MINUTES")
+	if reconcileIntervalStr == "" {
+		reconcileIntervalStr = "60"
+	}
+	reconcileInterval, err := strconv.Atoi(reconcileIntervalStr)
+	if err != nil {
+		return nil, errors.New("invalid RECONCILE_INTERVAL_MINUTES value")
+	}
+
+	reconcileToleranceStr := os.Getenv("RECONCILE_TOLERANCE")
+	if reconcileToleranceStr == "" {
+		reconcileToleranceStr = "0.01"
+	}
+	reconcileTolerance, err := strconv.ParseFloat(reconcileToleranceStr, 64)
+	if err != nil {
+		return nil, errors.New("invalid RECONCILE_TOLERANCE value")
+	}
+
+	reconcileFlagThresholdStr := os.Getenv("RECONCILE_FLAG_THRESHOLD")
+	if reconcileFlagThresholdStr == "" {
+		reconcileFlagThresholdStr = "100"
+	}
+	reconcileFlagThreshold, err := strconv.ParseFloat(reconcileFlagThresholdStr, 64)
+	if err != nil {
+		return nil, errors.New("invalid RECONCILE_FLAG_THRESHOLD value")
+	}
+
+	timezoneName := os.Getenv("TIMEZONE")
+	if timezoneName == "" {
+		timezoneName = "UTC"
+	}
+	timezone, err := time.LoadLocation(timezoneName)
+	if err != nil {
+		return nil, errors.New("invalid TIMEZONE value")
+	}
+
+	webhookURLs := splitList(os.Getenv("WEBHOOK_URLS"))
+	webhookSecret := os.Getenv("WEBHOOK_SECRET")
+
+	webhookMaxAttemptsStr := os.Getenv("WEBHOOK_MAX_ATTEMPTS")
+	if webhookMaxAttemptsStr == "" {
+		webhookMaxAttemptsStr = "6"
+	}
+	webhookMaxAttempts, err := strconv.Atoi(webhookMaxAttemptsStr)
+	if err != nil {
+		return nil, errors.New("invalid WEBHOOK_MAX_ATTEMPTS value")
+	}
+
+	webhookQueueSizeStr := os.Getenv("WEBHOOK_QUEUE_SIZE")
+	if webhookQueueSizeStr == "" {
+		webhookQueueSizeStr = "1000"
+	}
+	webhookQueueSize, err := strconv.Atoi(webhookQueueSizeStr)
+	if err != nil {
+		return nil, errors.New("invalid WEBHOOK_QUEUE_SIZE value")
+	}
+
 	return &Config{
 		Address:    address,
 		Port:       port,
@@ -94,5 +522,237 @@ func Load() (*Config, error) {
 		BotToken:   botToken,
 		MT5Port:    mt5Port,
 		ListenPort: listenPort,
+
+		BaseCurrency:     baseCurrency,
+		CurrencyRatesURL: currencyRatesURL,
+		CurrencyRateTTL:  time.Duration(currencyRateTTL) * time.Second,
+		MoneyDecimals:    moneyDecimals,
+		Timezone:         timezone,
+
+		MaxInFlightTrades:      maxInFlightTrades,
+		MaxOpenTradesPerSymbol: maxOpenTradesPerSymbol,
+
+		DemoCommissionRate: demoCommissionRate,
+		DemoSpreadRate:     demoSpreadRate,
+
+		WSCompression: wsCompression,
+
+		TLSCertFile: tlsCertFile,
+		TLSKeyFile:  tlsKeyFile,
+
+		MT5AllowedOrigins: mt5AllowedOrigins,
+		MT5AllowedIPs:     mt5AllowedIPs,
+
+		LogRetryQueueSize: logRetryQueueSize,
+		LogFallbackPath:   logFallbackPath,
+
+		LogRetention:          time.Duration(logRetentionDays) * 24 * time.Hour,
+		LogHighValueRetention: time.Duration(logHighValueRetentionDays) * 24 * time.Hour,
+		LogHighValueActions:   logHighValueActions,
+
+		LeaderMinClosedTrades: leaderMinClosedTrades,
+		LeaderMinVolume:       leaderMinVolume,
+		LeaderMinAccountAge:   time.Duration(leaderMinAccountAgeDays) * 24 * time.Hour,
+
+		CopyTradeMaxMirrorFailures: copyTradeMaxMirrorFailures,
+		CopyTradeMirrorWorkers:     copyTradeMirrorWorkers,
+
+		PriceFeedAPIKey: priceFeedAPIKey,
+
+		PriceOutlierPercent:  priceOutlierPercent,
+		PriceOutlierRecovery: time.Duration(priceOutlierRecovery) * time.Second,
+
+		MaxPendingAlertsPerUser: maxPendingAlerts,
+
+		MT5TimeoutAlertPercent:    mt5TimeoutAlertPercent,
+		MT5TimeoutAlertWindow:     time.Duration(mt5TimeoutAlertWindow) * time.Second,
+		MT5TimeoutAlertMinSamples: mt5TimeoutAlertMinSamples,
+
+		MT5BreakerThreshold: mt5BreakerThreshold,
+		MT5BreakerCooldown:  time.Duration(mt5BreakerCooldown) * time.Second,
+
+		MT5ResponseBuffer:        mt5ResponseBuffer,
+		TradeResponseBuffer:      tradeResponseBuffer,
+		TradeResponseSendTimeout: time.Duration(tradeResponseSendTimeout) * time.Millisecond,
+		RequoteTTL:               time.Duration(requoteTTL) * time.Second,
+		StreamLifetime:           time.Duration(streamLifetime) * time.Minute,
+		BalanceRefreshTTL:        time.Duration(balanceRefreshTTL) * time.Second,
+		StreamIdleTimeout:        time.Duration(streamIdleTimeout) * time.Second,
+
+		ReconcileInterval:      time.Duration(reconcileInterval) * time.Minute,
+		ReconcileTolerance:     reconcileTolerance,
+		ReconcileFlagThreshold: reconcileFlagThreshold,
+
+		WebhookURLs:        webhookURLs,
+		WebhookSecret:      webhookSecret,
+		WebhookMaxAttempts: webhookMaxAttempts,
+		WebhookQueueSize:   webhookQueueSize,
 	}, nil
 }
+
+// splitList parses a comma-separated env value, dropping empty entries.
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// TLSEnabled reports whether both servers should terminate TLS themselves.
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// Validate checks the loaded values and reports every problem in one error so
+// a misconfigured deployment can be fixed in a single pass.
+func (c *Config) Validate() error {
+	var problems []string
+
+	if c.MongoURI == "" {
+		problems = append(problems, "MONGO_URI is required")
+	} else if !strings.HasPrefix(c.MongoURI, "mongodb://") && !strings.HasPrefix(c.MongoURI, "mongodb+srv://") {
+		problems = append(problems, "MONGO_URI must start with mongodb:// or mongodb+srv://")
+	}
+	if c.Address == "" {
+		problems = append(problems, "ADDRESS is required")
+	}
+	if c.AdminUser == "" {
+		problems = append(problems, "ADMIN_USER is required")
+	}
+	if c.AdminPass == "" {
+		problems = append(problems, "ADMIN_PASS is required")
+	}
+	if c.JWTSecret == "" {
+		problems = append(problems, "JWT_SECRET is required")
+	}
+	if c.MT5Host == "" {
+		problems = append(problems, "MT5_HOST is required")
+	}
+	if c.PriceFeedAPIKey == "" {
+		problems = append(problems, "PRICE_FEED_API_KEY is required")
+	}
+
+	for name, port := range map[string]int{"PORT": c.Port, "MT5_PORT": c.MT5Port, "LISTEN_PORT": c.ListenPort} {
+		if port < 1 || port > 65535 {
+			problems = append(problems, fmt.Sprintf("%s must be between 1 and 65535, got %d", name, port))
+		}
+	}
+	if c.Port == c.ListenPort {
+		problems = append(problems, "PORT and LISTEN_PORT must differ")
+	}
+
+	if len(c.BaseCurrency) != 3 {
+		problems = append(problems, fmt.Sprintf("BASE_CURRENCY must be a 3-letter code, got %q", c.BaseCurrency))
+	}
+	if c.CurrencyRateTTL < time.Second || c.CurrencyRateTTL > 24*time.Hour {
+		problems = append(problems, "CURRENCY_RATE_TTL_SECONDS must be between 1 and 86400")
+	}
+	if c.MoneyDecimals < 0 || c.MoneyDecimals > 8 {
+		problems = append(problems, "MONEY_DECIMALS must be between 0 and 8")
+	}
+	if c.MaxInFlightTrades < 0 {
+		problems = append(problems, "MAX_IN_FLIGHT_TRADES must not be negative")
+	}
+	if c.MaxOpenTradesPerSymbol < 0 {
+		problems = append(problems, "MAX_OPEN_TRADES_PER_SYMBOL must not be negative")
+	}
+	if c.DemoCommissionRate < 0 || c.DemoCommissionRate > 1 || c.DemoSpreadRate < 0 || c.DemoSpreadRate > 1 {
+		problems = append(problems, "DEMO_COMMISSION_RATE and DEMO_SPREAD_RATE must be between 0 and 1")
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		problems = append(problems, "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	for name, path := range map[string]string{"TLS_CERT_FILE": c.TLSCertFile, "TLS_KEY_FILE": c.TLSKeyFile} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			problems = append(problems, fmt.Sprintf("%s is not readable: %v", name, err))
+		}
+	}
+
+	if c.LogRetryQueueSize < 1 {
+		problems = append(problems, "LOG_RETRY_QUEUE_SIZE must be at least 1")
+	}
+	if c.LogFallbackPath == "" {
+		problems = append(problems, "LOG_FALLBACK_PATH is required")
+	}
+	if c.LogRetention < 0 || c.LogHighValueRetention < 0 {
+		problems = append(problems, "LOG_RETENTION_DAYS and LOG_HIGH_VALUE_RETENTION_DAYS must not be negative")
+	}
+	if c.LeaderMinClosedTrades < 0 || c.LeaderMinVolume < 0 || c.LeaderMinAccountAge < 0 {
+		problems = append(problems, "LEADER_MIN_CLOSED_TRADES, LEADER_MIN_VOLUME and LEADER_MIN_ACCOUNT_AGE_DAYS must not be negative")
+	}
+	if c.CopyTradeMirrorWorkers < 1 {
+		problems = append(problems, "COPY_TRADE_MIRROR_WORKERS must be at least 1")
+	}
+	if c.PriceOutlierPercent < 0 || c.PriceOutlierRecovery < 0 {
+		problems = append(problems, "PRICE_OUTLIER_PERCENT and PRICE_OUTLIER_RECOVERY_SECONDS must not be negative")
+	}
+	if c.MaxPendingAlertsPerUser < 0 {
+		problems = append(problems, "MAX_PENDING_ALERTS_PER_USER must not be negative")
+	}
+	if c.MT5TimeoutAlertPercent < 0 || c.MT5TimeoutAlertPercent > 100 {
+		problems = append(problems, "MT5_TIMEOUT_ALERT_PERCENT must be between 0 and 100")
+	}
+	if c.MT5TimeoutAlertWindow < time.Second || c.MT5TimeoutAlertMinSamples < 1 {
+		problems = append(problems, "MT5_TIMEOUT_ALERT_WINDOW_SECONDS and MT5_TIMEOUT_ALERT_MIN_SAMPLES must be at least 1")
+	}
+	if c.MT5BreakerThreshold < 0 {
+		problems = append(problems, "MT5_BREAKER_THRESHOLD must not be negative")
+	}
+	if c.MT5BreakerThreshold > 0 && c.MT5BreakerCooldown < time.Second {
+		problems = append(problems, "MT5_BREAKER_COOLDOWN_SECONDS must be at least 1 when the breaker is enabled")
+	}
+	if c.MT5ResponseBuffer < 1 || c.TradeResponseBuffer < 1 {
+		problems = append(problems, "MT5_RESPONSE_BUFFER and TRADE_RESPONSE_BUFFER must be at least 1")
+	}
+	if c.TradeResponseSendTimeout < 0 {
+		problems = append(problems, "TRADE_RESPONSE_SEND_TIMEOUT_MS must not be negative")
+	}
+	if c.RequoteTTL < time.Second {
+		problems = append(problems, "REQUOTE_TTL_SECONDS must be at least 1")
+	}
+	if c.StreamLifetime < time.Minute {
+		problems = append(problems, "STREAM_LIFETIME_MINUTES must be at least 1")
+	}
+	// Clients are pinged every 54 seconds, so a shorter idle timeout would
+	// close healthy streams.
+	if c.StreamIdleTimeout < 0 || (c.StreamIdleTimeout > 0 && c.StreamIdleTimeout < time.Minute) {
+		problems = append(problems, "STREAM_IDLE_TIMEOUT_SECONDS must be 0 or at least 60")
+	}
+	if c.BalanceRefreshTTL < 0 {
+		problems = append(problems, "BALANCE_REFRESH_TTL_SECONDS must not be negative")
+	}
+	if c.ReconcileInterval < 0 || c.ReconcileTolerance < 0 || c.ReconcileFlagThreshold < 0 {
+		problems = append(problems, "RECONCILE_INTERVAL_MINUTES, RECONCILE_TOLERANCE and RECONCILE_FLAG_THRESHOLD must not be negative")
+	}
+	if len(c.WebhookURLs) > 0 && c.WebhookSecret == "" {
+		problems = append(problems, "WEBHOOK_SECRET is required when WEBHOOK_URLS is set")
+	}
+	for _, raw := range c.WebhookURLs {
+		if u, err := url.Parse(raw); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("WEBHOOK_URLS entry %q is not an http(s) URL", raw))
+		}
+	}
+	if c.WebhookMaxAttempts < 1 || c.WebhookQueueSize < 1 {
+		problems = append(problems, "WEBHOOK_MAX_ATTEMPTS and WEBHOOK_QUEUE_SIZE must be at least 1")
+	}
+	for _, entry := range c.MT5AllowedIPs {
+		if net.ParseIP(entry) == nil {
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				problems = append(problems, fmt.Sprintf("MT5_ALLOWED_IPS entry %q is not an IP or CIDR", entry))
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+}
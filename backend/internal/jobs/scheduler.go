@@ -0,0 +1,93 @@
+// Package jobs runs periodic background work. Every instance schedules the
+// same jobs, but each run first takes a lease in MongoDB, so a job executes on
+// only one instance at a time when the service is scaled out.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mehrbod2002/fxtrader/internal/repository"
+)
+
+// Job is a task run every Interval. Name identifies its lease and must be
+// unique across the jobs of all instances.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+type Scheduler struct {
+	leases repository.JobLeaseRepository
+	owner  string
+	jobs   []Job
+}
+
+func NewScheduler(leases repository.JobLeaseRepository) *Scheduler {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return &Scheduler{
+		leases: leases,
+		owner:  fmt.Sprintf("%s-%s", host, uuid.New().String()),
+	}
+}
+
+// Register adds a job. Jobs registered after Start are not run.
+func (s *Scheduler) Register(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Start runs every registered job on its own ticker until ctx is cancelled,
+// then releases the leases this instance holds.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		go s.loop(ctx, job)
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.leases.ReleaseLease(context.Background(), job.Name, s.owner); err != nil {
+				log.Printf("Failed to release lease for job %s: %v", job.Name, err)
+			}
+			return
+		case <-ticker.C:
+			s.tick(ctx, job)
+		}
+	}
+}
+
+// tick runs the job if this instance holds its lease. The lease outlives two
+// intervals, so the holder renews it every tick and another instance only
+// takes over once the holder has missed a run. A run that takes longer than
+// that may overlap with the next holder's.
+func (s *Scheduler) tick(ctx context.Context, job Job) {
+	held, err := s.leases.AcquireLease(ctx, job.Name, s.owner, 2*job.Interval)
+	if err != nil {
+		log.Printf("Failed to acquire lease for job %s: %v", job.Name, err)
+		return
+	}
+	if !held {
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Job %s panicked: %v", job.Name, r)
+		}
+	}()
+	if err := job.Run(ctx); err != nil {
+		log.Printf("Job %s failed: %v", job.Name, err)
+	}
+}
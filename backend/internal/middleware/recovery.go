@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/google/uuid"
+	"github.com/mehrbod2002/fxtrader/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RequestIDHeader carries the ID of a request back to the client.
+const RequestIDHeader = "X-Request-ID"
+
+// RecoveryMiddleware tags every request with an ID and turns a panic in a
+// later handler into a JSON 500 that quotes it. The panic, with its stack,
+// route and user, is written to the audit trail under the same ID so support
+// can find it from what the user reports.
+func RecoveryMiddleware(logService service.LogService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := uuid.New().String()
+		c.Set("request_id", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			stack := string(debug.Stack())
+			userID := c.GetString("user_id")
+			log.Printf("panic in %s %s (request %s): %v\n%s", c.Request.Method, c.Request.URL.Path, requestID, recovered, stack)
+
+			userObjID, _ := primitive.ObjectIDFromHex(userID)
+			metadata := map[string]interface{}{
+				"request_id": requestID,
+				"method":     c.Request.Method,
+				"route":      c.FullPath(),
+				"path":       c.Request.URL.Path,
+				"user_id":    userID,
+				"stack":      stack,
+			}
+			if err := logService.LogAction(userObjID, "Panic", fmt.Sprintf("%v", recovered), c.ClientIP(), metadata); err != nil {
+				log.Printf("error: %v", err)
+			}
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":      "Internal server error",
+				"request_id": requestID,
+			})
+		}()
+
+		c.Next()
+	}
+}
@@ -19,7 +19,7 @@ func UserAuthMiddleware(userService service.UserService) gin.HandlerFunc {
 			return
 		}
 
-		user, err := userService.GetUserByTelegramID(telegramID)
+		user, err := userService.GetUserByTelegramID(c.Request.Context(), telegramID)
 		if err != nil || user == nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid Telegram ID"})
 			return
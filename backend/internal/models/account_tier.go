@@ -0,0 +1,110 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// AccountTier grades an account for leverage and commission. Accounts that
+// were never assigned a tier are BASIC.
+type AccountTier string
+
+const (
+	AccountTierBasic AccountTier = "BASIC"
+	AccountTierPro   AccountTier = "PRO"
+	AccountTierVIP   AccountTier = "VIP"
+)
+
+// ParseAccountTier accepts a tier name in any casing.
+func ParseAccountTier(tier string) (AccountTier, error) {
+	switch t := AccountTier(strings.ToUpper(strings.TrimSpace(tier))); t {
+	case AccountTierBasic, AccountTierPro, AccountTierVIP:
+		return t, nil
+	}
+	return "", fmt.Errorf("unknown account tier %q: must be BASIC, PRO or VIP", tier)
+}
+
+// EffectiveTier is the account's tier, BASIC when none has been assigned.
+func (a *Account) EffectiveTier() AccountTier {
+	if a.Tier == "" {
+		return AccountTierBasic
+	}
+	return a.Tier
+}
+
+// TierTerms are a symbol's trading terms for one account tier. MinLeverage
+// and MaxLeverage bound the leverage an order may use (zero leaves that side
+// to the symbol); CommissionFee, when set, replaces the symbol's base
+// CommissionFee while volume commission tiers still apply on top.
+type TierTerms struct {
+	Tier          AccountTier `json:"tier" bson:"tier"`
+	MinLeverage   int         `json:"min_leverage,omitempty" bson:"min_leverage,omitempty"`
+	MaxLeverage   int         `json:"max_leverage,omitempty" bson:"max_leverage,omitempty"`
+	CommissionFee *float64    `json:"commission_fee,omitempty" bson:"commission_fee,omitempty"`
+}
+
+// TermsFor returns the symbol's terms for tier, or nil when it has none.
+func (s *Symbol) TermsFor(tier AccountTier) *TierTerms {
+	for i := range s.TierTerms {
+		if s.TierTerms[i].Tier == tier {
+			return &s.TierTerms[i]
+		}
+	}
+	return nil
+}
+
+// LeverageRange is the lowest and highest leverage an account of the tier may
+// use on the symbol. The minimum is at least 1.
+func (s *Symbol) LeverageRange(tier AccountTier) (int, int) {
+	low, high := 1, s.LeverageLimit()
+	if terms := s.TermsFor(tier); terms != nil {
+		if terms.MinLeverage > 0 {
+			low = terms.MinLeverage
+		}
+		if terms.MaxLeverage > 0 {
+			high = terms.MaxLeverage
+		}
+	}
+	return low, high
+}
+
+// CommissionForTier returns the commission an account of the tier pays on
+// the symbol given its rolling volume, and the volume tier applied (0 for the
+// base fee). The tier's fee, if set, is the base instead of CommissionFee.
+func (s *Symbol) CommissionForTier(tier AccountTier, volume float64) (float64, int) {
+	base := s.CommissionFee
+	if terms := s.TermsFor(tier); terms != nil && terms.CommissionFee != nil {
+		base = *terms.CommissionFee
+	}
+	return s.commissionFrom(base, volume)
+}
+
+// ValidateTierTerms normalizes the tier names and rejects unknown or repeated
+// tiers, negative values and a minimum above the maximum.
+func (s *Symbol) ValidateTierTerms() error {
+	seen := make(map[AccountTier]bool, len(s.TierTerms))
+	for i := range s.TierTerms {
+		terms := &s.TierTerms[i]
+		tier, err := ParseAccountTier(string(terms.Tier))
+		if err != nil {
+			return err
+		}
+		if seen[tier] {
+			return fmt.Errorf("terms for tier %s are listed twice", tier)
+		}
+		seen[tier] = true
+		terms.Tier = tier
+
+		if terms.MinLeverage < 0 || terms.MaxLeverage < 0 {
+			return errors.New("tier leverage cannot be negative")
+		}
+		if terms.MaxLeverage > 0 && terms.MinLeverage > terms.MaxLeverage {
+			return fmt.Errorf("minimum leverage for tier %s exceeds its maximum", tier)
+		}
+		if terms.CommissionFee != nil && *terms.CommissionFee < 0 {
+			return fmt.Errorf("commission fee for tier %s cannot be negative", tier)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package models
+
+import "strings"
+
+// Account types are stored, recorded on trades and sent to MT5 in lowercase.
+// Older records and clients use "DEMO"/"REAL", so incoming values go through
+// NormalizeAccountType and comparisons through SameAccountType.
+const (
+	AccountTypeDemo = "demo"
+	AccountTypeReal = "real"
+	// AccountTypeMain is the user's main balance, which only transfers name.
+	AccountTypeMain = "main"
+)
+
+func NormalizeAccountType(accountType string) string {
+	return strings.ToLower(strings.TrimSpace(accountType))
+}
+
+// IsTradingAccountType reports whether accountType is demo or real in any casing.
+func IsTradingAccountType(accountType string) bool {
+	switch NormalizeAccountType(accountType) {
+	case AccountTypeDemo, AccountTypeReal:
+		return true
+	}
+	return false
+}
+
+func SameAccountType(a, b string) bool {
+	return NormalizeAccountType(a) == NormalizeAccountType(b)
+}
@@ -21,6 +21,17 @@ const (
 	AlertStatusExpired   AlertStatus = "EXPIRED"
 )
 
+// AlertTrigger selects how a PRICE alert's condition is evaluated. The empty
+// value keeps the original behaviour of firing inside the SL/TP band.
+type AlertTrigger string
+
+const (
+	AlertTriggerLevel         AlertTrigger = "LEVEL"
+	AlertTriggerCrossAbove    AlertTrigger = "CROSS_ABOVE"
+	AlertTriggerCrossBelow    AlertTrigger = "CROSS_BELOW"
+	AlertTriggerPercentChange AlertTrigger = "PERCENT_CHANGE"
+)
+
 type Alert struct {
 	ID                 primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
 	UserID             string             `json:"user_id" bson:"user_id"`
@@ -33,9 +44,16 @@ type Alert struct {
 	NotificationMethod string             `json:"notification_method" bson:"notification_method"`
 }
 
+// AlertCondition describes when an alert fires. Crossing triggers compare
+// PriceTarget against the previous and current bid; PERCENT_CHANGE fires once
+// the bid has moved PercentChange percent either way from ReferencePrice,
+// which is the bid at the time the alert was created or last edited.
 type AlertCondition struct {
-	PriceTarget *float64   `json:"price_target,omitempty" bson:"price_target,omitempty"`
-	SL          *float64   `json:"sl,omitempty" bson:"sl,omitempty"`
-	TP          *float64   `json:"tp,omitempty" bson:"tp,omitempty"`
-	TriggerTime *time.Time `json:"trigger_time,omitempty" bson:"trigger_time,omitempty"`
+	Trigger        AlertTrigger `json:"trigger,omitempty" bson:"trigger,omitempty"`
+	PriceTarget    *float64     `json:"price_target,omitempty" bson:"price_target,omitempty"`
+	SL             *float64     `json:"sl,omitempty" bson:"sl,omitempty"`
+	TP             *float64     `json:"tp,omitempty" bson:"tp,omitempty"`
+	PercentChange  *float64     `json:"percent_change,omitempty" bson:"percent_change,omitempty"`
+	ReferencePrice *float64     `json:"reference_price,omitempty" bson:"reference_price,omitempty"`
+	TriggerTime    *time.Time   `json:"trigger_time,omitempty" bson:"trigger_time,omitempty"`
 }
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+type AnnouncementSeverity string
+
+const (
+	AnnouncementInfo     AnnouncementSeverity = "INFO"
+	AnnouncementWarning  AnnouncementSeverity = "WARNING"
+	AnnouncementCritical AnnouncementSeverity = "CRITICAL"
+)
+
+// Announcement is a platform message pushed to connected clients. When Symbol
+// is set only clients subscribed to that symbol receive it.
+type Announcement struct {
+	Type      string               `json:"type"`
+	Message   string               `json:"message"`
+	Severity  AnnouncementSeverity `json:"severity"`
+	Symbol    string               `json:"symbol,omitempty"`
+	CreatedAt time.Time            `json:"created_at"`
+}
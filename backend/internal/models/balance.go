@@ -1,5 +1,9 @@
 package models
 
+// BalanceAccountMain is the AccountType of balance updates for a user's main
+// wallet balance, as opposed to a DEMO or REAL trading account.
+const BalanceAccountMain = "MAIN"
+
 type BalanceData struct {
 	UserID      string  `json:"user_id"`
 	AccountType string  `json:"account_type"`
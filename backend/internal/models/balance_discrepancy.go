@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BalanceDiscrepancy records an account whose platform balance differed from
+// the balance MT5 reported during reconciliation. Difference is platform
+// minus broker. Flagged discrepancies exceeded the review threshold and stay
+// open until an admin marks them reviewed; the balance itself is never
+// changed by reconciliation.
+type BalanceDiscrepancy struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	AccountID       primitive.ObjectID `bson:"account_id" json:"account_id"`
+	UserID          primitive.ObjectID `bson:"user_id" json:"user_id"`
+	AccountName     string             `bson:"account_name" json:"account_name"`
+	AccountType     string             `bson:"account_type" json:"account_type"`
+	WalletID        string             `bson:"wallet_id" json:"wallet_id"`
+	PlatformBalance float64            `bson:"platform_balance" json:"platform_balance"`
+	BrokerBalance   float64            `bson:"broker_balance" json:"broker_balance"`
+	Difference      float64            `bson:"difference" json:"difference"`
+	Flagged         bool               `bson:"flagged" json:"flagged"`
+	Reviewed        bool               `bson:"reviewed" json:"reviewed"`
+	ReviewNote      string             `bson:"review_note,omitempty" json:"review_note,omitempty"`
+	ReviewedAt      *time.Time         `bson:"reviewed_at,omitempty" json:"reviewed_at,omitempty"`
+	CheckedAt       time.Time          `bson:"checked_at" json:"checked_at"`
+}
@@ -10,10 +10,11 @@ import (
 type Client struct {
 	ID           string
 	Conn         *websocket.Conn
-	Send         chan *PriceData
+	Prices       *PriceQueue
 	SendTrade    chan *TradeHistory
 	SendBalance  chan *BalanceData
 	SendOrders   chan OrderStreamResponse
+	SendNotice   chan *Announcement
 	Symbols      map[string]bool
 	SymbolsMu    sync.RWMutex
 	CloseHandler func()
@@ -46,10 +47,11 @@ func NewClient(id string, conn *websocket.Conn) *Client {
 	return &Client{
 		ID:          id,
 		Conn:        conn,
-		Send:        make(chan *PriceData, 256),
+		Prices:      NewPriceQueue(256),
 		SendTrade:   make(chan *TradeHistory, 256),
 		SendBalance: make(chan *BalanceData, 256),
 		SendOrders:  make(chan OrderStreamResponse, 256),
+		SendNotice:  make(chan *Announcement, 16),
 		Symbols:     make(map[string]bool),
 	}
 }
@@ -76,7 +78,10 @@ func (c *Client) Close() {
 	if c.CloseHandler != nil {
 		c.CloseHandler()
 	}
-	c.Conn.Close()
+	// Server-sent event clients have no socket of their own.
+	if c.Conn != nil {
+		c.Conn.Close()
+	}
 }
 
 type SocketMessage struct {
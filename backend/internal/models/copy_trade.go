@@ -1,6 +1,9 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -9,8 +12,9 @@ import (
 type ActivceStatus string
 
 const (
-	Active   ActivceStatus = "active"
-	Inactive ActivceStatus = "inactive"
+	Active   ActivceStatus = "ACTIVE"
+	Inactive ActivceStatus = "INACTIVE"
+	Paused   ActivceStatus = "PAUSED"
 )
 
 type CopyTradeSubscription struct {
@@ -22,7 +26,96 @@ type CopyTradeSubscription struct {
 	LeaderIDTelegram   string             `json:"leader_id_telegram" bson:"leader_id_telegram"`
 	AllocatedAmount    float64            `json:"allocated_amount" bson:"allocated_amount"`
 	Status             ActivceStatus      `json:"status" bson:"status"`
+	PausedReason       string             `json:"paused_reason,omitempty" bson:"paused_reason,omitempty"`
+	MirrorFailures     int                `json:"mirror_failures" bson:"mirror_failures"`
 	CreatedAt          time.Time          `json:"created_at" bson:"created_at"`
+	CancelledAt        *time.Time         `json:"cancelled_at,omitempty" bson:"cancelled_at,omitempty"`
+
+	CopySettings `bson:",inline"`
+}
+
+type SizingMode string
+
+const (
+	SizingProportional SizingMode = "PROPORTIONAL"
+	SizingFixedLot     SizingMode = "FIXED_LOT"
+	SizingMultiplier   SizingMode = "MULTIPLIER"
+)
+
+// CopySettings are the follower's rules for which leader trades are copied
+// and at what size. PROPORTIONAL (the default) scales by the leader's volume
+// to balance ratio, FIXED_LOT always copies FixedLot lots and MULTIPLIER
+// copies the leader's volume times Multiplier.
+type CopySettings struct {
+	AllowedSymbols  []string   `json:"allowed_symbols,omitempty" bson:"allowed_symbols,omitempty"`
+	MinLeaderVolume float64    `json:"min_leader_volume,omitempty" bson:"min_leader_volume,omitempty"`
+	SizingMode      SizingMode `json:"sizing_mode,omitempty" bson:"sizing_mode,omitempty"`
+	FixedLot        float64    `json:"fixed_lot,omitempty" bson:"fixed_lot,omitempty"`
+	Multiplier      float64    `json:"multiplier,omitempty" bson:"multiplier,omitempty"`
+	// SymbolMap substitutes follower symbols for leader ones, e.g. when the
+	// follower's account trades XAUUSD.m rather than XAUUSD.
+	SymbolMap map[string]string `json:"symbol_map,omitempty" bson:"symbol_map,omitempty"`
+}
+
+func (c *CopySettings) Validate() error {
+	if c.MinLeaderVolume < 0 {
+		return errors.New("minimum leader volume cannot be negative")
+	}
+	switch c.SizingMode {
+	case "", SizingProportional:
+	case SizingFixedLot:
+		if c.FixedLot <= 0 {
+			return errors.New("fixed lot must be positive for FIXED_LOT sizing")
+		}
+	case SizingMultiplier:
+		if c.Multiplier <= 0 {
+			return errors.New("multiplier must be positive for MULTIPLIER sizing")
+		}
+	default:
+		return fmt.Errorf("unknown sizing mode %q", c.SizingMode)
+	}
+	return nil
+}
+
+// Copies reports whether a leader trade passes the follower's filter. An
+// empty AllowedSymbols list copies every symbol.
+func (c *CopySettings) Copies(trade *TradeHistory) bool {
+	if trade.Volume < c.MinLeaderVolume {
+		return false
+	}
+	if len(c.AllowedSymbols) == 0 {
+		return true
+	}
+	for _, symbol := range c.AllowedSymbols {
+		if strings.EqualFold(symbol, trade.Symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// FollowerSymbol returns the symbol to copy a leader trade on, applying
+// SymbolMap when it has an entry for the leader's symbol.
+func (c *CopySettings) FollowerSymbol(leaderSymbol string) string {
+	for from, to := range c.SymbolMap {
+		if strings.EqualFold(from, leaderSymbol) {
+			return to
+		}
+	}
+	return leaderSymbol
+}
+
+// FollowerVolume sizes the follower's copy of a leader trade. proportional is
+// the volume PROPORTIONAL sizing would use.
+func (c *CopySettings) FollowerVolume(leaderVolume, proportional float64) float64 {
+	switch c.SizingMode {
+	case SizingFixedLot:
+		return c.FixedLot
+	case SizingMultiplier:
+		return leaderVolume * c.Multiplier
+	default:
+		return proportional
+	}
 }
 
 type CopyTrade struct {
@@ -34,3 +127,36 @@ type CopyTrade struct {
 	FollowerTradeIDTelegran primitive.ObjectID `json:"follower_trade_id_telegram" bson:"follower_trade_id_telegram"`
 	CreatedAt               time.Time          `json:"created_at" bson:"created_at"`
 }
+
+// CopyTradeLeg is one side of a mirrored trade as shown in copy trade history.
+type CopyTradeLeg struct {
+	TradeID   primitive.ObjectID `json:"trade_id"`
+	Symbol    string             `json:"symbol"`
+	TradeType TradeType          `json:"trade_type"`
+	Volume    float64            `json:"volume"`
+	Status    string             `json:"status"`
+	Profit    float64            `json:"profit"`
+}
+
+// CopyTradeHistoryEntry pairs a leader trade with the follower's copy. A leg
+// is nil when its trade no longer exists.
+type CopyTradeHistoryEntry struct {
+	CopyTradeID   primitive.ObjectID `json:"copy_trade_id"`
+	CreatedAt     time.Time          `json:"created_at"`
+	LeaderTrade   *CopyTradeLeg      `json:"leader_trade"`
+	FollowerTrade *CopyTradeLeg      `json:"follower_trade"`
+}
+
+func NewCopyTradeLeg(trade *TradeHistory) *CopyTradeLeg {
+	if trade == nil {
+		return nil
+	}
+	return &CopyTradeLeg{
+		TradeID:   trade.ID,
+		Symbol:    trade.Symbol,
+		TradeType: trade.TradeType,
+		Volume:    trade.Volume,
+		Status:    trade.Status,
+		Profit:    trade.Profit,
+	}
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ExchangeRate struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	From      string             `bson:"from" json:"from"`
+	To        string             `bson:"to" json:"to"`
+	Rate      float64            `bson:"rate" json:"rate"`
+	UpdatedBy string             `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DeadLetterReasonOrphan marks a response MT5 sent for a trade the platform
+// does not know, kept for reconciliation.
+const DeadLetterReasonOrphan = "ORPHAN_RESPONSE"
+
+// DeadLetter is an MT5 message that could not be processed, kept verbatim for debugging.
+type DeadLetter struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ClientID    string             `bson:"client_id" json:"client_id"`
+	MessageType string             `bson:"message_type,omitempty" json:"message_type,omitempty"`
+	Reason      string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	TradeID     string             `bson:"trade_id,omitempty" json:"trade_id,omitempty"`
+	Raw         string             `bson:"raw" json:"raw"`
+	Error       string             `bson:"error" json:"error"`
+	ReceivedAt  time.Time          `bson:"received_at" json:"received_at"`
+}
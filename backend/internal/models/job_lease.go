@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// JobLease records which instance currently runs a periodic job. A lease past
+// ExpiresAt is free for any instance to take.
+type JobLease struct {
+	Name      string    `bson:"_id" json:"name"`
+	Owner     string    `bson:"owner" json:"owner"`
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"`
+	RenewedAt time.Time `bson:"renewed_at" json:"renewed_at"`
+}
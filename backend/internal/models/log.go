@@ -14,4 +14,5 @@ type LogEntry struct {
 	IPAddress   string                 `json:"ip_address,omitempty" bson:"ip_address,omitempty"`
 	Timestamp   time.Time              `json:"timestamp" bson:"timestamp"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty" bson:"metadata,omitempty"`
+	ExpireAt    *time.Time             `json:"expire_at,omitempty" bson:"expire_at,omitempty"`
 }
@@ -1,8 +1,32 @@
 package models
 
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
 type PriceData struct {
 	Symbol    string  `json:"symbol"`
 	Ask       float64 `json:"ask"`
 	Bid       float64 `json:"bid"`
 	Timestamp int64   `json:"timestamp"`
 }
+
+// Validate checks the invariants every tick must hold before it is stored or
+// evaluated: a symbol, finite positive quotes and an ask no lower than the bid.
+func (p *PriceData) Validate() error {
+	if strings.TrimSpace(p.Symbol) == "" {
+		return errors.New("symbol is required")
+	}
+	if math.IsNaN(p.Bid) || math.IsInf(p.Bid, 0) || math.IsNaN(p.Ask) || math.IsInf(p.Ask, 0) {
+		return errors.New("bid and ask must be finite numbers")
+	}
+	if p.Bid <= 0 || p.Ask <= 0 {
+		return errors.New("bid and ask must be positive")
+	}
+	if p.Ask < p.Bid {
+		return errors.New("ask must not be below bid")
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package models
+
+import "sync"
+
+type BackpressurePolicy string
+
+const (
+	// PolicyCoalesce keeps only the latest pending tick per symbol.
+	PolicyCoalesce BackpressurePolicy = "coalesce"
+	// PolicyDropOldest queues every tick and evicts the oldest once full.
+	PolicyDropOldest BackpressurePolicy = "drop-oldest"
+)
+
+// PriceQueue is a bounded per-client buffer of price ticks. Unlike a buffered
+// channel it never drops the newest tick: coalesced symbols overwrite their
+// pending entry and, when full, the oldest entry is evicted.
+type PriceQueue struct {
+	mu       sync.Mutex
+	items    []*PriceData
+	capacity int
+	policies map[string]BackpressurePolicy
+	dropped  uint64
+	ready    chan struct{}
+}
+
+func NewPriceQueue(capacity int) *PriceQueue {
+	return &PriceQueue{
+		items:    make([]*PriceData, 0, capacity),
+		capacity: capacity,
+		policies: make(map[string]BackpressurePolicy),
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+func ParseBackpressurePolicy(s string) (BackpressurePolicy, bool) {
+	switch BackpressurePolicy(s) {
+	case "", PolicyCoalesce:
+		return PolicyCoalesce, true
+	case PolicyDropOldest:
+		return PolicyDropOldest, true
+	default:
+		return "", false
+	}
+}
+
+func (q *PriceQueue) SetPolicy(symbol string, policy BackpressurePolicy) {
+	q.mu.Lock()
+	q.policies[symbol] = policy
+	q.mu.Unlock()
+}
+
+func (q *PriceQueue) Push(price *PriceData) {
+	q.mu.Lock()
+	policy, ok := q.policies[price.Symbol]
+	if !ok {
+		policy = PolicyCoalesce
+	}
+
+	replaced := false
+	if policy == PolicyCoalesce {
+		for i, pending := range q.items {
+			if pending.Symbol == price.Symbol {
+				q.items[i] = price
+				replaced = true
+				break
+			}
+		}
+	}
+	if !replaced {
+		if len(q.items) >= q.capacity {
+			q.items = q.items[1:]
+			q.dropped++
+		}
+		q.items = append(q.items, price)
+	}
+	q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Ready is signalled whenever new ticks are available to Drain.
+func (q *PriceQueue) Ready() <-chan struct{} {
+	return q.ready
+}
+
+func (q *PriceQueue) Drain() []*PriceData {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	items := q.items
+	q.items = make([]*PriceData, 0, q.capacity)
+	return items
+}
+
+func (q *PriceQueue) Dropped() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// SettlementLine sums the closed trades of one user or one symbol. Commission
+// and swap are signed as reported by MT5, so NetProfit = Profit + Commission + Swap.
+type SettlementLine struct {
+	Key        string  `bson:"_id" json:"key"`
+	Trades     int     `bson:"trades" json:"trades"`
+	Profit     float64 `bson:"profit" json:"profit"`
+	Commission float64 `bson:"commission" json:"commission"`
+	Swap       float64 `bson:"swap" json:"swap"`
+	NetProfit  float64 `bson:"net_profit" json:"net_profit"`
+}
+
+type SettlementReport struct {
+	From        time.Time        `bson:"-" json:"from"`
+	To          time.Time        `bson:"-" json:"to"`
+	AccountType string           `bson:"-" json:"account_type,omitempty"`
+	ByUser      []SettlementLine `bson:"by_user" json:"by_user"`
+	BySymbol    []SettlementLine `bson:"by_symbol" json:"by_symbol"`
+}
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -13,20 +17,203 @@ type Symbol struct {
 	Category             string             `json:"category" bson:"category"`
 	DeniedAccounts       []string           `json:"denied_accounts" bson:"denied_accounts"`
 	Leverage             int                `json:"leverage" bson:"leverage"`
+	MaxLeverage          int                `json:"max_leverage,omitempty" bson:"max_leverage,omitempty"`
+	MarginRate           float64            `json:"margin_rate,omitempty" bson:"margin_rate,omitempty"`
 	MinLot               float64            `json:"min_lot" bson:"min_lot"`
 	MaxLot               float64            `json:"max_lot" bson:"max_lot"`
 	Spread               float64            `json:"spread" bson:"spread"`
+	Digits               int                `json:"digits" bson:"digits"`
+	TickSize             float64            `json:"tick_size" bson:"tick_size"`
 	CommissionDeposit    float64            `json:"commission_deposit" bson:"commission_deposit"`
 	CommissionFee        float64            `json:"commission_fee" bson:"commission_fee"`
 	CommissionWithdrawal float64            `json:"commission_withdrawal" bson:"commission_withdrawal"`
+	CommissionTiers      []CommissionTier   `json:"commission_tiers,omitempty" bson:"commission_tiers,omitempty"`
+	TierTerms            []TierTerms        `json:"tier_terms,omitempty" bson:"tier_terms,omitempty"`
 	TradingHours         TradingHours       `json:"trading_hours" bson:"trading_hours"`
 	IsTradingOpen        bool               `json:"is_trading_open" bson:"is_trading_open"`
+	NewsHalt             *NewsHalt          `json:"news_halt,omitempty" bson:"news_halt,omitempty"`
+	MaxOpenTrades        int                `json:"max_open_trades,omitempty" bson:"max_open_trades,omitempty"`
+	StopsLevel           int                `json:"stops_level,omitempty" bson:"stops_level,omitempty"`
+	ProfitCurrency       string             `json:"profit_currency,omitempty" bson:"profit_currency,omitempty"`
 	CreatedAt            time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt            time.Time          `json:"updated_at" bson:"updated_at"`
 }
 
+// CommissionTier replaces CommissionFee for traders whose volume over the last
+// 30 days reaches MinVolume lots. A zero Fee makes the tier commission-free.
+type CommissionTier struct {
+	MinVolume float64 `json:"min_volume" bson:"min_volume"`
+	Fee       float64 `json:"fee" bson:"fee"`
+}
+
+// NewsHalt is an admin-scheduled window around a high-impact event. While it
+// is active the symbol's spread is multiplied by SpreadMultiplier and, when
+// BlockMarket is set, MARKET orders are refused outright.
+type NewsHalt struct {
+	Start            time.Time `json:"start" bson:"start"`
+	End              time.Time `json:"end" bson:"end"`
+	SpreadMultiplier float64   `json:"spread_multiplier" bson:"spread_multiplier"`
+	BlockMarket      bool      `json:"block_market" bson:"block_market"`
+	Reason           string    `json:"reason,omitempty" bson:"reason,omitempty"`
+}
+
+func (h *NewsHalt) Validate() error {
+	if h.Start.IsZero() || h.End.IsZero() {
+		return errors.New("news halt start and end are required")
+	}
+	if !h.End.After(h.Start) {
+		return errors.New("news halt end must be after start")
+	}
+	if h.SpreadMultiplier != 0 && h.SpreadMultiplier < 1 {
+		return errors.New("spread multiplier must be at least 1")
+	}
+	return nil
+}
+
+// ActiveNewsHalt returns the symbol's news halt if now falls inside it.
+func (s *Symbol) ActiveNewsHalt(now time.Time) *NewsHalt {
+	if s.NewsHalt == nil || now.Before(s.NewsHalt.Start) || !now.Before(s.NewsHalt.End) {
+		return nil
+	}
+	return s.NewsHalt
+}
+
+// EffectiveSpread is the configured spread widened by any active news halt.
+func (s *Symbol) EffectiveSpread(now time.Time) float64 {
+	halt := s.ActiveNewsHalt(now)
+	if halt == nil || halt.SpreadMultiplier <= 1 {
+		return s.Spread
+	}
+	return s.Spread * halt.SpreadMultiplier
+}
+
 type TradingHours struct {
 	Unlimited bool   `json:"unlimited" bson:"unlimited"`
 	OpenTime  string `json:"open_time,omitempty" bson:"open_time,omitempty"`
 	CloseTime string `json:"close_time,omitempty" bson:"close_time,omitempty"`
 }
+
+// RoundPrice rounds a price to the symbol's quoted number of digits.
+// Symbols without Digits configured are returned unchanged.
+func (s *Symbol) RoundPrice(price float64) float64 {
+	if s.Digits <= 0 {
+		return price
+	}
+	scale := math.Pow(10, float64(s.Digits))
+	return math.Round(price*scale) / scale
+}
+
+// LeverageLimit is the highest leverage an order on the symbol may use.
+// Symbols configured before MaxLeverage existed keep using Leverage.
+func (s *Symbol) LeverageLimit() int {
+	if s.MaxLeverage > 0 {
+		return s.MaxLeverage
+	}
+	return s.Leverage
+}
+
+// ValidateMargin rejects a margin rate outside [0, 1] and a negative leverage
+// cap. A zero rate means margin comes from leverage alone.
+func (s *Symbol) ValidateMargin() error {
+	if s.MarginRate < 0 || s.MarginRate > 1 {
+		return errors.New("margin rate must be between 0 and 1")
+	}
+	if s.MaxLeverage < 0 {
+		return errors.New("max leverage cannot be negative")
+	}
+	return nil
+}
+
+// ValidateProfitCurrency normalizes the profit currency to an upper-case ISO
+// code. Empty means profit is already in the account's currency.
+func (s *Symbol) ValidateProfitCurrency() error {
+	s.ProfitCurrency = strings.ToUpper(strings.TrimSpace(s.ProfitCurrency))
+	if s.ProfitCurrency != "" && len(s.ProfitCurrency) != 3 {
+		return errors.New("profit currency must be a three-letter currency code")
+	}
+	return nil
+}
+
+// Point is the price value of one point: the last quoted digit, or the tick
+// size when Digits is not configured.
+func (s *Symbol) Point() float64 {
+	if s.Digits > 0 {
+		return math.Pow(10, -float64(s.Digits))
+	}
+	return s.TickSize
+}
+
+// MinStopDistance is how far stop loss and take profit must be from the
+// reference price, from StopsLevel points. Zero means no minimum.
+func (s *Symbol) MinStopDistance() float64 {
+	if s.StopsLevel <= 0 {
+		return 0
+	}
+	return float64(s.StopsLevel) * s.Point()
+}
+
+// IsTickAligned reports whether price is a whole multiple of the tick size.
+func (s *Symbol) IsTickAligned(price float64) bool {
+	if s.TickSize <= 0 || price == 0 {
+		return true
+	}
+	ticks := price / s.TickSize
+	return math.Abs(ticks-math.Round(ticks)) < 1e-6
+}
+
+// ValidateCommissionTiers rejects negative thresholds or fees and duplicate
+// thresholds, which would make the applicable tier ambiguous.
+func (s *Symbol) ValidateCommissionTiers() error {
+	seen := make(map[float64]bool, len(s.CommissionTiers))
+	for _, tier := range s.CommissionTiers {
+		if tier.MinVolume < 0 || tier.Fee < 0 {
+			return errors.New("commission tier volume and fee cannot be negative")
+		}
+		if seen[tier.MinVolume] {
+			return errors.New("commission tiers must have distinct volume thresholds")
+		}
+		seen[tier.MinVolume] = true
+	}
+	return nil
+}
+
+// commissionFrom returns the per-trade commission for a trader with the given
+// rolling volume, starting from base, along with the tier applied: 0 for the
+// base fee, otherwise the tier's 1-based position in ascending threshold order.
+func (s *Symbol) commissionFrom(base, volume float64) (float64, int) {
+	tiers := make([]CommissionTier, len(s.CommissionTiers))
+	copy(tiers, s.CommissionTiers)
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].MinVolume < tiers[j].MinVolume })
+
+	fee, applied := base, 0
+	for i, tier := range tiers {
+		if volume < tier.MinVolume {
+			break
+		}
+		fee, applied = tier.Fee, i+1
+	}
+	return fee, applied
+}
+
+// ResolveSymbol finds the symbol a client named. Clients may send either the
+// broker symbol name or the display name, in any casing; the broker name is
+// tried first so a display name can never shadow another symbol. Everything
+// stored and matched against prices (trades, alerts) uses the resolved
+// SymbolName. It returns nil when nothing matches.
+func ResolveSymbol(symbols []*Symbol, name string) *Symbol {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil
+	}
+	for _, sym := range symbols {
+		if strings.EqualFold(sym.SymbolName, name) {
+			return sym
+		}
+	}
+	for _, sym := range symbols {
+		if strings.EqualFold(sym.DisplayName, name) {
+			return sym
+		}
+	}
+	return nil
+}
@@ -1,33 +1,90 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// TradeHistory is a single order or position. RequestedVolume is the size
+// originally ordered and FilledVolume how much of it has executed; partial
+// fills split off into their own OPEN trades, so Volume on a pending order is
+// the unfilled remainder. MarginRate is the share of notional held as margin,
+// fixed when the order was placed. MARKET orders have no entry price until
+// they fill, so MarginPrice records the quote their margin was reserved at.
 type TradeHistory struct {
-	ID             primitive.ObjectID `bson:"_id" json:"_id"`
-	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`
-	Symbol         string             `bson:"symbol" json:"symbol"`
-	AccountID      primitive.ObjectID `bson:"account_id" json:"account_id"`
-	TradeType      TradeType          `bson:"trade_type" json:"trade_type"`
-	OrderType      string             `bson:"order_type" json:"order_type"`
-	Leverage       int                `bson:"leverage" json:"leverage"`
-	Volume         float64            `bson:"volume" json:"volume"`
-	EntryPrice     float64            `bson:"entry_price" json:"entry_price"`
-	ClosePrice     float64            `bson:"close_price,omitempty" json:"close_price,omitempty"`
-	StopLoss       float64            `bson:"stop_loss" json:"stop_loss"`
-	TakeProfit     float64            `bson:"take_profit" json:"take_profit"`
-	Profit         float64            `bson:"profit" json:"profit"`
-	OpenTime       time.Time          `bson:"open_time" json:"open_time"`
-	CloseTime      *time.Time         `bson:"close_time,omitempty" json:"close_time,omitempty"`
-	CloseReason    string             `bson:"close_reason,omitempty" json:"close_reason,omitempty"`
-	Status         string             `bson:"status" json:"Status"`
-	MatchedTradeID string             `bson:"matched_trade_id,omitempty" json:"matched_trade_id,omitempty"`
-	Expiration     *time.Time         `bson:"expiration,omitempty" json:"expiration,omitempty"`
-	AccountType    string             `bson:"account_type" json:"account_type"`
-	ExecutionType  ExecutionType      `bson:"execution_type" json:"execution_type"`
+	ID              primitive.ObjectID `bson:"_id" json:"_id"`
+	UserID          primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Symbol          string             `bson:"symbol" json:"symbol"`
+	AccountID       primitive.ObjectID `bson:"account_id" json:"account_id"`
+	TradeType       TradeType          `bson:"trade_type" json:"trade_type"`
+	OrderType       string             `bson:"order_type" json:"order_type"`
+	Leverage        int                `bson:"leverage" json:"leverage"`
+	MarginRate      float64            `bson:"margin_rate,omitempty" json:"margin_rate,omitempty"`
+	MarginPrice     float64            `bson:"margin_price,omitempty" json:"margin_price,omitempty"`
+	Volume          float64            `bson:"volume" json:"volume"`
+	RequestedVolume float64            `bson:"requested_volume,omitempty" json:"requested_volume,omitempty"`
+	FilledVolume    float64            `bson:"filled_volume" json:"filled_volume"`
+	EntryPrice      float64            `bson:"entry_price" json:"entry_price"`
+	ClosePrice      float64            `bson:"close_price,omitempty" json:"close_price,omitempty"`
+	StopLoss        float64            `bson:"stop_loss" json:"stop_loss"`
+	TakeProfit      float64            `bson:"take_profit" json:"take_profit"`
+	Profit          float64            `bson:"profit" json:"profit"`
+	Commission      float64            `bson:"commission" json:"commission"`
+	MT5Commission   float64            `bson:"mt5_commission,omitempty" json:"mt5_commission,omitempty"`
+	CommissionTier  int                `bson:"commission_tier" json:"commission_tier"`
+	Swap            float64            `bson:"swap" json:"swap"`
+	OpenTime        time.Time          `bson:"open_time" json:"open_time"`
+	CloseTime       *time.Time         `bson:"close_time,omitempty" json:"close_time,omitempty"`
+	CloseReason     CloseReason        `bson:"close_reason,omitempty" json:"close_reason,omitempty"`
+	Status          string             `bson:"status" json:"Status"`
+	MatchedTradeID  string             `bson:"matched_trade_id,omitempty" json:"matched_trade_id,omitempty"`
+	Expiration      *time.Time         `bson:"expiration,omitempty" json:"expiration,omitempty"`
+	AccountType     string             `bson:"account_type" json:"account_type"`
+	ExecutionType   ExecutionType      `bson:"execution_type" json:"execution_type"`
+	Requote         *Requote           `bson:"requote,omitempty" json:"requote,omitempty"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Requote is the price MT5 offered after refusing a MARKET order because the
+// market moved. The trade stays REQUOTED, with Held still reserved from the
+// balance, until the owner confirms it or ExpiresAt passes.
+type Requote struct {
+	Price     float64   `bson:"price" json:"price"`
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"`
+	Held      float64   `bson:"held" json:"held"`
+}
+
+// MarginFor is the margin held against volume lots at price. Trades saved
+// before MarginRate was recorded fall back to 1/Leverage.
+func (t *TradeHistory) MarginFor(volume, price float64) float64 {
+	if t.MarginRate > 0 {
+		return volume * price * t.MarginRate
+	}
+	if t.Leverage <= 0 {
+		return 0
+	}
+	return volume * price / float64(t.Leverage)
+}
+
+// Margin is the margin currently held by the trade: at the quote it was
+// reserved at for MARKET orders, otherwise at the entry price.
+func (t *TradeHistory) Margin() float64 {
+	if t.MarginPrice > 0 {
+		return t.MarginFor(t.Volume, t.MarginPrice)
+	}
+	return t.MarginFor(t.Volume, t.EntryPrice)
+}
+
+// MarkToMarket values the trade at the side of the book it would close on:
+// the bid for a BUY, the ask for a SELL. It returns that price and the
+// floating profit at it.
+func (t *TradeHistory) MarkToMarket(price *PriceData) (float64, float64) {
+	if t.TradeType == TradeTypeBuy {
+		return price.Bid, (price.Bid - t.EntryPrice) * t.Volume
+	}
+	return price.Ask, (t.EntryPrice - price.Ask) * t.Volume
 }
 
 type ExecutionType string
@@ -47,7 +104,43 @@ const (
 type TradeStatus string
 
 const (
-	TradeStatusPending TradeStatus = "PENDING"
-	TradeStatusOpen    TradeStatus = "OPEN"
-	TradeStatusClosed  TradeStatus = "CLOSED"
+	TradeStatusPending   TradeStatus = "PENDING"
+	TradeStatusOpen      TradeStatus = "OPEN"
+	TradeStatusClosed    TradeStatus = "CLOSED"
+	TradeStatusExpired   TradeStatus = "EXPIRED"
+	TradeStatusCancelled TradeStatus = "CANCELLED"
+	TradeStatusRequoted  TradeStatus = "REQUOTED"
 )
+
+type CloseReason string
+
+const (
+	CloseReasonManual       CloseReason = "MANUAL"
+	CloseReasonStopLoss     CloseReason = "STOP_LOSS"
+	CloseReasonTakeProfit   CloseReason = "TAKE_PROFIT"
+	CloseReasonStopOut      CloseReason = "STOP_OUT"
+	CloseReasonExpired      CloseReason = "EXPIRED"
+	CloseReasonBrokerReject CloseReason = "BROKER_REJECT"
+	CloseReasonTimeout      CloseReason = "TIMEOUT"
+)
+
+// ParseCloseReason maps the free-form reason reported by MT5 onto a CloseReason.
+// Anything unrecognised is treated as a manual close.
+func ParseCloseReason(raw string) CloseReason {
+	switch strings.ToUpper(strings.TrimSpace(raw)) {
+	case "SL", "STOP_LOSS", "STOPLOSS", "DEAL_REASON_SL":
+		return CloseReasonStopLoss
+	case "TP", "TAKE_PROFIT", "TAKEPROFIT", "DEAL_REASON_TP":
+		return CloseReasonTakeProfit
+	case "SO", "STOP_OUT", "STOPOUT", "DEAL_REASON_SO":
+		return CloseReasonStopOut
+	case "EXPIRED", "EXPIRATION", "ORDER_STATE_EXPIRED":
+		return CloseReasonExpired
+	case "REJECTED", "BROKER_REJECT", "ORDER_STATE_REJECTED":
+		return CloseReasonBrokerReject
+	case "TIMEOUT":
+		return CloseReasonTimeout
+	default:
+		return CloseReasonManual
+	}
+}
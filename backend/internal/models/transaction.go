@@ -19,6 +19,9 @@ type TransactionType string
 const (
 	TransactionTypeDeposit    TransactionType = "DEPOSIT"
 	TransactionTypeWithdrawal TransactionType = "WITHDRAWAL"
+	// TransactionTypeTransfer records a move between a user's own balances.
+	// It is written already approved and never goes through admin review.
+	TransactionTypeTransfer TransactionType = "TRANSFER"
 )
 
 type PaymentMethod string
@@ -35,10 +38,14 @@ type Transaction struct {
 	TransactionType TransactionType    `bson:"transaction_type" json:"transaction_type"`
 	PaymentMethod   PaymentMethod      `bson:"payment_method" json:"payment_method"`
 	Amount          float64            `bson:"amount" json:"amount"`
+	Currency        string             `bson:"currency,omitempty" json:"currency,omitempty"`
+	ConvertedAmount float64            `bson:"converted_amount,omitempty" json:"converted_amount,omitempty"`
 	ReceiptImage    string             `bson:"receipt_image,omitempty" json:"receipt_image"`
 	Status          TransactionStatus  `bson:"status" json:"status"`
 	RequestTime     time.Time          `bson:"request_time" json:"request_time"`
 	ResponseTime    *time.Time         `bson:"response_time,omitempty" json:"response_time"`
 	Reason          string             `bson:"reason,omitempty" json:"reason"`
 	AdminComment    string             `bson:"admin_comment,omitempty" json:"admin_comment"`
+	FromAccount     string             `bson:"from_account,omitempty" json:"from_account,omitempty"`
+	ToAccount       string             `bson:"to_account,omitempty" json:"to_account,omitempty"`
 }
@@ -1,42 +1,154 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Account struct {
-	ID               primitive.ObjectID `bson:"_id" json:"id"`
-	UserID           primitive.ObjectID `bson:"user_id" json:"user_id"`
-	AccountName      string             `bson:"account_name" json:"account_name"`
-	AccountType      string             `bson:"account_type" json:"account_type"`
-	WalletID         string             `bson:"wallet_id" json:"wallet_id"`
-	Balance          float64            `bson:"balance" json:"balance"`
-	RegistrationDate string             `bson:"registration_date" json:"registration_date"`
-	IsActive         bool               `bson:"is_active" json:"is_active"`
+	ID                primitive.ObjectID `bson:"_id" json:"id"`
+	UserID            primitive.ObjectID `bson:"user_id" json:"user_id"`
+	AccountName       string             `bson:"account_name" json:"account_name"`
+	AccountType       string             `bson:"account_type" json:"account_type"`
+	WalletID          string             `bson:"wallet_id" json:"wallet_id"`
+	Balance           float64            `bson:"balance" json:"balance"`
+	Currency          string             `bson:"currency,omitempty" json:"currency,omitempty"`
+	RegistrationDate  string             `bson:"registration_date" json:"registration_date"`
+	IsActive          bool               `bson:"is_active" json:"is_active"`
+	RiskTriggeredAt   *time.Time         `bson:"risk_triggered_at,omitempty" json:"risk_triggered_at,omitempty"`
+	Disabled          bool               `bson:"disabled,omitempty" json:"disabled,omitempty"`
+	DisabledReason    string             `bson:"disabled_reason,omitempty" json:"disabled_reason,omitempty"`
+	DisabledAt        *time.Time         `bson:"disabled_at,omitempty" json:"disabled_at,omitempty"`
+	MaxLeverage       int                `bson:"max_leverage,omitempty" json:"max_leverage,omitempty"`
+	Tier              AccountTier        `bson:"tier,omitempty" json:"tier,omitempty"`
+	AccountRiskLimits `bson:",inline"`
+}
+
+// AccountRiskLimits are the user's daily guards on an account. Once realized
+// plus floating P/L for the UTC day falls to -DailyLossLimit or reaches
+// DailyProfitTarget every open position is closed; with BlockAfterTrigger new
+// trades are also refused until the day ends. Separately, LossStreakLimit
+// losing closes in a row, all within LossStreakWindowMinutes, pause new trades
+// for LossCooldownMinutes. Zero disables a limit.
+type AccountRiskLimits struct {
+	DailyLossLimit          float64 `bson:"daily_loss_limit,omitempty" json:"daily_loss_limit,omitempty"`
+	DailyProfitTarget       float64 `bson:"daily_profit_target,omitempty" json:"daily_profit_target,omitempty"`
+	BlockAfterTrigger       bool    `bson:"block_after_trigger,omitempty" json:"block_after_trigger,omitempty"`
+	LossStreakLimit         int     `bson:"loss_streak_limit,omitempty" json:"loss_streak_limit,omitempty"`
+	LossStreakWindowMinutes int     `bson:"loss_streak_window_minutes,omitempty" json:"loss_streak_window_minutes,omitempty"`
+	LossCooldownMinutes     int     `bson:"loss_cooldown_minutes,omitempty" json:"loss_cooldown_minutes,omitempty"`
+}
+
+func (l AccountRiskLimits) Validate() error {
+	if l.DailyLossLimit < 0 || l.DailyProfitTarget < 0 {
+		return errors.New("daily loss limit and profit target cannot be negative")
+	}
+	if l.LossStreakLimit < 0 || l.LossStreakWindowMinutes < 0 || l.LossCooldownMinutes < 0 {
+		return errors.New("loss streak settings cannot be negative")
+	}
+	if l.LossStreakLimit > 0 && l.LossCooldownMinutes == 0 {
+		return errors.New("loss cooldown minutes required when a loss streak limit is set")
+	}
+	return nil
+}
+
+func (l AccountRiskLimits) Enabled() bool {
+	return l.DailyLossLimit > 0 || l.DailyProfitTarget > 0
+}
+
+// Breached reports whether the day's P/L has crossed either limit.
+func (l AccountRiskLimits) Breached(pnl float64) bool {
+	return (l.DailyLossLimit > 0 && pnl <= -l.DailyLossLimit) ||
+		(l.DailyProfitTarget > 0 && pnl >= l.DailyProfitTarget)
+}
+
+// StartOfDay is the UTC midnight the daily risk limits reset at.
+func StartOfDay(now time.Time) time.Time {
+	return now.UTC().Truncate(24 * time.Hour)
+}
+
+// RiskTriggeredToday reports whether the account's limits already fired today.
+func (a *Account) RiskTriggeredToday(now time.Time) bool {
+	return a.RiskTriggeredAt != nil && !a.RiskTriggeredAt.Before(StartOfDay(now))
+}
+
+// RiskBlocked reports whether new trades are refused for the rest of the day.
+func (a *Account) RiskBlocked(now time.Time) bool {
+	return a.BlockAfterTrigger && a.RiskTriggeredToday(now)
 }
 
 type User struct {
-	ID                       primitive.ObjectID `bson:"_id" json:"id"`
-	FullName                 string             `bson:"full_name" json:"full_name"`
-	PhoneNumber              string             `bson:"phone_number" json:"phone_number"`
-	TelegramID               string             `bson:"telegram_id" json:"telegram_id"`
-	Username                 string             `bson:"username" json:"username"`
-	CardNumber               string             `bson:"card_number" json:"card_number"`
-	Citizenship              string             `bson:"citizenship" json:"citizenship"`
-	NationalID               string             `bson:"national_id" json:"national_id"`
-	Residence                string             `bson:"residence" json:"residence"`
-	BirthDay                 string             `bson:"birthday" json:"birthday"`
-	RegistrationDate         string             `bson:"registration_date" json:"registration_date"`
-	IsActive                 bool               `bson:"is_active" json:"is_active"`
-	IsCopyTradeLeader        bool               `bson:"is_copy_trade_leader" json:"is_copy_trade_leader"`
-	AccountType              string             `bson:"account_type" json:"account_type"`
-	IsCopyPendingTradeLeader bool               `bson:"is_copy_pending_trade_leader" json:"is_copy_pending_trade_leader"`
-	Balance                  float64            `bson:"balance" json:"balance"` // Main account balance
-	Bonus                    float64            `bson:"bonus" json:"bonus"`
-	Leverage                 int                `bson:"leverage" json:"leverage"`
-	TradeType                string             `bson:"trade_type" json:"trade_type"`
-	WalletAddress            string             `bson:"wallet_address" json:"wallet_address"`
-	ReferralCode             string             `bson:"referral_code" json:"referral_code"`
-	ReferredBy               primitive.ObjectID `bson:"referred_by" json:"referred_by"`
-	AccountTypes             []string           `bson:"account_types" json:"account_types"`
+	ID                        primitive.ObjectID `bson:"_id" json:"id"`
+	FullName                  string             `bson:"full_name" json:"full_name"`
+	PhoneNumber               string             `bson:"phone_number" json:"phone_number"`
+	TelegramID                string             `bson:"telegram_id" json:"telegram_id"`
+	Username                  string             `bson:"username" json:"username"`
+	CardNumber                string             `bson:"card_number" json:"card_number"`
+	Citizenship               string             `bson:"citizenship" json:"citizenship"`
+	NationalID                string             `bson:"national_id" json:"national_id"`
+	Residence                 string             `bson:"residence" json:"residence"`
+	BirthDay                  string             `bson:"birthday" json:"birthday"`
+	RegistrationDate          string             `bson:"registration_date" json:"registration_date"`
+	IsActive                  bool               `bson:"is_active" json:"is_active"`
+	IsCopyTradeLeader         bool               `bson:"is_copy_trade_leader" json:"is_copy_trade_leader"`
+	AccountType               string             `bson:"account_type" json:"account_type"`
+	IsCopyPendingTradeLeader  bool               `bson:"is_copy_pending_trade_leader" json:"is_copy_pending_trade_leader"`
+	Balance                   float64            `bson:"balance" json:"balance"` // Main account balance
+	Bonus                     float64            `bson:"bonus" json:"bonus"`
+	Leverage                  int                `bson:"leverage" json:"leverage"`
+	TradeType                 string             `bson:"trade_type" json:"trade_type"`
+	WalletAddress             string             `bson:"wallet_address" json:"wallet_address"`
+	ReferralCode              string             `bson:"referral_code" json:"referral_code"`
+	ReferredBy                primitive.ObjectID `bson:"referred_by" json:"referred_by"`
+	AccountTypes              []string           `bson:"account_types" json:"account_types"`
+	CopyTradeNotificationsOff bool               `bson:"copy_trade_notifications_off" json:"copy_trade_notifications_off"`
+	LeaderLimits              `bson:",inline"`
+}
+
+// UserFilter narrows and orders a user listing. A nil field does not filter.
+type UserFilter struct {
+	IsActive *bool
+	IsLeader *bool
+	Sort     UserSort
+}
+
+// UserSort orders a user listing. The empty sort lists the newest users
+// first; a leading "-" sorts descending.
+type UserSort string
+
+const (
+	UserSortNewest               UserSort = ""
+	UserSortRegistrationDate     UserSort = "registration_date"
+	UserSortRegistrationDateDesc UserSort = "-registration_date"
+	UserSortBalance              UserSort = "balance"
+	UserSortBalanceDesc          UserSort = "-balance"
+)
+
+func ParseUserSort(sort string) (UserSort, error) {
+	switch s := UserSort(strings.TrimSpace(sort)); s {
+	case UserSortNewest, UserSortRegistrationDate, UserSortRegistrationDateDesc, UserSortBalance, UserSortBalanceDesc:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown sort %q: must be registration_date or balance, optionally prefixed with -", sort)
+}
+
+// LeaderLimits are a copy trade leader's terms for new followers. Zero leaves
+// a limit off.
+type LeaderLimits struct {
+	MinAllocation float64 `bson:"copy_min_allocation" json:"copy_min_allocation"`
+	MaxFollowers  int     `bson:"copy_max_followers" json:"copy_max_followers"`
+}
+
+func (l LeaderLimits) Validate() error {
+	if l.MinAllocation < 0 {
+		return errors.New("minimum allocation cannot be negative")
+	}
+	if l.MaxFollowers < 0 {
+		return errors.New("maximum followers cannot be negative")
+	}
+	return nil
 }
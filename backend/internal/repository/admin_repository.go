@@ -12,9 +12,9 @@ import (
 )
 
 type AdminRepository interface {
-	SaveAdmin(admin *models.AdminAccount) error
-	GetAdminByID(id primitive.ObjectID) (*models.AdminAccount, error)
-	GetAdminByUsername(username string) (*models.AdminAccount, error)
+	SaveAdmin(ctx context.Context, admin *models.AdminAccount) error
+	GetAdminByID(ctx context.Context, id primitive.ObjectID) (*models.AdminAccount, error)
+	GetAdminByUsername(ctx context.Context, username string) (*models.AdminAccount, error)
 }
 
 type MongoAdminRepository struct {
@@ -26,16 +26,16 @@ func NewAdminRepository(client *mongo.Client, dbName, collectionName string) Adm
 	return &MongoAdminRepository{collection: collection}
 }
 
-func (r *MongoAdminRepository) SaveAdmin(admin *models.AdminAccount) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoAdminRepository) SaveAdmin(ctx context.Context, admin *models.AdminAccount) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	_, err := r.collection.InsertOne(ctx, admin)
 	return err
 }
 
-func (r *MongoAdminRepository) GetAdminByID(id primitive.ObjectID) (*models.AdminAccount, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoAdminRepository) GetAdminByID(ctx context.Context, id primitive.ObjectID) (*models.AdminAccount, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var admin models.AdminAccount
@@ -49,8 +49,8 @@ func (r *MongoAdminRepository) GetAdminByID(id primitive.ObjectID) (*models.Admi
 	return &admin, nil
 }
 
-func (r *MongoAdminRepository) GetAdminByUsername(username string) (*models.AdminAccount, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoAdminRepository) GetAdminByUsername(ctx context.Context, username string) (*models.AdminAccount, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var admin models.AdminAccount
@@ -13,11 +13,14 @@ import (
 )
 
 type AlertRepository interface {
-	SaveAlert(alert *models.Alert) error
-	GetAlertByID(id primitive.ObjectID) (*models.Alert, error)
-	GetAlertsByUserID(userID string) ([]*models.Alert, error)
-	GetPendingAlerts() ([]*models.Alert, error)
-	UpdateAlert(id primitive.ObjectID, alert *models.Alert) error
+	SaveAlert(ctx context.Context, alert *models.Alert) error
+	GetAlertByID(ctx context.Context, id primitive.ObjectID) (*models.Alert, error)
+	GetAlertsByUserID(ctx context.Context, userID string) ([]*models.Alert, error)
+	GetPendingAlerts(ctx context.Context) ([]*models.Alert, error)
+	CountPendingAlertsByUserID(ctx context.Context, userID string) (int64, error)
+	UpdateAlert(ctx context.Context, id primitive.ObjectID, alert *models.Alert) error
+	UpdateAlertDefinition(ctx context.Context, id primitive.ObjectID, alert *models.Alert) error
+	DeleteAlert(ctx context.Context, id primitive.ObjectID) error
 }
 
 type MongoAlertRepository struct {
@@ -29,8 +32,8 @@ func NewAlertRepository(client *mongo.Client, dbName, collectionName string) Ale
 	return &MongoAlertRepository{collection: collection}
 }
 
-func (r *MongoAlertRepository) SaveAlert(alert *models.Alert) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoAlertRepository) SaveAlert(ctx context.Context, alert *models.Alert) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	alert.ID = primitive.NewObjectID()
@@ -39,8 +42,8 @@ func (r *MongoAlertRepository) SaveAlert(alert *models.Alert) error {
 	return err
 }
 
-func (r *MongoAlertRepository) GetAlertByID(id primitive.ObjectID) (*models.Alert, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoAlertRepository) GetAlertByID(ctx context.Context, id primitive.ObjectID) (*models.Alert, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var alert models.Alert
@@ -51,8 +54,8 @@ func (r *MongoAlertRepository) GetAlertByID(id primitive.ObjectID) (*models.Aler
 	return &alert, err
 }
 
-func (r *MongoAlertRepository) GetAlertsByUserID(userID string) ([]*models.Alert, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoAlertRepository) GetAlertsByUserID(ctx context.Context, userID string) ([]*models.Alert, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var alerts []*models.Alert
@@ -67,8 +70,8 @@ func (r *MongoAlertRepository) GetAlertsByUserID(userID string) ([]*models.Alert
 	return alerts, nil
 }
 
-func (r *MongoAlertRepository) GetPendingAlerts() ([]*models.Alert, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoAlertRepository) GetPendingAlerts(ctx context.Context) ([]*models.Alert, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var alerts []*models.Alert
@@ -83,8 +86,15 @@ func (r *MongoAlertRepository) GetPendingAlerts() ([]*models.Alert, error) {
 	return alerts, nil
 }
 
-func (r *MongoAlertRepository) UpdateAlert(id primitive.ObjectID, alert *models.Alert) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoAlertRepository) CountPendingAlertsByUserID(ctx context.Context, userID string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return r.collection.CountDocuments(ctx, bson.M{"user_id": userID, "status": models.AlertStatusPending})
+}
+
+func (r *MongoAlertRepository) UpdateAlert(ctx context.Context, id primitive.ObjectID, alert *models.Alert) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	update := bson.M{
@@ -96,3 +106,29 @@ func (r *MongoAlertRepository) UpdateAlert(id primitive.ObjectID, alert *models.
 	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
 	return err
 }
+
+// UpdateAlertDefinition rewrites what the alert watches for, leaving its
+// owner, status and timestamps alone.
+func (r *MongoAlertRepository) UpdateAlertDefinition(ctx context.Context, id primitive.ObjectID, alert *models.Alert) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"symbol_name":         alert.SymbolName,
+			"alert_type":          alert.AlertType,
+			"condition":           alert.Condition,
+			"notification_method": alert.NotificationMethod,
+		},
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (r *MongoAlertRepository) DeleteAlert(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type BalanceDiscrepancyRepository interface {
+	SaveDiscrepancy(ctx context.Context, discrepancy *models.BalanceDiscrepancy) error
+	GetDiscrepancies(ctx context.Context, flaggedOnly, openOnly bool, page, limit int) ([]*models.BalanceDiscrepancy, int64, error)
+	MarkReviewed(ctx context.Context, id primitive.ObjectID, note string, at time.Time) (*models.BalanceDiscrepancy, error)
+}
+
+type MongoBalanceDiscrepancyRepository struct {
+	collection *mongo.Collection
+}
+
+func NewBalanceDiscrepancyRepository(client *mongo.Client, dbName, collectionName string) BalanceDiscrepancyRepository {
+	collection := client.Database(dbName).Collection(collectionName)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "flagged", Value: 1}, {Key: "reviewed", Value: 1}, {Key: "checked_at", Value: -1}}},
+		{Keys: bson.D{{Key: "account_id", Value: 1}, {Key: "checked_at", Value: -1}}},
+	})
+	if err != nil {
+		fmt.Printf("Failed to create indexes: %v\n", err)
+	}
+
+	return &MongoBalanceDiscrepancyRepository{collection: collection}
+}
+
+func (r *MongoBalanceDiscrepancyRepository) SaveDiscrepancy(ctx context.Context, discrepancy *models.BalanceDiscrepancy) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	discrepancy.ID = primitive.NewObjectID()
+	_, err := r.collection.InsertOne(ctx, discrepancy)
+	return err
+}
+
+// GetDiscrepancies returns a page of discrepancies, newest first, with the
+// total count.
+func (r *MongoBalanceDiscrepancyRepository) GetDiscrepancies(ctx context.Context, flaggedOnly, openOnly bool, page, limit int) ([]*models.BalanceDiscrepancy, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if flaggedOnly {
+		filter["flagged"] = true
+	}
+	if openOnly {
+		filter["reviewed"] = false
+	}
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	skip := (page - 1) * limit
+	findOptions := options.Find().SetSort(bson.M{"checked_at": -1}).SetSkip(int64(skip)).SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	discrepancies := []*models.BalanceDiscrepancy{}
+	if err := cursor.All(ctx, &discrepancies); err != nil {
+		return nil, 0, err
+	}
+	return discrepancies, total, nil
+}
+
+// MarkReviewed closes a discrepancy and returns it, or nil when it does not
+// exist.
+func (r *MongoBalanceDiscrepancyRepository) MarkReviewed(ctx context.Context, id primitive.ObjectID, note string, at time.Time) (*models.BalanceDiscrepancy, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"reviewed": true, "review_note": note, "reviewed_at": at}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var discrepancy models.BalanceDiscrepancy
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&discrepancy)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &discrepancy, nil
+}
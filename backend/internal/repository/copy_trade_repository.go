@@ -9,15 +9,23 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type CopyTradeRepository interface {
-	SaveSubscription(subscription *models.CopyTradeSubscription) error
-	GetSubscriptionByID(id primitive.ObjectID) (*models.CopyTradeSubscription, error)
-	GetSubscriptionsByFollowerID(followerID string) ([]*models.CopyTradeSubscription, error)
-	GetAllSubscriptions() ([]*models.CopyTradeSubscription, error)
-	GetActiveSubscriptionsByLeaderID(leaderID string) ([]*models.CopyTradeSubscription, error)
-	SaveCopyTrade(copyTrade *models.CopyTrade) error
+	SaveSubscription(ctx context.Context, subscription *models.CopyTradeSubscription) error
+	GetSubscriptionByID(ctx context.Context, id primitive.ObjectID) (*models.CopyTradeSubscription, error)
+	GetSubscriptionsByFollowerID(ctx context.Context, followerID string) ([]*models.CopyTradeSubscription, error)
+	GetAllSubscriptions(ctx context.Context) ([]*models.CopyTradeSubscription, error)
+	GetActiveSubscriptionsByLeaderID(ctx context.Context, leaderID string) ([]*models.CopyTradeSubscription, error)
+	CountActiveSubscriptionsByLeaderID(ctx context.Context, leaderID string) (int64, error)
+	SaveCopyTrade(ctx context.Context, copyTrade *models.CopyTrade) error
+	PauseSubscriptionsByLeaderID(ctx context.Context, leaderID, reason string) ([]*models.CopyTradeSubscription, error)
+	PauseSubscription(ctx context.Context, id primitive.ObjectID, reason string) error
+	CancelSubscription(ctx context.Context, id primitive.ObjectID, at time.Time) (bool, error)
+	RecordMirrorFailure(ctx context.Context, id primitive.ObjectID) (int, error)
+	ResetMirrorFailures(ctx context.Context, id primitive.ObjectID) error
+	GetCopyTradesBySubscription(ctx context.Context, subID primitive.ObjectID) ([]*models.CopyTrade, error)
 }
 
 type MongoCopyTradeRepository struct {
@@ -29,8 +37,8 @@ func NewCopyTradeRepository(client *mongo.Client, dbName, collectionName string)
 	return &MongoCopyTradeRepository{collection: collection}
 }
 
-func (r *MongoCopyTradeRepository) SaveSubscription(subscription *models.CopyTradeSubscription) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoCopyTradeRepository) SaveSubscription(ctx context.Context, subscription *models.CopyTradeSubscription) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	subscription.ID = primitive.NewObjectID()
@@ -39,8 +47,8 @@ func (r *MongoCopyTradeRepository) SaveSubscription(subscription *models.CopyTra
 	return err
 }
 
-func (r *MongoCopyTradeRepository) GetSubscriptionByID(id primitive.ObjectID) (*models.CopyTradeSubscription, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoCopyTradeRepository) GetSubscriptionByID(ctx context.Context, id primitive.ObjectID) (*models.CopyTradeSubscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var subscription models.CopyTradeSubscription
@@ -51,8 +59,8 @@ func (r *MongoCopyTradeRepository) GetSubscriptionByID(id primitive.ObjectID) (*
 	return &subscription, err
 }
 
-func (r *MongoCopyTradeRepository) GetAllSubscriptions() ([]*models.CopyTradeSubscription, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoCopyTradeRepository) GetAllSubscriptions(ctx context.Context) ([]*models.CopyTradeSubscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var subscriptions []*models.CopyTradeSubscription
@@ -67,8 +75,8 @@ func (r *MongoCopyTradeRepository) GetAllSubscriptions() ([]*models.CopyTradeSub
 	return subscriptions, nil
 }
 
-func (r *MongoCopyTradeRepository) GetSubscriptionsByFollowerID(followerID string) ([]*models.CopyTradeSubscription, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoCopyTradeRepository) GetSubscriptionsByFollowerID(ctx context.Context, followerID string) ([]*models.CopyTradeSubscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var subscriptions []*models.CopyTradeSubscription
@@ -83,12 +91,12 @@ func (r *MongoCopyTradeRepository) GetSubscriptionsByFollowerID(followerID strin
 	return subscriptions, nil
 }
 
-func (r *MongoCopyTradeRepository) GetActiveSubscriptionsByLeaderID(leaderID string) ([]*models.CopyTradeSubscription, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoCopyTradeRepository) GetActiveSubscriptionsByLeaderID(ctx context.Context, leaderID string) ([]*models.CopyTradeSubscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var subscriptions []*models.CopyTradeSubscription
-	cursor, err := r.collection.Find(ctx, bson.M{"leader_id": leaderID, "status": "ACTIVE"})
+	cursor, err := r.collection.Find(ctx, bson.M{"leader_id": leaderID, "status": models.Active})
 	if err != nil {
 		return nil, err
 	}
@@ -99,8 +107,15 @@ func (r *MongoCopyTradeRepository) GetActiveSubscriptionsByLeaderID(leaderID str
 	return subscriptions, nil
 }
 
-func (r *MongoCopyTradeRepository) SaveCopyTrade(copyTrade *models.CopyTrade) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoCopyTradeRepository) CountActiveSubscriptionsByLeaderID(ctx context.Context, leaderID string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return r.collection.CountDocuments(ctx, bson.M{"leader_id": leaderID, "status": models.Active})
+}
+
+func (r *MongoCopyTradeRepository) SaveCopyTrade(ctx context.Context, copyTrade *models.CopyTrade) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	copyTrade.ID = primitive.NewObjectID()
@@ -108,3 +123,102 @@ func (r *MongoCopyTradeRepository) SaveCopyTrade(copyTrade *models.CopyTrade) er
 	_, err := r.collection.InsertOne(ctx, copyTrade)
 	return err
 }
+
+// PauseSubscriptionsByLeaderID pauses every active subscription following the
+// leader and returns the subscriptions it paused.
+func (r *MongoCopyTradeRepository) PauseSubscriptionsByLeaderID(ctx context.Context, leaderID, reason string) ([]*models.CopyTradeSubscription, error) {
+	subscriptions, err := r.GetActiveSubscriptionsByLeaderID(ctx, leaderID)
+	if err != nil || len(subscriptions) == 0 {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	ids := make([]primitive.ObjectID, len(subscriptions))
+	for i, sub := range subscriptions {
+		ids[i] = sub.ID
+	}
+	_, err = r.collection.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "status": models.Active},
+		bson.M{"$set": bson.M{"status": models.Paused, "paused_reason": reason}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range subscriptions {
+		sub.Status = models.Paused
+		sub.PausedReason = reason
+	}
+	return subscriptions, nil
+}
+
+func (r *MongoCopyTradeRepository) PauseSubscription(ctx context.Context, id primitive.ObjectID, reason string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"status": models.Paused, "paused_reason": reason}},
+	)
+	return err
+}
+
+// CancelSubscription marks the subscription INACTIVE so no further trades are
+// mirrored to it. It reports false when it was already cancelled.
+func (r *MongoCopyTradeRepository) CancelSubscription(ctx context.Context, id primitive.ObjectID, at time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": bson.M{"$ne": models.Inactive}},
+		bson.M{"$set": bson.M{"status": models.Inactive, "cancelled_at": at}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// RecordMirrorFailure increments the subscription's consecutive mirror
+// failure count and returns the new value.
+func (r *MongoCopyTradeRepository) RecordMirrorFailure(ctx context.Context, id primitive.ObjectID) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var subscription models.CopyTradeSubscription
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id},
+		bson.M{"$inc": bson.M{"mirror_failures": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&subscription)
+	if err != nil {
+		return 0, err
+	}
+	return subscription.MirrorFailures, nil
+}
+
+func (r *MongoCopyTradeRepository) ResetMirrorFailures(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"mirror_failures": 0}})
+	return err
+}
+
+func (r *MongoCopyTradeRepository) GetCopyTradesBySubscription(ctx context.Context, subID primitive.ObjectID) ([]*models.CopyTrade, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var copyTrades []*models.CopyTrade
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+	cursor, err := r.collection.Find(ctx, bson.M{"subscription_id": subID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	if err := cursor.All(ctx, &copyTrades); err != nil {
+		return nil, err
+	}
+	return copyTrades, nil
+}
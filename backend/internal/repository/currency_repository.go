@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CurrencyRateRepository interface {
+	SaveRate(ctx context.Context, rate *models.ExchangeRate) error
+	GetRate(ctx context.Context, from, to string) (*models.ExchangeRate, error)
+	GetAllRates(ctx context.Context) ([]*models.ExchangeRate, error)
+}
+
+type MongoCurrencyRateRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCurrencyRateRepository(client *mongo.Client, dbName, collectionName string) CurrencyRateRepository {
+	collection := client.Database(dbName).Collection(collectionName)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "from", Value: 1}, {Key: "to", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		fmt.Printf("Failed to create indexes: %v\n", err)
+	}
+
+	return &MongoCurrencyRateRepository{collection: collection}
+}
+
+func (r *MongoCurrencyRateRepository) SaveRate(ctx context.Context, rate *models.ExchangeRate) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rate.UpdatedAt = time.Now()
+	filter := bson.M{"from": rate.From, "to": rate.To}
+	update := bson.M{
+		"$set": bson.M{
+			"rate":       rate.Rate,
+			"updated_by": rate.UpdatedBy,
+			"updated_at": rate.UpdatedAt,
+		},
+	}
+	_, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r *MongoCurrencyRateRepository) GetRate(ctx context.Context, from, to string) (*models.ExchangeRate, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var rate models.ExchangeRate
+	err := r.collection.FindOne(ctx, bson.M{"from": from, "to": to}).Decode(&rate)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return &rate, err
+}
+
+func (r *MongoCurrencyRateRepository) GetAllRates(ctx context.Context) ([]*models.ExchangeRate, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var rates []*models.ExchangeRate
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "from", Value: 1}, {Key: "to", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	if err := cursor.All(ctx, &rates); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
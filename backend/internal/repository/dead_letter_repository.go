@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type DeadLetterRepository interface {
+	SaveDeadLetter(ctx context.Context, letter *models.DeadLetter) error
+	GetDeadLetters(ctx context.Context, reason string, page, limit int) ([]*models.DeadLetter, int64, error)
+}
+
+type MongoDeadLetterRepository struct {
+	collection *mongo.Collection
+}
+
+func NewDeadLetterRepository(client *mongo.Client, dbName, collectionName string) DeadLetterRepository {
+	collection := client.Database(dbName).Collection(collectionName)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "received_at", Value: -1}}},
+		{Keys: bson.D{{Key: "reason", Value: 1}, {Key: "received_at", Value: -1}}},
+	})
+	if err != nil {
+		fmt.Printf("Failed to create indexes: %v\n", err)
+	}
+
+	return &MongoDeadLetterRepository{collection: collection}
+}
+
+func (r *MongoDeadLetterRepository) SaveDeadLetter(ctx context.Context, letter *models.DeadLetter) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	letter.ID = primitive.NewObjectID()
+	if letter.ReceivedAt.IsZero() {
+		letter.ReceivedAt = time.Now()
+	}
+	_, err := r.collection.InsertOne(ctx, letter)
+	return err
+}
+
+// GetDeadLetters returns a page of dead letters, newest first, with the total
+// count. An empty reason matches every dead letter.
+func (r *MongoDeadLetterRepository) GetDeadLetters(ctx context.Context, reason string, page, limit int) ([]*models.DeadLetter, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if reason != "" {
+		filter["reason"] = reason
+	}
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	skip := (page - 1) * limit
+	findOptions := options.Find().SetSort(bson.M{"received_at": -1}).SetSkip(int64(skip)).SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	letters := []*models.DeadLetter{}
+	if err := cursor.All(ctx, &letters); err != nil {
+		return nil, 0, err
+	}
+	return letters, total, nil
+}
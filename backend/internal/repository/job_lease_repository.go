@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type JobLeaseRepository interface {
+	AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, name, owner string) error
+}
+
+type MongoJobLeaseRepository struct {
+	collection *mongo.Collection
+}
+
+func NewJobLeaseRepository(client *mongo.Client, dbName, collectionName string) JobLeaseRepository {
+	return &MongoJobLeaseRepository{collection: client.Database(dbName).Collection(collectionName)}
+}
+
+// AcquireLease takes or renews the named lease for owner until ttl from now.
+// It reports false while another owner holds an unexpired lease.
+func (r *MongoJobLeaseRepository) AcquireLease(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"_id": name,
+		"$or": bson.A{
+			bson.M{"owner": owner},
+			bson.M{"expires_at": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{
+		"owner":      owner,
+		"expires_at": now.Add(ttl),
+		"renewed_at": now,
+	}}
+	// When the lease is held by someone else the filter misses and the upsert
+	// collides with the existing _id.
+	_, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseLease gives up the named lease if owner still holds it, so another
+// instance can take over without waiting for it to expire.
+func (r *MongoJobLeaseRepository) ReleaseLease(ctx context.Context, name, owner string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": name, "owner": owner})
+	return err
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/mehrbod2002/fxtrader/internal/models"
@@ -9,13 +10,19 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ErrLeaderRequestPending is returned when saving a request for a user who
+// already has one awaiting review.
+var ErrLeaderRequestPending = errors.New("a leader request is already pending for this user")
+
 type LeaderRequestRepository interface {
-	SaveLeaderRequest(request *models.LeaderRequest) error
-	GetLeaderRequestByID(id primitive.ObjectID) (*models.LeaderRequest, error)
-	GetPendingLeaderRequests() ([]*models.LeaderRequest, error)
-	UpdateLeaderRequest(request *models.LeaderRequest) error
+	SaveLeaderRequest(ctx context.Context, request *models.LeaderRequest) error
+	GetLeaderRequestByID(ctx context.Context, id primitive.ObjectID) (*models.LeaderRequest, error)
+	GetPendingLeaderRequests(ctx context.Context) ([]*models.LeaderRequest, error)
+	GetLeaderRequestsByUserID(ctx context.Context, userID string) ([]*models.LeaderRequest, error)
+	UpdateLeaderRequest(ctx context.Context, request *models.LeaderRequest) error
 }
 
 type MongoLeaderRequestRepository struct {
@@ -27,19 +34,27 @@ func NewLeaderRequestRepository(client *mongo.Client, dbName, collectionName str
 	return &MongoLeaderRequestRepository{collection: collection}
 }
 
-func (r *MongoLeaderRequestRepository) SaveLeaderRequest(request *models.LeaderRequest) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoLeaderRequestRepository) SaveLeaderRequest(ctx context.Context, request *models.LeaderRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
+	pending, err := r.collection.CountDocuments(ctx, bson.M{"user_id": request.UserID, "status": "PENDING"})
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		return ErrLeaderRequestPending
+	}
+
 	request.ID = primitive.NewObjectID()
 	request.CreatedAt = time.Now()
 	request.UpdatedAt = time.Now()
-	_, err := r.collection.InsertOne(ctx, request)
+	_, err = r.collection.InsertOne(ctx, request)
 	return err
 }
 
-func (r *MongoLeaderRequestRepository) GetLeaderRequestByID(id primitive.ObjectID) (*models.LeaderRequest, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoLeaderRequestRepository) GetLeaderRequestByID(ctx context.Context, id primitive.ObjectID) (*models.LeaderRequest, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var request models.LeaderRequest
@@ -50,8 +65,8 @@ func (r *MongoLeaderRequestRepository) GetLeaderRequestByID(id primitive.ObjectI
 	return &request, err
 }
 
-func (r *MongoLeaderRequestRepository) GetPendingLeaderRequests() ([]*models.LeaderRequest, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoLeaderRequestRepository) GetPendingLeaderRequests(ctx context.Context) ([]*models.LeaderRequest, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var requests []*models.LeaderRequest
@@ -66,8 +81,26 @@ func (r *MongoLeaderRequestRepository) GetPendingLeaderRequests() ([]*models.Lea
 	return requests, nil
 }
 
-func (r *MongoLeaderRequestRepository) UpdateLeaderRequest(request *models.LeaderRequest) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// GetLeaderRequestsByUserID returns the user's requests, newest first.
+func (r *MongoLeaderRequestRepository) GetLeaderRequestsByUserID(ctx context.Context, userID string) ([]*models.LeaderRequest, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var requests []*models.LeaderRequest
+	opts := options.Find().SetSort(bson.M{"created_at": -1})
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	if err := cursor.All(ctx, &requests); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+func (r *MongoLeaderRequestRepository) UpdateLeaderRequest(ctx context.Context, request *models.LeaderRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	request.UpdatedAt = time.Now()
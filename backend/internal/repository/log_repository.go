@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/mehrbod2002/fxtrader/internal/models"
@@ -13,9 +14,9 @@ import (
 )
 
 type LogRepository interface {
-	SaveLog(log *models.LogEntry) error
-	GetAllLogs(page, limit int) ([]*models.LogEntry, error)
-	GetLogsByUserID(userID primitive.ObjectID, page, limit int) ([]*models.LogEntry, error)
+	SaveLog(ctx context.Context, log *models.LogEntry) error
+	GetAllLogs(ctx context.Context, page, limit int) ([]*models.LogEntry, int64, error)
+	GetLogsByUserID(ctx context.Context, userID primitive.ObjectID, page, limit int) ([]*models.LogEntry, int64, error)
 }
 
 type MongoLogRepository struct {
@@ -24,51 +25,69 @@ type MongoLogRepository struct {
 
 func NewLogRepository(client *mongo.Client, dbName, collectionName string) LogRepository {
 	collection := client.Database(dbName).Collection(collectionName)
-	return &MongoLogRepository{collection: collection}
-}
 
-func (r *MongoLogRepository) SaveLog(log *models.LogEntry) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	log.ID = primitive.NewObjectID()
-	log.Timestamp = time.Now()
-	_, err := r.collection.InsertOne(ctx, log)
-	return err
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "timestamp", Value: -1}}},
+		{Keys: bson.D{{Key: "timestamp", Value: -1}}},
+		// Entries without expire_at never expire.
+		{Keys: bson.D{{Key: "expire_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		fmt.Printf("Failed to create indexes: %v\n", err)
+	}
+
+	return &MongoLogRepository{collection: collection}
 }
 
-func (r *MongoLogRepository) GetAllLogs(page, limit int) ([]*models.LogEntry, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoLogRepository) SaveLog(ctx context.Context, log *models.LogEntry) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	var logs []*models.LogEntry
-	skip := (page - 1) * limit
-	findOptions := options.Find().SetSort(bson.M{"timestamp": -1}).SetSkip(int64(skip)).SetLimit(int64(limit))
-	cursor, err := r.collection.Find(ctx, bson.M{}, findOptions)
-	if err != nil {
-		return nil, err
+	// Keep ID and timestamp stable so a retried write is recognised as a duplicate.
+	if log.ID.IsZero() {
+		log.ID = primitive.NewObjectID()
 	}
-	defer cursor.Close(ctx)
-	if err := cursor.All(ctx, &logs); err != nil {
-		return nil, err
+	if log.Timestamp.IsZero() {
+		log.Timestamp = time.Now()
+	}
+	_, err := r.collection.InsertOne(ctx, log)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
 	}
-	return logs, nil
+	return err
 }
 
-func (r *MongoLogRepository) GetLogsByUserID(userID primitive.ObjectID, page, limit int) ([]*models.LogEntry, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoLogRepository) GetAllLogs(ctx context.Context, page, limit int) ([]*models.LogEntry, int64, error) {
+	return r.findLogs(ctx, bson.M{}, page, limit)
+}
+
+func (r *MongoLogRepository) GetLogsByUserID(ctx context.Context, userID primitive.ObjectID, page, limit int) ([]*models.LogEntry, int64, error) {
+	return r.findLogs(ctx, bson.M{"user_id": userID}, page, limit)
+}
+
+// findLogs returns one page of matching entries, newest first, with the total match count.
+func (r *MongoLogRepository) findLogs(ctx context.Context, filter bson.M, page, limit int) ([]*models.LogEntry, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	var logs []*models.LogEntry
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	logs := []*models.LogEntry{}
 	skip := (page - 1) * limit
 	findOptions := options.Find().SetSort(bson.M{"timestamp": -1}).SetSkip(int64(skip)).SetLimit(int64(limit))
-	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, findOptions)
+	cursor, err := r.collection.Find(ctx, filter, findOptions)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer cursor.Close(ctx)
 	if err := cursor.All(ctx, &logs); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return logs, nil
+	return logs, total, nil
 }
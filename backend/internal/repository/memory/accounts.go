@@ -0,0 +1,189 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type AccountRepository struct {
+	mu       sync.RWMutex
+	accounts map[primitive.ObjectID]models.Account
+	order    []primitive.ObjectID
+}
+
+func NewAccountRepository() *AccountRepository {
+	return &AccountRepository{accounts: make(map[primitive.ObjectID]models.Account)}
+}
+
+// SaveAccount inserts a demo or real account with a zero balance. Account
+// names are unique per user.
+func (r *AccountRepository) SaveAccount(ctx context.Context, account *models.Account) error {
+	if account.AccountType != models.AccountTypeDemo && account.AccountType != models.AccountTypeReal {
+		return fmt.Errorf("invalid account type: %s", account.AccountType)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.accounts[account.ID]; ok {
+		return ErrDuplicate
+	}
+	for _, existing := range r.accounts {
+		if existing.UserID == account.UserID && existing.AccountName == account.AccountName {
+			return ErrDuplicate
+		}
+	}
+	account.Balance = 0.0
+	r.accounts[account.ID] = *account
+	r.order = append(r.order, account.ID)
+	return nil
+}
+
+func (r *AccountRepository) GetAccountByID(ctx context.Context, id primitive.ObjectID) (*models.Account, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	account, ok := r.accounts[id]
+	if !ok {
+		return nil, nil
+	}
+	return &account, nil
+}
+
+func (r *AccountRepository) GetAccountByName(ctx context.Context, name string, userID primitive.ObjectID) (*models.Account, error) {
+	accounts := r.find(func(a *models.Account) bool { return a.AccountName == name && a.UserID == userID })
+	if len(accounts) == 0 {
+		return nil, nil
+	}
+	return accounts[0], nil
+}
+
+func (r *AccountRepository) GetAccountsByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Account, error) {
+	return r.find(func(a *models.Account) bool { return a.UserID == userID }), nil
+}
+
+func (r *AccountRepository) GetAccountsWithWallet(ctx context.Context) ([]*models.Account, error) {
+	return r.find(func(a *models.Account) bool { return a.WalletID != "" }), nil
+}
+
+func (r *AccountRepository) DeleteAccount(ctx context.Context, accountID, userID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[accountID]
+	if !ok || account.UserID != userID {
+		return mongo.ErrNoDocuments
+	}
+	delete(r.accounts, accountID)
+	return nil
+}
+
+func (r *AccountRepository) UpdateAccount(ctx context.Context, account *models.Account) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.accounts[account.ID]; ok {
+		r.accounts[account.ID] = *account
+	}
+	return nil
+}
+
+func (r *AccountRepository) AdjustBalance(ctx context.Context, accountID primitive.ObjectID, delta float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[accountID]
+	if !ok {
+		return fmt.Errorf("no account found with ID: %s", accountID.Hex())
+	}
+	account.Balance += delta
+	r.accounts[accountID] = account
+	return nil
+}
+
+func (r *AccountRepository) DebitBalance(ctx context.Context, accountID primitive.ObjectID, amount float64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[accountID]
+	if !ok || account.Balance < amount {
+		return false, nil
+	}
+	account.Balance -= amount
+	r.accounts[accountID] = account
+	return true, nil
+}
+
+func (r *AccountRepository) SetRiskLimits(ctx context.Context, accountID, userID primitive.ObjectID, limits models.AccountRiskLimits) error {
+	return r.updateOwned(accountID, userID, func(a *models.Account) { a.AccountRiskLimits = limits })
+}
+
+func (r *AccountRepository) DisableAccount(ctx context.Context, accountID, userID primitive.ObjectID, reason string, at time.Time) error {
+	return r.updateOwned(accountID, userID, func(a *models.Account) {
+		a.Disabled = true
+		a.DisabledReason = reason
+		a.DisabledAt = &at
+	})
+}
+
+func (r *AccountRepository) SetMaxLeverage(ctx context.Context, accountID, userID primitive.ObjectID, maxLeverage int) error {
+	return r.updateOwned(accountID, userID, func(a *models.Account) { a.MaxLeverage = maxLeverage })
+}
+
+func (r *AccountRepository) SetTier(ctx context.Context, accountID, userID primitive.ObjectID, tier models.AccountTier) error {
+	return r.updateOwned(accountID, userID, func(a *models.Account) { a.Tier = tier })
+}
+
+// MarkRiskTriggered reports false when the limits already fired on the same
+// UTC day as at.
+func (r *AccountRepository) MarkRiskTriggered(ctx context.Context, accountID primitive.ObjectID, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[accountID]
+	if !ok {
+		return false, nil
+	}
+	if account.RiskTriggeredAt != nil && !account.RiskTriggeredAt.Before(models.StartOfDay(at)) {
+		return false, nil
+	}
+	account.RiskTriggeredAt = &at
+	r.accounts[accountID] = account
+	return true, nil
+}
+
+// updateOwned applies update to the user's account, or returns
+// mongo.ErrNoDocuments as the Mongo repository does when nothing matches.
+func (r *AccountRepository) updateOwned(accountID, userID primitive.ObjectID, update func(*models.Account)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	account, ok := r.accounts[accountID]
+	if !ok || account.UserID != userID {
+		return mongo.ErrNoDocuments
+	}
+	update(&account)
+	r.accounts[accountID] = account
+	return nil
+}
+
+// find returns copies of the matching accounts in insertion order.
+func (r *AccountRepository) find(match func(*models.Account) bool) []*models.Account {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	accounts := []*models.Account{}
+	for _, id := range r.order {
+		account, ok := r.accounts[id]
+		if ok && match(&account) {
+			accounts = append(accounts, &account)
+		}
+	}
+	return accounts
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+	"github.com/mehrbod2002/fxtrader/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LeaderRequestRepository struct {
+	mu       sync.RWMutex
+	requests map[primitive.ObjectID]models.LeaderRequest
+	order    []primitive.ObjectID
+}
+
+func NewLeaderRequestRepository() *LeaderRequestRepository {
+	return &LeaderRequestRepository{requests: make(map[primitive.ObjectID]models.LeaderRequest)}
+}
+
+// SaveLeaderRequest inserts the request, refusing a second pending request for
+// the same user.
+func (r *LeaderRequestRepository) SaveLeaderRequest(ctx context.Context, request *models.LeaderRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.requests {
+		if existing.UserID == request.UserID && existing.Status == "PENDING" {
+			return repository.ErrLeaderRequestPending
+		}
+	}
+	request.ID = primitive.NewObjectID()
+	request.CreatedAt = time.Now()
+	request.UpdatedAt = request.CreatedAt
+	r.requests[request.ID] = *request
+	r.order = append(r.order, request.ID)
+	return nil
+}
+
+func (r *LeaderRequestRepository) GetLeaderRequestByID(ctx context.Context, id primitive.ObjectID) (*models.LeaderRequest, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	request, ok := r.requests[id]
+	if !ok {
+		return nil, nil
+	}
+	return &request, nil
+}
+
+func (r *LeaderRequestRepository) GetPendingLeaderRequests(ctx context.Context) ([]*models.LeaderRequest, error) {
+	return r.find(func(req *models.LeaderRequest) bool { return req.Status == "PENDING" }), nil
+}
+
+// GetLeaderRequestsByUserID returns the user's requests, newest first.
+func (r *LeaderRequestRepository) GetLeaderRequestsByUserID(ctx context.Context, userID string) ([]*models.LeaderRequest, error) {
+	requests := r.find(func(req *models.LeaderRequest) bool { return req.UserID == userID })
+	sort.SliceStable(requests, func(i, j int) bool { return requests[i].CreatedAt.After(requests[j].CreatedAt) })
+	return requests, nil
+}
+
+func (r *LeaderRequestRepository) UpdateLeaderRequest(ctx context.Context, request *models.LeaderRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.requests[request.ID]; !ok {
+		return nil
+	}
+	request.UpdatedAt = time.Now()
+	r.requests[request.ID] = *request
+	return nil
+}
+
+func (r *LeaderRequestRepository) find(match func(*models.LeaderRequest) bool) []*models.LeaderRequest {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	requests := []*models.LeaderRequest{}
+	for _, id := range r.order {
+		request := r.requests[id]
+		if match(&request) {
+			requests = append(requests, &request)
+		}
+	}
+	return requests
+}
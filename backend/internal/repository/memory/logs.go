@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type LogRepository struct {
+	mu   sync.RWMutex
+	logs map[primitive.ObjectID]models.LogEntry
+}
+
+func NewLogRepository() *LogRepository {
+	return &LogRepository{logs: make(map[primitive.ObjectID]models.LogEntry)}
+}
+
+// SaveLog ignores a retried entry whose ID is already stored.
+func (r *LogRepository) SaveLog(ctx context.Context, log *models.LogEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if log.ID.IsZero() {
+		log.ID = primitive.NewObjectID()
+	}
+	if log.Timestamp.IsZero() {
+		log.Timestamp = time.Now()
+	}
+	if _, ok := r.logs[log.ID]; !ok {
+		r.logs[log.ID] = *log
+	}
+	return nil
+}
+
+func (r *LogRepository) GetAllLogs(ctx context.Context, page, limit int) ([]*models.LogEntry, int64, error) {
+	logs := r.find(func(*models.LogEntry) bool { return true })
+	return paginate(logs, int64(page), int64(limit)), int64(len(logs)), nil
+}
+
+func (r *LogRepository) GetLogsByUserID(ctx context.Context, userID primitive.ObjectID, page, limit int) ([]*models.LogEntry, int64, error) {
+	logs := r.find(func(l *models.LogEntry) bool { return l.UserID == userID })
+	return paginate(logs, int64(page), int64(limit)), int64(len(logs)), nil
+}
+
+// find returns copies of the matching entries, newest first.
+func (r *LogRepository) find(match func(*models.LogEntry) bool) []*models.LogEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	logs := []*models.LogEntry{}
+	for _, entry := range r.logs {
+		if match(&entry) {
+			logs = append(logs, &entry)
+		}
+	}
+	sort.SliceStable(logs, func(i, j int) bool { return logs[i].Timestamp.After(logs[j].Timestamp) })
+	return logs
+}
@@ -0,0 +1,59 @@
+// Package memory holds map-backed implementations of the repository
+// interfaces for exercising services without MongoDB. They follow the Mongo
+// repositories' observable behaviour, including not-found results and the
+// conditional updates services rely on for concurrency, but keep everything
+// in process.
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/mehrbod2002/fxtrader/internal/repository"
+)
+
+var (
+	_ repository.UserRepository          = (*UserRepository)(nil)
+	_ repository.AccountRepository       = (*AccountRepository)(nil)
+	_ repository.TradeRepository         = (*TradeRepository)(nil)
+	_ repository.SymbolRepository        = (*SymbolRepository)(nil)
+	_ repository.LeaderRequestRepository = (*LeaderRequestRepository)(nil)
+	_ repository.TransactionRepository   = (*TransactionRepository)(nil)
+	_ repository.LogRepository           = (*LogRepository)(nil)
+	_ repository.Transactor              = (*Transactor)(nil)
+)
+
+// ErrDuplicate stands in for a unique index violation.
+var ErrDuplicate = errors.New("memory: duplicate key")
+
+// Transactor serialises transactions. Writes are applied immediately and are
+// not rolled back when fn fails, so tests of failure paths should assert on
+// the returned error rather than on untouched state.
+type Transactor struct {
+	mu sync.Mutex
+}
+
+func NewTransactor() *Transactor {
+	return &Transactor{}
+}
+
+func (t *Transactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return fn(ctx)
+}
+
+// paginate returns the 1-based page of items, as the Mongo repositories do
+// with skip and limit.
+func paginate[T any](items []T, page, limit int64) []T {
+	if page < 1 || limit < 1 {
+		return []T{}
+	}
+	start := (page - 1) * limit
+	if start >= int64(len(items)) {
+		return []T{}
+	}
+	end := min(start+limit, int64(len(items)))
+	return items[start:end]
+}
@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type SymbolRepository struct {
+	mu      sync.RWMutex
+	symbols map[primitive.ObjectID]models.Symbol
+	order   []primitive.ObjectID
+}
+
+func NewSymbolRepository() *SymbolRepository {
+	return &SymbolRepository{symbols: make(map[primitive.ObjectID]models.Symbol)}
+}
+
+// SaveSymbol inserts the symbol under a new ID.
+func (r *SymbolRepository) SaveSymbol(ctx context.Context, symbol *models.Symbol) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	symbol.ID = primitive.NewObjectID()
+	symbol.CreatedAt = time.Now()
+	symbol.UpdatedAt = symbol.CreatedAt
+	r.symbols[symbol.ID] = *symbol
+	r.order = append(r.order, symbol.ID)
+	return nil
+}
+
+func (r *SymbolRepository) GetSymbolByID(ctx context.Context, id primitive.ObjectID) (*models.Symbol, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	symbol, ok := r.symbols[id]
+	if !ok {
+		return nil, nil
+	}
+	return &symbol, nil
+}
+
+func (r *SymbolRepository) GetAllSymbols(ctx context.Context) ([]*models.Symbol, error) {
+	return r.find(func(*models.Symbol) bool { return true }), nil
+}
+
+// SearchSymbols returns up to limit symbols whose name or display name
+// contains query, ignoring case, ordered by symbol name.
+func (r *SymbolRepository) SearchSymbols(ctx context.Context, query string, limit int64) ([]*models.Symbol, error) {
+	query = strings.ToLower(query)
+	symbols := r.find(func(s *models.Symbol) bool {
+		return strings.Contains(strings.ToLower(s.SymbolName), query) ||
+			strings.Contains(strings.ToLower(s.DisplayName), query)
+	})
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].SymbolName < symbols[j].SymbolName })
+	if limit > 0 && int64(len(symbols)) > limit {
+		symbols = symbols[:limit]
+	}
+	return symbols, nil
+}
+
+// UpdateSymbol replaces the stored symbol; an unknown ID is not an error.
+func (r *SymbolRepository) UpdateSymbol(ctx context.Context, id primitive.ObjectID, symbol *models.Symbol) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.symbols[id]; !ok {
+		return nil
+	}
+	symbol.UpdatedAt = time.Now()
+	updated := *symbol
+	updated.ID = id
+	r.symbols[id] = updated
+	return nil
+}
+
+func (r *SymbolRepository) DeleteSymbol(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.symbols, id)
+	for i, existing := range r.order {
+		if existing == id {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (r *SymbolRepository) SetNewsHalt(ctx context.Context, id primitive.ObjectID, halt *models.NewsHalt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	symbol, ok := r.symbols[id]
+	if !ok {
+		return mongo.ErrNoDocuments
+	}
+	symbol.NewsHalt = halt
+	symbol.UpdatedAt = time.Now()
+	r.symbols[id] = symbol
+	return nil
+}
+
+func (r *SymbolRepository) find(match func(*models.Symbol) bool) []*models.Symbol {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	symbols := []*models.Symbol{}
+	for _, id := range r.order {
+		symbol := r.symbols[id]
+		if match(&symbol) {
+			symbols = append(symbols, &symbol)
+		}
+	}
+	return symbols
+}
@@ -0,0 +1,330 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TradeRepository struct {
+	mu     sync.RWMutex
+	trades map[primitive.ObjectID]models.TradeHistory
+}
+
+func NewTradeRepository() *TradeRepository {
+	return &TradeRepository{trades: make(map[primitive.ObjectID]models.TradeHistory)}
+}
+
+// SaveTrade inserts a trade without an ID, stamping its open time, and
+// otherwise upserts it.
+func (r *TradeRepository) SaveTrade(ctx context.Context, trade *models.TradeHistory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if trade.ID.IsZero() {
+		trade.ID = primitive.NewObjectID()
+		trade.OpenTime = time.Now()
+		trade.UpdatedAt = trade.OpenTime
+	} else {
+		trade.UpdatedAt = time.Now()
+	}
+	r.trades[trade.ID] = *trade
+	return nil
+}
+
+func (r *TradeRepository) GetTradeByID(ctx context.Context, id primitive.ObjectID) (*models.TradeHistory, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	trade, ok := r.trades[id]
+	if !ok {
+		return nil, nil
+	}
+	return &trade, nil
+}
+
+// GetTradesByUserID returns the user's trades, newest first.
+func (r *TradeRepository) GetTradesByUserID(ctx context.Context, userID primitive.ObjectID, accountType string) ([]*models.TradeHistory, error) {
+	trades := r.find(func(t *models.TradeHistory) bool {
+		return t.UserID == userID && matchesAccountType(t, accountType)
+	})
+	sort.Slice(trades, func(i, j int) bool { return trades[i].OpenTime.After(trades[j].OpenTime) })
+	return trades, nil
+}
+
+func (r *TradeRepository) GetAllTrades(ctx context.Context, accountType string) ([]*models.TradeHistory, error) {
+	return r.find(func(t *models.TradeHistory) bool { return matchesAccountType(t, accountType) }), nil
+}
+
+func (r *TradeRepository) GetTradesUpdatedSince(ctx context.Context, userID primitive.ObjectID, since time.Time) ([]*models.TradeHistory, error) {
+	trades := r.find(func(t *models.TradeHistory) bool { return t.UserID == userID && t.UpdatedAt.After(since) })
+	sort.Slice(trades, func(i, j int) bool { return trades[i].UpdatedAt.Before(trades[j].UpdatedAt) })
+	return trades, nil
+}
+
+func (r *TradeRepository) GetClosedTrades(ctx context.Context, from, to time.Time, accountType string) ([]*models.TradeHistory, error) {
+	trades := r.find(closedIn(from, to, accountType))
+	sort.Slice(trades, func(i, j int) bool { return trades[i].CloseTime.Before(*trades[j].CloseTime) })
+	return trades, nil
+}
+
+// GetSettlement groups the trades GetClosedTrades would return by user and by
+// symbol, sorted by key.
+func (r *TradeRepository) GetSettlement(ctx context.Context, from, to time.Time, accountType string) (*models.SettlementReport, error) {
+	trades := r.find(closedIn(from, to, accountType))
+	report := &models.SettlementReport{
+		From:        from,
+		To:          to,
+		AccountType: accountType,
+		ByUser:      settle(trades, func(t *models.TradeHistory) string { return t.UserID.Hex() }),
+		BySymbol:    settle(trades, func(t *models.TradeHistory) string { return t.Symbol }),
+	}
+	return report, nil
+}
+
+func (r *TradeRepository) MarkTradeClosed(ctx context.Context, trade *models.TradeHistory) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.trades[trade.ID]
+	if !ok || stored.Status != string(models.TradeStatusOpen) {
+		return false, nil
+	}
+	trade.UpdatedAt = time.Now()
+	stored.CloseTime = trade.CloseTime
+	stored.ClosePrice = trade.ClosePrice
+	stored.CloseReason = trade.CloseReason
+	stored.Profit = trade.Profit
+	stored.Commission = trade.Commission
+	stored.MT5Commission = trade.MT5Commission
+	stored.Swap = trade.Swap
+	stored.UpdatedAt = trade.UpdatedAt
+	stored.Status = string(models.TradeStatusClosed)
+	r.trades[trade.ID] = stored
+	return true, nil
+}
+
+func (r *TradeRepository) GetPendingTradesBySymbol(ctx context.Context, symbol string, executionType models.ExecutionType) ([]*models.TradeHistory, error) {
+	return r.find(func(t *models.TradeHistory) bool {
+		return t.Symbol == symbol && t.Status == string(models.TradeStatusPending) && t.ExecutionType == executionType
+	}), nil
+}
+
+func (r *TradeRepository) ActivatePendingTrade(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	return r.updatePending(id, func(t *models.TradeHistory) bool {
+		t.Status = string(models.TradeStatusOpen)
+		return true
+	})
+}
+
+func (r *TradeRepository) CancelPendingTrade(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	return r.updatePending(id, func(t *models.TradeHistory) bool {
+		now := time.Now()
+		t.Status = string(models.TradeStatusCancelled)
+		t.CloseTime = &now
+		return true
+	})
+}
+
+func (r *TradeRepository) FillPendingTrade(ctx context.Context, id primitive.ObjectID, expectedVolume, fillVolume float64, matchedTradeID string) (bool, error) {
+	return r.updatePending(id, func(t *models.TradeHistory) bool {
+		if t.Volume != expectedVolume {
+			return false
+		}
+		if fillVolume >= expectedVolume {
+			t.Status = string(models.TradeStatusOpen)
+			t.MatchedTradeID = matchedTradeID
+		} else {
+			t.Volume = expectedVolume - fillVolume
+		}
+		return true
+	})
+}
+
+func (r *TradeRepository) GetVolumeSince(ctx context.Context, userID primitive.ObjectID, since time.Time) (float64, error) {
+	var volume float64
+	for _, trade := range r.find(func(t *models.TradeHistory) bool {
+		return t.UserID == userID && !t.OpenTime.Before(since) &&
+			(t.Status == string(models.TradeStatusOpen) || t.Status == string(models.TradeStatusClosed))
+	}) {
+		volume += trade.Volume
+	}
+	return volume, nil
+}
+
+func (r *TradeRepository) CountTradesByStatus(ctx context.Context, userID primitive.ObjectID, status models.TradeStatus) (int64, error) {
+	trades := r.find(func(t *models.TradeHistory) bool { return t.UserID == userID && t.Status == string(status) })
+	return int64(len(trades)), nil
+}
+
+func (r *TradeRepository) CountActiveTradesBySymbol(ctx context.Context, accountID primitive.ObjectID, symbol string) (int64, error) {
+	trades := r.find(func(t *models.TradeHistory) bool {
+		return t.AccountID == accountID && t.Symbol == symbol &&
+			(t.Status == string(models.TradeStatusOpen) || t.Status == string(models.TradeStatusPending))
+	})
+	return int64(len(trades)), nil
+}
+
+func (r *TradeRepository) GetOpenTradesBySymbol(ctx context.Context, symbol string) ([]*models.TradeHistory, error) {
+	return r.find(func(t *models.TradeHistory) bool {
+		return t.Symbol == symbol && t.Status == string(models.TradeStatusOpen)
+	}), nil
+}
+
+func (r *TradeRepository) GetOpenTradesByAccount(ctx context.Context, accountID primitive.ObjectID) ([]*models.TradeHistory, error) {
+	return r.find(func(t *models.TradeHistory) bool {
+		return t.AccountID == accountID && t.Status == string(models.TradeStatusOpen)
+	}), nil
+}
+
+func (r *TradeRepository) GetPendingTradesByAccount(ctx context.Context, accountID primitive.ObjectID) ([]*models.TradeHistory, error) {
+	return r.find(func(t *models.TradeHistory) bool {
+		return t.AccountID == accountID && t.Status == string(models.TradeStatusPending)
+	}), nil
+}
+
+func (r *TradeRepository) ConfirmRequotedTrade(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	return r.updateInStatus(id, models.TradeStatusRequoted, func(t *models.TradeHistory) bool {
+		t.Status = string(models.TradeStatusPending)
+		return true
+	})
+}
+
+func (r *TradeRepository) CancelRequotedTrade(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	return r.updateInStatus(id, models.TradeStatusRequoted, func(t *models.TradeHistory) bool {
+		now := time.Now()
+		t.Status = string(models.TradeStatusCancelled)
+		t.CloseReason = models.CloseReasonExpired
+		t.CloseTime = &now
+		return true
+	})
+}
+
+func (r *TradeRepository) GetExpiredRequotes(ctx context.Context, now time.Time) ([]*models.TradeHistory, error) {
+	return r.find(func(t *models.TradeHistory) bool {
+		return t.Status == string(models.TradeStatusRequoted) && t.Requote != nil && t.Requote.ExpiresAt.Before(now)
+	}), nil
+}
+
+func (r *TradeRepository) GetExpiredPendingTrades(ctx context.Context, now time.Time) ([]*models.TradeHistory, error) {
+	trades := r.find(func(t *models.TradeHistory) bool {
+		return t.Status == string(models.TradeStatusPending) && t.Expiration != nil && t.Expiration.Before(now)
+	})
+	sort.Slice(trades, func(i, j int) bool { return trades[i].Expiration.Before(*trades[j].Expiration) })
+	return trades, nil
+}
+
+func (r *TradeRepository) ExpirePendingTrade(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	return r.updatePending(id, func(t *models.TradeHistory) bool {
+		now := time.Now()
+		t.Status = string(models.TradeStatusExpired)
+		t.CloseReason = models.CloseReasonExpired
+		t.CloseTime = &now
+		return true
+	})
+}
+
+func (r *TradeRepository) GetRealizedProfitSince(ctx context.Context, accountID primitive.ObjectID, since time.Time) (float64, error) {
+	var net float64
+	for _, trade := range r.find(func(t *models.TradeHistory) bool {
+		return t.AccountID == accountID && t.Status == string(models.TradeStatusClosed) &&
+			t.CloseTime != nil && !t.CloseTime.Before(since)
+	}) {
+		net += trade.Profit + trade.Commission + trade.Swap
+	}
+	return net, nil
+}
+
+func (r *TradeRepository) GetTradeHistoryPage(ctx context.Context, userID primitive.ObjectID, from, to time.Time, afterID primitive.ObjectID, limit int64) ([]*models.TradeHistory, error) {
+	trades := r.find(func(t *models.TradeHistory) bool {
+		return t.UserID == userID &&
+			(t.Status == string(models.TradeStatusOpen) || t.Status == string(models.TradeStatusClosed)) &&
+			!t.OpenTime.Before(from) && t.OpenTime.Before(to) &&
+			(afterID.IsZero() || t.ID.Hex() > afterID.Hex())
+	})
+	sort.Slice(trades, func(i, j int) bool { return trades[i].ID.Hex() < trades[j].ID.Hex() })
+	if limit > 0 && int64(len(trades)) > limit {
+		trades = trades[:limit]
+	}
+	return trades, nil
+}
+
+// updatePending applies update to a PENDING trade and reports whether it did;
+// update may decline by returning false.
+func (r *TradeRepository) updatePending(id primitive.ObjectID, update func(*models.TradeHistory) bool) (bool, error) {
+	return r.updateInStatus(id, models.TradeStatusPending, update)
+}
+
+// updateInStatus applies update only while the trade is in status.
+func (r *TradeRepository) updateInStatus(id primitive.ObjectID, status models.TradeStatus, update func(*models.TradeHistory) bool) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	trade, ok := r.trades[id]
+	if !ok || trade.Status != string(status) {
+		return false, nil
+	}
+	if !update(&trade) {
+		return false, nil
+	}
+	trade.UpdatedAt = time.Now()
+	r.trades[id] = trade
+	return true, nil
+}
+
+// find returns copies of the matching trades in no particular order.
+func (r *TradeRepository) find(match func(*models.TradeHistory) bool) []*models.TradeHistory {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	trades := []*models.TradeHistory{}
+	for _, trade := range r.trades {
+		if match(&trade) {
+			trades = append(trades, &trade)
+		}
+	}
+	return trades
+}
+
+// matchesAccountType ignores case, as trades have been stored with both
+// "demo" and "DEMO".
+func matchesAccountType(trade *models.TradeHistory, accountType string) bool {
+	return accountType == "" || strings.EqualFold(trade.AccountType, accountType)
+}
+
+func closedIn(from, to time.Time, accountType string) func(*models.TradeHistory) bool {
+	return func(t *models.TradeHistory) bool {
+		return t.Status == string(models.TradeStatusClosed) && t.CloseTime != nil &&
+			!t.CloseTime.Before(from) && t.CloseTime.Before(to) && matchesAccountType(t, accountType)
+	}
+}
+
+func settle(trades []*models.TradeHistory, key func(*models.TradeHistory) string) []models.SettlementLine {
+	lines := map[string]*models.SettlementLine{}
+	for _, trade := range trades {
+		k := key(trade)
+		line, ok := lines[k]
+		if !ok {
+			line = &models.SettlementLine{Key: k}
+			lines[k] = line
+		}
+		line.Trades++
+		line.Profit += trade.Profit
+		line.Commission += trade.Commission
+		line.Swap += trade.Swap
+		line.NetProfit = line.Profit + line.Commission + line.Swap
+	}
+
+	result := make([]models.SettlementLine, 0, len(lines))
+	for _, line := range lines {
+		result = append(result, *line)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TransactionRepository struct {
+	mu           sync.RWMutex
+	transactions map[primitive.ObjectID]models.Transaction
+}
+
+func NewTransactionRepository() *TransactionRepository {
+	return &TransactionRepository{transactions: make(map[primitive.ObjectID]models.Transaction)}
+}
+
+func (r *TransactionRepository) SaveTransaction(ctx context.Context, transaction *models.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	transaction.ID = primitive.NewObjectID()
+	transaction.RequestTime = time.Now()
+	r.transactions[transaction.ID] = *transaction
+	return nil
+}
+
+func (r *TransactionRepository) GetTransactionByID(ctx context.Context, id primitive.ObjectID) (*models.Transaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	transaction, ok := r.transactions[id]
+	if !ok {
+		return nil, nil
+	}
+	return &transaction, nil
+}
+
+func (r *TransactionRepository) GetTransactionsByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Transaction, error) {
+	return r.find(func(t *models.Transaction) bool { return t.UserID == userID.Hex() }), nil
+}
+
+func (r *TransactionRepository) GetAllTransactions(ctx context.Context) ([]*models.Transaction, error) {
+	return r.find(func(*models.Transaction) bool { return true }), nil
+}
+
+// ReviewTransaction applies the decision only while the transaction is
+// still pending.
+func (r *TransactionRepository) ReviewTransaction(ctx context.Context, id primitive.ObjectID, transaction *models.Transaction) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.transactions[id]
+	if !ok || stored.Status != models.TransactionStatusPending {
+		return false, nil
+	}
+	stored.Status = transaction.Status
+	stored.ResponseTime = transaction.ResponseTime
+	stored.Reason = transaction.Reason
+	stored.AdminComment = transaction.AdminComment
+	stored.ConvertedAmount = transaction.ConvertedAmount
+	r.transactions[id] = stored
+	return true, nil
+}
+
+// find returns copies of the matching transactions, newest request first.
+func (r *TransactionRepository) find(match func(*models.Transaction) bool) []*models.Transaction {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	transactions := []*models.Transaction{}
+	for _, transaction := range r.transactions {
+		if match(&transaction) {
+			transactions = append(transactions, &transaction)
+		}
+	}
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].RequestTime.After(transactions[j].RequestTime)
+	})
+	return transactions
+}
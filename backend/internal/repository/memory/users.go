@@ -0,0 +1,245 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+	"github.com/mehrbod2002/fxtrader/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type UserRepository struct {
+	mu    sync.RWMutex
+	users map[primitive.ObjectID]models.User
+	order []primitive.ObjectID
+}
+
+func NewUserRepository() *UserRepository {
+	return &UserRepository{users: make(map[primitive.ObjectID]models.User)}
+}
+
+// SaveUser inserts the user with a zero balance and bonus, like the Mongo
+// repository. Username, Telegram ID and referral code must be unique.
+func (r *UserRepository) SaveUser(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[user.ID]; ok {
+		return ErrDuplicate
+	}
+	for _, existing := range r.users {
+		if existing.Username == user.Username || existing.TelegramID == user.TelegramID || existing.ReferralCode == user.ReferralCode {
+			return ErrDuplicate
+		}
+	}
+	user.Balance = 0.0
+	user.Bonus = 0.0
+	r.users[user.ID] = *user
+	r.order = append(r.order, user.ID)
+	return nil
+}
+
+func (r *UserRepository) GetUserByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+func (r *UserRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.findOne(func(u *models.User) bool { return u.Username == username }), nil
+}
+
+func (r *UserRepository) GetUserByTelegramID(ctx context.Context, telegramID string) (*models.User, error) {
+	return r.findOne(func(u *models.User) bool { return u.TelegramID == telegramID }), nil
+}
+
+func (r *UserRepository) GetUserByReferralCode(ctx context.Context, code string) (*models.User, error) {
+	return r.findOne(func(u *models.User) bool { return u.ReferralCode == code }), nil
+}
+
+func (r *UserRepository) GetAllUsers(ctx context.Context) ([]*models.User, error) {
+	return r.find(func(*models.User) bool { return true }), nil
+}
+
+func (r *UserRepository) GetUsers(ctx context.Context, filter models.UserFilter, page, limit int64) ([]*models.User, int64, error) {
+	return r.SearchUsers(ctx, "", filter, page, limit)
+}
+
+// SearchUsers matches the same prefixes as the Mongo repository and sorts
+// the same way, with insertion order standing in for _id.
+func (r *UserRepository) SearchUsers(ctx context.Context, query string, filter models.UserFilter, page, limit int64) ([]*models.User, int64, error) {
+	query = strings.TrimSpace(query)
+	folded := strings.ToLower(query)
+	users := r.find(func(u *models.User) bool {
+		if !matchesUserFilter(u, filter) {
+			return false
+		}
+		return query == "" ||
+			strings.HasPrefix(strings.ToLower(u.FullName), folded) ||
+			strings.HasPrefix(strings.ToLower(u.Username), folded) ||
+			strings.HasPrefix(u.PhoneNumber, query) ||
+			strings.HasPrefix(u.TelegramID, query) ||
+			strings.HasPrefix(u.NationalID, query)
+	})
+	slices.Reverse(users)
+	sort.SliceStable(users, func(i, j int) bool {
+		a, b := users[i], users[j]
+		switch filter.Sort {
+		case models.UserSortRegistrationDate:
+			return a.RegistrationDate < b.RegistrationDate
+		case models.UserSortRegistrationDateDesc:
+			return a.RegistrationDate > b.RegistrationDate
+		case models.UserSortBalance:
+			return a.Balance < b.Balance
+		case models.UserSortBalanceDesc:
+			return a.Balance > b.Balance
+		}
+		return false
+	})
+	return paginate(users, page, limit), int64(len(users)), nil
+}
+
+func (r *UserRepository) CountUsers(ctx context.Context, filter models.UserFilter) (int64, error) {
+	return int64(len(r.find(func(u *models.User) bool { return matchesUserFilter(u, filter) }))), nil
+}
+
+func matchesUserFilter(u *models.User, filter models.UserFilter) bool {
+	return (filter.IsActive == nil || u.IsActive == *filter.IsActive) &&
+		(filter.IsLeader == nil || u.IsCopyTradeLeader == *filter.IsLeader)
+}
+
+func (r *UserRepository) GetUsersReferredBy(ctx context.Context, code string, page, limit int64) ([]*models.User, int64, error) {
+	users := r.find(func(u *models.User) bool { return u.ReferredBy.Hex() == code })
+	return paginate(users, page, limit), int64(len(users)), nil
+}
+
+func (r *UserRepository) GetAllReferrals(ctx context.Context, page, limit int64) ([]*models.User, int64, error) {
+	users := r.find(func(*models.User) bool { return true })
+	return paginate(users, page, limit), int64(len(users)), nil
+}
+
+// UpdateUser replaces the stored user. Like the Mongo $set, a missing user is
+// not an error.
+func (r *UserRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[user.ID]; ok {
+		r.users[user.ID] = *user
+	}
+	return nil
+}
+
+// EditUser writes the profile fields an admin may edit and leaves the rest,
+// such as account types and leader limits, as stored.
+func (r *UserRepository) EditUser(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[user.ID]
+	if !ok {
+		return fmt.Errorf("no user found with ID: %s", user.ID.Hex())
+	}
+	stored.Username = user.Username
+	stored.FullName = user.FullName
+	stored.PhoneNumber = user.PhoneNumber
+	stored.CardNumber = user.CardNumber
+	stored.NationalID = user.NationalID
+	stored.Citizenship = user.Citizenship
+	stored.Residence = user.Residence
+	stored.BirthDay = user.BirthDay
+	stored.TelegramID = user.TelegramID
+	stored.ReferralCode = user.ReferralCode
+	stored.ReferredBy = user.ReferredBy
+	stored.RegistrationDate = user.RegistrationDate
+	stored.IsActive = user.IsActive
+	stored.IsCopyTradeLeader = user.IsCopyTradeLeader
+	stored.IsCopyPendingTradeLeader = user.IsCopyPendingTradeLeader
+	stored.Balance = user.Balance
+	stored.Bonus = user.Bonus
+	stored.Leverage = user.Leverage
+	stored.TradeType = user.TradeType
+	stored.WalletAddress = user.WalletAddress
+	r.users[user.ID] = stored
+	return nil
+}
+
+func (r *UserRepository) AddBalance(ctx context.Context, userID primitive.ObjectID, amount float64) error {
+	if amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return fmt.Errorf("%w with ID: %s", repository.ErrUserNotFound, userID.Hex())
+	}
+	user.Balance += amount
+	r.users[userID] = user
+	return nil
+}
+
+func (r *UserRepository) SubtractBalance(ctx context.Context, userID primitive.ObjectID, amount float64) error {
+	if amount <= 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[userID]
+	if !ok {
+		return fmt.Errorf("%w with ID: %s", repository.ErrUserNotFound, userID.Hex())
+	}
+	if user.Balance < amount {
+		return fmt.Errorf("%w: requested withdrawal %f", repository.ErrInsufficientBalance, amount)
+	}
+	user.Balance -= amount
+	r.users[userID] = user
+	return nil
+}
+
+func (r *UserRepository) ActiveUser(ctx context.Context, userID primitive.ObjectID, active bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user, ok := r.users[userID]; ok {
+		user.IsActive = active
+		r.users[userID] = user
+	}
+	return nil
+}
+
+func (r *UserRepository) findOne(match func(*models.User) bool) *models.User {
+	users := r.find(match)
+	if len(users) == 0 {
+		return nil
+	}
+	return users[0]
+}
+
+// find returns copies of the matching users in insertion order.
+func (r *UserRepository) find(match func(*models.User) bool) []*models.User {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := []*models.User{}
+	for _, id := range r.order {
+		user, ok := r.users[id]
+		if ok && match(&user) {
+			users = append(users, &user)
+		}
+	}
+	return users
+}
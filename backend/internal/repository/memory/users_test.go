@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+	"github.com/mehrbod2002/fxtrader/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUserBalanceUpdates(t *testing.T) {
+	ctx := context.Background()
+	missing := primitive.NewObjectID()
+
+	// wantIs names the error the Mongo repository returns in the same case.
+	tests := []struct {
+		name    string
+		apply   func(r *UserRepository, userID primitive.ObjectID) error
+		wantErr bool
+		wantIs  error
+		want    float64
+	}{
+		{"add", func(r *UserRepository, id primitive.ObjectID) error { return r.AddBalance(ctx, id, 25) }, false, nil, 125},
+		{"add zero", func(r *UserRepository, id primitive.ObjectID) error { return r.AddBalance(ctx, id, 0) }, true, nil, 100},
+		{"add negative", func(r *UserRepository, id primitive.ObjectID) error { return r.AddBalance(ctx, id, -5) }, true, nil, 100},
+		{"add to missing user", func(r *UserRepository, _ primitive.ObjectID) error { return r.AddBalance(ctx, missing, 5) }, true, repository.ErrUserNotFound, 100},
+		{"subtract", func(r *UserRepository, id primitive.ObjectID) error { return r.SubtractBalance(ctx, id, 40) }, false, nil, 60},
+		{"subtract everything", func(r *UserRepository, id primitive.ObjectID) error { return r.SubtractBalance(ctx, id, 100) }, false, nil, 0},
+		{"subtract too much", func(r *UserRepository, id primitive.ObjectID) error { return r.SubtractBalance(ctx, id, 100.01) }, true, repository.ErrInsufficientBalance, 100},
+		{"subtract negative", func(r *UserRepository, id primitive.ObjectID) error { return r.SubtractBalance(ctx, id, -5) }, true, nil, 100},
+		{"subtract from missing user", func(r *UserRepository, _ primitive.ObjectID) error { return r.SubtractBalance(ctx, missing, 5) }, true, repository.ErrUserNotFound, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewUserRepository()
+			user := &models.User{ID: primitive.NewObjectID()}
+			if err := r.SaveUser(ctx, user); err != nil {
+				t.Fatalf("save user: %v", err)
+			}
+			if err := r.AddBalance(ctx, user.ID, 100); err != nil {
+				t.Fatalf("fund user: %v", err)
+			}
+
+			err := tt.apply(r, user.ID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+				t.Fatalf("error = %v, want %v", err, tt.wantIs)
+			}
+			stored, _ := r.GetUserByID(ctx, user.ID)
+			if stored.Balance != tt.want {
+				t.Errorf("balance = %v, want %v", stored.Balance, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubtractBalanceNeverOverdraws(t *testing.T) {
+	ctx := context.Background()
+	r := NewUserRepository()
+	user := &models.User{ID: primitive.NewObjectID()}
+	if err := r.SaveUser(ctx, user); err != nil {
+		t.Fatalf("save user: %v", err)
+	}
+	if err := r.AddBalance(ctx, user.ID, 10); err != nil {
+		t.Fatalf("fund user: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if r.SubtractBalance(ctx, user.ID, 1) == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	stored, _ := r.GetUserByID(ctx, user.ID)
+	if succeeded != 10 || stored.Balance != 0 {
+		t.Fatalf("%d debits succeeded leaving %v, want 10 leaving 0", succeeded, stored.Balance)
+	}
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// NormalizeAccountTypes lowercases the account_type of every document in the
+// named collections, returning how many were changed. It is safe to run on
+// every start: documents already in canonical form are not touched.
+func NormalizeAccountTypes(db *mongo.Database, collections ...string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	filter := bson.M{"account_type": bson.M{"$type": "string", "$regex": "[A-Z]|^\\s|\\s$"}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"account_type": bson.M{"$toLower": bson.M{"$trim": bson.M{"input": "$account_type"}}}}}},
+	}
+
+	var modified int64
+	for _, name := range collections {
+		result, err := db.Collection(name).UpdateMany(ctx, filter, update)
+		if err != nil {
+			return modified, err
+		}
+		modified += result.ModifiedCount
+	}
+	return modified, nil
+}
+
+// BackfillTradeOpenTimes sets open_time on trades saved before it was
+// written, from their unix timestamp field, returning how many were changed.
+// History pages, exports and the commission volume window all filter on
+// open_time, so without it those trades would never be found.
+func BackfillTradeOpenTimes(db *mongo.Database, collection string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"open_time": bson.M{"$exists": false},
+		"timestamp": bson.M{"$type": "number"},
+	}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"open_time": bson.M{"$toDate": bson.M{"$multiply": bson.A{"$timestamp", 1000}}}}}},
+	}
+
+	result, err := db.Collection(collection).UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
@@ -12,11 +12,11 @@ import (
 )
 
 type RuleRepository interface {
-	SaveRule(rule *models.Rule) error
-	GetRuleByID(id primitive.ObjectID) (*models.Rule, error)
-	GetAllRules() ([]*models.Rule, error)
-	UpdateRule(id primitive.ObjectID, rule *models.Rule) error
-	DeleteRule(id primitive.ObjectID) error
+	SaveRule(ctx context.Context, rule *models.Rule) error
+	GetRuleByID(ctx context.Context, id primitive.ObjectID) (*models.Rule, error)
+	GetAllRules(ctx context.Context) ([]*models.Rule, error)
+	UpdateRule(ctx context.Context, id primitive.ObjectID, rule *models.Rule) error
+	DeleteRule(ctx context.Context, id primitive.ObjectID) error
 }
 
 type MongoRuleRepository struct {
@@ -28,8 +28,8 @@ func NewRuleRepository(client *mongo.Client, dbName, collectionName string) Rule
 	return &MongoRuleRepository{collection: collection}
 }
 
-func (r *MongoRuleRepository) SaveRule(rule *models.Rule) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoRuleRepository) SaveRule(ctx context.Context, rule *models.Rule) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	rule.ID = primitive.NewObjectID()
@@ -38,8 +38,8 @@ func (r *MongoRuleRepository) SaveRule(rule *models.Rule) error {
 	return err
 }
 
-func (r *MongoRuleRepository) GetRuleByID(id primitive.ObjectID) (*models.Rule, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoRuleRepository) GetRuleByID(ctx context.Context, id primitive.ObjectID) (*models.Rule, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var rule models.Rule
@@ -50,8 +50,8 @@ func (r *MongoRuleRepository) GetRuleByID(id primitive.ObjectID) (*models.Rule,
 	return &rule, err
 }
 
-func (r *MongoRuleRepository) GetAllRules() ([]*models.Rule, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoRuleRepository) GetAllRules(ctx context.Context) ([]*models.Rule, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var rules []*models.Rule
@@ -66,8 +66,8 @@ func (r *MongoRuleRepository) GetAllRules() ([]*models.Rule, error) {
 	return rules, nil
 }
 
-func (r *MongoRuleRepository) UpdateRule(id primitive.ObjectID, rule *models.Rule) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoRuleRepository) UpdateRule(ctx context.Context, id primitive.ObjectID, rule *models.Rule) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	update := bson.M{"$set": bson.M{"content": rule.Content}}
@@ -75,8 +75,8 @@ func (r *MongoRuleRepository) UpdateRule(id primitive.ObjectID, rule *models.Rul
 	return err
 }
 
-func (r *MongoRuleRepository) DeleteRule(id primitive.ObjectID) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoRuleRepository) DeleteRule(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"regexp"
 	"time"
 
 	"github.com/mehrbod2002/fxtrader/internal/models"
@@ -9,14 +10,17 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type SymbolRepository interface {
-	SaveSymbol(symbol *models.Symbol) error
-	GetSymbolByID(id primitive.ObjectID) (*models.Symbol, error)
-	GetAllSymbols() ([]*models.Symbol, error)
-	UpdateSymbol(id primitive.ObjectID, symbol *models.Symbol) error
-	DeleteSymbol(id primitive.ObjectID) error
+	SaveSymbol(ctx context.Context, symbol *models.Symbol) error
+	GetSymbolByID(ctx context.Context, id primitive.ObjectID) (*models.Symbol, error)
+	GetAllSymbols(ctx context.Context) ([]*models.Symbol, error)
+	SearchSymbols(ctx context.Context, query string, limit int64) ([]*models.Symbol, error)
+	UpdateSymbol(ctx context.Context, id primitive.ObjectID, symbol *models.Symbol) error
+	DeleteSymbol(ctx context.Context, id primitive.ObjectID) error
+	SetNewsHalt(ctx context.Context, id primitive.ObjectID, halt *models.NewsHalt) error
 }
 
 type MongoSymbolRepository struct {
@@ -28,8 +32,8 @@ func NewSymbolRepository(client *mongo.Client, dbName, collectionName string) Sy
 	return &MongoSymbolRepository{collection: collection}
 }
 
-func (r *MongoSymbolRepository) SaveSymbol(symbol *models.Symbol) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoSymbolRepository) SaveSymbol(ctx context.Context, symbol *models.Symbol) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	symbol.ID = primitive.NewObjectID()
@@ -39,8 +43,8 @@ func (r *MongoSymbolRepository) SaveSymbol(symbol *models.Symbol) error {
 	return err
 }
 
-func (r *MongoSymbolRepository) GetSymbolByID(id primitive.ObjectID) (*models.Symbol, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoSymbolRepository) GetSymbolByID(ctx context.Context, id primitive.ObjectID) (*models.Symbol, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var symbol models.Symbol
@@ -51,8 +55,8 @@ func (r *MongoSymbolRepository) GetSymbolByID(id primitive.ObjectID) (*models.Sy
 	return &symbol, err
 }
 
-func (r *MongoSymbolRepository) GetAllSymbols() ([]*models.Symbol, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoSymbolRepository) GetAllSymbols(ctx context.Context) ([]*models.Symbol, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var symbols []*models.Symbol
@@ -67,8 +71,33 @@ func (r *MongoSymbolRepository) GetAllSymbols() ([]*models.Symbol, error) {
 	return symbols, nil
 }
 
-func (r *MongoSymbolRepository) UpdateSymbol(id primitive.ObjectID, symbol *models.Symbol) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// SearchSymbols returns up to limit symbols whose name or display name
+// contains query, ignoring case, ordered by symbol name.
+func (r *MongoSymbolRepository) SearchSymbols(ctx context.Context, query string, limit int64) ([]*models.Symbol, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	pattern := primitive.Regex{Pattern: regexp.QuoteMeta(query), Options: "i"}
+	filter := bson.M{"$or": []bson.M{
+		{"symbol_name": pattern},
+		{"display_name": pattern},
+	}}
+	opts := options.Find().SetSort(bson.M{"symbol_name": 1}).SetLimit(limit)
+
+	var symbols []*models.Symbol
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	if err := cursor.All(ctx, &symbols); err != nil {
+		return nil, err
+	}
+	return symbols, nil
+}
+
+func (r *MongoSymbolRepository) UpdateSymbol(ctx context.Context, id primitive.ObjectID, symbol *models.Symbol) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	symbol.UpdatedAt = time.Now()
@@ -77,10 +106,34 @@ func (r *MongoSymbolRepository) UpdateSymbol(id primitive.ObjectID, symbol *mode
 	return err
 }
 
-func (r *MongoSymbolRepository) DeleteSymbol(id primitive.ObjectID) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoSymbolRepository) DeleteSymbol(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
 	return err
 }
+
+// SetNewsHalt stores the symbol's news halt window, or removes it when halt is nil.
+func (r *MongoSymbolRepository) SetNewsHalt(ctx context.Context, id primitive.ObjectID, halt *models.NewsHalt) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{"news_halt": halt, "updated_at": time.Now()},
+	}
+	if halt == nil {
+		update = bson.M{
+			"$set":   bson.M{"updated_at": time.Now()},
+			"$unset": bson.M{"news_halt": ""},
+		}
+	}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
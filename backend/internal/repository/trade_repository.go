@@ -2,6 +2,10 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/mehrbod2002/fxtrader/internal/models"
@@ -13,10 +17,31 @@ import (
 )
 
 type TradeRepository interface {
-	SaveTrade(trade *models.TradeHistory) error
-	GetTradeByID(id primitive.ObjectID) (*models.TradeHistory, error)
-	GetTradesByUserID(userID primitive.ObjectID) ([]*models.TradeHistory, error)
-	GetAllTrades() ([]*models.TradeHistory, error)
+	SaveTrade(ctx context.Context, trade *models.TradeHistory) error
+	GetTradeByID(ctx context.Context, id primitive.ObjectID) (*models.TradeHistory, error)
+	GetTradesByUserID(ctx context.Context, userID primitive.ObjectID, accountType string) ([]*models.TradeHistory, error)
+	GetAllTrades(ctx context.Context, accountType string) ([]*models.TradeHistory, error)
+	MarkTradeClosed(ctx context.Context, trade *models.TradeHistory) (bool, error)
+	GetTradesUpdatedSince(ctx context.Context, userID primitive.ObjectID, since time.Time) ([]*models.TradeHistory, error)
+	GetClosedTrades(ctx context.Context, from, to time.Time, accountType string) ([]*models.TradeHistory, error)
+	GetSettlement(ctx context.Context, from, to time.Time, accountType string) (*models.SettlementReport, error)
+	GetPendingTradesBySymbol(ctx context.Context, symbol string, executionType models.ExecutionType) ([]*models.TradeHistory, error)
+	ActivatePendingTrade(ctx context.Context, id primitive.ObjectID) (bool, error)
+	CancelPendingTrade(ctx context.Context, id primitive.ObjectID) (bool, error)
+	FillPendingTrade(ctx context.Context, id primitive.ObjectID, expectedVolume, fillVolume float64, matchedTradeID string) (bool, error)
+	GetVolumeSince(ctx context.Context, userID primitive.ObjectID, since time.Time) (float64, error)
+	CountTradesByStatus(ctx context.Context, userID primitive.ObjectID, status models.TradeStatus) (int64, error)
+	CountActiveTradesBySymbol(ctx context.Context, accountID primitive.ObjectID, symbol string) (int64, error)
+	GetOpenTradesBySymbol(ctx context.Context, symbol string) ([]*models.TradeHistory, error)
+	GetOpenTradesByAccount(ctx context.Context, accountID primitive.ObjectID) ([]*models.TradeHistory, error)
+	GetPendingTradesByAccount(ctx context.Context, accountID primitive.ObjectID) ([]*models.TradeHistory, error)
+	GetRealizedProfitSince(ctx context.Context, accountID primitive.ObjectID, since time.Time) (float64, error)
+	GetTradeHistoryPage(ctx context.Context, userID primitive.ObjectID, from, to time.Time, afterID primitive.ObjectID, limit int64) ([]*models.TradeHistory, error)
+	ConfirmRequotedTrade(ctx context.Context, id primitive.ObjectID) (bool, error)
+	CancelRequotedTrade(ctx context.Context, id primitive.ObjectID) (bool, error)
+	GetExpiredRequotes(ctx context.Context, now time.Time) ([]*models.TradeHistory, error)
+	GetExpiredPendingTrades(ctx context.Context, now time.Time) ([]*models.TradeHistory, error)
+	ExpirePendingTrade(ctx context.Context, id primitive.ObjectID) (bool, error)
 }
 
 type MongoTradeRepository struct {
@@ -25,51 +50,132 @@ type MongoTradeRepository struct {
 
 func NewTradeRepository(client *mongo.Client, dbName, collectionName string) TradeRepository {
 	collection := client.Database(dbName).Collection(collectionName)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "open_time", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "updated_at", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "close_time", Value: 1}}},
+		{Keys: bson.D{{Key: "symbol", Value: 1}, {Key: "status", Value: 1}, {Key: "execution_type", Value: 1}}},
+		{Keys: bson.D{{Key: "account_id", Value: 1}, {Key: "symbol", Value: 1}, {Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "expiration", Value: 1}}},
+	})
+	if err != nil {
+		fmt.Printf("Failed to create indexes: %v\n", err)
+	}
+
 	return &MongoTradeRepository{collection: collection}
 }
 
-func (r *MongoTradeRepository) SaveTrade(trade *models.TradeHistory) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoTradeRepository) SaveTrade(ctx context.Context, trade *models.TradeHistory) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	if trade.ID.IsZero() {
 		trade.ID = primitive.NewObjectID()
 		trade.OpenTime = time.Now()
+		trade.UpdatedAt = trade.OpenTime
 		_, err := r.collection.InsertOne(ctx, trade)
 		return err
 	}
 
-	filter := bson.M{"_id": trade.ID}
-	update := bson.M{
-		"$set": bson.M{
-			"trade_id":         trade.ID.Hex(),
-			"account_id":       trade.AccountID,
-			"account_type":     trade.AccountType,
-			"trade_type":       trade.TradeType,
-			"order_type":       trade.OrderType,
-			"symbol":           trade.Symbol,
-			"leverage":         trade.Leverage,
-			"entry_price":      trade.EntryPrice,
-			"status":           trade.Status,
-			"volume":           trade.Volume,
-			"timestamp":        trade.OpenTime.Unix(),
-			"matched_trade_id": trade.MatchedTradeID,
-			"close_time":       trade.CloseTime,
-			"stop_loss":        trade.StopLoss,
-			"user_id":          trade.UserID,
-			"profit":           trade.Profit,
-			"take_profit":      trade.TakeProfit,
-			"expiration":       trade.Expiration,
-		},
+	trade.UpdatedAt = time.Now()
+	update, err := tradeUpdate(trade)
+	if err != nil {
+		return err
 	}
 
+	filter := bson.M{"_id": trade.ID}
 	opts := options.Update().SetUpsert(true)
-	_, err := r.collection.UpdateOne(ctx, filter, update, opts)
+	_, err = r.collection.UpdateOne(ctx, filter, update, opts)
 	return err
 }
 
-func (r *MongoTradeRepository) GetTradeByID(id primitive.ObjectID) (*models.TradeHistory, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// tradeUpdate writes the whole trade document, so no field can be left out of
+// an update; optional fields the trade no longer carries are unset rather
+// than left stale.
+func tradeUpdate(trade *models.TradeHistory) (bson.M, error) {
+	doc, err := bson.Marshal(trade)
+	if err != nil {
+		return nil, err
+	}
+	var fields bson.M
+	if err := bson.Unmarshal(doc, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "_id")
+	fields["trade_id"] = trade.ID.Hex()
+	fields["timestamp"] = trade.OpenTime.Unix()
+
+	update := bson.M{"$set": fields}
+	unset := bson.M{}
+	for _, key := range tradeOptionalFields {
+		if _, ok := fields[key]; !ok {
+			unset[key] = ""
+		}
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update, nil
+}
+
+// tradeOptionalFields are the omitempty fields of a trade document, which
+// SaveTrade unsets when the trade no longer carries them.
+var tradeOptionalFields = omitemptyFields(reflect.TypeOf(models.TradeHistory{}))
+
+func omitemptyFields(t reflect.Type) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		name, opts, _ := strings.Cut(t.Field(i).Tag.Get("bson"), ",")
+		if name != "" && name != "-" && strings.Contains(opts, "omitempty") {
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+// closeFields holds the close details MarkTradeClosed writes along with the
+// status, so a close is never persisted without them.
+func closeFields(trade *models.TradeHistory) bson.M {
+	return bson.M{
+		"close_time":     trade.CloseTime,
+		"close_price":    trade.ClosePrice,
+		"close_reason":   trade.CloseReason,
+		"profit":         trade.Profit,
+		"commission":     trade.Commission,
+		"mt5_commission": trade.MT5Commission,
+		"swap":           trade.Swap,
+		"updated_at":     trade.UpdatedAt,
+	}
+}
+
+// MarkTradeClosed flips a trade from OPEN to CLOSED and reports whether this
+// call performed the transition. A trade in any other state is left alone.
+func (r *MongoTradeRepository) MarkTradeClosed(ctx context.Context, trade *models.TradeHistory) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":    trade.ID,
+		"status": string(models.TradeStatusOpen),
+	}
+	trade.UpdatedAt = time.Now()
+	fields := closeFields(trade)
+	fields["status"] = string(models.TradeStatusClosed)
+	update := bson.M{"$set": fields}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *MongoTradeRepository) GetTradeByID(ctx context.Context, id primitive.ObjectID) (*models.TradeHistory, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var trade models.TradeHistory
@@ -80,37 +186,465 @@ func (r *MongoTradeRepository) GetTradeByID(id primitive.ObjectID) (*models.Trad
 	return &trade, err
 }
 
-func (r *MongoTradeRepository) GetTradesByUserID(userID primitive.ObjectID) ([]*models.TradeHistory, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// GetTradesByUserID returns the user's trades, newest first. A non-empty
+// accountType limits them to demo or real accounts.
+func (r *MongoTradeRepository) GetTradesByUserID(ctx context.Context, userID primitive.ObjectID, accountType string) ([]*models.TradeHistory, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"user_id": userID}
+	withAccountType(filter, accountType)
+
+	var trades []*models.TradeHistory
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "open_time", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	if err := cursor.All(ctx, &trades); err != nil {
+		return nil, err
+	}
+	return trades, nil
+}
+
+// GetTradesUpdatedSince returns the user's trades modified strictly after since,
+// oldest change first.
+func (r *MongoTradeRepository) GetTradesUpdatedSince(ctx context.Context, userID primitive.ObjectID, since time.Time) ([]*models.TradeHistory, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"user_id": userID, "updated_at": bson.M{"$gt": since}}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "updated_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var trades []*models.TradeHistory
+	if err := cursor.All(ctx, &trades); err != nil {
+		return nil, err
+	}
+	return trades, nil
+}
+
+func (r *MongoTradeRepository) GetPendingTradesBySymbol(ctx context.Context, symbol string, executionType models.ExecutionType) ([]*models.TradeHistory, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
+	filter := bson.M{
+		"symbol":         symbol,
+		"status":         string(models.TradeStatusPending),
+		"execution_type": executionType,
+	}
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
 	var trades []*models.TradeHistory
-	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID})
+	if err := cursor.All(ctx, &trades); err != nil {
+		return nil, err
+	}
+	return trades, nil
+}
+
+// ActivatePendingTrade moves a PENDING trade to OPEN and reports whether this
+// call made the change, so concurrent ticks cannot activate it twice.
+func (r *MongoTradeRepository) ActivatePendingTrade(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": id, "status": string(models.TradeStatusPending)}
+	update := bson.M{"$set": bson.M{
+		"status":     string(models.TradeStatusOpen),
+		"updated_at": time.Now(),
+	}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// CancelPendingTrade moves a PENDING trade to CANCELLED and reports whether
+// this call made the change, so it cannot race an activation or a fill.
+func (r *MongoTradeRepository) CancelPendingTrade(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{"_id": id, "status": string(models.TradeStatusPending)}
+	update := bson.M{"$set": bson.M{
+		"status":     string(models.TradeStatusCancelled),
+		"close_time": now,
+		"updated_at": now,
+	}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// ConfirmRequotedTrade moves a REQUOTED trade back to PENDING for
+// resubmission. Like CancelRequotedTrade it reports whether this call made
+// the change, so a confirmation and the expiry sweep cannot both win.
+func (r *MongoTradeRepository) ConfirmRequotedTrade(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": id, "status": string(models.TradeStatusRequoted)}
+	update := bson.M{"$set": bson.M{
+		"status":     string(models.TradeStatusPending),
+		"updated_at": time.Now(),
+	}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// CancelRequotedTrade moves a REQUOTED trade to CANCELLED as expired.
+func (r *MongoTradeRepository) CancelRequotedTrade(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{"_id": id, "status": string(models.TradeStatusRequoted)}
+	update := bson.M{"$set": bson.M{
+		"status":       string(models.TradeStatusCancelled),
+		"close_reason": models.CloseReasonExpired,
+		"close_time":   now,
+		"updated_at":   now,
+	}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// GetExpiredRequotes returns REQUOTED trades whose offer lapsed before now.
+func (r *MongoTradeRepository) GetExpiredRequotes(ctx context.Context, now time.Time) ([]*models.TradeHistory, error) {
+	return r.findTrades(ctx, bson.M{
+		"status":             string(models.TradeStatusRequoted),
+		"requote.expires_at": bson.M{"$lt": now},
+	})
+}
+
+// GetExpiredPendingTrades returns PENDING trades whose expiration passed
+// before now, oldest expiration first.
+func (r *MongoTradeRepository) GetExpiredPendingTrades(ctx context.Context, now time.Time) ([]*models.TradeHistory, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"status":     string(models.TradeStatusPending),
+		"expiration": bson.M{"$lt": now},
+	}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "expiration", Value: 1}}))
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
+
+	var trades []*models.TradeHistory
 	if err := cursor.All(ctx, &trades); err != nil {
 		return nil, err
 	}
+	return trades, nil
+}
+
+// ExpirePendingTrade moves a PENDING trade to EXPIRED and reports whether
+// this call made the change, so a fill that lands first is never undone.
+func (r *MongoTradeRepository) ExpirePendingTrade(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
 
 	now := time.Now()
-	for _, trade := range trades {
-		if trade.Expiration != nil && trade.Expiration.Before(now) && trade.Status == "PENDING" {
-			trade.Status = "EXPIRED"
-			if _, err := r.collection.UpdateOne(ctx, bson.M{"_id": trade.ID}, bson.M{"$set": bson.M{"status": "EXPIRED"}}); err != nil {
-				return nil, err
-			}
+	filter := bson.M{"_id": id, "status": string(models.TradeStatusPending)}
+	update := bson.M{"$set": bson.M{
+		"status":       string(models.TradeStatusExpired),
+		"close_reason": models.CloseReasonExpired,
+		"close_time":   now,
+		"updated_at":   now,
+	}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// FillPendingTrade takes fillVolume from a resting PENDING trade whose volume
+// is still expectedVolume. A full fill opens the trade against matchedTradeID;
+// a partial fill leaves the remainder pending. It reports false when the trade
+// changed underneath the caller.
+func (r *MongoTradeRepository) FillPendingTrade(ctx context.Context, id primitive.ObjectID, expectedVolume, fillVolume float64, matchedTradeID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":    id,
+		"status": string(models.TradeStatusPending),
+		"volume": expectedVolume,
+	}
+	set := bson.M{"updated_at": time.Now()}
+	if fillVolume >= expectedVolume {
+		set["status"] = string(models.TradeStatusOpen)
+		set["matched_trade_id"] = matchedTradeID
+	} else {
+		set["volume"] = expectedVolume - fillVolume
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func closedTradesFilter(from, to time.Time, accountType string) bson.M {
+	filter := bson.M{
+		"status":     string(models.TradeStatusClosed),
+		"close_time": bson.M{"$gte": from, "$lt": to},
+	}
+	withAccountType(filter, accountType)
+	return filter
+}
+
+// withAccountType narrows filter to one account type. Trades have been stored
+// with both "demo" and "DEMO", so the match ignores case.
+func withAccountType(filter bson.M, accountType string) {
+	if accountType == "" {
+		return
+	}
+	filter["account_type"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(accountType) + "$", Options: "i"}
+}
+
+// GetClosedTrades returns trades closed in [from, to), optionally limited to one account type.
+func (r *MongoTradeRepository) GetClosedTrades(ctx context.Context, from, to time.Time, accountType string) ([]*models.TradeHistory, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "close_time", Value: 1}})
+	cursor, err := r.collection.Find(ctx, closedTradesFilter(from, to, accountType), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var trades []*models.TradeHistory
+	if err := cursor.All(ctx, &trades); err != nil {
+		return nil, err
+	}
+	return trades, nil
+}
+
+// GetSettlement sums P/L, commission and swap over the same window as
+// GetClosedTrades, grouped per user and per symbol in a single aggregation.
+func (r *MongoTradeRepository) GetSettlement(ctx context.Context, from, to time.Time, accountType string) (*models.SettlementReport, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	sums := func(key interface{}) bson.A {
+		return bson.A{
+			bson.M{"$group": bson.M{
+				"_id":        key,
+				"trades":     bson.M{"$sum": 1},
+				"profit":     bson.M{"$sum": "$profit"},
+				"commission": bson.M{"$sum": bson.M{"$ifNull": bson.A{"$commission", 0}}},
+				"swap":       bson.M{"$sum": bson.M{"$ifNull": bson.A{"$swap", 0}}},
+			}},
+			bson.M{"$addFields": bson.M{"net_profit": bson.M{"$add": bson.A{"$profit", "$commission", "$swap"}}}},
+			bson.M{"$sort": bson.M{"_id": 1}},
+		}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: closedTradesFilter(from, to, accountType)}},
+		{{Key: "$facet", Value: bson.M{
+			"by_user":   sums(bson.M{"$toString": "$user_id"}),
+			"by_symbol": sums("$symbol"),
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	report := &models.SettlementReport{}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(report); err != nil {
+			return nil, err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	report.From, report.To, report.AccountType = from, to, accountType
+	return report, nil
+}
+
+// GetVolumeSince sums the filled volume of the user's trades opened at or
+// after since. Pending and cancelled orders have not traded and are excluded.
+func (r *MongoTradeRepository) GetVolumeSince(ctx context.Context, userID primitive.ObjectID, since time.Time) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"user_id":   userID,
+			"open_time": bson.M{"$gte": since},
+			"status":    bson.M{"$in": []models.TradeStatus{models.TradeStatusOpen, models.TradeStatusClosed}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    nil,
+			"volume": bson.M{"$sum": "$volume"},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		Volume float64 `bson:"volume"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return 0, err
 		}
 	}
+	return result.Volume, cursor.Err()
+}
+
+func (r *MongoTradeRepository) CountTradesByStatus(ctx context.Context, userID primitive.ObjectID, status models.TradeStatus) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return r.collection.CountDocuments(ctx, bson.M{"user_id": userID, "status": status})
+}
+
+// CountActiveTradesBySymbol counts an account's open positions and working
+// pending orders on symbol.
+func (r *MongoTradeRepository) CountActiveTradesBySymbol(ctx context.Context, accountID primitive.ObjectID, symbol string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"account_id": accountID,
+		"symbol":     symbol,
+		"status":     bson.M{"$in": bson.A{models.TradeStatusOpen, models.TradeStatusPending}},
+	}
+	return r.collection.CountDocuments(ctx, filter)
+}
+
+func (r *MongoTradeRepository) GetOpenTradesBySymbol(ctx context.Context, symbol string) ([]*models.TradeHistory, error) {
+	return r.findTrades(ctx, bson.M{"symbol": symbol, "status": models.TradeStatusOpen})
+}
+
+func (r *MongoTradeRepository) GetOpenTradesByAccount(ctx context.Context, accountID primitive.ObjectID) ([]*models.TradeHistory, error) {
+	return r.findTrades(ctx, bson.M{"account_id": accountID, "status": models.TradeStatusOpen})
+}
+
+func (r *MongoTradeRepository) GetPendingTradesByAccount(ctx context.Context, accountID primitive.ObjectID) ([]*models.TradeHistory, error) {
+	return r.findTrades(ctx, bson.M{"account_id": accountID, "status": models.TradeStatusPending})
+}
+
+func (r *MongoTradeRepository) findTrades(ctx context.Context, filter bson.M) ([]*models.TradeHistory, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var trades []*models.TradeHistory
+	if err := cursor.All(ctx, &trades); err != nil {
+		return nil, err
+	}
 	return trades, nil
 }
 
-func (r *MongoTradeRepository) GetAllTrades() ([]*models.TradeHistory, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// GetTradeHistoryPage returns up to limit of the user's open and closed trades
+// opened in [from, to), in ID order after afterID. Passing the last ID of one
+// page as afterID of the next walks the whole range without skipping.
+func (r *MongoTradeRepository) GetTradeHistoryPage(ctx context.Context, userID primitive.ObjectID, from, to time.Time, afterID primitive.ObjectID, limit int64) ([]*models.TradeHistory, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"user_id":   userID,
+		"status":    bson.M{"$in": bson.A{models.TradeStatusOpen, models.TradeStatusClosed}},
+		"open_time": bson.M{"$gte": from, "$lt": to},
+	}
+	if !afterID.IsZero() {
+		filter["_id"] = bson.M{"$gt": afterID}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var trades []*models.TradeHistory
+	if err := cursor.All(ctx, &trades); err != nil {
+		return nil, err
+	}
+	return trades, nil
+}
+
+// GetRealizedProfitSince sums profit, commission and swap of the account's
+// trades closed at or after since, matching the settlement report's net.
+func (r *MongoTradeRepository) GetRealizedProfitSince(ctx context.Context, accountID primitive.ObjectID, since time.Time) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"account_id": accountID,
+			"status":     models.TradeStatusClosed,
+			"close_time": bson.M{"$gte": since},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": nil,
+			"net": bson.M{"$sum": bson.M{"$add": bson.A{"$profit", "$commission", "$swap"}}},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		Net float64 `bson:"net"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return 0, err
+		}
+	}
+	return result.Net, cursor.Err()
+}
+
+func (r *MongoTradeRepository) GetAllTrades(ctx context.Context, accountType string) ([]*models.TradeHistory, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	cursor, err := r.collection.Find(ctx, bson.M{})
+	filter := bson.M{}
+	withAccountType(filter, accountType)
+	cursor, err := r.collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTradeUpdateCoversEveryField(t *testing.T) {
+	closedAt := time.Now()
+	trades := map[string]*models.TradeHistory{
+		"pending order": {
+			ID:        primitive.NewObjectID(),
+			Symbol:    "EURUSD",
+			Status:    string(models.TradeStatusPending),
+			OrderType: "BUY_LIMIT",
+			OpenTime:  time.Now(),
+		},
+		"closed trade": {
+			ID:             primitive.NewObjectID(),
+			Symbol:         "EURUSD",
+			Status:         string(models.TradeStatusClosed),
+			OrderType:      "MARKET",
+			MarginRate:     0.01,
+			MarginPrice:    1.1,
+			CommissionTier: 2,
+			ClosePrice:     1.2,
+			CloseTime:      &closedAt,
+			CloseReason:    models.CloseReasonManual,
+			MatchedTradeID: "42",
+			OpenTime:       time.Now(),
+		},
+	}
+
+	modelType := reflect.TypeOf(models.TradeHistory{})
+	for name, trade := range trades {
+		t.Run(name, func(t *testing.T) {
+			update, err := tradeUpdate(trade)
+			if err != nil {
+				t.Fatalf("tradeUpdate: %v", err)
+			}
+			set, _ := update["$set"].(bson.M)
+			unset, _ := update["$unset"].(bson.M)
+
+			for i := 0; i < modelType.NumField(); i++ {
+				key, _, _ := strings.Cut(modelType.Field(i).Tag.Get("bson"), ",")
+				if key == "_id" {
+					continue
+				}
+				_, inSet := set[key]
+				_, inUnset := unset[key]
+				if inSet == inUnset {
+					t.Errorf("field %q: in $set=%v, in $unset=%v; want exactly one", key, inSet, inUnset)
+				}
+			}
+			if _, ok := set["_id"]; ok {
+				t.Error("$set must not include _id")
+			}
+		})
+	}
+}
+
+func TestTradeUpdateKeepsValues(t *testing.T) {
+	trade := &models.TradeHistory{
+		ID:             primitive.NewObjectID(),
+		MarginRate:     0.02,
+		CommissionTier: 3,
+		ClosePrice:     1.2345,
+		CloseReason:    models.CloseReasonStopLoss,
+		OpenTime:       time.Unix(1700000000, 0),
+	}
+	update, err := tradeUpdate(trade)
+	if err != nil {
+		t.Fatalf("tradeUpdate: %v", err)
+	}
+	set := update["$set"].(bson.M)
+
+	if got := set["margin_rate"]; got != 0.02 {
+		t.Errorf("margin_rate = %v, want 0.02", got)
+	}
+	if got := set["commission_tier"]; got != int32(3) {
+		t.Errorf("commission_tier = %v, want 3", got)
+	}
+	if got, ok := set["open_time"].(primitive.DateTime); !ok || !got.Time().Equal(trade.OpenTime) {
+		t.Errorf("open_time = %v, want %v", set["open_time"], trade.OpenTime)
+	}
+	if got := set["close_price"]; got != 1.2345 {
+		t.Errorf("close_price = %v, want 1.2345", got)
+	}
+	if got := set["close_reason"]; got != string(models.CloseReasonStopLoss) {
+		t.Errorf("close_reason = %v, want %s", got, models.CloseReasonStopLoss)
+	}
+	if got := set["timestamp"]; got != int64(1700000000) {
+		t.Errorf("timestamp = %v, want 1700000000", got)
+	}
+	if got := set["trade_id"]; got != trade.ID.Hex() {
+		t.Errorf("trade_id = %v, want %s", got, trade.ID.Hex())
+	}
+}
@@ -13,11 +13,11 @@ import (
 )
 
 type TransactionRepository interface {
-	SaveTransaction(transaction *models.Transaction) error
-	GetTransactionByID(id primitive.ObjectID) (*models.Transaction, error)
-	GetTransactionsByUserID(userID primitive.ObjectID) ([]*models.Transaction, error)
-	GetAllTransactions() ([]*models.Transaction, error)
-	UpdateTransaction(id primitive.ObjectID, transaction *models.Transaction) error
+	SaveTransaction(ctx context.Context, transaction *models.Transaction) error
+	GetTransactionByID(ctx context.Context, id primitive.ObjectID) (*models.Transaction, error)
+	GetTransactionsByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Transaction, error)
+	GetAllTransactions(ctx context.Context) ([]*models.Transaction, error)
+	ReviewTransaction(ctx context.Context, id primitive.ObjectID, transaction *models.Transaction) (bool, error)
 }
 
 type MongoTransactionRepository struct {
@@ -29,8 +29,8 @@ func NewTransactionRepository(client *mongo.Client, dbName, collectionName strin
 	return &MongoTransactionRepository{collection: collection}
 }
 
-func (r *MongoTransactionRepository) SaveTransaction(transaction *models.Transaction) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoTransactionRepository) SaveTransaction(ctx context.Context, transaction *models.Transaction) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	transaction.ID = primitive.NewObjectID()
@@ -39,8 +39,8 @@ func (r *MongoTransactionRepository) SaveTransaction(transaction *models.Transac
 	return err
 }
 
-func (r *MongoTransactionRepository) GetTransactionByID(id primitive.ObjectID) (*models.Transaction, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoTransactionRepository) GetTransactionByID(ctx context.Context, id primitive.ObjectID) (*models.Transaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var transaction models.Transaction
@@ -51,8 +51,8 @@ func (r *MongoTransactionRepository) GetTransactionByID(id primitive.ObjectID) (
 	return &transaction, err
 }
 
-func (r *MongoTransactionRepository) GetTransactionsByUserID(userID primitive.ObjectID) ([]*models.Transaction, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoTransactionRepository) GetTransactionsByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Transaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var transactions []*models.Transaction
@@ -67,8 +67,8 @@ func (r *MongoTransactionRepository) GetTransactionsByUserID(userID primitive.Ob
 	return transactions, nil
 }
 
-func (r *MongoTransactionRepository) GetAllTransactions() ([]*models.Transaction, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoTransactionRepository) GetAllTransactions(ctx context.Context) ([]*models.Transaction, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var transactions []*models.Transaction
@@ -83,18 +83,26 @@ func (r *MongoTransactionRepository) GetAllTransactions() ([]*models.Transaction
 	return transactions, nil
 }
 
-func (r *MongoTransactionRepository) UpdateTransaction(id primitive.ObjectID, transaction *models.Transaction) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// ReviewTransaction records the admin decision on a transaction that is still
+// pending and reports whether it matched. ctx may carry a session so the update
+// can take part in a multi-document transaction.
+func (r *MongoTransactionRepository) ReviewTransaction(ctx context.Context, id primitive.ObjectID, transaction *models.Transaction) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
+	filter := bson.M{"_id": id, "status": models.TransactionStatusPending}
 	update := bson.M{
 		"$set": bson.M{
-			"status":        transaction.Status,
-			"response_time": transaction.ResponseTime,
-			"reason":        transaction.Reason,
-			"admin_comment": transaction.AdminComment,
+			"status":           transaction.Status,
+			"response_time":    transaction.ResponseTime,
+			"reason":           transaction.Reason,
+			"admin_comment":    transaction.AdminComment,
+			"converted_amount": transaction.ConvertedAmount,
 		},
 	}
-	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
-	return err
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount == 1, nil
 }
@@ -2,7 +2,10 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/mehrbod2002/fxtrader/internal/models"
@@ -13,22 +16,30 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Errors returned by AddBalance and SubtractBalance, wrapped with the user ID
+// or amount.
+var (
+	ErrUserNotFound        = errors.New("no user found")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+)
+
 type UserRepository interface {
-	Collection() *mongo.Collection
-	SaveUser(user *models.User) error
-	GetUserByID(id primitive.ObjectID) (*models.User, error)
-	GetUserByUsername(username string) (*models.User, error)
-	GetUserByTelegramID(telegramID string) (*models.User, error)
-	GetAllUsers() ([]*models.User, error)
-	GetUsersByLeaderStatus(isLeader bool) ([]*models.User, error)
-	UpdateUser(user *models.User) error
-	EditUser(user *models.User) error
-	GetUserByReferralCode(code string) (*models.User, error)
-	GetUsersReferredBy(code string, page, limit int64) ([]*models.User, int64, error)
-	GetAllReferrals(page, limit int64) ([]*models.User, int64, error)
-	AddBalance(userID primitive.ObjectID, amount float64) error
-	SubtractBalance(userID primitive.ObjectID, amount float64) error
-	ActiveUser(userID primitive.ObjectID, active bool) error
+	SaveUser(ctx context.Context, user *models.User) error
+	GetUserByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	GetUserByTelegramID(ctx context.Context, telegramID string) (*models.User, error)
+	GetAllUsers(ctx context.Context) ([]*models.User, error)
+	GetUsers(ctx context.Context, filter models.UserFilter, page, limit int64) ([]*models.User, int64, error)
+	SearchUsers(ctx context.Context, query string, filter models.UserFilter, page, limit int64) ([]*models.User, int64, error)
+	CountUsers(ctx context.Context, filter models.UserFilter) (int64, error)
+	UpdateUser(ctx context.Context, user *models.User) error
+	EditUser(ctx context.Context, user *models.User) error
+	GetUserByReferralCode(ctx context.Context, code string) (*models.User, error)
+	GetUsersReferredBy(ctx context.Context, code string, page, limit int64) ([]*models.User, int64, error)
+	GetAllReferrals(ctx context.Context, page, limit int64) ([]*models.User, int64, error)
+	AddBalance(ctx context.Context, userID primitive.ObjectID, amount float64) error
+	SubtractBalance(ctx context.Context, userID primitive.ObjectID, amount float64) error
+	ActiveUser(ctx context.Context, userID primitive.ObjectID, active bool) error
 }
 
 type MongoUserRepository struct {
@@ -46,6 +57,12 @@ func NewUserRepository(client *mongo.Client, dbName, collectionName string) User
 		{Keys: bson.M{"telegram_id": 1}, Options: options.Index().SetUnique(true)},
 		{Keys: bson.M{"referral_code": 1}, Options: options.Index().SetUnique(true)},
 		{Keys: bson.M{"referred_by": 1}},
+		{Keys: bson.M{"is_copy_trade_leader": 1}},
+		{Keys: bson.M{"full_name": 1}},
+		{Keys: bson.M{"phone_number": 1}},
+		{Keys: bson.M{"national_id": 1}},
+		{Keys: bson.M{"registration_date": 1}},
+		{Keys: bson.M{"balance": 1}},
 	})
 	if err != nil {
 		fmt.Printf("Failed to create indexes: %v\n", err)
@@ -58,8 +75,8 @@ func (r *MongoUserRepository) Collection() *mongo.Collection {
 	return r.collection
 }
 
-func (r *MongoUserRepository) EditUser(user *models.User) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoUserRepository) EditUser(ctx context.Context, user *models.User) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	update := bson.M{
@@ -97,8 +114,8 @@ func (r *MongoUserRepository) EditUser(user *models.User) error {
 	return nil
 }
 
-func (r *MongoUserRepository) ActiveUser(userID primitive.ObjectID, active bool) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoUserRepository) ActiveUser(ctx context.Context, userID primitive.ObjectID, active bool) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	_, err := r.collection.UpdateOne(
@@ -109,8 +126,8 @@ func (r *MongoUserRepository) ActiveUser(userID primitive.ObjectID, active bool)
 	return err
 }
 
-func (r *MongoUserRepository) UpdateUser(user *models.User) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoUserRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	update := bson.M{"$set": user}
@@ -118,8 +135,8 @@ func (r *MongoUserRepository) UpdateUser(user *models.User) error {
 	return err
 }
 
-func (r *MongoUserRepository) SaveUser(user *models.User) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoUserRepository) SaveUser(ctx context.Context, user *models.User) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	user.Balance = 0.0
@@ -129,8 +146,8 @@ func (r *MongoUserRepository) SaveUser(user *models.User) error {
 	return err
 }
 
-func (r *MongoUserRepository) GetUserByID(id primitive.ObjectID) (*models.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoUserRepository) GetUserByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var user models.User
@@ -141,8 +158,8 @@ func (r *MongoUserRepository) GetUserByID(id primitive.ObjectID) (*models.User,
 	return &user, err
 }
 
-func (r *MongoUserRepository) GetUserByUsername(username string) (*models.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoUserRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var user models.User
@@ -156,8 +173,8 @@ func (r *MongoUserRepository) GetUserByUsername(username string) (*models.User,
 	return &user, err
 }
 
-func (r *MongoUserRepository) GetUserByTelegramID(telegramID string) (*models.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoUserRepository) GetUserByTelegramID(ctx context.Context, telegramID string) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var user models.User
@@ -168,8 +185,8 @@ func (r *MongoUserRepository) GetUserByTelegramID(telegramID string) (*models.Us
 	return &user, err
 }
 
-func (r *MongoUserRepository) GetAllUsers() ([]*models.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoUserRepository) GetAllUsers(ctx context.Context) ([]*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	cursor, err := r.collection.Find(ctx, bson.M{})
@@ -185,34 +202,102 @@ func (r *MongoUserRepository) GetAllUsers() ([]*models.User, error) {
 	return users, nil
 }
 
-func (r *MongoUserRepository) GetUsersByLeaderStatus(isLeader bool) ([]*models.User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// GetUsers returns a page of the users matching filter, in the filter's sort
+// order, with the total count.
+func (r *MongoUserRepository) GetUsers(ctx context.Context, filter models.UserFilter, page, limit int64) ([]*models.User, int64, error) {
+	return r.SearchUsers(ctx, "", filter, page, limit)
+}
+
+// SearchUsers is GetUsers restricted to users whose full name or username
+// starts with query, ignoring case, or whose phone number, Telegram ID or
+// national ID starts with it exactly. The exact prefixes can use the field
+// indexes. An empty query matches every user.
+func (r *MongoUserRepository) SearchUsers(ctx context.Context, search string, filter models.UserFilter, page, limit int64) ([]*models.User, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	var users []*models.User
-	cursor, err := r.collection.Find(ctx, bson.M{"is_copy_trade_leader": isLeader})
+	query := userFilterQuery(filter)
+	if search = strings.TrimSpace(search); search != "" {
+		prefix := "^" + regexp.QuoteMeta(search)
+		exact := primitive.Regex{Pattern: prefix}
+		folded := primitive.Regex{Pattern: prefix, Options: "i"}
+		query["$or"] = []bson.M{
+			{"full_name": folded},
+			{"username": folded},
+			{"phone_number": exact},
+			{"telegram_id": exact},
+			{"national_id": exact},
+		}
+	}
+	total, err := r.collection.CountDocuments(ctx, query)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+
+	skip := (page - 1) * limit
+	opts := options.Find().SetSort(userSortOrder(filter.Sort)).SetSkip(skip).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, err
 	}
 	defer cursor.Close(ctx)
+
+	users := []*models.User{}
 	if err := cursor.All(ctx, &users); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return users, nil
+	return users, total, nil
+}
+
+func (r *MongoUserRepository) CountUsers(ctx context.Context, filter models.UserFilter) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return r.collection.CountDocuments(ctx, userFilterQuery(filter))
+}
+
+// userSortOrder breaks ties on _id, newest first, so pages stay stable.
+func userSortOrder(sort models.UserSort) bson.D {
+	order := bson.D{}
+	switch sort {
+	case models.UserSortRegistrationDate:
+		order = append(order, bson.E{Key: "registration_date", Value: 1})
+	case models.UserSortRegistrationDateDesc:
+		order = append(order, bson.E{Key: "registration_date", Value: -1})
+	case models.UserSortBalance:
+		order = append(order, bson.E{Key: "balance", Value: 1})
+	case models.UserSortBalanceDesc:
+		order = append(order, bson.E{Key: "balance", Value: -1})
+	}
+	return append(order, bson.E{Key: "_id", Value: -1})
 }
 
-func (r *MongoUserRepository) GetUserByReferralCode(code string) (*models.User, error) {
+func userFilterQuery(filter models.UserFilter) bson.M {
+	query := bson.M{}
+	if filter.IsActive != nil {
+		query["is_active"] = *filter.IsActive
+	}
+	if filter.IsLeader != nil {
+		query["is_copy_trade_leader"] = *filter.IsLeader
+	}
+	return query
+}
+
+func (r *MongoUserRepository) GetUserByReferralCode(ctx context.Context, code string) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
 	filter := bson.M{"referral_code": code}
 	var user models.User
-	err := r.collection.FindOne(context.TODO(), filter).Decode(&user)
+	err := r.collection.FindOne(ctx, filter).Decode(&user)
 	if err == mongo.ErrNoDocuments {
 		return nil, nil
 	}
 	return &user, err
 }
 
-func (r *MongoUserRepository) GetUsersReferredBy(code string, page, limit int64) ([]*models.User, int64, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoUserRepository) GetUsersReferredBy(ctx context.Context, code string, page, limit int64) ([]*models.User, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	// Count total referred users
@@ -238,8 +323,8 @@ func (r *MongoUserRepository) GetUsersReferredBy(code string, page, limit int64)
 	return users, total, nil
 }
 
-func (r *MongoUserRepository) GetAllReferrals(page, limit int64) ([]*models.User, int64, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoUserRepository) GetAllReferrals(ctx context.Context, page, limit int64) ([]*models.User, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	total, err := r.collection.CountDocuments(ctx, bson.M{})
@@ -264,13 +349,20 @@ func (r *MongoUserRepository) GetAllReferrals(page, limit int64) ([]*models.User
 }
 
 type AccountRepository interface {
-	Collection() *mongo.Collection
-	SaveAccount(account *models.Account) error
-	GetAccountByID(id primitive.ObjectID) (*models.Account, error)
-	GetAccountByName(name string, userID primitive.ObjectID) (*models.Account, error)
-	GetAccountsByUserID(userID primitive.ObjectID) ([]*models.Account, error)
-	DeleteAccount(accountID, userID primitive.ObjectID) error
-	UpdateAccount(account *models.Account) error
+	SaveAccount(ctx context.Context, account *models.Account) error
+	GetAccountByID(ctx context.Context, id primitive.ObjectID) (*models.Account, error)
+	GetAccountByName(ctx context.Context, name string, userID primitive.ObjectID) (*models.Account, error)
+	GetAccountsByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Account, error)
+	GetAccountsWithWallet(ctx context.Context) ([]*models.Account, error)
+	DeleteAccount(ctx context.Context, accountID, userID primitive.ObjectID) error
+	UpdateAccount(ctx context.Context, account *models.Account) error
+	AdjustBalance(ctx context.Context, accountID primitive.ObjectID, delta float64) error
+	DebitBalance(ctx context.Context, accountID primitive.ObjectID, amount float64) (bool, error)
+	SetRiskLimits(ctx context.Context, accountID, userID primitive.ObjectID, limits models.AccountRiskLimits) error
+	MarkRiskTriggered(ctx context.Context, accountID primitive.ObjectID, at time.Time) (bool, error)
+	DisableAccount(ctx context.Context, accountID, userID primitive.ObjectID, reason string, at time.Time) error
+	SetMaxLeverage(ctx context.Context, accountID, userID primitive.ObjectID, maxLeverage int) error
+	SetTier(ctx context.Context, accountID, userID primitive.ObjectID, tier models.AccountTier) error
 }
 
 type MongoAccountRepository struct {
@@ -298,11 +390,11 @@ func (r *MongoAccountRepository) Collection() *mongo.Collection {
 	return r.collection
 }
 
-func (r *MongoAccountRepository) SaveAccount(account *models.Account) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoAccountRepository) SaveAccount(ctx context.Context, account *models.Account) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	if account.AccountType != "demo" && account.AccountType != "real" {
+	if account.AccountType != models.AccountTypeDemo && account.AccountType != models.AccountTypeReal {
 		return fmt.Errorf("invalid account type: %s", account.AccountType)
 	}
 
@@ -312,8 +404,8 @@ func (r *MongoAccountRepository) SaveAccount(account *models.Account) error {
 	return err
 }
 
-func (r *MongoAccountRepository) GetAccountByID(id primitive.ObjectID) (*models.Account, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoAccountRepository) GetAccountByID(ctx context.Context, id primitive.ObjectID) (*models.Account, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var account models.Account
@@ -324,8 +416,8 @@ func (r *MongoAccountRepository) GetAccountByID(id primitive.ObjectID) (*models.
 	return &account, err
 }
 
-func (r *MongoAccountRepository) GetAccountByName(name string, userID primitive.ObjectID) (*models.Account, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoAccountRepository) GetAccountByName(ctx context.Context, name string, userID primitive.ObjectID) (*models.Account, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var account models.Account
@@ -339,8 +431,8 @@ func (r *MongoAccountRepository) GetAccountByName(name string, userID primitive.
 	return &account, err
 }
 
-func (r *MongoAccountRepository) GetAccountsByUserID(userID primitive.ObjectID) ([]*models.Account, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoAccountRepository) GetAccountsByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.Account, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID})
@@ -356,8 +448,26 @@ func (r *MongoAccountRepository) GetAccountsByUserID(userID primitive.ObjectID)
 	return accounts, nil
 }
 
-func (r *MongoAccountRepository) DeleteAccount(accountID, userID primitive.ObjectID) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// GetAccountsWithWallet returns every account with a registered MT5 wallet.
+func (r *MongoAccountRepository) GetAccountsWithWallet(ctx context.Context) ([]*models.Account, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := r.collection.Find(ctx, bson.M{"wallet_id": bson.M{"$nin": bson.A{"", nil}}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var accounts []*models.Account
+	if err := cursor.All(ctx, &accounts); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+func (r *MongoAccountRepository) DeleteAccount(ctx context.Context, accountID, userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": accountID, "user_id": userID})
@@ -365,13 +475,13 @@ func (r *MongoAccountRepository) DeleteAccount(accountID, userID primitive.Objec
 		return fmt.Errorf("failed to delete account: %w", err)
 	}
 	if result.DeletedCount == 0 {
-		return fmt.Errorf("account not found")
+		return mongo.ErrNoDocuments
 	}
 	return nil
 }
 
-func (r *MongoAccountRepository) UpdateAccount(account *models.Account) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoAccountRepository) UpdateAccount(ctx context.Context, account *models.Account) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	update := bson.M{"$set": account}
@@ -379,61 +489,186 @@ func (r *MongoAccountRepository) UpdateAccount(account *models.Account) error {
 	return err
 }
 
-func (r *MongoUserRepository) AddBalance(userID primitive.ObjectID, amount float64) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (r *MongoAccountRepository) SetRiskLimits(ctx context.Context, accountID, userID primitive.ObjectID, limits models.AccountRiskLimits) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	if amount <= 0 {
-		return fmt.Errorf("amount must be positive")
+	update := bson.M{"$set": bson.M{
+		"daily_loss_limit":           limits.DailyLossLimit,
+		"daily_profit_target":        limits.DailyProfitTarget,
+		"block_after_trigger":        limits.BlockAfterTrigger,
+		"loss_streak_limit":          limits.LossStreakLimit,
+		"loss_streak_window_minutes": limits.LossStreakWindowMinutes,
+		"loss_cooldown_minutes":      limits.LossCooldownMinutes,
+	}}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": accountID, "user_id": userID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
 	}
+	return nil
+}
 
-	update := bson.M{
-		"$inc": bson.M{"balance": amount},
+// DisableAccount stops the account from trading until an admin re-enables it.
+func (r *MongoAccountRepository) DisableAccount(ctx context.Context, accountID, userID primitive.ObjectID, reason string, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{
+		"disabled":        true,
+		"disabled_reason": reason,
+		"disabled_at":     at,
+	}}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": accountID, "user_id": userID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
 	}
+	return nil
+}
+
+// SetMaxLeverage stores the account's leverage override; zero removes it.
+func (r *MongoAccountRepository) SetMaxLeverage(ctx context.Context, accountID, userID primitive.ObjectID, maxLeverage int) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
 
-	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": userID}, update)
+	update := bson.M{"$set": bson.M{"max_leverage": maxLeverage}}
+	if maxLeverage == 0 {
+		update = bson.M{"$unset": bson.M{"max_leverage": ""}}
+	}
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": accountID, "user_id": userID}, update)
 	if err != nil {
-		return fmt.Errorf("failed to add balance: %w", err)
+		return err
 	}
 	if result.MatchedCount == 0 {
-		return fmt.Errorf("no user found with ID: %s", userID.Hex())
+		return mongo.ErrNoDocuments
 	}
+	return nil
+}
 
+func (r *MongoAccountRepository) SetTier(ctx context.Context, accountID, userID primitive.ObjectID, tier models.AccountTier) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": accountID, "user_id": userID}, bson.M{"$set": bson.M{"tier": tier}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
 	return nil
 }
 
-func (r *MongoUserRepository) SubtractBalance(userID primitive.ObjectID, amount float64) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// MarkRiskTriggered records that the account's daily limits fired at at. It
+// reports false when they had already fired that day, so concurrent ticks
+// only act on a breach once.
+func (r *MongoAccountRepository) MarkRiskTriggered(ctx context.Context, accountID primitive.ObjectID, at time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id": accountID,
+		"$or": bson.A{
+			bson.M{"risk_triggered_at": bson.M{"$exists": false}},
+			bson.M{"risk_triggered_at": bson.M{"$lt": models.StartOfDay(at)}},
+		},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"risk_triggered_at": at}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *MongoUserRepository) AddBalance(ctx context.Context, userID primitive.ObjectID, amount float64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	if amount <= 0 {
 		return fmt.Errorf("amount must be positive")
 	}
 
-	var user models.User
-	err := r.collection.FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
-	if err == mongo.ErrNoDocuments {
-		return fmt.Errorf("no user found with ID: %s", userID.Hex())
-	}
+	filter, update := balanceCredit(userID, amount)
+	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return fmt.Errorf("failed to fetch user: %w", err)
+		return fmt.Errorf("failed to add balance: %w", err)
 	}
-
-	if user.Balance < amount {
-		return fmt.Errorf("insufficient balance: current balance %f, requested withdrawal %f", user.Balance, amount)
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w with ID: %s", ErrUserNotFound, userID.Hex())
 	}
 
-	update := bson.M{
-		"$inc": bson.M{"balance": -amount},
+	return nil
+}
+
+func (r *MongoUserRepository) SubtractBalance(ctx context.Context, userID primitive.ObjectID, amount float64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if amount <= 0 {
+		return fmt.Errorf("amount must be positive")
 	}
 
-	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": userID}, update)
+	filter, update := balanceDebit(userID, amount)
+	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return fmt.Errorf("failed to subtract balance: %w", err)
 	}
 	if result.MatchedCount == 0 {
-		return fmt.Errorf("no user found with ID: %s", userID.Hex())
+		count, err := r.collection.CountDocuments(ctx, bson.M{"_id": userID})
+		if err != nil {
+			return fmt.Errorf("failed to fetch user: %w", err)
+		}
+		if count == 0 {
+			return fmt.Errorf("%w with ID: %s", ErrUserNotFound, userID.Hex())
+		}
+		return fmt.Errorf("%w: requested withdrawal %f", ErrInsufficientBalance, amount)
+	}
+
+	return nil
+}
+
+// balanceCredit is the filter and update AddBalance applies.
+func balanceCredit(userID primitive.ObjectID, amount float64) (bson.M, bson.M) {
+	return bson.M{"_id": userID}, bson.M{"$inc": bson.M{"balance": amount}}
+}
+
+// balanceDebit is the filter and update SubtractBalance applies. The balance
+// check lives in the filter so concurrent debits cannot overdraw the user
+// between a read and the $inc.
+func balanceDebit(userID primitive.ObjectID, amount float64) (bson.M, bson.M) {
+	filter := bson.M{"_id": userID, "balance": bson.M{"$gte": amount}}
+	return filter, bson.M{"$inc": bson.M{"balance": -amount}}
+}
+
+// DebitBalance takes amount from the account only if the balance covers it,
+// reporting whether it did.
+func (r *MongoAccountRepository) DebitBalance(ctx context.Context, accountID primitive.ObjectID, amount float64) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": accountID, "balance": bson.M{"$gte": amount}}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"balance": -amount}})
+	if err != nil {
+		return false, fmt.Errorf("failed to debit account balance: %w", err)
 	}
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *MongoAccountRepository) AdjustBalance(ctx context.Context, accountID primitive.ObjectID, delta float64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
 
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": accountID}, bson.M{"$inc": bson.M{"balance": delta}})
+	if err != nil {
+		return fmt.Errorf("failed to adjust account balance: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("no account found with ID: %s", accountID.Hex())
+	}
 	return nil
 }
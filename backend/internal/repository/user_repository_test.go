@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBalanceUpdates(t *testing.T) {
+	userID := primitive.NewObjectID()
+
+	tests := []struct {
+		name       string
+		build      func(primitive.ObjectID, float64) (bson.M, bson.M)
+		wantFilter bson.M
+		wantUpdate bson.M
+	}{
+		{
+			name:       "credit",
+			build:      balanceCredit,
+			wantFilter: bson.M{"_id": userID},
+			wantUpdate: bson.M{"$inc": bson.M{"balance": 25.5}},
+		},
+		{
+			// The filter only matches a balance that covers the debit, so an
+			// overdraw matches nothing instead of going negative.
+			name:       "debit",
+			build:      balanceDebit,
+			wantFilter: bson.M{"_id": userID, "balance": bson.M{"$gte": 25.5}},
+			wantUpdate: bson.M{"$inc": bson.M{"balance": -25.5}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, update := tt.build(userID, 25.5)
+			if !reflect.DeepEqual(filter, tt.wantFilter) {
+				t.Errorf("filter = %v, want %v", filter, tt.wantFilter)
+			}
+			if !reflect.DeepEqual(update, tt.wantUpdate) {
+				t.Errorf("update = %v, want %v", update, tt.wantUpdate)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/interfaces"
+	"github.com/mehrbod2002/fxtrader/internal/models"
+	"github.com/mehrbod2002/fxtrader/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// LiquidationReport is the outcome of liquidating an account. The account is
+// disabled even when some positions or orders fail to close; those are listed
+// so the admin can retry.
+type LiquidationReport struct {
+	AccountID     string                     `json:"account_id"`
+	UserID        string                     `json:"user_id"`
+	Reason        string                     `json:"reason"`
+	DisabledAt    time.Time                  `json:"disabled_at"`
+	Positions     interfaces.BulkCloseResult `json:"positions"`
+	PendingOrders interfaces.BulkCloseResult `json:"pending_orders"`
+	Complete      bool                       `json:"complete"`
+}
+
+type AdminService interface {
+	LiquidateAccount(ctx context.Context, accountID, userID primitive.ObjectID, reason string) (*LiquidationReport, error)
+	SetAccountMaxLeverage(ctx context.Context, accountID, userID primitive.ObjectID, maxLeverage int) error
+	SetAccountTier(ctx context.Context, accountID, userID primitive.ObjectID, tier string) (models.AccountTier, error)
+}
+
+type adminService struct {
+	accountRepo  repository.AccountRepository
+	tradeRepo    repository.TradeRepository
+	tradeService interfaces.TradeService
+	logService   LogService
+}
+
+func NewAdminService(accountRepo repository.AccountRepository, tradeRepo repository.TradeRepository, tradeService interfaces.TradeService, logService LogService) AdminService {
+	return &adminService{
+		accountRepo:  accountRepo,
+		tradeRepo:    tradeRepo,
+		tradeService: tradeService,
+		logService:   logService,
+	}
+}
+
+// LiquidateAccount disables the account first so nothing new can be opened,
+// then cancels its pending orders and closes its open positions. Running it
+// again on a disabled account retries whatever is still open.
+func (s *adminService) LiquidateAccount(ctx context.Context, accountID, userID primitive.ObjectID, reason string) (*LiquidationReport, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, newError(ErrInvalidInput, "a liquidation reason is required")
+	}
+
+	now := time.Now()
+	if err := s.accountRepo.DisableAccount(ctx, accountID, userID, reason, now); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+
+	report := &LiquidationReport{
+		AccountID:  accountID.Hex(),
+		UserID:     userID.Hex(),
+		Reason:     reason,
+		DisabledAt: now,
+	}
+
+	pending, err := s.tradeRepo.GetPendingTradesByAccount(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("account disabled but pending orders could not be listed: %w", err)
+	}
+	report.PendingOrders = s.cancelPendingOrders(pending, userID)
+
+	positions, err := s.tradeService.CloseTradesByGroup(userID.Hex(), "", accountID.Hex(), "", "")
+	if err != nil {
+		return nil, fmt.Errorf("account disabled but positions could not be closed: %w", err)
+	}
+	report.Positions = positions
+	report.Complete = report.PendingOrders.Failed == 0 && report.Positions.Failed == 0
+
+	metadata := map[string]interface{}{
+		"account_id":       report.AccountID,
+		"reason":           reason,
+		"positions_closed": report.Positions.Closed,
+		"positions_failed": report.Positions.Failed,
+		"orders_cancelled": report.PendingOrders.Closed,
+		"orders_failed":    report.PendingOrders.Failed,
+		"complete":         report.Complete,
+	}
+	if err := s.logService.LogAction(userID, "AccountLiquidated", "Account liquidated and disabled by admin", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+	return report, nil
+}
+
+func (s *adminService) cancelPendingOrders(trades []*models.TradeHistory, userID primitive.ObjectID) interfaces.BulkCloseResult {
+	result := interfaces.BulkCloseResult{
+		Requested: len(trades),
+		Results:   make([]interfaces.CloseResult, 0, len(trades)),
+	}
+	for _, trade := range trades {
+		res := interfaces.CloseResult{TradeID: trade.ID.Hex()}
+		response, err := s.tradeService.CancelPendingOrder(trade.ID.Hex(), userID.Hex())
+		if err != nil {
+			res.Error = err.Error()
+			result.Failed++
+		} else {
+			res.Status = response.Status
+			result.Closed++
+		}
+		result.Results = append(result.Results, res)
+	}
+	return result
+}
+
+// SetAccountMaxLeverage overrides the symbols' leverage limits for one
+// account. Zero restores the symbol limits. Open positions keep the margin
+// they were opened with.
+func (s *adminService) SetAccountMaxLeverage(ctx context.Context, accountID, userID primitive.ObjectID, maxLeverage int) error {
+	if maxLeverage < 0 {
+		return newError(ErrInvalidInput, "max leverage cannot be negative")
+	}
+	err := s.accountRepo.SetMaxLeverage(ctx, accountID, userID, maxLeverage)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrAccountNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	metadata := map[string]interface{}{
+		"account_id":   accountID.Hex(),
+		"max_leverage": maxLeverage,
+	}
+	if err := s.logService.LogAction(userID, "AccountLeverageSet", "Account leverage override set by admin", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+	return nil
+}
+
+// SetAccountTier moves the account to another tier. New orders use the tier's
+// leverage bounds and commission at once; open positions are unaffected.
+func (s *adminService) SetAccountTier(ctx context.Context, accountID, userID primitive.ObjectID, tier string) (models.AccountTier, error) {
+	parsed, err := models.ParseAccountTier(tier)
+	if err != nil {
+		return "", newError(ErrInvalidInput, "%s", err)
+	}
+	err = s.accountRepo.SetTier(ctx, accountID, userID, parsed)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return "", ErrAccountNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+
+	metadata := map[string]interface{}{
+		"account_id": accountID.Hex(),
+		"tier":       parsed,
+	}
+	if err := s.logService.LogAction(userID, "AccountTierSet", "Account tier set by admin", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+	return parsed, nil
+}
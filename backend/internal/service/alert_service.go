@@ -1,21 +1,46 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
-	"time"
+	"math"
+	"sync"
 
+	"github.com/mehrbod2002/fxtrader/internal/clock"
+	"github.com/mehrbod2002/fxtrader/internal/config"
 	"github.com/mehrbod2002/fxtrader/internal/models"
 	"github.com/mehrbod2002/fxtrader/internal/repository"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ErrAlertLimitReached is returned by CreateAlert once the user already has
+// the configured number of pending alerts.
+var ErrAlertLimitReached = newError(ErrConflict, "pending alert limit reached")
+
+var (
+	ErrInvalidAlertID   = newError(ErrInvalidInput, "invalid alert ID")
+	ErrAlertNotFound    = newError(ErrNotFound, "alert not found")
+	ErrAlertForbidden   = newError(ErrForbidden, "alert belongs to another user")
+	ErrAlertNotEditable = newError(ErrConflict, "only pending alerts can be edited")
+)
+
+// AlertQuota reports how many pending alerts a user holds against the cap.
+// A Limit of zero means the cap is disabled.
+type AlertQuota struct {
+	Pending int64 `json:"pending"`
+	Limit   int   `json:"limit"`
+}
+
 type AlertService interface {
-	CreateAlert(userID string, alert *models.Alert) error
-	GetAlert(id string) (*models.Alert, error)
-	GetAlertsByUserID(userID string) ([]*models.Alert, error)
+	CreateAlert(ctx context.Context, userID string, alert *models.Alert) error
+	GetAlertQuota(ctx context.Context, userID string) (*AlertQuota, error)
+	GetAlert(ctx context.Context, id, userID string) (*models.Alert, error)
+	GetAlertsByUserID(ctx context.Context, userID string) ([]*models.Alert, error)
+	UpdateAlert(ctx context.Context, id, userID string, alert *models.Alert) error
+	DeleteAlert(ctx context.Context, id, userID string) error
 	ProcessPriceForAlerts(price *models.PriceData) error
 	ProcessTimeBasedAlerts() error
 }
@@ -25,84 +50,202 @@ type alertService struct {
 	symbolRepo repository.SymbolRepository
 	logService LogService
 	notifyFunc func(userID, message string) error
+	maxPending int
+	clock      clock.Clock
+
+	lastBidMu sync.Mutex
+	lastBid   map[string]float64
 }
 
-func NewAlertService(alertRepo repository.AlertRepository, symbolRepo repository.SymbolRepository, logService LogService) AlertService {
+func NewAlertService(alertRepo repository.AlertRepository, symbolRepo repository.SymbolRepository, logService LogService, clk clock.Clock, cfg *config.Config) AlertService {
 	return &alertService{
 		alertRepo:  alertRepo,
 		symbolRepo: symbolRepo,
 		logService: logService,
 		notifyFunc: func(userID, message string) error { return nil },
+		maxPending: cfg.MaxPendingAlertsPerUser,
+		clock:      clk,
+		lastBid:    make(map[string]float64),
+	}
+}
+
+func (s *alertService) CreateAlert(ctx context.Context, userID string, alert *models.Alert) error {
+	if err := s.validateAlert(ctx, alert); err != nil {
+		return err
+	}
+
+	if s.maxPending > 0 {
+		quota, err := s.GetAlertQuota(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if quota.Pending >= int64(quota.Limit) {
+			return ErrAlertLimitReached
+		}
+	}
+
+	alert.UserID = userID
+	alert.Status = models.AlertStatusPending
+	s.anchorReference(alert)
+
+	if err := s.alertRepo.SaveAlert(ctx, alert); err != nil {
+		return err
+	}
+
+	metadata := map[string]interface{}{
+		"alert_id":    alert.ID.Hex(),
+		"symbol_name": alert.SymbolName,
+		"alert_type":  alert.AlertType,
+	}
+	if err := s.logService.LogAction(primitive.ObjectID{}, "CreateAlert", "Alert created", "", metadata); err != nil {
+		log.Printf("error: %v", err)
 	}
+
+	return nil
 }
 
-func (s *alertService) CreateAlert(userID string, alert *models.Alert) error {
+func (s *alertService) validateAlert(ctx context.Context, alert *models.Alert) error {
 	if alert.AlertType != models.AlertTypePrice && alert.AlertType != models.AlertTypeTime {
 		return errors.New("invalid alert type")
 	}
 	switch alert.AlertType {
 	case models.AlertTypePrice:
-		if alert.Condition.PriceTarget == nil || *alert.Condition.PriceTarget <= 0 {
-			return errors.New("price target required and must be positive")
-		}
-		if alert.Condition.SL == nil && alert.Condition.TP == nil {
-			return errors.New("comparison must be ABOVE or BELOW")
+		if err := validatePriceCondition(&alert.Condition); err != nil {
+			return err
 		}
 	case models.AlertTypeTime:
-		if alert.Condition.TriggerTime == nil || alert.Condition.TriggerTime.Before(time.Now()) {
+		if alert.Condition.TriggerTime == nil || alert.Condition.TriggerTime.Before(s.clock.Now()) {
 			return errors.New("trigger time required and must be in the future")
 		}
 	}
 
-	symbols, err := s.symbolRepo.GetAllSymbols()
+	symbols, err := s.symbolRepo.GetAllSymbols(ctx)
 	if err != nil {
 		return errors.New("failed to fetch symbols")
 	}
-	var symbolExists bool
-	for _, sym := range symbols {
-		if sym.SymbolName == alert.SymbolName {
-			symbolExists = true
-			break
+	// Prices arrive under the broker symbol name, so that is what is stored.
+	symbol := models.ResolveSymbol(symbols, alert.SymbolName)
+	if symbol == nil {
+		return errors.New("symbol not found")
+	}
+	alert.SymbolName = symbol.SymbolName
+	return nil
+}
+
+func validatePriceCondition(condition *models.AlertCondition) error {
+	switch condition.Trigger {
+	case "", models.AlertTriggerLevel:
+		if condition.PriceTarget == nil || *condition.PriceTarget <= 0 {
+			return errors.New("price target required and must be positive")
+		}
+		if condition.SL == nil && condition.TP == nil {
+			return errors.New("comparison must be ABOVE or BELOW")
 		}
+	case models.AlertTriggerCrossAbove, models.AlertTriggerCrossBelow:
+		if condition.PriceTarget == nil || *condition.PriceTarget <= 0 {
+			return errors.New("price target required and must be positive")
+		}
+	case models.AlertTriggerPercentChange:
+		if condition.PercentChange == nil || *condition.PercentChange <= 0 {
+			return errors.New("percent change required and must be positive")
+		}
+	default:
+		return errors.New("trigger must be LEVEL, CROSS_ABOVE, CROSS_BELOW or PERCENT_CHANGE")
 	}
-	if !symbolExists {
-		return errors.New("symbol not found")
+	return nil
+}
+
+// anchorReference pins a PERCENT_CHANGE alert to the latest bid seen for its
+// symbol. With no tick seen yet the reference is left unset and the first
+// tick to arrive fills it in.
+func (s *alertService) anchorReference(alert *models.Alert) {
+	alert.Condition.ReferencePrice = nil
+	if alert.Condition.Trigger != models.AlertTriggerPercentChange {
+		return
+	}
+	s.lastBidMu.Lock()
+	bid, ok := s.lastBid[alert.SymbolName]
+	s.lastBidMu.Unlock()
+	if ok {
+		alert.Condition.ReferencePrice = &bid
 	}
+}
 
-	alert.UserID = userID
-	alert.Status = models.AlertStatusPending
+func (s *alertService) GetAlertQuota(ctx context.Context, userID string) (*AlertQuota, error) {
+	pending, err := s.alertRepo.CountPendingAlertsByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count pending alerts: %w", err)
+	}
+	return &AlertQuota{Pending: pending, Limit: s.maxPending}, nil
+}
+
+func (s *alertService) GetAlert(ctx context.Context, id, userID string) (*models.Alert, error) {
+	return s.ownedAlert(ctx, id, userID)
+}
+
+func (s *alertService) GetAlertsByUserID(ctx context.Context, userID string) ([]*models.Alert, error) {
+	return s.alertRepo.GetAlertsByUserID(ctx, userID)
+}
 
-	err = s.alertRepo.SaveAlert(alert)
+// ownedAlert loads an alert and checks it belongs to userID.
+func (s *alertService) ownedAlert(ctx context.Context, id, userID string) (*models.Alert, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return err
+		return nil, ErrInvalidAlertID
+	}
+	alert, err := s.alertRepo.GetAlertByID(ctx, objID)
+	if err != nil {
+		return nil, err
+	}
+	if alert == nil {
+		return nil, ErrAlertNotFound
+	}
+	if alert.UserID != userID {
+		return nil, ErrAlertForbidden
 	}
+	return alert, nil
+}
 
-	metadata := map[string]interface{}{
-		"alert_id":    alert.ID.Hex(),
-		"symbol_name": alert.SymbolName,
-		"alert_type":  alert.AlertType,
+func (s *alertService) UpdateAlert(ctx context.Context, id, userID string, alert *models.Alert) error {
+	existing, err := s.ownedAlert(ctx, id, userID)
+	if err != nil {
+		return err
 	}
-	if err := s.logService.LogAction(primitive.ObjectID{}, "CreateAlert", "Alert created", "", metadata); err != nil {
-		log.Printf("error: %v", err)
+	if existing.Status != models.AlertStatusPending {
+		return ErrAlertNotEditable
 	}
+	if err := s.validateAlert(ctx, alert); err != nil {
+		return err
+	}
+	s.anchorReference(alert)
 
+	if err := s.alertRepo.UpdateAlertDefinition(ctx, existing.ID, alert); err != nil {
+		return err
+	}
+	alert.ID = existing.ID
+	alert.UserID = existing.UserID
+	alert.Status = existing.Status
+	alert.CreatedAt = existing.CreatedAt
 	return nil
 }
 
-func (s *alertService) GetAlert(id string) (*models.Alert, error) {
-	objID, err := primitive.ObjectIDFromHex(id)
+func (s *alertService) DeleteAlert(ctx context.Context, id, userID string) error {
+	alert, err := s.ownedAlert(ctx, id, userID)
 	if err != nil {
-		return nil, errors.New("invalid alert ID")
+		return err
 	}
-	return s.alertRepo.GetAlertByID(objID)
-}
-
-func (s *alertService) GetAlertsByUserID(userID string) ([]*models.Alert, error) {
-	return s.alertRepo.GetAlertsByUserID(userID)
+	return s.alertRepo.DeleteAlert(ctx, alert.ID)
 }
 
 func (s *alertService) ProcessPriceForAlerts(price *models.PriceData) error {
-	alerts, err := s.alertRepo.GetPendingAlerts()
+	ctx := context.Background()
+
+	s.lastBidMu.Lock()
+	previous, hasPrevious := s.lastBid[price.Symbol]
+	s.lastBid[price.Symbol] = price.Bid
+	s.lastBidMu.Unlock()
+
+	alerts, err := s.alertRepo.GetPendingAlerts(ctx)
 	if err != nil {
 		return err
 	}
@@ -112,33 +255,55 @@ func (s *alertService) ProcessPriceForAlerts(price *models.PriceData) error {
 			continue
 		}
 
+		condition := &alert.Condition
 		shouldTrigger := false
-		if price.Ask <= *alert.Condition.SL && price.Ask >= *alert.Condition.PriceTarget {
-			shouldTrigger = true
-		}
-
-		if price.Bid >= *alert.Condition.TP && price.Bid <= *alert.Condition.PriceTarget {
-			shouldTrigger = true
+		switch condition.Trigger {
+		case models.AlertTriggerCrossAbove:
+			shouldTrigger = hasPrevious && previous < *condition.PriceTarget && price.Bid >= *condition.PriceTarget
+		case models.AlertTriggerCrossBelow:
+			shouldTrigger = hasPrevious && previous > *condition.PriceTarget && price.Bid <= *condition.PriceTarget
+		case models.AlertTriggerPercentChange:
+			if condition.ReferencePrice == nil || *condition.ReferencePrice <= 0 {
+				bid := price.Bid
+				condition.ReferencePrice = &bid
+				if err := s.alertRepo.UpdateAlertDefinition(ctx, alert.ID, alert); err != nil {
+					log.Printf("Failed to anchor alert %s: %v", alert.ID.Hex(), err)
+				}
+				continue
+			}
+			moved := math.Abs(price.Bid-*condition.ReferencePrice) / *condition.ReferencePrice * 100
+			shouldTrigger = moved >= *condition.PercentChange
+		default:
+			if condition.SL != nil && price.Ask <= *condition.SL && price.Ask >= *condition.PriceTarget {
+				shouldTrigger = true
+			}
+			if condition.TP != nil && price.Bid >= *condition.TP && price.Bid <= *condition.PriceTarget {
+				shouldTrigger = true
+			}
 		}
 
 		if shouldTrigger {
-			now := time.Now()
+			now := s.clock.Now()
 			alert.Status = models.AlertStatusTriggered
 			alert.TriggeredAt = &now
-			err = s.alertRepo.UpdateAlert(alert.ID, alert)
+			err = s.alertRepo.UpdateAlert(ctx, alert.ID, alert)
 			if err != nil {
 				continue
 			}
 
-			message := "Alert triggered for " + alert.SymbolName + " at price " + fmt.Sprintf("%f", *alert.Condition.PriceTarget)
+			message := "Alert triggered for " + alert.SymbolName + " at price " + fmt.Sprintf("%f", price.Bid)
 			if err := s.notifyFunc(alert.UserID, message); err != nil {
 				continue
 			}
 
 			metadata := map[string]interface{}{
-				"alert_id":     alert.ID.Hex(),
-				"symbol_name":  alert.SymbolName,
-				"price_target": *alert.Condition.PriceTarget,
+				"alert_id":    alert.ID.Hex(),
+				"symbol_name": alert.SymbolName,
+				"trigger":     condition.Trigger,
+				"bid":         price.Bid,
+			}
+			if condition.PriceTarget != nil {
+				metadata["price_target"] = *condition.PriceTarget
 			}
 			if err := s.logService.LogAction(primitive.ObjectID{}, "AlertTriggered", "Price alert triggered", "", metadata); err != nil {
 				continue
@@ -150,12 +315,14 @@ func (s *alertService) ProcessPriceForAlerts(price *models.PriceData) error {
 }
 
 func (s *alertService) ProcessTimeBasedAlerts() error {
-	alerts, err := s.alertRepo.GetPendingAlerts()
+	ctx := context.Background()
+
+	alerts, err := s.alertRepo.GetPendingAlerts(ctx)
 	if err != nil {
 		return err
 	}
 
-	now := time.Now()
+	now := s.clock.Now()
 	for _, alert := range alerts {
 		if alert.AlertType != models.AlertTypeTime || alert.Condition.TriggerTime == nil {
 			continue
@@ -164,12 +331,12 @@ func (s *alertService) ProcessTimeBasedAlerts() error {
 		if now.After(*alert.Condition.TriggerTime) {
 			alert.Status = models.AlertStatusTriggered
 			alert.TriggeredAt = &now
-			err = s.alertRepo.UpdateAlert(alert.ID, alert)
+			err = s.alertRepo.UpdateAlert(ctx, alert.ID, alert)
 			if err != nil {
 				continue
 			}
 
-			message := "Time-based alert triggered for " + alert.SymbolName
+			message := "Time-based alert triggered for " + alert.SymbolName + " at " + alert.Condition.TriggerTime.In(now.Location()).Format("2006-01-02 15:04 MST")
 			if err := s.notifyFunc(alert.UserID, message); err != nil {
 				continue
 			}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+	"github.com/mehrbod2002/fxtrader/internal/ws"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AnnouncementService interface {
+	Broadcast(notice *models.Announcement, viaTelegram bool) error
+}
+
+type announcementService struct {
+	hub             *ws.Hub
+	userService     UserService
+	telegramService TelegramService
+	logService      LogService
+}
+
+// NewAnnouncementService builds the admin broadcast service. telegramService
+// may be nil, in which case announcements only reach connected clients.
+func NewAnnouncementService(hub *ws.Hub, userService UserService, telegramService TelegramService, logService LogService) AnnouncementService {
+	return &announcementService{
+		hub:             hub,
+		userService:     userService,
+		telegramService: telegramService,
+		logService:      logService,
+	}
+}
+
+func (s *announcementService) Broadcast(notice *models.Announcement, viaTelegram bool) error {
+	notice.Message = strings.TrimSpace(notice.Message)
+	if notice.Message == "" {
+		return errors.New("message is required")
+	}
+	switch notice.Severity {
+	case "":
+		notice.Severity = models.AnnouncementInfo
+	case models.AnnouncementInfo, models.AnnouncementWarning, models.AnnouncementCritical:
+	default:
+		return errors.New("severity must be INFO, WARNING or CRITICAL")
+	}
+	if viaTelegram && s.telegramService == nil {
+		return errors.New("telegram notifications are not configured")
+	}
+
+	notice.Type = "announcement"
+	notice.CreatedAt = time.Now()
+	s.hub.BroadcastAnnouncement(notice)
+
+	if viaTelegram {
+		go s.sendTelegram(notice)
+	}
+	return nil
+}
+
+// sendTelegram delivers the announcement to every user with a linked chat.
+// It runs in the background since a large user base takes a while to reach.
+func (s *announcementService) sendTelegram(notice *models.Announcement) {
+	users, err := s.userService.GetAllUsers(context.Background())
+	if err != nil {
+		log.Printf("Failed to load users for announcement: %v", err)
+		return
+	}
+
+	text := "[" + string(notice.Severity) + "] " + notice.Message
+	if notice.Symbol != "" {
+		text = "[" + string(notice.Severity) + "] " + notice.Symbol + ": " + notice.Message
+	}
+
+	sent, failed := 0, 0
+	for _, user := range users {
+		chatID, err := strconv.ParseInt(user.TelegramID, 10, 64)
+		if err != nil || chatID == 0 {
+			continue
+		}
+		if err := s.telegramService.SendMessageToChat(chatID, text); err != nil {
+			failed++
+			continue
+		}
+		sent++
+	}
+
+	metadata := map[string]interface{}{
+		"severity": notice.Severity,
+		"symbol":   notice.Symbol,
+		"sent":     sent,
+		"failed":   failed,
+	}
+	if err := s.logService.LogAction(primitive.ObjectID{}, "AnnouncementTelegram", "Announcement delivered over Telegram", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+}
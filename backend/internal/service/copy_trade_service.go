@@ -1,10 +1,17 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"log"
 	"math"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/mehrbod2002/fxtrader/interfaces"
+	"github.com/mehrbod2002/fxtrader/internal/config"
 	"github.com/mehrbod2002/fxtrader/internal/models"
 	"github.com/mehrbod2002/fxtrader/internal/repository"
 
@@ -12,60 +19,158 @@ import (
 )
 
 type CopyTradeService interface {
-	CreateSubscription(followerID, leaderID string, allocatedAmount float64, accountType string) (*models.CopyTradeSubscription, error)
-	GetSubscription(id string) (*models.CopyTradeSubscription, error)
-	GetSubscriptionsByFollowerID(followerID string) ([]*models.CopyTradeSubscription, error)
-	GetAllSubscriptions() ([]*models.CopyTradeSubscription, error)
+	CreateSubscription(ctx context.Context, followerID, leaderID string, allocatedAmount float64, accountType string, settings models.CopySettings) (*models.CopyTradeSubscription, error)
+	GetSubscription(ctx context.Context, id string) (*models.CopyTradeSubscription, error)
+	GetSubscriptionsByFollowerID(ctx context.Context, followerID string) ([]*models.CopyTradeSubscription, error)
+	GetAllSubscriptions(ctx context.Context) ([]*models.CopyTradeSubscription, error)
 	MirrorTrade(leaderTrade *models.TradeHistory, accountType string) error
 	SetTradeService(tradeService interfaces.TradeService)
+	SetNotificationPreference(ctx context.Context, userID string, enabled bool) error
+	SetLeaderLimits(ctx context.Context, userID string, limits models.LeaderLimits) error
+	CancelSubscription(ctx context.Context, subscriptionID, userID string, closeTrades bool) (interfaces.BulkCloseResult, error)
+	GetCopyTradeHistory(ctx context.Context, subscriptionID, userID string) ([]*models.CopyTradeHistoryEntry, error)
 }
 
+// errSymbolUnavailable marks a leader trade the follower cannot take because
+// its symbol is not offered to them. It does not count as a mirror failure.
+var errSymbolUnavailable = errors.New("symbol unavailable for follower")
+
+var (
+	ErrInvalidSubscriptionID = newError(ErrInvalidInput, "invalid subscription ID")
+	ErrSubscriptionNotFound  = newError(ErrNotFound, "subscription not found")
+	ErrSubscriptionForbidden = newError(ErrForbidden, "subscription belongs to another user")
+	ErrLeaderAtCapacity      = newError(ErrConflict, "leader is not accepting new followers")
+)
+
 type copyTradeService struct {
 	copyTradeRepo  repository.CopyTradeRepository
+	symbolRepo     repository.SymbolRepository
 	tradeService   interfaces.TradeService
 	userService    UserService
 	accountService AccountService
 	logService     LogService
+	notifier       *copyTradeNotifier
+
+	maxMirrorFailures int
+	// mirrorSlots bounds how many follower trades are placed at once across
+	// all leaders; leaderLocks serialises mirroring per leader so followers
+	// see a leader's trades in the order they were placed.
+	mirrorSlots chan struct{}
+	leaderLocks sync.Map
 }
 
 func (s *copyTradeService) SetTradeService(tradeService interfaces.TradeService) {
 	s.tradeService = tradeService
 }
 
-func NewCopyTradeService(copyTradeRepo repository.CopyTradeRepository, tradeService interfaces.TradeService, userService UserService, accountService AccountService, logService LogService) CopyTradeService {
+func NewCopyTradeService(copyTradeRepo repository.CopyTradeRepository, symbolRepo repository.SymbolRepository, tradeService interfaces.TradeService, userService UserService, accountService AccountService, logService LogService, telegramService TelegramService, cfg *config.Config) CopyTradeService {
 	return &copyTradeService{
 		copyTradeRepo:  copyTradeRepo,
+		symbolRepo:     symbolRepo,
 		tradeService:   tradeService,
 		userService:    userService,
 		accountService: accountService,
 		logService:     logService,
+		notifier:       newCopyTradeNotifier(telegramService, userService),
+
+		maxMirrorFailures: cfg.CopyTradeMaxMirrorFailures,
+		mirrorSlots:       make(chan struct{}, cfg.CopyTradeMirrorWorkers),
+	}
+}
+
+// copyTradeNotifier tells users about copy-trading activity on their behalf
+// over Telegram. It is a no-op when no bot is configured and skips users who
+// have opted out.
+type copyTradeNotifier struct {
+	telegramService TelegramService
+	userService     UserService
+}
+
+func newCopyTradeNotifier(telegramService TelegramService, userService UserService) *copyTradeNotifier {
+	return &copyTradeNotifier{telegramService: telegramService, userService: userService}
+}
+
+func (n *copyTradeNotifier) notify(userID, message string) {
+	if n.telegramService == nil {
+		return
+	}
+	user, err := n.userService.GetUser(context.Background(), userID)
+	if err != nil || user == nil || user.CopyTradeNotificationsOff {
+		return
+	}
+	if err := n.telegramService.SendMessage(userID, message); err != nil {
+		log.Printf("Failed to notify user %s: %v", userID, err)
+	}
+}
+
+func (s *copyTradeService) SetNotificationPreference(ctx context.Context, userID string, enabled bool) error {
+	user, err := s.userService.GetUser(ctx, userID)
+	if err != nil || user == nil {
+		return errors.New("user not found")
+	}
+	user.CopyTradeNotificationsOff = !enabled
+	return s.userService.UpdateUser(ctx, user)
+}
+
+// SetLeaderLimits stores the leader's minimum allocation and follower cap.
+// Existing subscriptions are not affected; the limits apply to new ones.
+func (s *copyTradeService) SetLeaderLimits(ctx context.Context, userID string, limits models.LeaderLimits) error {
+	if err := limits.Validate(); err != nil {
+		return newError(ErrInvalidInput, "%s", err)
 	}
+	user, err := s.userService.GetUser(ctx, userID)
+	if err != nil || user == nil {
+		return newError(ErrNotFound, "user not found")
+	}
+	if !user.IsCopyTradeLeader {
+		return newError(ErrForbidden, "only approved copy trade leaders can set follower limits")
+	}
+	user.LeaderLimits = limits
+	return s.userService.UpdateUser(ctx, user)
 }
 
-func (s *copyTradeService) CreateSubscription(followerID, leaderID string, allocatedAmount float64, accountType string) (*models.CopyTradeSubscription, error) {
+func (s *copyTradeService) CreateSubscription(ctx context.Context, followerID, leaderID string, allocatedAmount float64, accountType string, settings models.CopySettings) (*models.CopyTradeSubscription, error) {
 	if allocatedAmount <= 0 {
 		return nil, errors.New("allocated amount must be positive")
 	}
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+	if settings.SizingMode == "" {
+		settings.SizingMode = models.SizingProportional
+	}
 
-	follower, err := s.userService.GetUser(followerID)
+	follower, err := s.userService.GetUser(ctx, followerID)
 	if err != nil || follower == nil {
 		return nil, errors.New("follower not found")
 	}
-	leader, err := s.userService.GetUser(leaderID)
+	leader, err := s.userService.GetUser(ctx, leaderID)
 	if err != nil || leader == nil {
 		return nil, errors.New("leader not found")
 	}
 	if !leader.IsCopyTradeLeader {
 		return nil, errors.New("user is not an approved copy trade leader")
 	}
+	if allocatedAmount < leader.MinAllocation {
+		return nil, newError(ErrInvalidInput, "allocated amount must be at least %.2f for this leader", leader.MinAllocation)
+	}
+	if leader.MaxFollowers > 0 {
+		followers, err := s.copyTradeRepo.CountActiveSubscriptionsByLeaderID(ctx, leaderID)
+		if err != nil {
+			return nil, errors.New("failed to count leader followers")
+		}
+		if followers >= int64(leader.MaxFollowers) {
+			return nil, ErrLeaderAtCapacity
+		}
+	}
 
-	accounts, err := s.accountService.GetAccountsByUserID(followerID)
+	accounts, err := s.accountService.GetAccountsByUserID(ctx, followerID)
 	if err != nil {
 		return nil, errors.New("failed to fetch follower accounts")
 	}
 	var followerAccount *models.Account
 	for _, acc := range accounts {
-		if acc.AccountType == accountType {
+		if models.SameAccountType(acc.AccountType, accountType) {
 			followerAccount = acc
 			break
 		}
@@ -74,7 +179,7 @@ func (s *copyTradeService) CreateSubscription(followerID, leaderID string, alloc
 		return nil, errors.New("follower does not have account of type " + accountType)
 	}
 
-	followerBalance, err := s.tradeService.RequestBalance(followerID, followerAccount.ID.Hex(), accountType)
+	followerBalance, _, err := s.tradeService.CachedBalance(followerID, followerAccount.ID.Hex(), accountType)
 	if err != nil {
 		return nil, errors.New("failed to fetch follower balance")
 	}
@@ -89,10 +194,11 @@ func (s *copyTradeService) CreateSubscription(followerID, leaderID string, alloc
 		LeaderIDTelegram:   leader.TelegramID,
 		AllocatedAmount:    allocatedAmount,
 		AccountType:        accountType,
-		Status:             "ACTIVE",
+		Status:             models.Active,
+		CopySettings:       settings,
 	}
 
-	err = s.copyTradeRepo.SaveSubscription(subscription)
+	err = s.copyTradeRepo.SaveSubscription(ctx, subscription)
 	if err != nil {
 		return nil, err
 	}
@@ -106,34 +212,41 @@ func (s *copyTradeService) CreateSubscription(followerID, leaderID string, alloc
 	if err := s.logService.LogAction(primitive.ObjectID{}, "CreateCopySubscription", "Copy trade subscription created", "", metadata); err != nil {
 		return nil, err
 	}
+	s.notifier.notify(leaderID, fmt.Sprintf("%s is now copying your trades.", follower.Username))
 
 	return subscription, nil
 }
 
-func (s *copyTradeService) GetSubscription(id string) (*models.CopyTradeSubscription, error) {
+func (s *copyTradeService) GetSubscription(ctx context.Context, id string) (*models.CopyTradeSubscription, error) {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return nil, errors.New("invalid subscription ID")
+		return nil, ErrInvalidSubscriptionID
 	}
-	return s.copyTradeRepo.GetSubscriptionByID(objID)
+	return s.copyTradeRepo.GetSubscriptionByID(ctx, objID)
 }
 
-func (s *copyTradeService) GetSubscriptionsByFollowerID(followerID string) ([]*models.CopyTradeSubscription, error) {
-	return s.copyTradeRepo.GetSubscriptionsByFollowerID(followerID)
+func (s *copyTradeService) GetSubscriptionsByFollowerID(ctx context.Context, followerID string) ([]*models.CopyTradeSubscription, error) {
+	return s.copyTradeRepo.GetSubscriptionsByFollowerID(ctx, followerID)
 }
 
-func (s *copyTradeService) GetAllSubscriptions() ([]*models.CopyTradeSubscription, error) {
-	return s.copyTradeRepo.GetAllSubscriptions()
+func (s *copyTradeService) GetAllSubscriptions(ctx context.Context) ([]*models.CopyTradeSubscription, error) {
+	return s.copyTradeRepo.GetAllSubscriptions(ctx)
 }
 
 func (s *copyTradeService) MirrorTrade(leaderTrade *models.TradeHistory, accountType string) error {
-	subscriptions, err := s.copyTradeRepo.GetActiveSubscriptionsByLeaderID(leaderTrade.UserID.Hex())
+	ctx := context.Background()
+
+	lock, _ := s.leaderLocks.LoadOrStore(leaderTrade.UserID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	subscriptions, err := s.copyTradeRepo.GetActiveSubscriptionsByLeaderID(ctx, leaderTrade.UserID.Hex())
 	if err != nil {
 		return err
 	}
 
 	leaderAccountID := leaderTrade.AccountID.Hex()
-	leaderBalance, err := s.tradeService.RequestBalance(leaderTrade.UserID.Hex(), leaderAccountID, accountType)
+	leaderBalance, _, err := s.tradeService.CachedBalance(leaderTrade.UserID.Hex(), leaderAccountID, accountType)
 	if err != nil {
 		return errors.New("failed to fetch leader balance")
 	}
@@ -143,71 +256,280 @@ func (s *copyTradeService) MirrorTrade(leaderTrade *models.TradeHistory, account
 
 	volumeRatio := leaderTrade.Volume / leaderBalance
 
+	var wg sync.WaitGroup
 	for _, sub := range subscriptions {
-		if sub.AccountType != accountType {
+		if !models.SameAccountType(sub.AccountType, accountType) || !sub.Copies(leaderTrade) {
 			continue
 		}
 
-		accounts, err := s.accountService.GetAccountsByUserID(sub.FollowerID)
-		if err != nil {
-			continue
-		}
-		var followerAccount *models.Account
-		for _, acc := range accounts {
-			if acc.AccountType == accountType {
-				followerAccount = acc
-				break
-			}
+		s.mirrorSlots <- struct{}{}
+		wg.Add(1)
+		go func(sub *models.CopyTradeSubscription) {
+			defer func() {
+				<-s.mirrorSlots
+				wg.Done()
+			}()
+			s.mirrorSubscription(ctx, sub, leaderTrade, accountType, volumeRatio)
+		}(sub)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (s *copyTradeService) mirrorSubscription(ctx context.Context, sub *models.CopyTradeSubscription, leaderTrade *models.TradeHistory, accountType string, volumeRatio float64) {
+	followerVolume, err := s.mirrorToFollower(ctx, sub, leaderTrade, accountType, volumeRatio)
+	if errors.Is(err, errSymbolUnavailable) {
+		log.Printf("Not mirroring trade %s to subscription %s: %v", leaderTrade.ID.Hex(), sub.ID.Hex(), err)
+		s.notifier.notify(sub.FollowerID, fmt.Sprintf("A %s %s trade from your leader was not copied because %s is not available on your account. Add a symbol mapping to your subscription to copy it.",
+			leaderTrade.TradeType, leaderTrade.Symbol, sub.FollowerSymbol(leaderTrade.Symbol)))
+		return
+	}
+	if err != nil {
+		s.recordMirrorFailure(ctx, sub, err)
+		return
+	}
+	if sub.MirrorFailures > 0 {
+		if err := s.copyTradeRepo.ResetMirrorFailures(ctx, sub.ID); err != nil {
+			log.Printf("Failed to reset mirror failures for subscription %s: %v", sub.ID.Hex(), err)
 		}
-		if followerAccount == nil {
-			continue
+	}
+	s.notifier.notify(sub.FollowerID, fmt.Sprintf("Copied %s %s %g lots from your leader into your %s account.",
+		leaderTrade.TradeType, leaderTrade.Symbol, followerVolume, accountType))
+}
+
+// mirrorToFollower places the follower's copy of a leader trade and returns
+// the volume placed. Errors are the follower-side failures that count towards
+// auto-pausing the subscription.
+func (s *copyTradeService) mirrorToFollower(ctx context.Context, sub *models.CopyTradeSubscription, leaderTrade *models.TradeHistory, accountType string, volumeRatio float64) (float64, error) {
+	symbol, err := s.resolveFollowerSymbol(ctx, sub, leaderTrade.Symbol)
+	if err != nil {
+		return 0, err
+	}
+
+	accounts, err := s.accountService.GetAccountsByUserID(ctx, sub.FollowerID)
+	if err != nil {
+		return 0, errors.New("failed to fetch follower accounts")
+	}
+	var followerAccount *models.Account
+	for _, acc := range accounts {
+		if models.SameAccountType(acc.AccountType, accountType) {
+			followerAccount = acc
+			break
 		}
+	}
+	if followerAccount == nil {
+		return 0, errors.New("follower does not have account of type " + accountType)
+	}
+
+	followerBalance, _, err := s.tradeService.CachedBalance(sub.FollowerID, followerAccount.ID.Hex(), accountType)
+	if err != nil {
+		return 0, errors.New("failed to fetch follower balance")
+	}
 
-		followerBalance, err := s.tradeService.RequestBalance(sub.FollowerID, followerAccount.ID.Hex(), accountType)
+	followerVolume := sub.FollowerVolume(leaderTrade.Volume, math.Min(sub.AllocatedAmount, followerBalance)*volumeRatio)
+	followerTrade, _, err := s.tradeService.PlaceTrade(
+		sub.FollowerID,
+		followerAccount.ID.Hex(),
+		symbol,
+		accountType,
+		leaderTrade.TradeType,
+		leaderTrade.OrderType,
+		leaderTrade.Leverage,
+		followerVolume,
+		leaderTrade.EntryPrice,
+		leaderTrade.StopLoss,
+		leaderTrade.TakeProfit,
+		leaderTrade.Expiration,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	copyTrade := &models.CopyTrade{
+		SubscriptionID:  sub.ID,
+		LeaderTradeID:   leaderTrade.ID,
+		FollowerTradeID: followerTrade.ID,
+	}
+	if err := s.copyTradeRepo.SaveCopyTrade(ctx, copyTrade); err != nil {
+		// The follower's trade was placed; only the link is missing.
+		log.Printf("Failed to record copy trade for subscription %s: %v", sub.ID.Hex(), err)
+		return followerVolume, nil
+	}
+
+	metadata := map[string]interface{}{
+		"copy_trade_id":     copyTrade.ID.Hex(),
+		"subscription_id":   sub.ID.Hex(),
+		"leader_trade_id":   leaderTrade.ID.Hex(),
+		"follower_trade_id": followerTrade.ID.Hex(),
+		"follower_volume":   followerVolume,
+	}
+	if err := s.logService.LogAction(primitive.ObjectID{}, "MirrorTrade", "Trade mirrored for follower", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+	return followerVolume, nil
+}
+
+// resolveFollowerSymbol maps the leader's symbol through the subscription's
+// SymbolMap and returns the broker symbol name to place the mirror under.
+func (s *copyTradeService) resolveFollowerSymbol(ctx context.Context, sub *models.CopyTradeSubscription, leaderSymbol string) (string, error) {
+	want := sub.FollowerSymbol(leaderSymbol)
+	symbols, err := s.symbolRepo.GetAllSymbols(ctx)
+	if err != nil {
+		return "", errors.New("failed to fetch symbols")
+	}
+	sym := models.ResolveSymbol(symbols, want)
+	if sym == nil || slices.Contains(sym.DeniedAccounts, sub.AccountType) {
+		return "", fmt.Errorf("%w: %s", errSymbolUnavailable, want)
+	}
+	return sym.SymbolName, nil
+}
+
+// recordMirrorFailure counts a failed mirror against the subscription and
+// pauses it once maxMirrorFailures consecutive mirrors have failed.
+func (s *copyTradeService) recordMirrorFailure(ctx context.Context, sub *models.CopyTradeSubscription, cause error) {
+	failures, err := s.copyTradeRepo.RecordMirrorFailure(ctx, sub.ID)
+	if err != nil {
+		log.Printf("Failed to record mirror failure for subscription %s: %v", sub.ID.Hex(), err)
+		return
+	}
+	if s.maxMirrorFailures <= 0 || failures < s.maxMirrorFailures {
+		return
+	}
+
+	reason := fmt.Sprintf("%d consecutive mirror failures, last: %v", failures, cause)
+	if err := s.copyTradeRepo.PauseSubscription(ctx, sub.ID, reason); err != nil {
+		log.Printf("Failed to pause subscription %s: %v", sub.ID.Hex(), err)
+		return
+	}
+
+	metadata := map[string]interface{}{
+		"subscription_id": sub.ID.Hex(),
+		"follower_id":     sub.FollowerID,
+		"leader_id":       sub.LeaderID,
+		"reason":          reason,
+	}
+	if err := s.logService.LogAction(primitive.ObjectID{}, "PauseCopySubscription", "Copy trade subscription auto-paused", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+	s.notifier.notify(sub.FollowerID, fmt.Sprintf("Your copy trading subscription has been paused after %d trades could not be copied (%v). Check your account balance and resubscribe.", failures, cause))
+}
+
+// GetCopyTradeHistory lists what was copied under a subscription, newest
+// first, with the current state of both the leader's and follower's trades.
+// CancelSubscription stops copying for the follower. With closeTrades the
+// follower's copies that are still open or pending are closed too; otherwise
+// they are left for the follower to manage.
+func (s *copyTradeService) CancelSubscription(ctx context.Context, subscriptionID, userID string, closeTrades bool) (interfaces.BulkCloseResult, error) {
+	sub, err := s.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return interfaces.BulkCloseResult{}, err
+	}
+	if sub == nil {
+		return interfaces.BulkCloseResult{}, ErrSubscriptionNotFound
+	}
+	if sub.FollowerID != userID {
+		return interfaces.BulkCloseResult{}, ErrSubscriptionForbidden
+	}
+
+	cancelled, err := s.copyTradeRepo.CancelSubscription(ctx, sub.ID, time.Now())
+	if err != nil {
+		return interfaces.BulkCloseResult{}, err
+	}
+	if !cancelled {
+		return interfaces.BulkCloseResult{}, newError(ErrConflict, "subscription is already cancelled")
+	}
+
+	result := interfaces.BulkCloseResult{Results: []interfaces.CloseResult{}}
+	if closeTrades {
+		result, err = s.closeFollowerTrades(ctx, sub)
 		if err != nil {
-			continue
+			return result, fmt.Errorf("subscription cancelled but copied trades could not be listed: %v", err)
 		}
+	}
 
-		followerVolume := math.Min(sub.AllocatedAmount, followerBalance) * volumeRatio
-		followerTrade, _, err := s.tradeService.PlaceTrade(
-			sub.FollowerID,
-			followerAccount.ID.Hex(),
-			leaderTrade.Symbol,
-			accountType,
-			leaderTrade.TradeType,
-			leaderTrade.OrderType,
-			leaderTrade.Leverage,
-			followerVolume,
-			leaderTrade.EntryPrice,
-			leaderTrade.StopLoss,
-			leaderTrade.TakeProfit,
-			leaderTrade.Expiration,
-		)
-		if err != nil {
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+	metadata := map[string]interface{}{
+		"subscription_id": subscriptionID,
+		"leader_id":       sub.LeaderID,
+		"close_trades":    closeTrades,
+		"closed":          result.Closed,
+		"failed":          result.Failed,
+	}
+	if err := s.logService.LogAction(userObjID, "CancelCopySubscription", "Copy trade subscription cancelled", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+	s.notifier.notify(sub.LeaderID, "A follower has stopped copying your trades.")
+
+	return result, nil
+}
+
+// closeFollowerTrades closes the subscription's copied trades that are still
+// open and cancels those still pending.
+func (s *copyTradeService) closeFollowerTrades(ctx context.Context, sub *models.CopyTradeSubscription) (interfaces.BulkCloseResult, error) {
+	result := interfaces.BulkCloseResult{Results: []interfaces.CloseResult{}}
+	copyTrades, err := s.copyTradeRepo.GetCopyTradesBySubscription(ctx, sub.ID)
+	if err != nil {
+		return result, err
+	}
+
+	for _, ct := range copyTrades {
+		trade, err := s.tradeService.GetTrade(ctx, ct.FollowerTradeID.Hex())
+		if err != nil || trade == nil {
 			continue
 		}
 
-		copyTrade := &models.CopyTrade{
-			SubscriptionID:  sub.ID,
-			LeaderTradeID:   leaderTrade.ID,
-			FollowerTradeID: followerTrade.ID,
+		var response interfaces.TradeResponse
+		switch models.TradeStatus(trade.Status) {
+		case models.TradeStatusOpen:
+			response, err = s.tradeService.CloseTrade(trade.ID.Hex(), sub.FollowerID)
+		case models.TradeStatusPending:
+			response, err = s.tradeService.CancelPendingOrder(trade.ID.Hex(), sub.FollowerID)
+		default:
+			continue
 		}
-		err = s.copyTradeRepo.SaveCopyTrade(copyTrade)
+
+		result.Requested++
+		res := interfaces.CloseResult{TradeID: trade.ID.Hex()}
 		if err != nil {
-			continue
+			res.Error = err.Error()
+			result.Failed++
+		} else {
+			res.Status = response.Status
+			result.Closed++
 		}
+		result.Results = append(result.Results, res)
+	}
+	return result, nil
+}
 
-		metadata := map[string]interface{}{
-			"copy_trade_id":     copyTrade.ID.Hex(),
-			"subscription_id":   sub.ID.Hex(),
-			"leader_trade_id":   leaderTrade.ID.Hex(),
-			"follower_trade_id": followerTrade.ID.Hex(),
-			"follower_volume":   followerVolume,
+func (s *copyTradeService) GetCopyTradeHistory(ctx context.Context, subscriptionID, userID string) ([]*models.CopyTradeHistoryEntry, error) {
+	sub, err := s.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	if sub.FollowerID != userID {
+		return nil, ErrSubscriptionForbidden
+	}
+
+	copyTrades, err := s.copyTradeRepo.GetCopyTradesBySubscription(ctx, sub.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]*models.CopyTradeHistoryEntry, 0, len(copyTrades))
+	for _, ct := range copyTrades {
+		entry := &models.CopyTradeHistoryEntry{CopyTradeID: ct.ID, CreatedAt: ct.CreatedAt}
+		if trade, err := s.tradeService.GetTrade(ctx, ct.LeaderTradeID.Hex()); err == nil {
+			entry.LeaderTrade = models.NewCopyTradeLeg(trade)
 		}
-		if err := s.logService.LogAction(primitive.ObjectID{}, "MirrorTrade", "Trade mirrored for follower", "", metadata); err != nil {
-			return nil
+		if trade, err := s.tradeService.GetTrade(ctx, ct.FollowerTradeID.Hex()); err == nil {
+			entry.FollowerTrade = models.NewCopyTradeLeg(trade)
 		}
+		history = append(history, entry)
 	}
-
-	return nil
+	return history, nil
 }
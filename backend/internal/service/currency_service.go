@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+	"github.com/mehrbod2002/fxtrader/internal/repository"
+)
+
+type CurrencyService interface {
+	Convert(ctx context.Context, amount float64, from, to string) (float64, error)
+	BaseCurrency() string
+	SetRate(ctx context.Context, from, to string, rate float64, updatedBy string) error
+	GetRates(ctx context.Context) ([]*models.ExchangeRate, error)
+}
+
+// RateProvider returns rates quoted as units of each currency per one unit of base.
+type RateProvider interface {
+	FetchRates(base string) (map[string]float64, error)
+}
+
+type httpRateProvider struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPRateProvider(url string) RateProvider {
+	return &httpRateProvider{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (p *httpRateProvider) FetchRates(base string) (map[string]float64, error) {
+	req, err := http.NewRequest(http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	q := req.URL.Query()
+	q.Set("base", base)
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rate provider returned status %d", resp.StatusCode)
+	}
+
+	var payload struct {
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("failed to decode rates: %v", err)
+	}
+	return payload.Rates, nil
+}
+
+type currencyService struct {
+	rateRepo     repository.CurrencyRateRepository
+	provider     RateProvider
+	baseCurrency string
+	ttl          time.Duration
+
+	mu          sync.RWMutex
+	overrides   map[string]float64
+	rates       map[string]float64
+	refreshedAt time.Time
+}
+
+func NewCurrencyService(rateRepo repository.CurrencyRateRepository, provider RateProvider, baseCurrency string, ttl time.Duration) CurrencyService {
+	return &currencyService{
+		rateRepo:     rateRepo,
+		provider:     provider,
+		baseCurrency: normalizeCurrency(baseCurrency),
+		ttl:          ttl,
+		overrides:    make(map[string]float64),
+		rates:        make(map[string]float64),
+	}
+}
+
+func normalizeCurrency(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func pairKey(from, to string) string {
+	return from + "/" + to
+}
+
+func (s *currencyService) BaseCurrency() string {
+	return s.baseCurrency
+}
+
+func (s *currencyService) Convert(ctx context.Context, amount float64, from, to string) (float64, error) {
+	from = normalizeCurrency(from)
+	to = normalizeCurrency(to)
+	if from == "" {
+		from = s.baseCurrency
+	}
+	if to == "" {
+		to = s.baseCurrency
+	}
+	if from == to {
+		return amount, nil
+	}
+
+	rate, err := s.rate(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+	return amount * rate, nil
+}
+
+func (s *currencyService) rate(ctx context.Context, from, to string) (float64, error) {
+	if err := s.refreshIfStale(ctx); err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if rate, ok := s.overrides[pairKey(from, to)]; ok {
+		return rate, nil
+	}
+	if rate, ok := s.overrides[pairKey(to, from)]; ok && rate > 0 {
+		return 1 / rate, nil
+	}
+
+	fromRate, fromOK := s.baseRate(from)
+	toRate, toOK := s.baseRate(to)
+	if !fromOK || !toOK {
+		return 0, fmt.Errorf("no exchange rate available for %s/%s", from, to)
+	}
+	return toRate / fromRate, nil
+}
+
+// baseRate must be called with s.mu held.
+func (s *currencyService) baseRate(currency string) (float64, bool) {
+	if currency == s.baseCurrency {
+		return 1, true
+	}
+	if rate, ok := s.overrides[pairKey(s.baseCurrency, currency)]; ok && rate > 0 {
+		return rate, true
+	}
+	if rate, ok := s.overrides[pairKey(currency, s.baseCurrency)]; ok && rate > 0 {
+		return 1 / rate, true
+	}
+	rate, ok := s.rates[currency]
+	return rate, ok && rate > 0
+}
+
+func (s *currencyService) refreshIfStale(ctx context.Context) error {
+	s.mu.RLock()
+	fresh := !s.refreshedAt.IsZero() && time.Since(s.refreshedAt) < s.ttl
+	s.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	stored, err := s.rateRepo.GetAllRates(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load exchange rates: %v", err)
+	}
+	overrides := make(map[string]float64, len(stored))
+	for _, r := range stored {
+		overrides[pairKey(r.From, r.To)] = r.Rate
+	}
+
+	var rates map[string]float64
+	if s.provider != nil {
+		rates, err = s.provider.FetchRates(s.baseCurrency)
+		if err != nil {
+			log.Printf("Failed to fetch exchange rates: %v", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.overrides = overrides
+	if rates != nil {
+		s.rates = make(map[string]float64, len(rates))
+		for code, rate := range rates {
+			s.rates[normalizeCurrency(code)] = rate
+		}
+	}
+	s.refreshedAt = time.Now()
+	return nil
+}
+
+func (s *currencyService) SetRate(ctx context.Context, from, to string, rate float64, updatedBy string) error {
+	from = normalizeCurrency(from)
+	to = normalizeCurrency(to)
+	if from == "" || to == "" || from == to {
+		return errors.New("invalid currency pair")
+	}
+	if rate <= 0 {
+		return errors.New("rate must be positive")
+	}
+
+	if err := s.rateRepo.SaveRate(ctx, &models.ExchangeRate{From: from, To: to, Rate: rate, UpdatedBy: updatedBy}); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.overrides[pairKey(from, to)] = rate
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *currencyService) GetRates(ctx context.Context) ([]*models.ExchangeRate, error) {
+	return s.rateRepo.GetAllRates(ctx)
+}
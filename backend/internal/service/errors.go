@@ -0,0 +1,42 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error kinds. Handlers decide the HTTP status of a service error by testing
+// it against these with errors.Is instead of inspecting its message.
+var (
+	ErrInvalidInput        = errors.New("invalid input")
+	ErrNotFound            = errors.New("not found")
+	ErrForbidden           = errors.New("forbidden")
+	ErrConflict            = errors.New("conflict")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrTimeout             = errors.New("timed out")
+	ErrUnavailable         = errors.New("unavailable")
+)
+
+// Error is a service error whose message is safe to show the client. It
+// matches its Kind under errors.Is while keeping its own text.
+type Error struct {
+	Kind    error
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// newError builds an Error of the given kind with a formatted message.
+func newError(kind error, format string, args ...interface{}) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// ErrAccountNotFound is returned when an account does not exist or is not
+// owned by the caller.
+var ErrAccountNotFound = newError(ErrNotFound, "account not found")
@@ -1,9 +1,14 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
+	"strings"
+	"time"
 
+	"github.com/mehrbod2002/fxtrader/internal/config"
 	"github.com/mehrbod2002/fxtrader/internal/models"
 	"github.com/mehrbod2002/fxtrader/internal/repository"
 
@@ -11,40 +16,114 @@ import (
 )
 
 type LeaderRequestService interface {
-	CreateLeaderRequest(userID, reason string) (*models.LeaderRequest, error)
-	ApproveLeaderRequest(requestID string, adminReason string) error
-	DenyLeaderRequest(requestID string, adminReason string) error
-	GetPendingLeaderRequests() ([]*models.LeaderRequest, error)
-	GetApprovedLeaders() ([]*models.User, error)
+	CreateLeaderRequest(ctx context.Context, userID, reason string) (*models.LeaderRequest, error)
+	ApproveLeaderRequest(ctx context.Context, requestID string, adminReason string) error
+	DenyLeaderRequest(ctx context.Context, requestID string, adminReason string) error
+	GetPendingLeaderRequests(ctx context.Context) ([]*models.LeaderRequest, error)
+	GetUserLeaderRequests(ctx context.Context, userID string) ([]*models.LeaderRequest, error)
+	GetApprovedLeaders(ctx context.Context, page, limit int64) ([]*models.User, int64, error)
+	RevokeLeader(ctx context.Context, userID, adminReason string) error
+}
+
+// LeaderEligibilityError lists the track-record requirements an applicant has
+// not met yet.
+type LeaderEligibilityError struct {
+	Unmet []string
+}
+
+func (e *LeaderEligibilityError) Error() string {
+	return "not eligible to become a leader: " + strings.Join(e.Unmet, "; ")
 }
 
 type leaderRequestService struct {
 	leaderRequestRepo repository.LeaderRequestRepository
 	userService       UserService
+	tradeRepo         repository.TradeRepository
+	copyTradeRepo     repository.CopyTradeRepository
 	logService        LogService
+	notifier          *copyTradeNotifier
+	minClosedTrades   int
+	minVolume         float64
+	minAccountAge     time.Duration
 }
 
 func NewLeaderRequestService(
 	leaderRequestRepo repository.LeaderRequestRepository,
 	userService UserService,
+	tradeRepo repository.TradeRepository,
+	copyTradeRepo repository.CopyTradeRepository,
 	logService LogService,
+	telegramService TelegramService,
+	cfg *config.Config,
 ) LeaderRequestService {
 	return &leaderRequestService{
 		leaderRequestRepo: leaderRequestRepo,
 		userService:       userService,
+		tradeRepo:         tradeRepo,
+		copyTradeRepo:     copyTradeRepo,
 		logService:        logService,
+		notifier:          newCopyTradeNotifier(telegramService, userService),
+		minClosedTrades:   cfg.LeaderMinClosedTrades,
+		minVolume:         cfg.LeaderMinVolume,
+		minAccountAge:     cfg.LeaderMinAccountAge,
+	}
+}
+
+// checkEligibility compares the user's trading history against the configured
+// minimums and returns a LeaderEligibilityError naming every unmet one.
+func (s *leaderRequestService) checkEligibility(ctx context.Context, user *models.User) error {
+	var unmet []string
+
+	if s.minClosedTrades > 0 {
+		closed, err := s.tradeRepo.CountTradesByStatus(ctx, user.ID, models.TradeStatusClosed)
+		if err != nil {
+			return fmt.Errorf("failed to count closed trades: %v", err)
+		}
+		if closed < int64(s.minClosedTrades) {
+			unmet = append(unmet, fmt.Sprintf("at least %d closed trades required (have %d)", s.minClosedTrades, closed))
+		}
+	}
+
+	if s.minVolume > 0 {
+		volume, err := s.tradeRepo.GetVolumeSince(ctx, user.ID, time.Time{})
+		if err != nil {
+			return fmt.Errorf("failed to compute traded volume: %v", err)
+		}
+		if volume < s.minVolume {
+			unmet = append(unmet, fmt.Sprintf("at least %g lots traded required (have %g)", s.minVolume, volume))
+		}
+	}
+
+	if s.minAccountAge > 0 {
+		registered, err := time.Parse(time.RFC3339, user.RegistrationDate)
+		if err != nil {
+			registered = user.ID.Timestamp()
+		}
+		if age := time.Since(registered); age < s.minAccountAge {
+			unmet = append(unmet, fmt.Sprintf("account must be at least %d days old (is %d)", int(s.minAccountAge.Hours()/24), int(age.Hours()/24)))
+		}
 	}
+
+	if len(unmet) > 0 {
+		return &LeaderEligibilityError{Unmet: unmet}
+	}
+	return nil
 }
 
-func (s *leaderRequestService) CreateLeaderRequest(userID, reason string) (*models.LeaderRequest, error) {
-	user, err := s.userService.GetUser(userID)
+func (s *leaderRequestService) CreateLeaderRequest(ctx context.Context, userID, reason string) (*models.LeaderRequest, error) {
+	user, err := s.userService.GetUser(ctx, userID)
 	if err != nil || user == nil {
 		return nil, errors.New("user not found")
 	}
 
-	if user.IsCopyPendingTradeLeader {
+	// The pending flag alone is not trusted: denials used to leave it set, so
+	// an outstanding request is detected by the repository instead.
+	if user.IsCopyTradeLeader {
 		return nil, errors.New("user is already a copy trade leader")
 	}
+	if err := s.checkEligibility(ctx, user); err != nil {
+		return nil, err
+	}
 
 	request := &models.LeaderRequest{
 		UserID:     userID,
@@ -52,13 +131,16 @@ func (s *leaderRequestService) CreateLeaderRequest(userID, reason string) (*mode
 		Status:     "PENDING",
 		TelegramID: user.TelegramID,
 	}
-	err = s.leaderRequestRepo.SaveLeaderRequest(request)
+	err = s.leaderRequestRepo.SaveLeaderRequest(ctx, request)
+	if errors.Is(err, repository.ErrLeaderRequestPending) {
+		return nil, newError(ErrConflict, "%s", err)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	user.IsCopyPendingTradeLeader = true
-	err = s.userService.UpdateUser(user)
+	err = s.userService.UpdateUser(ctx, user)
 	if err != nil {
 		return nil, err
 	}
@@ -73,13 +155,13 @@ func (s *leaderRequestService) CreateLeaderRequest(userID, reason string) (*mode
 	return request, nil
 }
 
-func (s *leaderRequestService) ApproveLeaderRequest(requestID, adminReason string) error {
+func (s *leaderRequestService) ApproveLeaderRequest(ctx context.Context, requestID, adminReason string) error {
 	objID, err := primitive.ObjectIDFromHex(requestID)
 	if err != nil {
 		return errors.New("invalid request ID")
 	}
 
-	request, err := s.leaderRequestRepo.GetLeaderRequestByID(objID)
+	request, err := s.leaderRequestRepo.GetLeaderRequestByID(ctx, objID)
 	if err != nil {
 		return err
 	}
@@ -92,17 +174,17 @@ func (s *leaderRequestService) ApproveLeaderRequest(requestID, adminReason strin
 
 	request.Status = "APPROVED"
 	request.AdminReason = adminReason
-	err = s.leaderRequestRepo.UpdateLeaderRequest(request)
+	err = s.leaderRequestRepo.UpdateLeaderRequest(ctx, request)
 	if err != nil {
 		return err
 	}
 
-	user, err := s.userService.GetUser(request.UserID)
+	user, err := s.userService.GetUser(ctx, request.UserID)
 	if err != nil || user == nil {
 		return errors.New("user not found")
 	}
 	user.IsCopyTradeLeader = true
-	err = s.userService.UpdateUser(user)
+	err = s.userService.UpdateUser(ctx, user)
 	if err != nil {
 		return err
 	}
@@ -118,13 +200,13 @@ func (s *leaderRequestService) ApproveLeaderRequest(requestID, adminReason strin
 	return nil
 }
 
-func (s *leaderRequestService) DenyLeaderRequest(requestID, adminReason string) error {
+func (s *leaderRequestService) DenyLeaderRequest(ctx context.Context, requestID, adminReason string) error {
 	objID, err := primitive.ObjectIDFromHex(requestID)
 	if err != nil {
 		return errors.New("invalid request ID")
 	}
 
-	request, err := s.leaderRequestRepo.GetLeaderRequestByID(objID)
+	request, err := s.leaderRequestRepo.GetLeaderRequestByID(ctx, objID)
 	if err != nil {
 		return err
 	}
@@ -137,11 +219,27 @@ func (s *leaderRequestService) DenyLeaderRequest(requestID, adminReason string)
 
 	request.Status = "DENIED"
 	request.AdminReason = adminReason
-	err = s.leaderRequestRepo.UpdateLeaderRequest(request)
+	err = s.leaderRequestRepo.UpdateLeaderRequest(ctx, request)
 	if err != nil {
 		return err
 	}
 
+	// Clear the pending flag so the user can apply again.
+	user, err := s.userService.GetUser(ctx, request.UserID)
+	if err != nil || user == nil {
+		return errors.New("user not found")
+	}
+	user.IsCopyPendingTradeLeader = false
+	if err := s.userService.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+	resetMetadata := map[string]interface{}{
+		"request_id": requestID,
+	}
+	if err := s.logService.LogAction(user.ID, "LeaderPendingFlagReset", "Pending leader flag cleared after denial", "", resetMetadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+
 	metadata := map[string]interface{}{
 		"request_id":   requestID,
 		"user_id":      request.UserID,
@@ -153,10 +251,51 @@ func (s *leaderRequestService) DenyLeaderRequest(requestID, adminReason string)
 	return nil
 }
 
-func (s *leaderRequestService) GetPendingLeaderRequests() ([]*models.LeaderRequest, error) {
-	return s.leaderRequestRepo.GetPendingLeaderRequests()
+func (s *leaderRequestService) GetPendingLeaderRequests(ctx context.Context) ([]*models.LeaderRequest, error) {
+	return s.leaderRequestRepo.GetPendingLeaderRequests(ctx)
+}
+
+func (s *leaderRequestService) GetUserLeaderRequests(ctx context.Context, userID string) ([]*models.LeaderRequest, error) {
+	return s.leaderRequestRepo.GetLeaderRequestsByUserID(ctx, userID)
+}
+
+func (s *leaderRequestService) GetApprovedLeaders(ctx context.Context, page, limit int64) ([]*models.User, int64, error) {
+	isLeader := true
+	return s.userService.GetUsers(ctx, models.UserFilter{IsLeader: &isLeader}, page, limit)
 }
 
-func (s *leaderRequestService) GetApprovedLeaders() ([]*models.User, error) {
-	return s.userService.GetUsersByLeaderStatus(true)
+// RevokeLeader withdraws a user's leader status and pauses everyone copying
+// them, so no further trades are mirrored from the revoked account.
+func (s *leaderRequestService) RevokeLeader(ctx context.Context, userID, adminReason string) error {
+	user, err := s.userService.GetUser(ctx, userID)
+	if err != nil || user == nil {
+		return errors.New("user not found")
+	}
+	if !user.IsCopyTradeLeader {
+		return errors.New("user is not a copy trade leader")
+	}
+
+	user.IsCopyTradeLeader = false
+	if err := s.userService.UpdateUser(ctx, user); err != nil {
+		return err
+	}
+
+	paused, err := s.copyTradeRepo.PauseSubscriptionsByLeaderID(ctx, userID, "leader revoked: "+adminReason)
+	if err != nil {
+		return fmt.Errorf("leader revoked but failed to pause subscriptions: %v", err)
+	}
+	for _, sub := range paused {
+		message := fmt.Sprintf("Your copy trading subscription to %s has been paused because they are no longer an approved leader.", user.Username)
+		s.notifier.notify(sub.FollowerID, message)
+	}
+
+	metadata := map[string]interface{}{
+		"user_id":              userID,
+		"admin_reason":         adminReason,
+		"paused_subscriptions": len(paused),
+	}
+	if err := s.logService.LogAction(primitive.ObjectID{}, "RevokeLeader", "Copy trade leader revoked", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+	return nil
 }
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mehrbod2002/fxtrader/internal/config"
+	"github.com/mehrbod2002/fxtrader/internal/models"
+	"github.com/mehrbod2002/fxtrader/internal/repository/memory"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestLeaderRequestCanBeRenewedAfterDenial(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{LogRetryQueueSize: 16}
+	users := memory.NewUserRepository()
+	userService := NewUserService(users)
+	requests := NewLeaderRequestService(memory.NewLeaderRequestRepository(), userService,
+		memory.NewTradeRepository(), nil, NewLogService(memory.NewLogRepository(), cfg), nil, cfg)
+
+	user := &models.User{ID: primitive.NewObjectID(), Username: "leader"}
+	if err := users.SaveUser(ctx, user); err != nil {
+		t.Fatalf("save user: %v", err)
+	}
+	pending := func() bool {
+		t.Helper()
+		stored, err := users.GetUserByID(ctx, user.ID)
+		if err != nil || stored == nil {
+			t.Fatalf("get user: %v", err)
+		}
+		return stored.IsCopyPendingTradeLeader
+	}
+
+	first, err := requests.CreateLeaderRequest(ctx, user.ID.Hex(), "consistent returns")
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if !pending() {
+		t.Fatal("pending flag not set after applying")
+	}
+
+	_, err = requests.CreateLeaderRequest(ctx, user.ID.Hex(), "again")
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("second apply while pending: got %v, want a conflict", err)
+	}
+
+	if err := requests.DenyLeaderRequest(ctx, first.ID.Hex(), "not enough history"); err != nil {
+		t.Fatalf("deny: %v", err)
+	}
+	if pending() {
+		t.Fatal("pending flag still set after denial")
+	}
+
+	second, err := requests.CreateLeaderRequest(ctx, user.ID.Hex(), "more history now")
+	if err != nil {
+		t.Fatalf("re-apply after denial: %v", err)
+	}
+	if second.ID == first.ID || second.Status != "PENDING" {
+		t.Fatalf("re-apply returned %+v, want a new pending request", second)
+	}
+	if !pending() {
+		t.Fatal("pending flag not set after re-applying")
+	}
+}
@@ -1,45 +1,152 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/internal/config"
 	"github.com/mehrbod2002/fxtrader/internal/models"
 	"github.com/mehrbod2002/fxtrader/internal/repository"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	logRetryAttempts   = 5
+	logRetryBaseDelay  = 500 * time.Millisecond
+	logRetryMaxBackoff = 30 * time.Second
+)
+
 type LogService interface {
 	LogAction(userID primitive.ObjectID, action, description, ipAddress string, metadata map[string]interface{}) error
-	GetAllLogs(page, limit int) ([]*models.LogEntry, error)
-	GetLogsByUserID(userID string, page, limit int) ([]*models.LogEntry, error)
+	GetAllLogs(ctx context.Context, page, limit int) ([]*models.LogEntry, int64, error)
+	GetLogsByUserID(ctx context.Context, userID string, page, limit int) ([]*models.LogEntry, int64, error)
 }
 
 type logService struct {
-	logRepo repository.LogRepository
+	logRepo            repository.LogRepository
+	retryQueue         chan *models.LogEntry
+	fallbackPath       string
+	fallbackMu         sync.Mutex
+	retention          time.Duration
+	highValueRetention time.Duration
+	highValueActions   map[string]bool
+}
+
+func NewLogService(logRepo repository.LogRepository, cfg *config.Config) LogService {
+	s := &logService{
+		logRepo:            logRepo,
+		retryQueue:         make(chan *models.LogEntry, cfg.LogRetryQueueSize),
+		fallbackPath:       cfg.LogFallbackPath,
+		retention:          cfg.LogRetention,
+		highValueRetention: cfg.LogHighValueRetention,
+		highValueActions:   make(map[string]bool, len(cfg.LogHighValueActions)),
+	}
+	for _, action := range cfg.LogHighValueActions {
+		s.highValueActions[action] = true
+	}
+	go s.retryLoop()
+	return s
 }
 
-func NewLogService(logRepo repository.LogRepository) LogService {
-	return &logService{logRepo: logRepo}
+// expiry returns when an entry for action may be purged, or nil to keep it
+// forever. High-value actions use their own retention, where zero means keep.
+func (s *logService) expiry(action string, at time.Time) *time.Time {
+	retention := s.retention
+	if s.highValueActions[action] {
+		retention = s.highValueRetention
+	}
+	if retention <= 0 {
+		return nil
+	}
+	expireAt := at.Add(retention)
+	return &expireAt
 }
 
+// LogAction writes an audit entry. A failed write is queued for retry, and
+// written to the fallback file if the queue is full, so the caller only sees
+// an error when the entry could not be kept anywhere.
 func (s *logService) LogAction(userID primitive.ObjectID, action, description, ipAddress string, metadata map[string]interface{}) error {
 	logEntry := &models.LogEntry{
+		ID:          primitive.NewObjectID(),
 		UserID:      userID,
 		Action:      action,
 		Description: description,
 		IPAddress:   ipAddress,
+		Timestamp:   time.Now(),
 		Metadata:    metadata,
 	}
-	return s.logRepo.SaveLog(logEntry)
+	logEntry.ExpireAt = s.expiry(action, logEntry.Timestamp)
+	err := s.logRepo.SaveLog(context.Background(), logEntry)
+	if err == nil {
+		return nil
+	}
+
+	select {
+	case s.retryQueue <- logEntry:
+		return nil
+	default:
+	}
+	if fallbackErr := s.writeFallback(logEntry, err); fallbackErr != nil {
+		return fmt.Errorf("failed to save log: %v; fallback failed: %v", err, fallbackErr)
+	}
+	return nil
+}
+
+func (s *logService) retryLoop() {
+	for entry := range s.retryQueue {
+		var err error
+		delay := logRetryBaseDelay
+		for attempt := 1; attempt <= logRetryAttempts; attempt++ {
+			time.Sleep(delay)
+			if err = s.logRepo.SaveLog(context.Background(), entry); err == nil {
+				break
+			}
+			delay = min(delay*2, logRetryMaxBackoff)
+		}
+		if err == nil {
+			continue
+		}
+		if fallbackErr := s.writeFallback(entry, err); fallbackErr != nil {
+			log.Printf("Dropping audit log %s (%s): %v; fallback failed: %v", entry.ID.Hex(), entry.Action, err, fallbackErr)
+		}
+	}
+}
+
+// writeFallback appends the entry as a JSON line to the local fallback file.
+func (s *logService) writeFallback(entry *models.LogEntry, cause error) error {
+	line, err := json.Marshal(struct {
+		*models.LogEntry
+		Cause string `json:"cause"`
+	}{entry, cause.Error()})
+	if err != nil {
+		return err
+	}
+
+	s.fallbackMu.Lock()
+	defer s.fallbackMu.Unlock()
+	f, err := os.OpenFile(s.fallbackPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
 }
 
-func (s *logService) GetAllLogs(page, limit int) ([]*models.LogEntry, error) {
-	return s.logRepo.GetAllLogs(page, limit)
+func (s *logService) GetAllLogs(ctx context.Context, page, limit int) ([]*models.LogEntry, int64, error) {
+	return s.logRepo.GetAllLogs(ctx, page, limit)
 }
 
-func (s *logService) GetLogsByUserID(userID string, page, limit int) ([]*models.LogEntry, error) {
+func (s *logService) GetLogsByUserID(ctx context.Context, userID string, page, limit int) ([]*models.LogEntry, int64, error) {
 	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return s.logRepo.GetLogsByUserID(objID, page, limit)
+	return s.logRepo.GetLogsByUserID(ctx, objID, page, limit)
 }
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/interfaces"
+	"github.com/mehrbod2002/fxtrader/internal/clock"
+	"github.com/mehrbod2002/fxtrader/internal/config"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrMT5Unavailable is returned without contacting MT5 while the circuit
+// breaker is open.
+var ErrMT5Unavailable = newError(ErrUnavailable, "MT5 unavailable, please retry shortly")
+
+// mt5Breaker stops requests from queueing up behind a bridge that is not
+// answering. After threshold consecutive send failures or timeouts it opens
+// and requests fail at once. When the cooldown has passed, or the bridge
+// reconnects, it half-opens: a single request is let through as a probe and
+// its outcome closes or reopens the circuit. A threshold of zero disables it.
+type mt5Breaker struct {
+	logService LogService
+	clock      clock.Clock
+	threshold  int
+	cooldown   time.Duration
+
+	mu           sync.Mutex
+	state        string
+	failures     int
+	openedAt     time.Time
+	probing      bool
+	probeStarted time.Time
+}
+
+func newMT5Breaker(logService LogService, clk clock.Clock, cfg *config.Config) *mt5Breaker {
+	return &mt5Breaker{
+		logService: logService,
+		clock:      clk,
+		threshold:  cfg.MT5BreakerThreshold,
+		cooldown:   cfg.MT5BreakerCooldown,
+		state:      interfaces.MT5CircuitClosed,
+	}
+}
+
+// allow reports whether a request may be sent to MT5. In the half-open state
+// only one probe is outstanding at a time; a probe that never resolves stops
+// blocking others after a cooldown.
+func (b *mt5Breaker) allow() error {
+	if b.threshold <= 0 {
+		return nil
+	}
+	now := b.clock.Now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case interfaces.MT5CircuitOpen:
+		if now.Before(b.openedAt.Add(b.cooldown)) {
+			return ErrMT5Unavailable
+		}
+		b.state = interfaces.MT5CircuitHalfOpen
+	case interfaces.MT5CircuitHalfOpen:
+		if b.probing && now.Before(b.probeStarted.Add(b.cooldown)) {
+			return ErrMT5Unavailable
+		}
+	default:
+		return nil
+	}
+	b.probing = true
+	b.probeStarted = now
+	return nil
+}
+
+// success records a reply from MT5, which closes the circuit.
+func (b *mt5Breaker) success() {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.failures = 0
+	b.probing = false
+	reopened := b.state != interfaces.MT5CircuitClosed
+	b.state = interfaces.MT5CircuitClosed
+	b.mu.Unlock()
+
+	if reopened {
+		b.audit("MT5CircuitClosed", "MT5 answered again; requests are being sent", nil)
+	}
+}
+
+// failure records a request that could not be sent or was not answered in
+// time. A failed probe reopens the circuit for another cooldown.
+func (b *mt5Breaker) failure() {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.failures++
+	failures := b.failures
+	opened := b.state == interfaces.MT5CircuitHalfOpen ||
+		(b.state == interfaces.MT5CircuitClosed && b.failures >= b.threshold)
+	if opened {
+		b.state = interfaces.MT5CircuitOpen
+		b.openedAt = b.clock.Now()
+		b.probing = false
+	}
+	b.mu.Unlock()
+
+	if opened {
+		description := fmt.Sprintf("%d consecutive MT5 requests failed; rejecting requests for %s", failures, b.cooldown)
+		b.audit("MT5CircuitOpened", description, map[string]interface{}{
+			"consecutive_failures": failures,
+			"cooldown_seconds":     b.cooldown.Seconds(),
+		})
+	}
+}
+
+// halfOpen lets the next request through at once, without waiting out the
+// cooldown. It is called when the bridge reconnects.
+func (b *mt5Breaker) halfOpen() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == interfaces.MT5CircuitOpen {
+		b.state = interfaces.MT5CircuitHalfOpen
+		b.probing = false
+	}
+}
+
+func (b *mt5Breaker) snapshot() interfaces.MT5Circuit {
+	if b.threshold <= 0 {
+		return interfaces.MT5Circuit{State: interfaces.MT5CircuitDisabled}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	circuit := interfaces.MT5Circuit{
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+	}
+	if b.state != interfaces.MT5CircuitClosed {
+		openedAt := b.openedAt
+		circuit.OpenedAt = &openedAt
+	}
+	if b.state == interfaces.MT5CircuitOpen {
+		retryAt := b.openedAt.Add(b.cooldown)
+		circuit.RetryAt = &retryAt
+	}
+	return circuit
+}
+
+func (b *mt5Breaker) audit(action, description string, metadata map[string]interface{}) {
+	log.Printf("%s: %s", action, description)
+	if err := b.logService.LogAction(primitive.NilObjectID, action, description, "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/interfaces"
+	"github.com/mehrbod2002/fxtrader/internal/config"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Kinds of MT5 request whose reply is correlated by trade ID.
+const (
+	mt5RequestTrade   = "trade"
+	mt5RequestClose   = "close"
+	mt5RequestModify  = "modify"
+	mt5RequestBalance = "balance"
+	mt5RequestCancel  = "cancel"
+)
+
+type mt5KindTotals struct {
+	requests   int64
+	timeouts   int64
+	latencySum time.Duration
+	latencyMax time.Duration
+}
+
+type mt5Outcome struct {
+	at       time.Time
+	timedOut bool
+}
+
+// mt5Metrics records how long MT5 takes to answer correlated requests. When
+// the share of timeouts over the window crosses the alert threshold, and
+// again when it drops back, an audit entry is written so operators learn the
+// bridge is degraded from the log rather than from users.
+type mt5Metrics struct {
+	logService   LogService
+	alertPercent float64
+	window       time.Duration
+	minSamples   int
+
+	mu       sync.Mutex
+	kinds    map[string]*mt5KindTotals
+	recent   []mt5Outcome
+	degraded bool
+}
+
+func newMT5Metrics(logService LogService, cfg *config.Config) *mt5Metrics {
+	return &mt5Metrics{
+		logService:   logService,
+		alertPercent: cfg.MT5TimeoutAlertPercent,
+		window:       cfg.MT5TimeoutAlertWindow,
+		minSamples:   cfg.MT5TimeoutAlertMinSamples,
+		kinds:        make(map[string]*mt5KindTotals),
+	}
+}
+
+// observe records one request of the given kind that was answered after
+// latency, or that gave up waiting if timedOut.
+func (m *mt5Metrics) observe(kind string, latency time.Duration, timedOut bool) {
+	now := time.Now()
+
+	m.mu.Lock()
+	totals, ok := m.kinds[kind]
+	if !ok {
+		totals = &mt5KindTotals{}
+		m.kinds[kind] = totals
+	}
+	totals.requests++
+	if timedOut {
+		totals.timeouts++
+	} else {
+		totals.latencySum += latency
+		totals.latencyMax = max(totals.latencyMax, latency)
+	}
+
+	m.pruneLocked(now)
+	m.recent = append(m.recent, mt5Outcome{at: now, timedOut: timedOut})
+	samples, timeouts, percent := m.windowLocked()
+
+	var action string
+	switch {
+	case m.alertPercent <= 0:
+	case !m.degraded && samples >= m.minSamples && percent >= m.alertPercent:
+		m.degraded = true
+		action = "MT5Degraded"
+	case m.degraded && percent < m.alertPercent:
+		m.degraded = false
+		action = "MT5Recovered"
+	}
+	m.mu.Unlock()
+
+	if action == "" {
+		return
+	}
+	description := fmt.Sprintf("%d of %d MT5 requests timed out in the last %s (%.1f%%, threshold %.1f%%)",
+		timeouts, samples, m.window, percent, m.alertPercent)
+	log.Printf("%s: %s", action, description)
+	metadata := map[string]interface{}{
+		"window_requests": samples,
+		"window_timeouts": timeouts,
+		"timeout_percent": percent,
+		"threshold":       m.alertPercent,
+	}
+	if err := m.logService.LogAction(primitive.NilObjectID, action, description, "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+}
+
+func (m *mt5Metrics) snapshot() interfaces.MT5Metrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.pruneLocked(time.Now())
+	samples, timeouts, percent := m.windowLocked()
+	metrics := interfaces.MT5Metrics{
+		ByKind:               make(map[string]interfaces.MT5RequestStats, len(m.kinds)),
+		WindowRequests:       samples,
+		WindowTimeouts:       timeouts,
+		WindowTimeoutPercent: percent,
+		Degraded:             m.degraded,
+	}
+	for kind, totals := range m.kinds {
+		stats := interfaces.MT5RequestStats{
+			Requests:     totals.requests,
+			Timeouts:     totals.timeouts,
+			MaxLatencyMs: float64(totals.latencyMax) / float64(time.Millisecond),
+		}
+		if answered := totals.requests - totals.timeouts; answered > 0 {
+			stats.AvgLatencyMs = float64(totals.latencySum) / float64(answered) / float64(time.Millisecond)
+		}
+		metrics.ByKind[kind] = stats
+	}
+	return metrics
+}
+
+// pruneLocked drops outcomes that have left the window.
+func (m *mt5Metrics) pruneLocked(now time.Time) {
+	cutoff := now.Add(-m.window)
+	keep := 0
+	for keep < len(m.recent) && m.recent[keep].at.Before(cutoff) {
+		keep++
+	}
+	m.recent = m.recent[keep:]
+}
+
+func (m *mt5Metrics) windowLocked() (samples, timeouts int, percent float64) {
+	samples = len(m.recent)
+	for _, outcome := range m.recent {
+		if outcome.timedOut {
+			timeouts++
+		}
+	}
+	if samples > 0 {
+		percent = float64(timeouts) / float64(samples) * 100
+	}
+	return samples, timeouts, percent
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// bookEntry is a resting user-to-user limit order.
+type bookEntry struct {
+	tradeID  primitive.ObjectID
+	userID   primitive.ObjectID
+	side     models.TradeType
+	price    float64
+	volume   float64
+	placedAt time.Time
+}
+
+// symbolBook holds the resting orders for one symbol and account type. Bids
+// are kept best (highest) first and asks best (lowest) first, ties by time.
+// The database stays the source of truth: a book that is not loaded is
+// rebuilt from pending trades before it is matched against.
+type symbolBook struct {
+	mu     sync.Mutex
+	loaded bool
+	bids   []*bookEntry
+	asks   []*bookEntry
+}
+
+type orderBook struct {
+	mu    sync.Mutex
+	books map[string]*symbolBook
+}
+
+func newOrderBook() *orderBook {
+	return &orderBook{books: make(map[string]*symbolBook)}
+}
+
+func bookKey(symbol, accountType string) string {
+	return strings.ToUpper(accountType) + ":" + symbol
+}
+
+func (b *orderBook) get(symbol, accountType string) *symbolBook {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := bookKey(symbol, accountType)
+	book, ok := b.books[key]
+	if !ok {
+		book = &symbolBook{}
+		b.books[key] = book
+	}
+	return book
+}
+
+// isBookOrder reports whether a trade belongs in the internal book.
+func isBookOrder(trade *models.TradeHistory) bool {
+	return trade.ExecutionType == models.ExecutionTypeUserToUser &&
+		(trade.OrderType == "BUY_LIMIT" || trade.OrderType == "SELL_LIMIT")
+}
+
+func newBookEntry(trade *models.TradeHistory) *bookEntry {
+	return &bookEntry{
+		tradeID:  trade.ID,
+		userID:   trade.UserID,
+		side:     trade.TradeType,
+		price:    trade.EntryPrice,
+		volume:   trade.Volume,
+		placedAt: trade.OpenTime,
+	}
+}
+
+// insert must be called with b.mu held. Duplicate trade IDs are ignored.
+func (b *symbolBook) insert(e *bookEntry) {
+	if b.find(e.tradeID) != nil {
+		return
+	}
+	if e.side == models.TradeTypeBuy {
+		b.bids = append(b.bids, e)
+		sort.SliceStable(b.bids, func(i, j int) bool {
+			if b.bids[i].price != b.bids[j].price {
+				return b.bids[i].price > b.bids[j].price
+			}
+			return b.bids[i].placedAt.Before(b.bids[j].placedAt)
+		})
+		return
+	}
+	b.asks = append(b.asks, e)
+	sort.SliceStable(b.asks, func(i, j int) bool {
+		if b.asks[i].price != b.asks[j].price {
+			return b.asks[i].price < b.asks[j].price
+		}
+		return b.asks[i].placedAt.Before(b.asks[j].placedAt)
+	})
+}
+
+// find must be called with b.mu held.
+func (b *symbolBook) find(tradeID primitive.ObjectID) *bookEntry {
+	for _, side := range [][]*bookEntry{b.bids, b.asks} {
+		for _, e := range side {
+			if e.tradeID == tradeID {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// remove must be called with b.mu held.
+func (b *symbolBook) remove(tradeID primitive.ObjectID) {
+	drop := func(entries []*bookEntry) []*bookEntry {
+		for i, e := range entries {
+			if e.tradeID == tradeID {
+				return append(entries[:i], entries[i+1:]...)
+			}
+		}
+		return entries
+	}
+	b.bids = drop(b.bids)
+	b.asks = drop(b.asks)
+}
+
+// crossing returns the opposing entries an incoming order at price can fill
+// against, in priority order. Must be called with b.mu held.
+func (b *symbolBook) crossing(side models.TradeType, price float64) []*bookEntry {
+	var matches []*bookEntry
+	if side == models.TradeTypeBuy {
+		for _, e := range b.asks {
+			if e.price > price {
+				break
+			}
+			matches = append(matches, e)
+		}
+		return matches
+	}
+	for _, e := range b.bids {
+		if e.price < price {
+			break
+		}
+		matches = append(matches, e)
+	}
+	return matches
+}
+
+// reset drops every entry so the next match reloads from the database.
+// Must be called with b.mu held.
+func (b *symbolBook) reset() {
+	b.loaded = false
+	b.bids = nil
+	b.asks = nil
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/interfaces"
+	"github.com/mehrbod2002/fxtrader/internal/models"
+)
+
+// ExpirePendingOrders expires pending orders whose expiration has passed.
+// Orders resting at MT5 are cancelled there first and only expired here once
+// MT5 confirms, so the platform never releases margin for an order that is
+// still live at the broker. Orders MT5 does not confirm stay pending and are
+// tried again on the next run. Book orders are expired locally, as
+// CancelPendingOrder cancels them.
+func (s *tradeService) ExpirePendingOrders(ctx context.Context) error {
+	trades, err := s.tradeRepo.GetExpiredPendingTrades(ctx, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	for _, trade := range trades {
+		if trade.ExecutionType == models.ExecutionTypeUserToUser {
+			err = s.expireTrade(ctx, trade, "")
+		} else {
+			err = s.expireMT5Order(ctx, trade)
+		}
+		if err != nil {
+			log.Printf("Failed to expire pending order %s: %v", trade.ID.Hex(), err)
+		}
+	}
+	return nil
+}
+
+// expireMT5Order asks MT5 to cancel the order and expires it once MT5
+// confirms.
+func (s *tradeService) expireMT5Order(ctx context.Context, trade *models.TradeHistory) error {
+	response, err := s.cancelAtMT5(ctx, trade, models.CloseReasonExpired)
+	if err != nil {
+		return err
+	}
+	if !isCancelConfirmation(response.Status) {
+		// Either MT5 filled the order before the cancel reached it, and
+		// HandleTradeResponse has applied the fill, or it refused to cancel.
+		current, err := s.tradeRepo.GetTradeByID(ctx, trade.ID)
+		if err != nil {
+			return err
+		}
+		if current != nil && current.Status != string(models.TradeStatusPending) {
+			log.Printf("Pending order %s was %s before it could expire", trade.ID.Hex(), strings.ToLower(current.Status))
+			return nil
+		}
+		return fmt.Errorf("MT5 did not cancel the order: %s", response.Status)
+	}
+	return s.expireTrade(ctx, trade, response.Status)
+}
+
+// cancelAtMT5 sends a cancel_order_request for a pending order and returns
+// MT5's answer. The cancel bypasses the circuit breaker and its timeouts are
+// not counted against it: an unanswered cancel must not stop trading.
+func (s *tradeService) cancelAtMT5(ctx context.Context, trade *models.TradeHistory, reason models.CloseReason) (interfaces.TradeResponse, error) {
+	account, err := s.accountRepo.GetAccountByID(ctx, trade.AccountID)
+	if err != nil {
+		return interfaces.TradeResponse{}, err
+	}
+	if account == nil {
+		return interfaces.TradeResponse{}, ErrAccountNotFound
+	}
+
+	tradeID := trade.ID.Hex()
+	cancelRequest := map[string]interface{}{
+		"type":         "cancel_order_request",
+		"trade_id":     tradeID,
+		"user_id":      trade.UserID.Hex(),
+		"account_id":   trade.AccountID.Hex(),
+		"account_type": trade.AccountType,
+		"wallet_id":    account.WalletID,
+		"reason":       string(reason),
+		"timestamp":    time.Now().Unix(),
+	}
+
+	responseChan, release := s.awaitTradeResponse(tradeID)
+	defer release()
+	done := s.expectControlReply(tradeID, time.Now().Add(mt5ResponseTimeout))
+	defer done()
+
+	sentAt := time.Now()
+	if err := s.sendToMT5(cancelRequest); err != nil {
+		return interfaces.TradeResponse{}, fmt.Errorf("failed to send cancel order request: %w", err)
+	}
+
+	select {
+	case response := <-responseChan:
+		s.mt5Metrics.observe(mt5RequestCancel, time.Since(sentAt), false)
+		return response, nil
+	case <-time.After(mt5ResponseTimeout):
+		s.mt5Metrics.observe(mt5RequestCancel, mt5ResponseTimeout, true)
+		return interfaces.TradeResponse{}, newError(ErrTimeout, "timeout waiting for MT5 cancel order response")
+	}
+}
+
+// expireTrade moves the trade to EXPIRED and releases its margin. A fill that
+// landed first keeps the order from expiring; a partial one has already
+// reduced the volume to refund.
+func (s *tradeService) expireTrade(ctx context.Context, trade *models.TradeHistory, mt5Status string) error {
+	expired, err := s.tradeRepo.ExpirePendingTrade(ctx, trade.ID)
+	if err != nil {
+		return err
+	}
+	if !expired {
+		return nil
+	}
+	current, err := s.tradeRepo.GetTradeByID(ctx, trade.ID)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		return ErrTradeNotFound
+	}
+
+	s.removeFromBook(current)
+	s.refundTrade(ctx, current.AccountID, current.Margin())
+	s.hub.BroadcastTrade(current)
+
+	metadata := map[string]interface{}{
+		"trade_id":   current.ID.Hex(),
+		"account_id": current.AccountID.Hex(),
+		"symbol":     current.Symbol,
+		"volume":     current.Volume,
+		"expiration": current.Expiration,
+		"refunded":   current.Margin(),
+	}
+	if mt5Status != "" {
+		metadata["mt5_status"] = mt5Status
+	}
+	if err := s.logService.LogAction(current.UserID, "PendingOrderExpired", "Pending order expired", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+	return nil
+}
+
+// expectControlReply marks tradeID as having a modify or cancel request at
+// MT5 until deadline or until the returned func is called, whichever is first.
+// MT5 answers those requests with a trade_response too.
+func (s *tradeService) expectControlReply(tradeID string, deadline time.Time) func() {
+	s.controlMu.Lock()
+	s.controlReplies[tradeID] = deadline
+	s.controlMu.Unlock()
+
+	return func() {
+		s.controlMu.Lock()
+		if s.controlReplies[tradeID].Equal(deadline) {
+			delete(s.controlReplies, tradeID)
+		}
+		s.controlMu.Unlock()
+	}
+}
+
+// awaitingControlReply reports whether a modify or cancel request for tradeID
+// is still waiting for MT5's answer.
+func (s *tradeService) awaitingControlReply(tradeID string) bool {
+	s.controlMu.Lock()
+	defer s.controlMu.Unlock()
+	deadline, ok := s.controlReplies[tradeID]
+	if ok && !time.Now().Before(deadline) {
+		delete(s.controlReplies, tradeID)
+		return false
+	}
+	return ok
+}
+
+// isCancelConfirmation reports whether MT5's status says a pending order is
+// no longer resting at the broker without having been filled.
+func isCancelConfirmation(status string) bool {
+	switch strings.ToUpper(strings.TrimSpace(status)) {
+	case "CANCELLED", "CANCELED", "ORDER_STATE_CANCELED", "EXPIRED", "ORDER_STATE_EXPIRED":
+		return true
+	}
+	return false
+}
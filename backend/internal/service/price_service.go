@@ -1,39 +1,138 @@
 package service
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/interfaces"
+	"github.com/mehrbod2002/fxtrader/internal/clock"
+	"github.com/mehrbod2002/fxtrader/internal/config"
 	"github.com/mehrbod2002/fxtrader/internal/models"
 	"github.com/mehrbod2002/fxtrader/internal/repository"
 	"github.com/mehrbod2002/fxtrader/internal/ws"
 )
 
+// ErrInvalidPrice wraps the reason a tick failed PriceData.Validate.
+var ErrInvalidPrice = newError(ErrInvalidInput, "invalid price")
+
+// ErrPriceOutlier is returned for a tick that jumps too far from the last
+// accepted price of its symbol and has not yet outlasted the recovery window.
+var ErrPriceOutlier = errors.New("price deviates too far from the last accepted tick")
+
 type PriceService interface {
 	ProcessPrice(data *models.PriceData) error
+	SetTradeService(tradeService interfaces.TradeService)
+}
+
+// outlierState tracks the reference price of a symbol and, while ticks are
+// being rejected, when the first of them arrived.
+type outlierState struct {
+	lastMid       float64
+	rejectedSince time.Time
 }
 
 type priceService struct {
 	repo         repository.PriceRepository
 	hub          *ws.Hub
 	alertService AlertService
+	tradeService interfaces.TradeService
+	clock        clock.Clock
+
+	outlierPercent  float64
+	outlierRecovery time.Duration
+	outlierMu       sync.Mutex
+	outliers        map[string]*outlierState
 }
 
-func NewPriceService(repo repository.PriceRepository, hub *ws.Hub, alertService AlertService) PriceService {
+func NewPriceService(repo repository.PriceRepository, hub *ws.Hub, alertService AlertService, clk clock.Clock, cfg *config.Config) PriceService {
 	return &priceService{
-		repo:         repo,
-		hub:          hub,
-		alertService: alertService,
+		repo:            repo,
+		hub:             hub,
+		alertService:    alertService,
+		clock:           clk,
+		outlierPercent:  cfg.PriceOutlierPercent,
+		outlierRecovery: cfg.PriceOutlierRecovery,
+		outliers:        make(map[string]*outlierState),
 	}
 }
 
+func (s *priceService) SetTradeService(tradeService interfaces.TradeService) {
+	s.tradeService = tradeService
+}
+
 func (s *priceService) ProcessPrice(data *models.PriceData) error {
+	if err := data.Validate(); err != nil {
+		log.Printf("Rejected malformed tick for %q: bid=%v ask=%v: %v", data.Symbol, data.Bid, data.Ask, err)
+		return fmt.Errorf("%w: %v", ErrInvalidPrice, err)
+	}
+
+	if err := s.checkOutlier(data); err != nil {
+		return err
+	}
+
 	if err := s.repo.SavePrice(data); err != nil {
 		return err
 	}
 
 	s.hub.BroadcastPrice(data)
 
+	if s.tradeService != nil {
+		if err := s.tradeService.ActivatePendingOrders(data); err != nil {
+			log.Printf("Failed to activate pending orders for %s: %v", data.Symbol, err)
+		}
+		if err := s.tradeService.EvaluateRiskLimits(data); err != nil {
+			log.Printf("Failed to evaluate risk limits for %s: %v", data.Symbol, err)
+		}
+	}
+
 	if err := s.alertService.ProcessPriceForAlerts(data); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// checkOutlier compares the tick's mid price with the last accepted one. A
+// deviating tick is rejected until deviating ticks have kept arriving for the
+// recovery window, after which the new level is taken as a genuine gap.
+func (s *priceService) checkOutlier(data *models.PriceData) error {
+	if s.outlierPercent <= 0 {
+		return nil
+	}
+	mid := (data.Ask + data.Bid) / 2
+
+	s.outlierMu.Lock()
+	defer s.outlierMu.Unlock()
+
+	state, ok := s.outliers[data.Symbol]
+	if !ok || state.lastMid <= 0 {
+		s.outliers[data.Symbol] = &outlierState{lastMid: mid}
+		return nil
+	}
+
+	deviation := math.Abs(mid-state.lastMid) / state.lastMid * 100
+	if deviation <= s.outlierPercent {
+		state.lastMid = mid
+		state.rejectedSince = time.Time{}
+		return nil
+	}
+
+	now := s.clock.Now()
+	if state.rejectedSince.IsZero() {
+		state.rejectedSince = now
+	}
+	if now.Sub(state.rejectedSince) >= s.outlierRecovery {
+		log.Printf("Accepting %s at %.5f after %.2f%% gap persisted for %s", data.Symbol, mid, deviation, s.outlierRecovery)
+		state.lastMid = mid
+		state.rejectedSince = time.Time{}
+		return nil
+	}
+
+	log.Printf("Rejected outlier tick for %s: bid=%.5f ask=%.5f deviates %.2f%% from %.5f (limit %.2f%%)",
+		data.Symbol, data.Bid, data.Ask, deviation, state.lastMid, s.outlierPercent)
+	return ErrPriceOutlier
+}
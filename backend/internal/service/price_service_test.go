@@ -0,0 +1,44 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/internal/clock"
+	"github.com/mehrbod2002/fxtrader/internal/models"
+)
+
+func TestCheckOutlierRecovery(t *testing.T) {
+	clk := clock.NewManual(time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC))
+	s := &priceService{
+		clock:           clk,
+		outlierPercent:  1,
+		outlierRecovery: 10 * time.Second,
+		outliers:        make(map[string]*outlierState),
+	}
+	tick := func(mid float64) error {
+		return s.checkOutlier(&models.PriceData{Symbol: "EURUSD", Bid: mid, Ask: mid})
+	}
+
+	if err := tick(1.1); err != nil {
+		t.Fatalf("first tick: %v", err)
+	}
+	if err := tick(1.105); err != nil {
+		t.Fatalf("tick within the limit: %v", err)
+	}
+	if err := tick(1.2); !errors.Is(err, ErrPriceOutlier) {
+		t.Fatalf("gap tick: got %v, want ErrPriceOutlier", err)
+	}
+	clk.Advance(9 * time.Second)
+	if err := tick(1.2); !errors.Is(err, ErrPriceOutlier) {
+		t.Fatalf("gap tick inside the recovery window: got %v, want ErrPriceOutlier", err)
+	}
+	clk.Advance(time.Second)
+	if err := tick(1.2); err != nil {
+		t.Fatalf("gap tick after the recovery window: %v", err)
+	}
+	if err := tick(1.201); err != nil {
+		t.Fatalf("tick at the new level: %v", err)
+	}
+}
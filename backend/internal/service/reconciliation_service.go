@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/interfaces"
+	"github.com/mehrbod2002/fxtrader/internal/config"
+	"github.com/mehrbod2002/fxtrader/internal/models"
+	"github.com/mehrbod2002/fxtrader/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// reconcileWorkers bounds how many balance requests are outstanding with MT5
+// at once during a reconciliation run.
+const reconcileWorkers = 4
+
+// ReconciliationFailure is an account whose broker balance could not be
+// fetched during a run.
+type ReconciliationFailure struct {
+	AccountID string `json:"account_id"`
+	Error     string `json:"error"`
+}
+
+// BalanceReconciliationReport summarises one reconciliation run. Mismatched
+// counts every discrepancy recorded; Flagged is the subset above the review
+// threshold.
+type BalanceReconciliationReport struct {
+	StartedAt  time.Time               `json:"started_at"`
+	FinishedAt time.Time               `json:"finished_at"`
+	Checked    int                     `json:"checked"`
+	Matched    int                     `json:"matched"`
+	Mismatched int                     `json:"mismatched"`
+	Flagged    int                     `json:"flagged"`
+	Failed     int                     `json:"failed"`
+	Failures   []ReconciliationFailure `json:"failures"`
+}
+
+type ReconciliationService interface {
+	ReconcileBalances(ctx context.Context) (*BalanceReconciliationReport, error)
+	GetDiscrepancies(ctx context.Context, flaggedOnly, openOnly bool, page, limit int) ([]*models.BalanceDiscrepancy, int64, error)
+	ReviewDiscrepancy(ctx context.Context, id, note string) (*models.BalanceDiscrepancy, error)
+}
+
+type reconciliationService struct {
+	accountRepo     repository.AccountRepository
+	discrepancyRepo repository.BalanceDiscrepancyRepository
+	tradeService    interfaces.TradeService
+	logService      LogService
+	tolerance       float64
+	flagThreshold   float64
+	running         sync.Mutex
+}
+
+func NewReconciliationService(accountRepo repository.AccountRepository, discrepancyRepo repository.BalanceDiscrepancyRepository, tradeService interfaces.TradeService, logService LogService, cfg *config.Config) ReconciliationService {
+	return &reconciliationService{
+		accountRepo:     accountRepo,
+		discrepancyRepo: discrepancyRepo,
+		tradeService:    tradeService,
+		logService:      logService,
+		tolerance:       cfg.ReconcileTolerance,
+		flagThreshold:   cfg.ReconcileFlagThreshold,
+	}
+}
+
+// ReconcileBalances asks MT5 for the balance of every account with a
+// registered wallet and records each one that differs from the platform by
+// more than the tolerance. Balances are never corrected here; differences
+// above the flag threshold wait for an admin to review them. Only one run
+// happens at a time.
+func (s *reconciliationService) ReconcileBalances(ctx context.Context) (*BalanceReconciliationReport, error) {
+	if !s.running.TryLock() {
+		return nil, newError(ErrConflict, "a balance reconciliation is already running")
+	}
+	defer s.running.Unlock()
+
+	accounts, err := s.accountRepo.GetAccountsWithWallet(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &BalanceReconciliationReport{
+		StartedAt: time.Now(),
+		Failures:  []ReconciliationFailure{},
+	}
+	var mu sync.Mutex
+	jobs := make(chan *models.Account)
+	var wg sync.WaitGroup
+	for i := 0; i < reconcileWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for account := range jobs {
+				discrepancy, err := s.reconcileAccount(ctx, account)
+
+				mu.Lock()
+				report.Checked++
+				switch {
+				case err != nil:
+					report.Failed++
+					report.Failures = append(report.Failures, ReconciliationFailure{AccountID: account.ID.Hex(), Error: err.Error()})
+				case discrepancy == nil:
+					report.Matched++
+				default:
+					report.Mismatched++
+					if discrepancy.Flagged {
+						report.Flagged++
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, account := range accounts {
+		jobs <- account
+	}
+	close(jobs)
+	wg.Wait()
+	report.FinishedAt = time.Now()
+
+	metadata := map[string]interface{}{
+		"checked":    report.Checked,
+		"matched":    report.Matched,
+		"mismatched": report.Mismatched,
+		"flagged":    report.Flagged,
+		"failed":     report.Failed,
+	}
+	if err := s.logService.LogAction(primitive.NilObjectID, "BalanceReconciliation", "Platform balances reconciled against MT5", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+	return report, nil
+}
+
+// reconcileAccount returns the discrepancy it recorded, or nil when the
+// balances agree. The platform balance is read after MT5 answers so that the
+// two are as close in time as possible.
+func (s *reconciliationService) reconcileAccount(ctx context.Context, account *models.Account) (*models.BalanceDiscrepancy, error) {
+	brokerBalance, err := s.tradeService.RequestBalance(account.UserID.Hex(), account.ID.Hex(), account.AccountType)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.accountRepo.GetAccountByID(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, ErrAccountNotFound
+	}
+
+	difference := current.Balance - brokerBalance
+	if math.Abs(difference) <= s.tolerance {
+		return nil, nil
+	}
+
+	discrepancy := &models.BalanceDiscrepancy{
+		AccountID:       current.ID,
+		UserID:          current.UserID,
+		AccountName:     current.AccountName,
+		AccountType:     models.NormalizeAccountType(current.AccountType),
+		WalletID:        current.WalletID,
+		PlatformBalance: current.Balance,
+		BrokerBalance:   brokerBalance,
+		Difference:      difference,
+		Flagged:         math.Abs(difference) > s.flagThreshold,
+		CheckedAt:       time.Now(),
+	}
+	if err := s.discrepancyRepo.SaveDiscrepancy(ctx, discrepancy); err != nil {
+		return nil, err
+	}
+
+	if discrepancy.Flagged {
+		metadata := map[string]interface{}{
+			"discrepancy_id":   discrepancy.ID.Hex(),
+			"account_id":       current.ID.Hex(),
+			"platform_balance": current.Balance,
+			"broker_balance":   brokerBalance,
+			"difference":       difference,
+		}
+		if err := s.logService.LogAction(current.UserID, "BalanceDiscrepancyFlagged", "Platform balance differs from MT5 beyond the review threshold", "", metadata); err != nil {
+			log.Printf("error: %v", err)
+		}
+	}
+	return discrepancy, nil
+}
+
+func (s *reconciliationService) GetDiscrepancies(ctx context.Context, flaggedOnly, openOnly bool, page, limit int) ([]*models.BalanceDiscrepancy, int64, error) {
+	return s.discrepancyRepo.GetDiscrepancies(ctx, flaggedOnly, openOnly, page, limit)
+}
+
+// ReviewDiscrepancy closes a discrepancy with the admin's note. Any balance
+// correction is made separately, through the usual transaction flow.
+func (s *reconciliationService) ReviewDiscrepancy(ctx context.Context, id, note string) (*models.BalanceDiscrepancy, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, newError(ErrInvalidInput, "invalid discrepancy ID")
+	}
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return nil, newError(ErrInvalidInput, "a review note is required")
+	}
+
+	discrepancy, err := s.discrepancyRepo.MarkReviewed(ctx, objID, note, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if discrepancy == nil {
+		return nil, newError(ErrNotFound, "discrepancy not found")
+	}
+
+	metadata := map[string]interface{}{
+		"discrepancy_id": id,
+		"account_id":     discrepancy.AccountID.Hex(),
+		"note":           note,
+	}
+	if err := s.logService.LogAction(discrepancy.UserID, "BalanceDiscrepancyReviewed", "Balance discrepancy reviewed by admin", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+	return discrepancy, nil
+}
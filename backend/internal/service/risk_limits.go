@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// riskCheckInterval spaces out risk evaluations per symbol so a fast feed
+// doesn't query every holder's positions on each tick.
+const riskCheckInterval = time.Second
+
+// EvaluateRiskLimits checks the daily loss limit and profit target of every
+// account holding an open position in the ticked symbol. Accounts that breach
+// a limit have all their positions closed in the background.
+func (s *tradeService) EvaluateRiskLimits(price *models.PriceData) error {
+	ctx := context.Background()
+
+	now := s.clock.Now()
+	s.riskCheckMu.Lock()
+	if now.Sub(s.riskCheckedAt[price.Symbol]) < riskCheckInterval {
+		s.riskCheckMu.Unlock()
+		return nil
+	}
+	s.riskCheckedAt[price.Symbol] = now
+	s.riskCheckMu.Unlock()
+
+	trades, err := s.tradeRepo.GetOpenTradesBySymbol(ctx, price.Symbol)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[primitive.ObjectID]bool)
+	for _, trade := range trades {
+		if seen[trade.AccountID] {
+			continue
+		}
+		seen[trade.AccountID] = true
+
+		account, err := s.accountRepo.GetAccountByID(ctx, trade.AccountID)
+		if err != nil || account == nil {
+			continue
+		}
+		if !account.Enabled() || account.RiskTriggeredToday(now) {
+			continue
+		}
+
+		pnl, positions, err := s.dailyProfit(ctx, account, price, now)
+		if err != nil {
+			log.Printf("Failed to compute daily P/L for account %s: %v", account.ID.Hex(), err)
+			continue
+		}
+		if !account.Breached(pnl) {
+			continue
+		}
+
+		marked, err := s.accountRepo.MarkRiskTriggered(ctx, account.ID, now)
+		if err != nil || !marked {
+			continue
+		}
+
+		metadata := map[string]interface{}{
+			"account_id":          account.ID.Hex(),
+			"daily_pnl":           pnl,
+			"daily_loss_limit":    account.DailyLossLimit,
+			"daily_profit_target": account.DailyProfitTarget,
+			"positions":           len(positions),
+			"block_new_trades":    account.BlockAfterTrigger,
+		}
+		if err := s.logService.LogAction(account.UserID, "RiskLimitTriggered", "Account daily risk limit reached, closing positions", "", metadata); err != nil {
+			log.Printf("error: %v", err)
+		}
+
+		go s.closePositions(account.UserID, positions)
+	}
+	return nil
+}
+
+// dailyProfit is the account's realized P/L since UTC midnight plus the
+// floating P/L of its open positions, marked at the latest tick of each
+// symbol. Positions without a known price contribute nothing.
+func (s *tradeService) dailyProfit(ctx context.Context, account *models.Account, tick *models.PriceData, now time.Time) (float64, []*models.TradeHistory, error) {
+	realized, err := s.tradeRepo.GetRealizedProfitSince(ctx, account.ID, models.StartOfDay(now))
+	if err != nil {
+		return 0, nil, err
+	}
+	positions, err := s.tradeRepo.GetOpenTradesByAccount(ctx, account.ID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	floating := 0.0
+	for _, position := range positions {
+		price := tick
+		if position.Symbol != tick.Symbol {
+			last, ok := s.hub.LastPrice(position.Symbol)
+			if !ok {
+				continue
+			}
+			price = last
+		}
+		_, pnl := position.MarkToMarket(price)
+		floating += pnl
+	}
+	return realized + floating, positions, nil
+}
+
+func (s *tradeService) closePositions(userID primitive.ObjectID, positions []*models.TradeHistory) {
+	for _, position := range positions {
+		if _, err := s.CloseTrade(position.ID.Hex(), userID.Hex()); err != nil {
+			log.Printf("Failed to close trade %s after risk limit: %v", position.ID.Hex(), err)
+		}
+	}
+}
+
+// CooldownError is returned while an account is paused after a losing streak.
+type CooldownError struct {
+	Losses    int
+	Remaining time.Duration
+}
+
+func (e *CooldownError) Error() string {
+	return fmt.Sprintf("trading paused after %d consecutive losses; try again in %s", e.Losses, e.Remaining.Round(time.Second))
+}
+
+// lossStreak tracks the close times of an account's current run of losing
+// trades and, once the run triggers a cooldown, when trading resumes.
+type lossStreak struct {
+	losses      []time.Time
+	lockedUntil time.Time
+	lockedAfter int
+}
+
+// recordCloseOutcome updates the account's losing streak with a trade that
+// just closed for net, starting a cooldown when the streak reaches the
+// account's LossStreakLimit.
+func (s *tradeService) recordCloseOutcome(ctx context.Context, trade *models.TradeHistory, net float64) {
+	account, err := s.accountRepo.GetAccountByID(ctx, trade.AccountID)
+	if err != nil || account == nil || account.LossStreakLimit <= 0 {
+		return
+	}
+
+	closedAt := s.clock.Now()
+	if trade.CloseTime != nil {
+		closedAt = *trade.CloseTime
+	}
+
+	s.lossStreakMu.Lock()
+	defer s.lossStreakMu.Unlock()
+
+	streak, ok := s.lossStreaks[account.ID]
+	if !ok {
+		streak = &lossStreak{}
+		s.lossStreaks[account.ID] = streak
+	}
+	if net >= 0 {
+		streak.losses = nil
+		return
+	}
+
+	streak.losses = append(streak.losses, closedAt)
+	if account.LossStreakWindowMinutes > 0 {
+		cutoff := closedAt.Add(-time.Duration(account.LossStreakWindowMinutes) * time.Minute)
+		for len(streak.losses) > 0 && streak.losses[0].Before(cutoff) {
+			streak.losses = streak.losses[1:]
+		}
+	}
+	if len(streak.losses) < account.LossStreakLimit {
+		return
+	}
+
+	streak.lockedUntil = s.clock.Now().Add(time.Duration(account.LossCooldownMinutes) * time.Minute)
+	streak.lockedAfter = len(streak.losses)
+	streak.losses = nil
+
+	metadata := map[string]interface{}{
+		"account_id":     account.ID.Hex(),
+		"losses":         streak.lockedAfter,
+		"cooldown_until": streak.lockedUntil,
+	}
+	if err := s.logService.LogAction(account.UserID, "LossCooldownStarted", "Trading paused after consecutive losses", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+}
+
+// checkLossCooldown returns a CooldownError while the account is paused.
+func (s *tradeService) checkLossCooldown(accountID primitive.ObjectID) error {
+	s.lossStreakMu.Lock()
+	defer s.lossStreakMu.Unlock()
+
+	streak, ok := s.lossStreaks[accountID]
+	if !ok {
+		return nil
+	}
+	remaining := streak.lockedUntil.Sub(s.clock.Now())
+	if remaining <= 0 {
+		return nil
+	}
+	return &CooldownError{Losses: streak.lockedAfter, Remaining: remaining}
+}
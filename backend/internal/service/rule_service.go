@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"github.com/mehrbod2002/fxtrader/internal/models"
 	"github.com/mehrbod2002/fxtrader/internal/repository"
 
@@ -8,11 +9,11 @@ import (
 )
 
 type RuleService interface {
-	CreateRule(rule *models.Rule) error
-	GetRule(id string) (*models.Rule, error)
-	GetAllRules() ([]*models.Rule, error)
-	UpdateRule(id string, rule *models.Rule) error
-	DeleteRule(id string) error
+	CreateRule(ctx context.Context, rule *models.Rule) error
+	GetRule(ctx context.Context, id string) (*models.Rule, error)
+	GetAllRules(ctx context.Context) ([]*models.Rule, error)
+	UpdateRule(ctx context.Context, id string, rule *models.Rule) error
+	DeleteRule(ctx context.Context, id string) error
 }
 
 type ruleService struct {
@@ -23,34 +24,34 @@ func NewRuleService(ruleRepo repository.RuleRepository) RuleService {
 	return &ruleService{ruleRepo: ruleRepo}
 }
 
-func (s *ruleService) CreateRule(rule *models.Rule) error {
-	return s.ruleRepo.SaveRule(rule)
+func (s *ruleService) CreateRule(ctx context.Context, rule *models.Rule) error {
+	return s.ruleRepo.SaveRule(ctx, rule)
 }
 
-func (s *ruleService) GetRule(id string) (*models.Rule, error) {
+func (s *ruleService) GetRule(ctx context.Context, id string) (*models.Rule, error) {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, err
 	}
-	return s.ruleRepo.GetRuleByID(objID)
+	return s.ruleRepo.GetRuleByID(ctx, objID)
 }
 
-func (s *ruleService) GetAllRules() ([]*models.Rule, error) {
-	return s.ruleRepo.GetAllRules()
+func (s *ruleService) GetAllRules(ctx context.Context) ([]*models.Rule, error) {
+	return s.ruleRepo.GetAllRules(ctx)
 }
 
-func (s *ruleService) UpdateRule(id string, rule *models.Rule) error {
+func (s *ruleService) UpdateRule(ctx context.Context, id string, rule *models.Rule) error {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
-	return s.ruleRepo.UpdateRule(objID, rule)
+	return s.ruleRepo.UpdateRule(ctx, objID, rule)
 }
 
-func (s *ruleService) DeleteRule(id string) error {
+func (s *ruleService) DeleteRule(ctx context.Context, id string) error {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
-	return s.ruleRepo.DeleteRule(objID)
+	return s.ruleRepo.DeleteRule(ctx, objID)
 }
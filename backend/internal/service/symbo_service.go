@@ -1,18 +1,34 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
 	"github.com/mehrbod2002/fxtrader/internal/models"
 	"github.com/mehrbod2002/fxtrader/internal/repository"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ErrSymbolNotFound = newError(ErrNotFound, "symbol not found")
+
+// Bounds on a symbol search so a picker never pulls the whole catalogue.
+const (
+	maxSymbolSearchQuery = 32
+	maxSymbolSearchLimit = 50
 )
 
 type SymbolService interface {
-	CreateSymbol(symbol *models.Symbol) error
-	GetSymbol(id string) (*models.Symbol, error)
-	GetAllSymbols() ([]*models.Symbol, error)
-	UpdateSymbol(id string, symbol *models.Symbol) error
-	DeleteSymbol(id string) error
+	CreateSymbol(ctx context.Context, symbol *models.Symbol) error
+	GetSymbol(ctx context.Context, id string) (*models.Symbol, error)
+	GetAllSymbols(ctx context.Context) ([]*models.Symbol, error)
+	SearchSymbols(ctx context.Context, query string, limit int) ([]*models.Symbol, error)
+	UpdateSymbol(ctx context.Context, id string, symbol *models.Symbol) error
+	DeleteSymbol(ctx context.Context, id string) error
+	SetNewsHalt(ctx context.Context, id string, halt *models.NewsHalt) error
 }
 
 type symbolService struct {
@@ -23,34 +39,81 @@ func NewSymbolService(symbolRepo repository.SymbolRepository) SymbolService {
 	return &symbolService{symbolRepo: symbolRepo}
 }
 
-func (s *symbolService) CreateSymbol(symbol *models.Symbol) error {
-	return s.symbolRepo.SaveSymbol(symbol)
+func (s *symbolService) CreateSymbol(ctx context.Context, symbol *models.Symbol) error {
+	return s.symbolRepo.SaveSymbol(ctx, symbol)
 }
 
-func (s *symbolService) GetSymbol(id string) (*models.Symbol, error) {
+func (s *symbolService) GetSymbol(ctx context.Context, id string) (*models.Symbol, error) {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, err
 	}
-	return s.symbolRepo.GetSymbolByID(objID)
+	return s.symbolRepo.GetSymbolByID(ctx, objID)
+}
+
+func (s *symbolService) GetAllSymbols(ctx context.Context) ([]*models.Symbol, error) {
+	return s.symbolRepo.GetAllSymbols(ctx)
+}
+
+// SearchSymbols matches query against symbol and display names, ignoring
+// case. Symbols whose name starts with the query are listed before those that
+// merely contain it.
+func (s *symbolService) SearchSymbols(ctx context.Context, query string, limit int) ([]*models.Symbol, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, newError(ErrInvalidInput, "search query is required")
+	}
+	if len(query) > maxSymbolSearchQuery {
+		return nil, newError(ErrInvalidInput, "search query must be at most %d characters", maxSymbolSearchQuery)
+	}
+	if limit < 1 || limit > maxSymbolSearchLimit {
+		return nil, newError(ErrInvalidInput, "limit must be between 1 and %d", maxSymbolSearchLimit)
+	}
+
+	symbols, err := s.symbolRepo.SearchSymbols(ctx, query, int64(limit))
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.ToUpper(query)
+	sort.SliceStable(symbols, func(i, j int) bool {
+		return strings.HasPrefix(strings.ToUpper(symbols[i].SymbolName), prefix) &&
+			!strings.HasPrefix(strings.ToUpper(symbols[j].SymbolName), prefix)
+	})
+	return symbols, nil
 }
 
-func (s *symbolService) GetAllSymbols() ([]*models.Symbol, error) {
-	return s.symbolRepo.GetAllSymbols()
+func (s *symbolService) UpdateSymbol(ctx context.Context, id string, symbol *models.Symbol) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	return s.symbolRepo.UpdateSymbol(ctx, objID, symbol)
 }
 
-func (s *symbolService) UpdateSymbol(id string, symbol *models.Symbol) error {
+func (s *symbolService) DeleteSymbol(ctx context.Context, id string) error {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
-	return s.symbolRepo.UpdateSymbol(objID, symbol)
+	return s.symbolRepo.DeleteSymbol(ctx, objID)
 }
 
-func (s *symbolService) DeleteSymbol(id string) error {
+// SetNewsHalt schedules a news window on the symbol; a nil halt clears it.
+func (s *symbolService) SetNewsHalt(ctx context.Context, id string, halt *models.NewsHalt) error {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return err
 	}
-	return s.symbolRepo.DeleteSymbol(objID)
+	if halt != nil {
+		if err := halt.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := s.symbolRepo.SetNewsHalt(ctx, objID, halt); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrSymbolNotFound
+		}
+		return err
+	}
+	return nil
 }
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"strconv"
 
@@ -37,7 +38,7 @@ func (s *telegramService) SendMessage(userID, message string) error {
 		return errors.New("message cannot be empty")
 	}
 
-	user, err := s.userService.GetUser(userID)
+	user, err := s.userService.GetUser(context.Background(), userID)
 	if err != nil || user == nil {
 		return errors.New("user not found")
 	}
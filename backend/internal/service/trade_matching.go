@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// splitFill opens volume of trade at price against counterpartyID. A full fill
+// updates trade in place; a partial fill returns a new OPEN trade for the filled
+// part and leaves the remainder on trade. Either way trade.FilledVolume tracks
+// how much of the original order has filled.
+func splitFill(trade *models.TradeHistory, volume, price float64, counterpartyID string) *models.TradeHistory {
+	trade.FilledVolume = roundVolume(trade.FilledVolume + math.Min(volume, trade.Volume))
+	if volume >= trade.Volume {
+		trade.Status = string(models.TradeStatusOpen)
+		trade.EntryPrice = price
+		trade.MatchedTradeID = counterpartyID
+		return trade
+	}
+
+	fill := *trade
+	fill.ID = primitive.NewObjectID()
+	fill.Volume = volume
+	fill.RequestedVolume = volume
+	fill.FilledVolume = volume
+	fill.EntryPrice = price
+	fill.Status = string(models.TradeStatusOpen)
+	fill.MatchedTradeID = counterpartyID
+	fill.OpenTime = time.Now()
+	fill.Expiration = nil
+	// The order's commission is charged once, so the first fill carries it.
+	trade.Commission = 0
+	trade.Volume = roundVolume(trade.Volume - volume)
+	return &fill
+}
+
+// roundVolume trims float noise left over from subtracting lot sizes.
+func roundVolume(v float64) float64 {
+	return math.Round(v*1e8) / 1e8
+}
+
+func (s *tradeService) loadBook(ctx context.Context, book *symbolBook, symbol, accountType string) error {
+	trades, err := s.tradeRepo.GetPendingTradesBySymbol(ctx, symbol, models.ExecutionTypeUserToUser)
+	if err != nil {
+		return err
+	}
+	now := s.clock.Now()
+	for _, trade := range trades {
+		if !models.SameAccountType(trade.AccountType, accountType) || !isBookOrder(trade) {
+			continue
+		}
+		if trade.Expiration != nil && trade.Expiration.Before(now) {
+			continue
+		}
+		book.insert(newBookEntry(trade))
+	}
+	book.loaded = true
+	return nil
+}
+
+// matchInternally fills the incoming limit order against opposing resting
+// orders from other users, best price first, at the resting order's price.
+// It returns the taker-side fills and the volume left for MT5.
+func (s *tradeService) matchInternally(ctx context.Context, trade *models.TradeHistory) ([]*models.TradeHistory, float64, error) {
+	remaining := trade.Volume
+	book := s.book.get(trade.Symbol, trade.AccountType)
+	book.mu.Lock()
+	defer book.mu.Unlock()
+
+	if !book.loaded {
+		if err := s.loadBook(ctx, book, trade.Symbol, trade.AccountType); err != nil {
+			return nil, remaining, err
+		}
+	}
+
+	var fills []*models.TradeHistory
+	for _, maker := range book.crossing(trade.TradeType, trade.EntryPrice) {
+		if remaining <= 0 {
+			break
+		}
+		if maker.userID == trade.UserID {
+			continue
+		}
+
+		volume := math.Min(remaining, maker.volume)
+		claimed, err := s.tradeRepo.FillPendingTrade(ctx, maker.tradeID, maker.volume, volume, trade.ID.Hex())
+		if err != nil {
+			return fills, remaining, err
+		}
+		if !claimed {
+			// The resting order changed elsewhere; rebuild the book next time.
+			book.reset()
+			break
+		}
+
+		if err := s.settleMakerFill(ctx, maker, volume, trade.ID.Hex()); err != nil {
+			log.Printf("Failed to settle resting order %s: %v", maker.tradeID.Hex(), err)
+		}
+		if maker.volume = roundVolume(maker.volume - volume); maker.volume <= 0 {
+			book.remove(maker.tradeID)
+		}
+
+		fills = append(fills, splitFill(trade, volume, maker.price, maker.tradeID.Hex()))
+		remaining = roundVolume(remaining - volume)
+	}
+	return fills, remaining, nil
+}
+
+// settleTakerFills persists the taker side of internal fills and settles the
+// margin difference from filling at the resting price instead of the limit.
+func (s *tradeService) settleTakerFills(ctx context.Context, trade *models.TradeHistory, fills []*models.TradeHistory, limitPrice float64) {
+	var marginDelta float64
+	for _, fill := range fills {
+		marginDelta += fill.MarginFor(fill.Volume, limitPrice-fill.EntryPrice)
+		if fill == trade {
+			continue
+		}
+		if err := s.tradeRepo.SaveTrade(ctx, fill); err != nil {
+			log.Printf("Failed to save internal fill %s: %v", fill.ID.Hex(), err)
+			continue
+		}
+		s.hub.BroadcastTrade(fill)
+	}
+	if marginDelta != 0 {
+		s.refundTrade(ctx, trade.AccountID, marginDelta)
+	}
+}
+
+// settleMakerFill records the maker side of an internal fill and tells MT5 to
+// shrink or cancel its copy of the resting order.
+func (s *tradeService) settleMakerFill(ctx context.Context, maker *bookEntry, volume float64, takerTradeID string) error {
+	resting, err := s.tradeRepo.GetTradeByID(ctx, maker.tradeID)
+	if err != nil {
+		return err
+	}
+	if resting == nil {
+		return fmt.Errorf("resting trade %s not found", maker.tradeID.Hex())
+	}
+
+	request := map[string]interface{}{
+		"trade_id":     resting.ID.Hex(),
+		"user_id":      resting.UserID.Hex(),
+		"account_id":   resting.AccountID.Hex(),
+		"account_type": resting.AccountType,
+	}
+
+	filled := resting
+	if resting.Status == string(models.TradeStatusPending) {
+		// Partial fill: the repository already reduced the resting volume.
+		resting.Volume = maker.volume
+		filled = splitFill(resting, volume, maker.price, takerTradeID)
+		if err := s.tradeRepo.SaveTrade(ctx, filled); err != nil {
+			return err
+		}
+		request["type"] = "modify_trade_request"
+		request["entry_price"] = resting.EntryPrice
+		request["volume"] = roundVolume(maker.volume - volume)
+	} else {
+		request["type"] = "cancel_order_request"
+	}
+
+	// Nobody waits for the answer; marking it keeps HandleTradeResponse from
+	// settling the order on a failed update.
+	s.expectControlReply(resting.ID.Hex(), time.Now().Add(mt5ModifyTimeout))
+	if err := s.sendToMT5(request); err != nil {
+		log.Printf("Failed to update MT5 order %s after internal fill: %v", resting.ID.Hex(), err)
+	}
+
+	metadata := map[string]interface{}{
+		"trade_id":         filled.ID.Hex(),
+		"resting_trade_id": resting.ID.Hex(),
+		"matched_trade_id": takerTradeID,
+		"volume":           volume,
+		"price":            maker.price,
+	}
+	if err := s.logService.LogAction(resting.UserID, "InternalFill", "Resting order filled against another user", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+	s.hub.BroadcastTrade(filled)
+	return nil
+}
+
+// addToBook rests a pending user-to-user limit order so later orders can
+// match it. Books that are not loaded pick it up on their next rebuild.
+func (s *tradeService) addToBook(trade *models.TradeHistory) {
+	if !isBookOrder(trade) || trade.Status != string(models.TradeStatusPending) {
+		return
+	}
+	book := s.book.get(trade.Symbol, trade.AccountType)
+	book.mu.Lock()
+	defer book.mu.Unlock()
+	if book.loaded {
+		book.insert(newBookEntry(trade))
+	}
+}
+
+func (s *tradeService) removeFromBook(trade *models.TradeHistory) {
+	book := s.book.get(trade.Symbol, trade.AccountType)
+	book.mu.Lock()
+	defer book.mu.Unlock()
+	book.remove(trade.ID)
+}
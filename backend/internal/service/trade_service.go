@@ -5,16 +5,22 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"math"
 	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/mehrbod2002/fxtrader/interfaces"
+	"github.com/mehrbod2002/fxtrader/internal/clock"
+	"github.com/mehrbod2002/fxtrader/internal/config"
 	"github.com/mehrbod2002/fxtrader/internal/constants"
 	"github.com/mehrbod2002/fxtrader/internal/models"
 	"github.com/mehrbod2002/fxtrader/internal/repository"
-	"github.com/mehrbod2002/fxtrader/internal/socket"
 	"github.com/mehrbod2002/fxtrader/internal/ws"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -25,6 +31,103 @@ const (
 	mt5ReconnectMaxAttempts    = 5
 )
 
+const maxBatchOrders = 20
+
+// commissionVolumeWindow is how far back traded volume counts towards a
+// symbol's commission tiers.
+const commissionVolumeWindow = 30 * 24 * time.Hour
+
+const volumeCacheTTL = time.Minute
+
+// How long to wait for MT5 to answer a trade or close request, and a modify.
+// They are variables only so tests can shorten them.
+var (
+	mt5ResponseTimeout = 30 * time.Second
+	mt5ModifyTimeout   = 10 * time.Second
+)
+
+type cachedVolume struct {
+	volume float64
+	at     time.Time
+}
+
+var ErrTooManyInFlightTrades = errors.New("too many trades awaiting execution, please retry shortly")
+
+var (
+	ErrInvalidTradeID = newError(ErrInvalidInput, "invalid trade ID")
+	ErrTradeNotFound  = newError(ErrNotFound, "trade not found")
+	ErrTradeForbidden = newError(ErrForbidden, "trade belongs to another user or account")
+)
+
+// RequoteError is returned when MT5 refuses a MARKET order because the price
+// moved and offers Price instead. The order keeps its reservation and can be
+// resubmitted with ConfirmRequote until ExpiresAt.
+type RequoteError struct {
+	TradeID   string
+	Price     float64
+	ExpiresAt time.Time
+}
+
+func (e *RequoteError) Error() string {
+	return fmt.Sprintf("price moved: requoted at %v, confirm before %s", e.Price, e.ExpiresAt.UTC().Format(time.RFC3339))
+}
+
+// isRequote reports whether retcode is MT5 refusing an order because the
+// price moved: TRADE_RETCODE_REQUOTE or TRADE_RETCODE_PRICE_CHANGED.
+func isRequote(retcode int) bool {
+	return retcode == 10004 || retcode == 10020
+}
+
+// StopsLevelError is returned when a stop loss or take profit is closer to
+// the reference price than the symbol's stops level allows.
+type StopsLevelError struct {
+	Field       string
+	Distance    float64
+	MinDistance float64
+}
+
+func (e *StopsLevelError) Error() string {
+	return fmt.Sprintf("%s is %v from the price; the minimum distance is %v", e.Field, e.Distance, e.MinDistance)
+}
+
+func (e *StopsLevelError) Unwrap() error {
+	return ErrInvalidInput
+}
+
+// checkStopsLevel validates stop loss and take profit against the symbol's
+// minimum distance from reference. Unset levels are skipped.
+func checkStopsLevel(symbol *models.Symbol, reference, stopLoss, takeProfit float64) error {
+	minDistance := symbol.MinStopDistance()
+	if minDistance <= 0 || reference <= 0 {
+		return nil
+	}
+	levels := []struct {
+		name  string
+		value float64
+	}{{"stop loss", stopLoss}, {"take profit", takeProfit}}
+	for _, level := range levels {
+		if level.value <= 0 {
+			continue
+		}
+		distance := symbol.RoundPrice(math.Abs(reference - level.value))
+		if distance < minDistance-1e-9 {
+			return &StopsLevelError{Field: level.name, Distance: distance, MinDistance: minDistance}
+		}
+	}
+	return nil
+}
+
+// PositionLimitError is returned when an account already holds the maximum
+// number of open trades allowed on a symbol.
+type PositionLimitError struct {
+	Symbol string
+	Limit  int
+}
+
+func (e *PositionLimitError) Error() string {
+	return fmt.Sprintf("open trade limit of %d reached for %s on this account", e.Limit, e.Symbol)
+}
+
 type tradeService struct {
 	tradeRepo           repository.TradeRepository
 	symbolRepo          repository.SymbolRepository
@@ -34,15 +137,43 @@ type tradeService struct {
 	mt5Conn             *websocket.Conn
 	mt5ConnMu           sync.Mutex
 	responseChan        chan interface{}
-	balanceChan         chan interfaces.BalanceResponse
+	balanceWaiters      map[string][]balanceWaiter
+	brokerBalances      map[string]brokerBalance
+	brokerBalanceMu     sync.Mutex
+	balanceRefreshTTL   time.Duration
+	balanceWaitersMu    sync.Mutex
 	hub                 *ws.Hub
-	socketServer        *socket.WebSocketServer
-	copyTradeService    CopyTradeService
+	socketServer        interfaces.MT5Transport
+	copyTradeService    interfaces.TradeMirror
+	events              interfaces.EventPublisher
+	transactor          repository.Transactor
+	currencyService     CurrencyService
+	clock               clock.Clock
 	tradeResponseChans  map[string]chan interfaces.TradeResponse
 	tradeResponseMu     sync.Mutex
-	streamCtx           map[string]context.CancelFunc
-	ordersResponseChans map[string]chan models.OrderStreamResponse
+	orderStreams        map[string]*orderStream
 	ordersResponseMu    sync.Mutex
+	streamLifetime      time.Duration
+	streamIdleTimeout   time.Duration
+	inFlightTrades      atomic.Int64
+	maxInFlightTrades   int64
+	maxOpenPerSymbol    int
+	demoCommissionRate  float64
+	demoSpreadRate      float64
+	book                *orderBook
+	volumeCache         map[primitive.ObjectID]cachedVolume
+	volumeMu            sync.Mutex
+	riskCheckedAt       map[string]time.Time
+	controlMu           sync.Mutex
+	controlReplies      map[string]time.Time
+	riskCheckMu         sync.Mutex
+	lossStreaks         map[primitive.ObjectID]*lossStreak
+	lossStreakMu        sync.Mutex
+	mt5Metrics          *mt5Metrics
+	mt5Breaker          *mt5Breaker
+	tradeResponseBuffer int
+	responseSendTimeout time.Duration
+	requoteTTL          time.Duration
 }
 
 func NewTradeService(
@@ -52,8 +183,13 @@ func NewTradeService(
 	accountRepo repository.AccountRepository,
 	logService LogService,
 	hub *ws.Hub,
-	socketServer *socket.WebSocketServer,
+	socketServer interfaces.MT5Transport,
 	copyTradeService CopyTradeService,
+	events interfaces.EventPublisher,
+	transactor repository.Transactor,
+	currencyService CurrencyService,
+	clk clock.Clock,
+	cfg *config.Config,
 ) (interfaces.TradeService, error) {
 	return &tradeService{
 		tradeRepo:           tradeRepo,
@@ -61,24 +197,89 @@ func NewTradeService(
 		userRepo:            userRepo,
 		accountRepo:         accountRepo,
 		logService:          logService,
-		responseChan:        make(chan interface{}, 100),
-		balanceChan:         make(chan interfaces.BalanceResponse, 100),
+		responseChan:        make(chan interface{}, cfg.MT5ResponseBuffer),
+		balanceWaiters:      make(map[string][]balanceWaiter),
+		brokerBalances:      make(map[string]brokerBalance),
+		balanceRefreshTTL:   cfg.BalanceRefreshTTL,
 		hub:                 hub,
 		socketServer:        socketServer,
-		copyTradeService:    copyTradeService,
+		copyTradeService:    tradeMirror(copyTradeService),
+		events:              events,
+		transactor:          transactor,
+		currencyService:     currencyService,
+		clock:               clk,
 		tradeResponseChans:  make(map[string]chan interfaces.TradeResponse),
-		streamCtx:           make(map[string]context.CancelFunc),
-		ordersResponseChans: make(map[string]chan models.OrderStreamResponse),
+		orderStreams:        make(map[string]*orderStream),
+		streamLifetime:      cfg.StreamLifetime,
+		streamIdleTimeout:   cfg.StreamIdleTimeout,
+		maxInFlightTrades:   int64(cfg.MaxInFlightTrades),
+		maxOpenPerSymbol:    cfg.MaxOpenTradesPerSymbol,
+		demoCommissionRate:  cfg.DemoCommissionRate,
+		demoSpreadRate:      cfg.DemoSpreadRate,
+		book:                newOrderBook(),
+		volumeCache:         make(map[primitive.ObjectID]cachedVolume),
+		riskCheckedAt:       make(map[string]time.Time),
+		controlReplies:      make(map[string]time.Time),
+		lossStreaks:         make(map[primitive.ObjectID]*lossStreak),
+		mt5Metrics:          newMT5Metrics(logService, cfg),
+		mt5Breaker:          newMT5Breaker(logService, clk, cfg),
+		tradeResponseBuffer: cfg.TradeResponseBuffer,
+		responseSendTimeout: cfg.TradeResponseSendTimeout,
+		requoteTTL:          cfg.RequoteTTL,
 	}, nil
 }
 
+func (s *tradeService) InFlightTradeCount() int {
+	return int(s.inFlightTrades.Load())
+}
+
+func (s *tradeService) MT5Metrics() interfaces.MT5Metrics {
+	metrics := s.mt5Metrics.snapshot()
+	metrics.Circuit = s.mt5Breaker.snapshot()
+	return metrics
+}
+
+// observeMT5 records the outcome of a request awaiting an MT5 reply in the
+// metrics and the circuit breaker.
+func (s *tradeService) observeMT5(kind string, latency time.Duration, timedOut bool) {
+	s.mt5Metrics.observe(kind, latency, timedOut)
+	if timedOut {
+		s.mt5Breaker.failure()
+	} else {
+		s.mt5Breaker.success()
+	}
+}
+
+// acquireInFlightSlot reserves room for one more trade awaiting an MT5 response.
+func (s *tradeService) acquireInFlightSlot() error {
+	if n := s.inFlightTrades.Add(1); s.maxInFlightTrades > 0 && n > s.maxInFlightTrades {
+		s.inFlightTrades.Add(-1)
+		return ErrTooManyInFlightTrades
+	}
+	return nil
+}
+
+func (s *tradeService) releaseInFlightSlot() {
+	s.inFlightTrades.Add(-1)
+}
+
+// RegisterMT5Connection is called whenever the bridge (re)connects. A new
+// bridge session knows nothing of the order streams requested from the old
+// one, so they are requested again.
 func (s *tradeService) RegisterMT5Connection(conn *websocket.Conn) {
 	s.mt5ConnMu.Lock()
 	s.mt5Conn = conn
 	s.mt5ConnMu.Unlock()
+	s.mt5Breaker.halfOpen()
+
+	// The socket server calls in with its client lock held and sending takes
+	// that lock, so resume from another goroutine.
+	go s.resumeOrderStreams()
 }
 
 func (s *tradeService) RegisterWallet(userID, accountID, walletID string) error {
+	ctx := context.Background()
+
 	userObjID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return errors.New("invalid user ID")
@@ -88,12 +289,12 @@ func (s *tradeService) RegisterWallet(userID, accountID, walletID string) error
 		return errors.New("invalid account ID")
 	}
 
-	user, err := s.userRepo.GetUserByID(userObjID)
+	user, err := s.userRepo.GetUserByID(ctx, userObjID)
 	if err != nil || user == nil {
 		return errors.New("user not found")
 	}
 
-	account, err := s.accountRepo.GetAccountByID(accountObjID)
+	account, err := s.accountRepo.GetAccountByID(ctx, accountObjID)
 	if err != nil || account == nil {
 		return errors.New("account not found")
 	}
@@ -107,13 +308,26 @@ func (s *tradeService) RegisterWallet(userID, accountID, walletID string) error
 	}
 
 	account.WalletID = walletID
-	if err := s.accountRepo.UpdateAccount(account); err != nil {
+	if err := s.accountRepo.UpdateAccount(ctx, account); err != nil {
 		return fmt.Errorf("failed to update account with wallet ID: %v", err)
 	}
 
 	return nil
 }
 
+// requestMT5 sends a request whose reply the caller will wait for. While the
+// circuit breaker is open it fails at once with ErrMT5Unavailable.
+func (s *tradeService) requestMT5(msg map[string]interface{}) error {
+	if err := s.mt5Breaker.allow(); err != nil {
+		return err
+	}
+	if err := s.sendToMT5(msg); err != nil {
+		s.mt5Breaker.failure()
+		return err
+	}
+	return nil
+}
+
 func (s *tradeService) sendToMT5(msg interface{}) error {
 	switch msg.(type) {
 	case map[string]interface{}:
@@ -131,7 +345,7 @@ func (s *tradeService) sendToMT5(msg interface{}) error {
 			return s.socketServer.SendOrderStreamRequest(message)
 		case "balance_request":
 			return s.socketServer.SendBalanceRequest(message)
-		case "modify_trade_request":
+		case "modify_trade_request", "cancel_order_request":
 			return s.socketServer.SendTradeRequest(message)
 		default:
 			return fmt.Errorf("unsupported message type: %s", msgType)
@@ -141,117 +355,422 @@ func (s *tradeService) sendToMT5(msg interface{}) error {
 	}
 }
 
+// preparedTrade is an order that passed validation and is ready to be sent to MT5.
+type preparedTrade struct {
+	userObjID      primitive.ObjectID
+	account        *models.Account
+	symbol         *models.Symbol
+	accountName    string
+	accountType    string
+	tradeType      models.TradeType
+	orderType      string
+	leverage       int
+	marginRate     float64
+	marginPrice    float64
+	volume         float64
+	entryPrice     float64
+	stopLoss       float64
+	takeProfit     float64
+	expiration     *time.Time
+	margin         float64
+	commission     float64
+	commissionTier int
+	spread         float64
+}
+
+func (p *preparedTrade) cost() float64 {
+	return p.margin + p.commission
+}
+
+// leverageRange is the leverage the account may use on the symbol. Its tier
+// sets both bounds; an admin override on the account replaces the maximum.
+func leverageRange(account *models.Account, symbol *models.Symbol) (int, int) {
+	low, high := symbol.LeverageRange(account.EffectiveTier())
+	if account.MaxLeverage > 0 {
+		high = account.MaxLeverage
+	}
+	return low, high
+}
+
+// effectiveMarginRate is the share of notional held as margin. Leverage sets
+// it, but never below the symbol's own margin requirement, so a 1:500 order on
+// a symbol with a 50% rate still holds half its notional.
+func effectiveMarginRate(symbol *models.Symbol, leverage int) float64 {
+	return math.Max(1/float64(leverage), symbol.MarginRate)
+}
+
 func (s *tradeService) PlaceTrade(userID, accountID, symbol, accountType string, tradeType models.TradeType, orderType string, leverage int, volume, entryPrice, stopLoss, takeProfit float64, expiration *time.Time) (*models.TradeHistory, interfaces.TradeResponse, error) {
+	// Execution is deliberately detached from the caller's request: once an
+	// order is on its way to MT5 the margin and history writes must complete.
+	ctx := context.Background()
+
+	order := interfaces.TradeOrder{
+		AccountID:   accountID,
+		Symbol:      symbol,
+		AccountType: accountType,
+		TradeType:   tradeType,
+		OrderType:   orderType,
+		Leverage:    leverage,
+		Volume:      volume,
+		EntryPrice:  entryPrice,
+		StopLoss:    stopLoss,
+		TakeProfit:  takeProfit,
+		Expiration:  expiration,
+	}
+	prepared, err := s.prepareTrade(ctx, userID, order)
+	if err != nil {
+		s.logRejection(userID, order, err, nil)
+		return nil, interfaces.TradeResponse{}, err
+	}
+	return s.executeTrade(ctx, prepared)
+}
+
+// logRejection records an order refused before it reached MT5, with what was
+// submitted, so repeated invalid attempts by a user can be spotted. Extra
+// fields are merged into the metadata.
+func (s *tradeService) logRejection(userID string, order interfaces.TradeOrder, reason error, extra map[string]interface{}) {
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+	metadata := map[string]interface{}{
+		"reason":       reason.Error(),
+		"account_id":   order.AccountID,
+		"account_type": order.AccountType,
+		"symbol":       order.Symbol,
+		"trade_type":   order.TradeType,
+		"order_type":   order.OrderType,
+		"leverage":     order.Leverage,
+		"volume":       order.Volume,
+		"entry_price":  order.EntryPrice,
+		"stop_loss":    order.StopLoss,
+		"take_profit":  order.TakeProfit,
+	}
+	if order.Expiration != nil {
+		metadata["expiration"] = *order.Expiration
+	}
+	maps.Copy(metadata, extra)
+	if err := s.logService.LogAction(userObjID, "TradeRejected", "Trade rejected before execution", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+}
+
+func (s *tradeService) prepareTrade(ctx context.Context, userID string, order interfaces.TradeOrder) (*preparedTrade, error) {
 	userObjID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return nil, interfaces.TradeResponse{}, errors.New("invalid user ID")
+		return nil, newError(ErrInvalidInput, "invalid user ID")
 	}
 
-	user, err := s.userRepo.GetUserByID(userObjID)
+	user, err := s.userRepo.GetUserByID(ctx, userObjID)
 	if err != nil {
-		return nil, interfaces.TradeResponse{}, errors.New("failed to fetch user")
+		return nil, errors.New("failed to fetch user")
 	}
 	if user == nil {
-		return nil, interfaces.TradeResponse{}, errors.New("user not found")
+		return nil, newError(ErrNotFound, "user not found")
 	}
 
-	account, err := s.accountRepo.GetAccountByName(accountID, userObjID)
+	account, err := s.accountRepo.GetAccountByName(ctx, order.AccountID, userObjID)
 	if err != nil {
-		return nil, interfaces.TradeResponse{}, errors.New("failed to fetch account")
+		return nil, errors.New("failed to fetch account")
 	}
 	if account == nil || account.UserID != userObjID {
-		return nil, interfaces.TradeResponse{}, errors.New("account not found or does not belong to user")
+		return nil, newError(ErrNotFound, "account not found or does not belong to user")
 	}
-	if account.AccountType != accountType {
-		return nil, interfaces.TradeResponse{}, fmt.Errorf("account type mismatch: expected %s, got %s", account.AccountType, accountType)
+	if !models.SameAccountType(account.AccountType, order.AccountType) {
+		return nil, newError(ErrInvalidInput, "account type mismatch: expected %s, got %s", account.AccountType, order.AccountType)
+	}
+	if account.Disabled {
+		return nil, newError(ErrForbidden, "account is disabled: %s", account.DisabledReason)
+	}
+	if account.RiskBlocked(s.clock.Now()) {
+		return nil, newError(ErrForbidden, "trading is blocked for the rest of the day: daily risk limit reached")
+	}
+	if err := s.checkLossCooldown(account.ID); err != nil {
+		return nil, err
 	}
 
-	symbols, err := s.symbolRepo.GetAllSymbols()
+	symbols, err := s.symbolRepo.GetAllSymbols(ctx)
 	if err != nil {
-		return nil, interfaces.TradeResponse{}, errors.New("failed to fetch symbols")
+		return nil, errors.New("failed to fetch symbols")
 	}
 
-	var symbolObj *models.Symbol
-	for _, sym := range symbols {
-		if sym.DisplayName == symbol {
-			symbolObj = sym
-			symbol = sym.SymbolName
-			break
-		}
-	}
+	symbolObj := models.ResolveSymbol(symbols, order.Symbol)
 	if symbolObj == nil {
-		return nil, interfaces.TradeResponse{}, errors.New("symbol not found")
+		return nil, ErrSymbolNotFound
+	}
+
+	entryPrice := symbolObj.RoundPrice(order.EntryPrice)
+	stopLoss := symbolObj.RoundPrice(order.StopLoss)
+	takeProfit := symbolObj.RoundPrice(order.TakeProfit)
+	prices := []struct {
+		name  string
+		value float64
+	}{{"entry price", entryPrice}, {"stop loss", stopLoss}, {"take profit", takeProfit}}
+	for _, p := range prices {
+		if !symbolObj.IsTickAligned(p.value) {
+			return nil, newError(ErrInvalidInput, "%s %v is not aligned to tick size %v", p.name, p.value, symbolObj.TickSize)
+		}
 	}
 
-	requiredMargin := volume * entryPrice / float64(leverage)
-	if account.Balance < requiredMargin+symbolObj.CommissionFee {
-		return nil, interfaces.TradeResponse{}, errors.New("insufficient balance")
+	if order.Leverage <= 0 {
+		return nil, newError(ErrInvalidInput, "leverage must be positive")
+	}
+	tier := account.EffectiveTier()
+	low, high := leverageRange(account, symbolObj)
+	if order.Leverage > high {
+		return nil, newError(ErrInvalidInput, "leverage 1:%d exceeds the limit of 1:%d for %s", order.Leverage, high, symbolObj.SymbolName)
+	}
+	if order.Leverage < low {
+		return nil, newError(ErrInvalidInput, "leverage 1:%d is below the %s minimum of 1:%d for %s", order.Leverage, tier, low, symbolObj.SymbolName)
+	}
+	// MARKET orders have no entry price; their margin is priced at the quote
+	// they would fill at.
+	marginPrice := entryPrice
+	if order.OrderType == "MARKET" {
+		quote, ok := s.quoteFor(symbolObj.SymbolName, order.TradeType)
+		if !ok {
+			return nil, newError(ErrUnavailable, "no price available for %s yet, please retry shortly", symbolObj.SymbolName)
+		}
+		marginPrice = quote
+	}
+	marginRate := effectiveMarginRate(symbolObj, order.Leverage)
+	requiredMargin := order.Volume * marginPrice * marginRate
+	recentVolume, err := s.rollingVolume(ctx, userObjID)
+	if err != nil {
+		return nil, errors.New("failed to compute trading volume")
+	}
+	commission, commissionTier := symbolObj.CommissionForTier(tier, recentVolume)
+	if models.SameAccountType(account.AccountType, models.AccountTypeDemo) {
+		commission *= s.demoCommissionRate
+	}
+	if account.Balance < requiredMargin+commission {
+		return nil, newError(ErrInsufficientBalance, "insufficient balance")
 	}
 
-	if tradeType != models.TradeTypeBuy && tradeType != models.TradeTypeSell {
-		return nil, interfaces.TradeResponse{}, errors.New("invalid trade type")
+	if order.TradeType != models.TradeTypeBuy && order.TradeType != models.TradeTypeSell {
+		return nil, newError(ErrInvalidInput, "invalid trade type")
 	}
 
 	validOrderTypes := []string{"MARKET", "BUY_STOP", "SELL_STOP", "BUY_LIMIT", "SELL_LIMIT"}
-	isValidOrderType := slices.Contains(validOrderTypes, orderType)
+	isValidOrderType := slices.Contains(validOrderTypes, order.OrderType)
 	if !isValidOrderType {
-		return nil, interfaces.TradeResponse{}, errors.New("invalid order type")
+		return nil, newError(ErrInvalidInput, "invalid order type")
 	}
 
-	if volume < symbolObj.MinLot || volume > symbolObj.MaxLot {
-		return nil, interfaces.TradeResponse{}, errors.New("volume out of allowed range")
+	if order.Volume < symbolObj.MinLot || order.Volume > symbolObj.MaxLot {
+		return nil, newError(ErrInvalidInput, "volume out of allowed range")
 	}
 
-	if leverage > symbolObj.Leverage {
-		return nil, interfaces.TradeResponse{}, errors.New("leverage exceeds symbol limit")
+	if order.OrderType != "MARKET" && entryPrice <= 0 {
+		return nil, newError(ErrInvalidInput, "entry price required for non-market orders")
+	}
+	if order.OrderType == "MARKET" && entryPrice > 0 {
+		return nil, newError(ErrInvalidInput, "entry price not allowed for market orders")
 	}
 
-	if orderType != "MARKET" && entryPrice <= 0 {
-		return nil, interfaces.TradeResponse{}, errors.New("entry price required for non-market orders")
+	if stopLoss < 0 || takeProfit < 0 {
+		return nil, newError(ErrInvalidInput, "stop loss and take profit cannot be negative")
 	}
-	if orderType == "MARKET" && entryPrice > 0 {
-		return nil, interfaces.TradeResponse{}, errors.New("entry price not allowed for market orders")
+	// Market orders fill at the current quote, so that is what MT5 measures
+	// the stops from.
+	if err := checkStopsLevel(symbolObj, marginPrice, stopLoss, takeProfit); err != nil {
+		return nil, err
 	}
 
-	if stopLoss < 0 || takeProfit < 0 {
-		return nil, interfaces.TradeResponse{}, errors.New("stop loss and take profit cannot be negative")
+	if order.Expiration != nil && order.Expiration.Before(s.clock.Now()) {
+		return nil, newError(ErrInvalidInput, "expiration time must be in the future")
+	}
+
+	if err := s.checkPositionLimit(ctx, account.ID, symbolObj); err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+	if halt := symbolObj.ActiveNewsHalt(now); halt != nil && halt.BlockMarket && order.OrderType == "MARKET" {
+		return nil, newError(ErrForbidden, "market orders on %s are halted for news until %s", symbolObj.SymbolName, halt.End.UTC().Format(time.RFC3339))
+	}
+
+	return &preparedTrade{
+		userObjID:      userObjID,
+		account:        account,
+		symbol:         symbolObj,
+		accountName:    order.AccountID,
+		accountType:    models.NormalizeAccountType(order.AccountType),
+		tradeType:      order.TradeType,
+		orderType:      order.OrderType,
+		leverage:       order.Leverage,
+		marginRate:     marginRate,
+		marginPrice:    marginPrice,
+		volume:         order.Volume,
+		entryPrice:     entryPrice,
+		stopLoss:       stopLoss,
+		takeProfit:     takeProfit,
+		expiration:     order.Expiration,
+		margin:         requiredMargin,
+		commission:     commission,
+		commissionTier: commissionTier,
+		spread:         s.spreadFor(account, symbolObj),
+	}, nil
+}
+
+// spreadFor is the spread sent to MT5 for an order on the account: the
+// symbol's current spread, scaled down on demo accounts.
+func (s *tradeService) spreadFor(account *models.Account, symbol *models.Symbol) float64 {
+	spread := symbol.EffectiveSpread(s.clock.Now())
+	if models.SameAccountType(account.AccountType, models.AccountTypeDemo) {
+		spread *= s.demoSpreadRate
 	}
+	return spread
+}
 
-	if expiration != nil && expiration.Before(time.Now()) {
-		return nil, interfaces.TradeResponse{}, errors.New("expiration time must be in the future")
+// symbolByName finds a symbol by the broker name stored on trades.
+func (s *tradeService) symbolByName(ctx context.Context, name string) (*models.Symbol, error) {
+	symbols, err := s.symbolRepo.GetAllSymbols(ctx)
+	if err != nil {
+		return nil, errors.New("failed to fetch symbols")
 	}
+	for _, sym := range symbols {
+		if sym.SymbolName == name {
+			return sym, nil
+		}
+	}
+	return nil, ErrSymbolNotFound
+}
 
-	account.Balance -= requiredMargin + symbolObj.CommissionFee
-	if err := s.accountRepo.UpdateAccount(account); err != nil {
+// checkPositionLimit enforces the symbol's MaxOpenTrades, falling back to the
+// global MAX_OPEN_TRADES_PER_SYMBOL. Zero in both places means no limit.
+func (s *tradeService) checkPositionLimit(ctx context.Context, accountID primitive.ObjectID, symbol *models.Symbol) error {
+	limit := symbol.MaxOpenTrades
+	if limit <= 0 {
+		limit = s.maxOpenPerSymbol
+	}
+	if limit <= 0 {
+		return nil
+	}
+	count, err := s.tradeRepo.CountActiveTradesBySymbol(ctx, accountID, symbol.SymbolName)
+	if err != nil {
+		return errors.New("failed to count open trades")
+	}
+	if count >= int64(limit) {
+		return &PositionLimitError{Symbol: symbol.SymbolName, Limit: limit}
+	}
+	return nil
+}
+
+// rollingVolume returns the lots a user has had filled within the commission
+// tier window. Results are cached briefly so bursts of orders from one user
+// don't each re-aggregate their history.
+func (s *tradeService) rollingVolume(ctx context.Context, userID primitive.ObjectID) (float64, error) {
+	now := s.clock.Now()
+	s.volumeMu.Lock()
+	cached, ok := s.volumeCache[userID]
+	s.volumeMu.Unlock()
+	if ok && now.Sub(cached.at) < volumeCacheTTL {
+		return cached.volume, nil
+	}
+
+	volume, err := s.tradeRepo.GetVolumeSince(ctx, userID, now.Add(-commissionVolumeWindow))
+	if err != nil {
+		return 0, err
+	}
+	s.volumeMu.Lock()
+	s.volumeCache[userID] = cachedVolume{volume: volume, at: now}
+	s.volumeMu.Unlock()
+	return volume, nil
+}
+
+func (s *tradeService) refundTrade(ctx context.Context, accountID primitive.ObjectID, amount float64) {
+	if amount == 0 {
+		return
+	}
+	if err := s.accountRepo.AdjustBalance(ctx, accountID, amount); err != nil {
+		log.Printf("Failed to refund account %s: %v", accountID.Hex(), err)
+	}
+}
+
+func (s *tradeService) executeTrade(ctx context.Context, p *preparedTrade) (*models.TradeHistory, interfaces.TradeResponse, error) {
+	if err := s.acquireInFlightSlot(); err != nil {
+		return nil, interfaces.TradeResponse{}, err
+	}
+	defer s.releaseInFlightSlot()
+
+	account := p.account
+	if err := s.accountRepo.AdjustBalance(ctx, account.ID, -p.cost()); err != nil {
 		return nil, interfaces.TradeResponse{}, fmt.Errorf("failed to update account balance: %v", err)
 	}
 
 	trade := &models.TradeHistory{
-		ID:          primitive.NewObjectID(),
-		UserID:      userObjID,
-		AccountID:   account.ID,
-		Symbol:      symbol,
-		TradeType:   tradeType,
-		OrderType:   orderType,
-		Leverage:    leverage,
-		Volume:      volume,
-		EntryPrice:  entryPrice,
-		StopLoss:    stopLoss,
-		TakeProfit:  takeProfit,
-		OpenTime:    time.Now(),
-		Status:      string(models.TradeStatusPending),
-		Expiration:  expiration,
-		AccountType: accountType,
+		ID:              primitive.NewObjectID(),
+		UserID:          p.userObjID,
+		AccountID:       account.ID,
+		Symbol:          p.symbol.SymbolName,
+		TradeType:       p.tradeType,
+		OrderType:       p.orderType,
+		Leverage:        p.leverage,
+		MarginRate:      p.marginRate,
+		Volume:          p.volume,
+		EntryPrice:      p.entryPrice,
+		StopLoss:        p.stopLoss,
+		TakeProfit:      p.takeProfit,
+		OpenTime:        s.clock.Now(),
+		Status:          string(models.TradeStatusPending),
+		Expiration:      p.expiration,
+		AccountType:     p.accountType,
+		ExecutionType:   executionTypeFor(p.orderType),
+		RequestedVolume: p.volume,
+		CommissionTier:  p.commissionTier,
+	}
+	if p.entryPrice == 0 {
+		trade.MarginPrice = p.marginPrice
+	}
+	// The commission is recorded as a charge, signed like MT5's, so that
+	// profit+commission+swap is the trade's net result.
+	if p.commission > 0 {
+		trade.Commission = -p.commission
+	}
+
+	reserved := p.cost()
+	if isBookOrder(trade) {
+		fills, remaining, err := s.matchInternally(ctx, trade)
+		if err != nil {
+			log.Printf("Internal matching failed for trade %s: %v", trade.ID.Hex(), err)
+		}
+		if len(fills) > 0 {
+			s.settleTakerFills(ctx, trade, fills, p.entryPrice)
+			// Commission stays charged once any part of the order has filled.
+			reserved = trade.MarginFor(remaining, p.entryPrice)
+		}
+		if remaining <= 0 {
+			if err := s.tradeRepo.SaveTrade(ctx, trade); err != nil {
+				return nil, interfaces.TradeResponse{}, err
+			}
+			s.hub.BroadcastTrade(trade)
+			s.mirrorTrade(trade, p.accountType)
+			return trade, interfaces.TradeResponse{
+				TradeID:        trade.ID.Hex(),
+				UserID:         trade.UserID.Hex(),
+				MatchedTradeID: trade.MatchedTradeID,
+				MatchedVolume:  p.volume,
+				AccountType:    trade.AccountType,
+				AccountID:      trade.AccountID.Hex(),
+				Status:         "MATCHED",
+				Timestamp:      float64(time.Now().Unix()),
+			}, nil
+		}
 	}
 
+	return s.dispatchTrade(ctx, trade, account, p.spread, reserved)
+}
+
+// dispatchTrade persists trade, sends it to MT5 and waits for the answer.
+// reserved is what the order holds from the balance; it is refunded when the
+// order does not go through, and kept on the trade when MT5 requotes.
+func (s *tradeService) dispatchTrade(ctx context.Context, trade *models.TradeHistory, account *models.Account, spread, reserved float64) (*models.TradeHistory, interfaces.TradeResponse, error) {
 	tradeRequest := map[string]interface{}{
 		"type":         "trade_request",
 		"trade_id":     trade.ID.Hex(),
 		"trade_code":   "",
 		"user_id":      trade.UserID.Hex(),
 		"account_id":   trade.AccountID.Hex(),
-		"account_type": accountType,
-		"account_name": accountID,
+		"account_type": trade.AccountType,
+		"account_name": account.AccountName,
 		"wallet_id":    account.WalletID,
 		"symbol":       trade.Symbol,
 		"trade_type":   trade.TradeType,
@@ -261,6 +780,7 @@ func (s *tradeService) PlaceTrade(userID, accountID, symbol, accountType string,
 		"entry_price":  trade.EntryPrice,
 		"stop_loss":    trade.StopLoss,
 		"take_profit":  trade.TakeProfit,
+		"spread":       spread,
 		"timestamp":    trade.OpenTime.Unix(),
 		"expiration":   0,
 	}
@@ -268,169 +788,421 @@ func (s *tradeService) PlaceTrade(userID, accountID, symbol, accountType string,
 		tradeRequest["expiration"] = trade.Expiration.Unix()
 	}
 
-	responseChan := make(chan interfaces.TradeResponse, 1)
-	s.tradeResponseMu.Lock()
-	s.tradeResponseChans[trade.ID.Hex()] = responseChan
-	s.tradeResponseMu.Unlock()
-
-	defer func() {
-		s.tradeResponseMu.Lock()
-		delete(s.tradeResponseChans, trade.ID.Hex())
-		close(responseChan)
-		s.tradeResponseMu.Unlock()
-	}()
+	responseChan, release := s.awaitTradeResponse(trade.ID.Hex())
+	defer release()
 
-	if err := s.sendToMT5(tradeRequest); err != nil {
-		account.Balance += requiredMargin + symbolObj.CommissionFee
-		s.accountRepo.UpdateAccount(account)
+	// Persist before sending so HandleTradeResponse can find the trade however
+	// quickly MT5 answers.
+	if err := s.tradeRepo.SaveTrade(ctx, trade); err != nil {
+		s.refundTrade(ctx, account.ID, reserved)
 		return nil, interfaces.TradeResponse{}, err
 	}
 
-	err = s.tradeRepo.SaveTrade(trade)
-	if err != nil {
-		account.Balance += requiredMargin + symbolObj.CommissionFee
-		s.accountRepo.UpdateAccount(account)
+	sentAt := time.Now()
+	if err := s.requestMT5(tradeRequest); err != nil {
+		trade.Status = string(models.TradeStatusCancelled)
+		trade.Commission = 0
+		_ = s.tradeRepo.SaveTrade(ctx, trade)
+		s.refundTrade(ctx, account.ID, reserved)
 		return nil, interfaces.TradeResponse{}, err
 	}
 
 	var tradeResponse interfaces.TradeResponse
 	select {
 	case response := <-responseChan:
+		s.observeMT5(mt5RequestTrade, time.Since(sentAt), false)
 		tradeResponse = response
 		if tradeResponse.TradeID != trade.ID.Hex() {
-			account.Balance += requiredMargin + symbolObj.CommissionFee
-			s.accountRepo.UpdateAccount(account)
+			s.refundTrade(ctx, account.ID, reserved)
 			return nil, interfaces.TradeResponse{}, errors.New("received response for wrong trade ID")
 		}
-		trade.Status = tradeResponse.Status
-		trade.MatchedTradeID = tradeResponse.MatchedTradeID
-
-		switch tradeResponse.Status {
-		case "MATCHED":
-			trade.Status = string(models.TradeStatusOpen)
-		case "PENDING":
-			trade.Status = string(models.TradeStatusPending)
-		default:
-			trade.Status = string(models.TradeStatusClosed)
-			trade.CloseTime = &time.Time{}
-			*trade.CloseTime = time.Now()
-			trade.CloseReason = tradeResponse.Status
-			_ = s.tradeRepo.SaveTrade(trade)
-			account.Balance += requiredMargin + symbolObj.CommissionFee
-			s.accountRepo.UpdateAccount(account)
-			return nil, interfaces.TradeResponse{}, fmt.Errorf("%s", constants.TradeRetcodes[tradeResponse.TradeRetcode]["fa"])
-		}
 
-		err = s.tradeRepo.SaveTrade(trade)
+		// HandleTradeResponse has already applied and persisted the response,
+		// including any partial fill and the margin refund on rejection.
+		updated, err := s.tradeRepo.GetTradeByID(ctx, trade.ID)
 		if err != nil {
-			account.Balance += requiredMargin + symbolObj.CommissionFee
-			s.accountRepo.UpdateAccount(account)
 			return nil, interfaces.TradeResponse{}, err
 		}
-	case <-time.After(30 * time.Second):
+		if updated != nil {
+			trade = updated
+		}
+
+		switch models.TradeStatus(trade.Status) {
+		case models.TradeStatusPending:
+			s.addToBook(trade)
+		case models.TradeStatusClosed:
+			s.refundTrade(ctx, account.ID, reserved-trade.Margin())
+			if trade.Commission != 0 {
+				trade.Commission = 0
+				if err := s.tradeRepo.SaveTrade(ctx, trade); err != nil {
+					log.Printf("Failed to clear refunded commission of trade %s: %v", trade.ID.Hex(), err)
+				}
+			}
+			return nil, interfaces.TradeResponse{}, fmt.Errorf("%s", constants.TradeRetcodes[tradeResponse.TradeRetcode]["fa"])
+		case models.TradeStatusRequoted:
+			// Without the stored requote the order could be neither confirmed
+			// nor expired, and its reservation would never be released.
+			if trade.Requote == nil {
+				cancelled, err := s.tradeRepo.CancelRequotedTrade(ctx, trade.ID)
+				if err != nil {
+					return nil, interfaces.TradeResponse{}, err
+				}
+				if cancelled {
+					s.refundTrade(ctx, account.ID, reserved)
+				}
+				return nil, interfaces.TradeResponse{}, errors.New("requote could not be recorded; the order was cancelled")
+			}
+			trade.Requote.Held = reserved
+			if err := s.tradeRepo.SaveTrade(ctx, trade); err != nil {
+				log.Printf("Failed to record requote hold for trade %s: %v", trade.ID.Hex(), err)
+			}
+			return nil, tradeResponse, &RequoteError{TradeID: trade.ID.Hex(), Price: trade.Requote.Price, ExpiresAt: trade.Requote.ExpiresAt}
+		}
+	case <-time.After(mt5ResponseTimeout):
+		s.observeMT5(mt5RequestTrade, mt5ResponseTimeout, true)
 		trade.Status = string(models.TradeStatusClosed)
 		trade.CloseTime = &time.Time{}
-		*trade.CloseTime = time.Now()
-		trade.CloseReason = "TIMEOUT"
-		_ = s.tradeRepo.SaveTrade(trade)
-		account.Balance += requiredMargin + symbolObj.CommissionFee
-		s.accountRepo.UpdateAccount(account)
+		*trade.CloseTime = s.clock.Now()
+		trade.CloseReason = models.CloseReasonTimeout
+		trade.Commission = 0
+		_ = s.tradeRepo.SaveTrade(ctx, trade)
+		s.refundTrade(ctx, account.ID, reserved)
 		return nil, interfaces.TradeResponse{}, errors.New("timeout waiting for MT5 trade response")
 	}
 
+	s.mirrorTrade(trade, trade.AccountType)
+
+	return trade, tradeResponse, nil
+}
+
+// SetTradeMirror wires in copy trading, which is constructed after the trade
+// service because it places follower trades through it.
+func (s *tradeService) SetTradeMirror(mirror interfaces.TradeMirror) {
+	s.copyTradeService = mirror
+}
+
+// tradeMirror keeps a nil CopyTradeService from becoming a non-nil interface.
+func tradeMirror(copyTradeService CopyTradeService) interfaces.TradeMirror {
+	if copyTradeService == nil {
+		return nil
+	}
+	return copyTradeService
+}
+
+// mirrorTrade copies trade to the owner's followers in the background.
+func (s *tradeService) mirrorTrade(trade *models.TradeHistory, accountType string) {
+	if s.copyTradeService == nil {
+		return
+	}
 	go func() {
 		if err := s.copyTradeService.MirrorTrade(trade, accountType); err != nil {
 			log.Printf("Failed to mirror trade: %v", err)
 		}
 	}()
-
-	return trade, tradeResponse, nil
 }
 
-func (s *tradeService) HandleBalanceResponse(response interfaces.BalanceResponse) error {
-	userObjID, err := primitive.ObjectIDFromHex(response.UserID)
-	if err != nil {
-		return errors.New("invalid user ID")
+// executionTypeFor routes market orders to the platform; resting orders are
+// eligible for internal user-to-user execution.
+func executionTypeFor(orderType string) models.ExecutionType {
+	if orderType == "MARKET" {
+		return models.ExecutionTypePlatform
 	}
-	accountObjID, err := primitive.ObjectIDFromHex(response.AccountID)
-	if err != nil {
-		return errors.New("invalid account ID")
+	return models.ExecutionTypeUserToUser
+}
+
+// pendingOrderTriggered reports whether price crosses the trade's entry level.
+// Buys fill on the ask and sells on the bid: limits trigger when the price
+// reaches a better level, stops when it moves through the entry.
+func pendingOrderTriggered(trade *models.TradeHistory, price *models.PriceData) bool {
+	switch trade.OrderType {
+	case "BUY_LIMIT":
+		return price.Ask > 0 && price.Ask <= trade.EntryPrice
+	case "BUY_STOP":
+		return price.Ask > 0 && price.Ask >= trade.EntryPrice
+	case "SELL_LIMIT":
+		return price.Bid > 0 && price.Bid >= trade.EntryPrice
+	case "SELL_STOP":
+		return price.Bid > 0 && price.Bid <= trade.EntryPrice
+	default:
+		return false
 	}
+}
 
-	user, err := s.userRepo.GetUserByID(userObjID)
+// ActivatePendingOrders opens user-to-user pending orders on price.Symbol
+// whose entry level the tick has reached. MT5 triggers platform orders itself.
+func (s *tradeService) ActivatePendingOrders(price *models.PriceData) error {
+	ctx := context.Background()
+
+	trades, err := s.tradeRepo.GetPendingTradesBySymbol(ctx, price.Symbol, models.ExecutionTypeUserToUser)
 	if err != nil {
-		return fmt.Errorf("failed to fetch user: %v", err)
+		return err
 	}
-	if user == nil {
-		return errors.New("user not found")
+
+	now := s.clock.Now()
+	for _, trade := range trades {
+		if trade.Expiration != nil && trade.Expiration.Before(now) {
+			continue
+		}
+		if !pendingOrderTriggered(trade, price) {
+			continue
+		}
+
+		activated, err := s.tradeRepo.ActivatePendingTrade(ctx, trade.ID)
+		if err != nil {
+			log.Printf("Failed to activate pending trade %s: %v", trade.ID.Hex(), err)
+			continue
+		}
+		if !activated {
+			continue
+		}
+		trade.Status = string(models.TradeStatusOpen)
+		s.removeFromBook(trade)
+
+		metadata := map[string]interface{}{
+			"trade_id":    trade.ID.Hex(),
+			"symbol":      trade.Symbol,
+			"order_type":  trade.OrderType,
+			"entry_price": trade.EntryPrice,
+			"bid":         price.Bid,
+			"ask":         price.Ask,
+		}
+		if err := s.logService.LogAction(trade.UserID, "PendingOrderActivated", "Pending order triggered by price", "", metadata); err != nil {
+			log.Printf("error: %v", err)
+		}
+		s.hub.BroadcastTrade(trade)
 	}
+	return nil
+}
 
-	account, err := s.accountRepo.GetAccountByID(accountObjID)
-	if err != nil {
-		return fmt.Errorf("failed to fetch account: %v", err)
+// PlaceTradeBatch validates every order up front and checks the combined
+// margin per account before anything is sent to MT5. With failFast any invalid
+// order rejects the whole batch; otherwise invalid orders are skipped.
+func (s *tradeService) PlaceTradeBatch(userID string, orders []interfaces.TradeOrder, failFast bool) ([]interfaces.BatchTradeResult, error) {
+	ctx := context.Background()
+
+	if len(orders) == 0 {
+		return nil, newError(ErrInvalidInput, "batch contains no orders")
 	}
-	if account == nil || account.UserID != userObjID {
-		return errors.New("account not found or does not belong to user")
+	if len(orders) > maxBatchOrders {
+		return nil, newError(ErrInvalidInput, "batch cannot contain more than %d orders", maxBatchOrders)
+	}
+	if s.maxInFlightTrades > 0 && s.inFlightTrades.Load()+int64(len(orders)) > s.maxInFlightTrades {
+		return nil, ErrTooManyInFlightTrades
 	}
-	if account.AccountType != response.AccountType {
-		return fmt.Errorf("account type mismatch: expected %s, got %s", account.AccountType, response.AccountType)
+
+	results := make([]interfaces.BatchTradeResult, len(orders))
+	prepared := make([]*preparedTrade, len(orders))
+	committed := make(map[primitive.ObjectID]float64)
+	failed := false
+
+	for i, order := range orders {
+		results[i].Index = i
+		p, err := s.prepareTrade(ctx, userID, order)
+		if err == nil && committed[p.account.ID]+p.cost() > p.account.Balance {
+			err = newError(ErrInsufficientBalance, "insufficient balance for batch")
+		}
+		if err != nil {
+			s.logRejection(userID, order, err, map[string]interface{}{"batch_index": i})
+			results[i].Error = err.Error()
+			failed = true
+			continue
+		}
+		committed[p.account.ID] += p.cost()
+		prepared[i] = p
 	}
 
-	account.Balance = response.Balance
-	if err := s.accountRepo.UpdateAccount(account); err != nil {
-		return fmt.Errorf("failed to update account balance: %v", err)
+	if failed && failFast {
+		return results, errors.New("batch rejected: one or more orders failed validation")
 	}
 
-	balanceData := &models.BalanceData{
-		UserID:      response.UserID,
-		AccountType: response.AccountType,
-		Balance:     response.Balance,
-		Timestamp:   time.Now().Unix(),
+	var wg sync.WaitGroup
+	for i, p := range prepared {
+		if p == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, p *preparedTrade) {
+			defer wg.Done()
+			trade, _, err := s.executeTrade(ctx, p)
+			if err != nil {
+				results[i].Error = err.Error()
+				return
+			}
+			results[i].Trade = trade
+		}(i, p)
 	}
-	s.hub.BroadcastBalance(balanceData)
+	wg.Wait()
+
+	return results, nil
+}
 
+// HandleBalanceResponse passes a broker balance to the RequestBalance calls
+// waiting on it. It never writes the balance to the account: platform and
+// broker balances are compared by reconciliation, not overwritten.
+func (s *tradeService) HandleBalanceResponse(response interfaces.BalanceResponse) error {
+	key := balanceKey(response.UserID, response.AccountType)
+
+	s.balanceWaitersMu.Lock()
+	var delivered, remaining []balanceWaiter
+	for _, waiter := range s.balanceWaiters[key] {
+		// The bridge does not always echo the account ID; without it the
+		// response answers every request for the user's account type.
+		if response.AccountID == "" || response.AccountID == waiter.accountID {
+			delivered = append(delivered, waiter)
+		} else {
+			remaining = append(remaining, waiter)
+		}
+	}
+	if len(remaining) > 0 {
+		s.balanceWaiters[key] = remaining
+	} else {
+		delete(s.balanceWaiters, key)
+	}
+	s.balanceWaitersMu.Unlock()
+
+	if len(delivered) == 0 {
+		log.Printf("Dropped balance response for %s: no request is waiting for it", key)
+		return nil
+	}
+	for _, waiter := range delivered {
+		waiter.ch <- response
+	}
 	return nil
 }
 
+// balanceWaiter is a RequestBalance call waiting for MT5's answer. ch has
+// room for one response so delivery never blocks.
+type balanceWaiter struct {
+	accountID string
+	ch        chan interfaces.BalanceResponse
+}
+
+func balanceKey(userID, accountType string) string {
+	return userID + ":" + models.NormalizeAccountType(accountType)
+}
+
+func (s *tradeService) awaitBalanceResponse(userID, accountID, accountType string) (chan interfaces.BalanceResponse, func()) {
+	key := balanceKey(userID, accountType)
+	waiter := balanceWaiter{accountID: accountID, ch: make(chan interfaces.BalanceResponse, 1)}
+	s.balanceWaitersMu.Lock()
+	s.balanceWaiters[key] = append(s.balanceWaiters[key], waiter)
+	s.balanceWaitersMu.Unlock()
+
+	return waiter.ch, func() {
+		s.balanceWaitersMu.Lock()
+		defer s.balanceWaitersMu.Unlock()
+		waiters := s.balanceWaiters[key]
+		for i, w := range waiters {
+			if w.ch == waiter.ch {
+				waiters = append(waiters[:i], waiters[i+1:]...)
+				break
+			}
+		}
+		if len(waiters) > 0 {
+			s.balanceWaiters[key] = waiters
+		} else {
+			delete(s.balanceWaiters, key)
+		}
+	}
+}
+
 func (s *tradeService) HandleTradeResponse(response interfaces.TradeResponse) error {
+	ctx := context.Background()
+
 	tradeID, err := primitive.ObjectIDFromHex(response.TradeID)
 	if err != nil {
-		return errors.New("invalid trade ID")
+		return s.orphanResponse("trade_response", response)
 	}
 
-	trade, err := s.tradeRepo.GetTradeByID(tradeID)
+	trade, err := s.tradeRepo.GetTradeByID(ctx, tradeID)
 	if err != nil {
 		return err
 	}
 	if trade == nil {
-		return errors.New("trade not found")
+		return s.orphanResponse("trade_response", response)
+	}
+
+	// While a modify or cancel is at MT5, any answer other than a fill is the
+	// reply to it and is applied by the request waiting for it. A cancel
+	// confirmation for an order that has since filled changes nothing.
+	isFill := response.Status == "MATCHED" || response.Status == "PENDING"
+	if (!isFill && s.awaitingControlReply(response.TradeID)) || isModifyReply(response.Status) ||
+		(isCancelConfirmation(response.Status) && models.TradeStatus(trade.Status) != models.TradeStatusPending) {
+		s.notifyTradeResponse(response)
+		return nil
 	}
 
-	account, err := s.accountRepo.GetAccountByID(trade.AccountID)
+	account, err := s.accountRepo.GetAccountByID(ctx, trade.AccountID)
 	if err != nil || account == nil {
 		return errors.New("account not found")
 	}
 
-	if response.MatchedVolume > 0 {
-		trade.Volume -= response.MatchedVolume
+	if trade.OrderType == "MARKET" && isRequote(response.TradeRetcode) && models.TradeStatus(trade.Status) == models.TradeStatusPending {
+		if price := s.requotePrice(trade, response); price > 0 {
+			return s.requoteTrade(ctx, trade, response, price)
+		}
+	}
+
+	// A matched volume below the outstanding volume is a partial fill: the
+	// filled part opens as its own trade and the remainder stays pending.
+	partial := response.MatchedVolume > 0 && response.MatchedVolume < trade.Volume &&
+		(response.Status == "MATCHED" || response.Status == "PENDING")
+
+	next := models.TradeStatusClosed
+	switch {
+	case partial:
+		next = models.TradeStatusPending
+	case response.Status == "MATCHED":
+		next = models.TradeStatusOpen
+	case response.Status == "PENDING":
+		next = models.TradeStatusPending
+	}
+	if !canTransition(models.TradeStatus(trade.Status), next) {
+		err := transitionError(trade, next)
+		log.Printf("Rejected trade response: %v", err)
+		return err
+	}
+
+	// MARKET orders learn their price when they fill. The bridge does not
+	// always report it, in which case the quote the margin was priced at
+	// stands in.
+	fillPrice := trade.EntryPrice
+	if fillPrice == 0 {
+		fillPrice = response.Price
+		if fillPrice <= 0 {
+			fillPrice = trade.MarginPrice
+		}
 	}
 
-	switch response.Status {
-	case "MATCHED":
+	switch {
+	case partial:
+		fill := splitFill(trade, response.MatchedVolume, fillPrice, response.MatchedTradeID)
+		trade.Status = string(models.TradeStatusPending)
+		if err := s.tradeRepo.SaveTrade(ctx, fill); err != nil {
+			return err
+		}
+		s.hub.BroadcastTrade(fill)
+		s.events.Publish(models.WebhookEventTradeOpened, fill)
+	case response.Status == "MATCHED":
+		trade.FilledVolume = roundVolume(trade.FilledVolume + trade.Volume)
+		trade.EntryPrice = fillPrice
 		trade.Status = string(models.TradeStatusOpen)
 		trade.MatchedTradeID = response.MatchedTradeID
-	case "PENDING":
+	case response.Status == "PENDING":
 		trade.Status = string(models.TradeStatusPending)
 	default:
 		trade.Status = string(models.TradeStatusClosed)
 		trade.CloseTime = &time.Time{}
-		*trade.CloseTime = time.Now()
-		trade.CloseReason = response.Status
-		margin := trade.Volume * trade.EntryPrice / float64(trade.Leverage)
-		account.Balance += margin
-		s.accountRepo.UpdateAccount(account)
+		*trade.CloseTime = s.clock.Now()
+		trade.CloseReason = models.ParseCloseReason(response.Status)
+		if trade.CloseReason == models.CloseReasonManual {
+			trade.CloseReason = models.CloseReasonBrokerReject
+		}
+		// Only the unfilled remainder is released; filled parts live on as
+		// their own trades and keep their margin.
+		s.refundTrade(ctx, account.ID, trade.Margin())
 	}
-	err = s.tradeRepo.SaveTrade(trade)
+	err = s.tradeRepo.SaveTrade(ctx, trade)
 	if err != nil {
 		return err
 	}
@@ -444,38 +1216,244 @@ func (s *tradeService) HandleTradeResponse(response interfaces.TradeResponse) er
 	if err := s.logService.LogAction(trade.UserID, "TradeResponse", "Trade status updated", "", metadata); err != nil {
 		log.Printf("error: %v", err)
 	}
+	if response.Status == "MATCHED" && !partial {
+		s.events.Publish(models.WebhookEventTradeOpened, trade)
+	}
 
-	s.tradeResponseMu.Lock()
-	if ch, exists := s.tradeResponseChans[response.TradeID]; exists {
-		select {
-		case ch <- response:
-		default:
-			log.Printf("Trade response channel for trade %s is full or closed", response.TradeID)
-		}
+	s.notifyTradeResponse(response)
+
+	return nil
+}
+
+// isModifyReply reports whether status is the bridge's answer to a modify
+// request: MODIFIED, or a numbered failure such as "FAILED 19". Neither says
+// anything about the order's own state, so they never settle it.
+func isModifyReply(status string) bool {
+	if status == "MODIFIED" {
+		return true
 	}
-	s.tradeResponseMu.Unlock()
+	code, ok := strings.CutPrefix(status, "FAILED ")
+	if !ok {
+		return false
+	}
+	_, err := strconv.Atoi(code)
+	return err == nil
+}
+
+// requotePrice is the price MT5 offered with a requote, or failing that the
+// latest quote on the side the order would fill.
+func (s *tradeService) requotePrice(trade *models.TradeHistory, response interfaces.TradeResponse) float64 {
+	if response.Price > 0 {
+		return response.Price
+	}
+	price, _ := s.quoteFor(trade.Symbol, trade.TradeType)
+	return price
+}
+
+// quoteFor is the latest quote on the side an order of tradeType fills at:
+// the ask for a BUY, the bid for a SELL.
+func (s *tradeService) quoteFor(symbol string, tradeType models.TradeType) (float64, bool) {
+	price, ok := s.hub.LastPrice(symbol)
+	if !ok {
+		return 0, false
+	}
+	if tradeType == models.TradeTypeSell {
+		return price.Bid, true
+	}
+	return price.Ask, true
+}
+
+// requoteTrade parks a MARKET order MT5 requoted. Nothing is refunded: the
+// order placing it records what stays held, and the order is either confirmed
+// or expired by ExpireRequotes.
+func (s *tradeService) requoteTrade(ctx context.Context, trade *models.TradeHistory, response interfaces.TradeResponse, price float64) error {
+	trade.Status = string(models.TradeStatusRequoted)
+	trade.Requote = &models.Requote{Price: price, ExpiresAt: s.clock.Now().Add(s.requoteTTL)}
+	if err := s.tradeRepo.SaveTrade(ctx, trade); err != nil {
+		return err
+	}
+
+	metadata := map[string]interface{}{
+		"trade_id":   trade.ID.Hex(),
+		"account_id": trade.AccountID.Hex(),
+		"retcode":    response.TradeRetcode,
+		"price":      price,
+		"expires_at": trade.Requote.ExpiresAt,
+	}
+	if err := s.logService.LogAction(trade.UserID, "TradeRequoted", "Market order requoted by MT5", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+
+	s.notifyTradeResponse(response)
+	return nil
+}
+
+// ConfirmRequote accepts the price MT5 requoted and resubmits the order with
+// the balance it still holds. A lapsed requote is cancelled instead.
+func (s *tradeService) ConfirmRequote(ctx context.Context, tradeID, userID string) (*models.TradeHistory, interfaces.TradeResponse, error) {
+	objID, err := primitive.ObjectIDFromHex(tradeID)
+	if err != nil {
+		return nil, interfaces.TradeResponse{}, ErrInvalidTradeID
+	}
+	trade, err := s.tradeRepo.GetTradeByID(ctx, objID)
+	if err != nil {
+		return nil, interfaces.TradeResponse{}, err
+	}
+	if trade == nil {
+		return nil, interfaces.TradeResponse{}, ErrTradeNotFound
+	}
+	if trade.UserID.Hex() != userID {
+		return nil, interfaces.TradeResponse{}, ErrTradeForbidden
+	}
+	if models.TradeStatus(trade.Status) != models.TradeStatusRequoted || trade.Requote == nil {
+		return nil, interfaces.TradeResponse{}, newError(ErrConflict, "trade is not awaiting requote confirmation")
+	}
+	if !s.clock.Now().Before(trade.Requote.ExpiresAt) {
+		s.expireRequote(ctx, trade)
+		return nil, interfaces.TradeResponse{}, newError(ErrConflict, "requote expired and the order was cancelled")
+	}
+
+	account, err := s.accountRepo.GetAccountByID(ctx, trade.AccountID)
+	if err != nil || account == nil {
+		return nil, interfaces.TradeResponse{}, errors.New("account not found")
+	}
+	symbol, err := s.symbolByName(ctx, trade.Symbol)
+	if err != nil {
+		return nil, interfaces.TradeResponse{}, err
+	}
+
+	if err := s.acquireInFlightSlot(); err != nil {
+		return nil, interfaces.TradeResponse{}, err
+	}
+	defer s.releaseInFlightSlot()
+
+	confirmed, err := s.tradeRepo.ConfirmRequotedTrade(ctx, trade.ID)
+	if err != nil {
+		return nil, interfaces.TradeResponse{}, err
+	}
+	if !confirmed {
+		return nil, interfaces.TradeResponse{}, newError(ErrConflict, "requote is no longer open")
+	}
+	trade.Status = string(models.TradeStatusPending)
+	trade.OpenTime = s.clock.Now()
+
+	metadata := map[string]interface{}{
+		"trade_id":   trade.ID.Hex(),
+		"account_id": trade.AccountID.Hex(),
+		"price":      trade.Requote.Price,
+	}
+	if err := s.logService.LogAction(trade.UserID, "RequoteConfirmed", "Requoted market order resubmitted", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+
+	return s.dispatchTrade(ctx, trade, account, s.spreadFor(account, symbol), trade.Requote.Held)
+}
 
+// ExpireRequotes cancels requoted orders that were not confirmed in time and
+// releases what they held.
+func (s *tradeService) ExpireRequotes(ctx context.Context) error {
+	trades, err := s.tradeRepo.GetExpiredRequotes(ctx, s.clock.Now())
+	if err != nil {
+		return err
+	}
+	for _, trade := range trades {
+		s.expireRequote(ctx, trade)
+	}
 	return nil
 }
 
-func (s *tradeService) GetTrade(id string) (*models.TradeHistory, error) {
+func (s *tradeService) expireRequote(ctx context.Context, trade *models.TradeHistory) {
+	cancelled, err := s.tradeRepo.CancelRequotedTrade(ctx, trade.ID)
+	if err != nil {
+		log.Printf("Failed to expire requote for trade %s: %v", trade.ID.Hex(), err)
+		return
+	}
+	if !cancelled {
+		return
+	}
+	s.refundTrade(ctx, trade.AccountID, trade.Requote.Held)
+
+	trade.Status = string(models.TradeStatusCancelled)
+	trade.CloseReason = models.CloseReasonExpired
+	s.hub.BroadcastTrade(trade)
+
+	metadata := map[string]interface{}{
+		"trade_id":   trade.ID.Hex(),
+		"account_id": trade.AccountID.Hex(),
+		"refunded":   trade.Requote.Held,
+	}
+	if err := s.logService.LogAction(trade.UserID, "RequoteExpired", "Requoted market order cancelled", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+}
+
+func (s *tradeService) GetTrade(ctx context.Context, id string) (*models.TradeHistory, error) {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, err
 	}
-	return s.tradeRepo.GetTradeByID(objID)
+	return s.tradeRepo.GetTradeByID(ctx, objID)
+}
+
+func (s *tradeService) GetTradesByUserID(ctx context.Context, userID, accountType string) ([]*models.TradeHistory, error) {
+	objID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.tradeRepo.GetTradesByUserID(ctx, objID, accountType)
 }
 
-func (s *tradeService) GetTradesByUserID(userID string) ([]*models.TradeHistory, error) {
+func (s *tradeService) GetTradesUpdatedSince(ctx context.Context, userID string, since time.Time) ([]*models.TradeHistory, error) {
 	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return nil, err
 	}
-	return s.tradeRepo.GetTradesByUserID(objID)
+	return s.tradeRepo.GetTradesUpdatedSince(ctx, objID, since)
+}
+
+func (s *tradeService) GetSettlementReport(ctx context.Context, from, to time.Time, accountType string) (*models.SettlementReport, error) {
+	if !to.After(from) {
+		return nil, errors.New("settlement window end must be after its start")
+	}
+	return s.tradeRepo.GetSettlement(ctx, from, to, accountType)
+}
+
+// exportPageSize is how many trades ExportTradeHistory loads per query.
+const exportPageSize = 500
+
+// ExportTradeHistory hands the user's open and closed trades opened in
+// [from, to) to emit one page at a time, so an export of any size never
+// holds more than a page in memory.
+func (s *tradeService) ExportTradeHistory(ctx context.Context, userID string, from, to time.Time, emit func([]*models.TradeHistory) error) error {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return newError(ErrInvalidInput, "invalid user ID")
+	}
+	if !to.After(from) {
+		return newError(ErrInvalidInput, "export window end must be after its start")
+	}
+
+	var afterID primitive.ObjectID
+	for {
+		page, err := s.tradeRepo.GetTradeHistoryPage(ctx, userObjID, from, to, afterID, exportPageSize)
+		if err != nil {
+			return err
+		}
+		if len(page) == 0 {
+			return nil
+		}
+		if err := emit(page); err != nil {
+			return err
+		}
+		if len(page) < exportPageSize {
+			return nil
+		}
+		afterID = page[len(page)-1].ID
+	}
 }
 
-func (s *tradeService) GetAllTrades() ([]*models.TradeHistory, error) {
-	return s.tradeRepo.GetAllTrades()
+func (s *tradeService) GetAllTrades(ctx context.Context, accountType string) ([]*models.TradeHistory, error) {
+	return s.tradeRepo.GetAllTrades(ctx, accountType)
 }
 
 func (s *tradeService) HandleTradeRequest(request map[string]interface{}) error {
@@ -556,7 +1534,7 @@ func (s *tradeService) HandleTradeRequest(request map[string]interface{}) error
 	if err != nil {
 		return errors.New("invalid account ID")
 	}
-	account, err := s.accountRepo.GetAccountByID(accountObjID)
+	account, err := s.accountRepo.GetAccountByID(context.Background(), accountObjID)
 	if err != nil || account == nil {
 		return errors.New("account not found")
 	}
@@ -564,246 +1542,633 @@ func (s *tradeService) HandleTradeRequest(request map[string]interface{}) error
 		return errors.New("wallet ID mismatch")
 	}
 
-	_, _, err = s.PlaceTrade(userID, accountID, symbol, accountTypeStr, tradeType, orderType, int(leverage), volume, entryPrice, stopLoss, takeProfit, expiration)
-	return err
+	_, _, err = s.PlaceTrade(userID, accountID, symbol, accountTypeStr, tradeType, orderType, int(leverage), volume, entryPrice, stopLoss, takeProfit, expiration)
+	return err
+}
+
+func (s *tradeService) HandleBalanceRequest(request map[string]interface{}) error {
+	userID, ok := request["user_id"].(string)
+	if !ok {
+		return errors.New("missing or invalid user_id")
+	}
+	accountID, ok := request["account_id"].(string)
+	if !ok {
+		return errors.New("missing or invalid account_id")
+	}
+	accountType, ok := request["account_type"].(string)
+	if !ok {
+		return errors.New("missing or invalid account_type")
+	}
+	_, err := s.RequestBalance(userID, accountID, accountType)
+	return err
+}
+
+func (s *tradeService) RequestBalance(userID, accountID, accountType string) (float64, error) {
+	ctx := context.Background()
+
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return 0, errors.New("invalid user ID")
+	}
+
+	accountObjID, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil {
+		return 0, errors.New("invalid account ID")
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userObjID)
+	if err != nil {
+		return 0, errors.New("failed to fetch user")
+	}
+
+	if user == nil {
+		return 0, errors.New("user not found")
+	}
+
+	account, err := s.accountRepo.GetAccountByID(ctx, accountObjID)
+	if err != nil {
+		return 0, errors.New("failed to fetch account")
+	}
+
+	if account == nil || account.UserID != userObjID {
+		return 0, errors.New("account not found or does not belong to user")
+	}
+
+	if !models.SameAccountType(account.AccountType, accountType) {
+		return 0, fmt.Errorf("account type mismatch: expected %s, got %s", account.AccountType, accountType)
+	}
+
+	balanceRequest := map[string]interface{}{
+		"type":         "balance_request",
+		"account_name": account.AccountName,
+		"user_id":      userID,
+		"account_id":   accountID,
+		"account_type": models.NormalizeAccountType(account.AccountType),
+		"wallet_id":    account.WalletID,
+		"timestamp":    time.Now().Unix(),
+	}
+
+	responseChan, release := s.awaitBalanceResponse(userID, accountID, account.AccountType)
+	defer release()
+
+	sentAt := time.Now()
+	if err := s.requestMT5(balanceRequest); err != nil {
+		return 0, fmt.Errorf("failed to send balance request: %w", err)
+	}
+
+	select {
+	case response := <-responseChan:
+		s.observeMT5(mt5RequestBalance, time.Since(sentAt), false)
+		if response.Error != "" {
+			return 0, fmt.Errorf("MT5 balance error: %s", response.Error)
+		}
+		return response.Balance, nil
+	case <-time.After(10 * time.Second):
+		s.observeMT5(mt5RequestBalance, 10*time.Second, true)
+		return 0, newError(ErrTimeout, "timeout waiting for balance response")
+	}
+}
+
+// brokerBalance is a balance MT5 reported and when it was fetched.
+type brokerBalance struct {
+	balance float64
+	at      time.Time
+}
+
+// CachedBalance is RequestBalance with MT5's answer reused for the balance
+// refresh TTL, for callers that can live with a balance a few seconds old.
+func (s *tradeService) CachedBalance(userID, accountID, accountType string) (float64, time.Time, error) {
+	s.brokerBalanceMu.Lock()
+	cached, ok := s.brokerBalances[accountID]
+	s.brokerBalanceMu.Unlock()
+	if ok && time.Since(cached.at) < s.balanceRefreshTTL {
+		return cached.balance, cached.at, nil
+	}
+
+	balance, err := s.RequestBalance(userID, accountID, accountType)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	fetched := brokerBalance{balance: balance, at: time.Now()}
+	s.brokerBalanceMu.Lock()
+	s.brokerBalances[accountID] = fetched
+	s.brokerBalanceMu.Unlock()
+	return fetched.balance, fetched.at, nil
+}
+
+// AccountBalance returns the stored balance of the user's account without
+// contacting MT5. With refresh it also fetches the MT5 balance, reusing one
+// fetched within the refresh TTL. The stored balance is never overwritten.
+func (s *tradeService) AccountBalance(ctx context.Context, userID, accountID string, refresh bool) (*interfaces.AccountBalance, error) {
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, newError(ErrInvalidInput, "invalid user ID")
+	}
+	accountObjID, err := primitive.ObjectIDFromHex(accountID)
+	if err != nil {
+		return nil, newError(ErrInvalidInput, "invalid account ID")
+	}
+	account, err := s.accountRepo.GetAccountByID(ctx, accountObjID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || account.UserID != userObjID {
+		return nil, ErrAccountNotFound
+	}
+
+	result := &interfaces.AccountBalance{
+		AccountID:   account.ID.Hex(),
+		AccountType: models.NormalizeAccountType(account.AccountType),
+		Currency:    account.Currency,
+		Balance:     account.Balance,
+	}
+	if !refresh {
+		return result, nil
+	}
+
+	requestedAt := time.Now()
+	balance, at, err := s.CachedBalance(userID, accountID, account.AccountType)
+	if err != nil {
+		return nil, err
+	}
+	result.BrokerBalance = &balance
+	result.BrokerCheckedAt = &at
+	result.Cached = at.Before(requestedAt)
+	return result, nil
+}
+
+func (s *tradeService) CloseTrade(tradeID, userID string) (interfaces.TradeResponse, error) {
+	ctx := context.Background()
+
+	tradeObjID, err := primitive.ObjectIDFromHex(tradeID)
+	if err != nil {
+		return interfaces.TradeResponse{}, ErrInvalidTradeID
+	}
+	userObjID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return interfaces.TradeResponse{}, newError(ErrInvalidInput, "invalid user ID")
+	}
+	trade, err := s.tradeRepo.GetTradeByID(ctx, tradeObjID)
+	if err != nil {
+		return interfaces.TradeResponse{}, err
+	}
+	if trade == nil {
+		return interfaces.TradeResponse{}, ErrTradeNotFound
+	}
+	if trade.UserID != userObjID {
+		return interfaces.TradeResponse{}, ErrTradeForbidden
+	}
+
+	// The trade's account must still belong to the caller; ownership can change
+	// after the trade was opened.
+	account, err := s.accountRepo.GetAccountByID(ctx, trade.AccountID)
+	if err != nil || account == nil {
+		return interfaces.TradeResponse{}, ErrAccountNotFound
+	}
+	if account.UserID != userObjID {
+		return interfaces.TradeResponse{}, ErrTradeForbidden
+	}
+	if !models.SameAccountType(account.AccountType, trade.AccountType) {
+		return interfaces.TradeResponse{}, newError(ErrForbidden, "trade is not associated with %s account", account.AccountType)
+	}
+	accountID := trade.AccountID.Hex()
+	accountType := trade.AccountType
+
+	closeRequest := map[string]interface{}{
+		"type":         "close_trade_request",
+		"trade_id":     tradeID,
+		"user_id":      userID,
+		"account_id":   accountID,
+		"account_type": accountType,
+		"wallet_id":    account.WalletID, // Include wallet ID
+		"timestamp":    time.Now().Unix(),
+	}
+
+	responseChan, release := s.awaitTradeResponse(tradeID)
+	defer release()
+
+	sentAt := time.Now()
+	if err := s.requestMT5(closeRequest); err != nil {
+		return interfaces.TradeResponse{}, fmt.Errorf("failed to send close trade request: %w", err)
+	}
+
+	select {
+	case response := <-responseChan:
+		s.observeMT5(mt5RequestClose, time.Since(sentAt), false)
+		if response.TradeID != tradeID {
+			return interfaces.TradeResponse{}, errors.New("received response for wrong trade ID")
+		}
+		return response, nil
+	case <-time.After(mt5ResponseTimeout):
+		s.observeMT5(mt5RequestClose, mt5ResponseTimeout, true)
+		return interfaces.TradeResponse{}, newError(ErrTimeout, "timeout waiting for MT5 close trade response")
+	}
 }
 
-func (s *tradeService) HandleBalanceRequest(request map[string]interface{}) error {
-	userID, ok := request["user_id"].(string)
-	if !ok {
-		return errors.New("missing or invalid user_id")
-	}
-	accountID, ok := request["account_id"].(string)
-	if !ok {
-		return errors.New("missing or invalid account_id")
+// CloseTradesBySymbol closes every open trade on the account in symbol. A
+// non-empty accountType must match the account's.
+func (s *tradeService) CloseTradesBySymbol(userID, accountType, accountID, symbol string) (interfaces.BulkCloseResult, error) {
+	if symbol == "" {
+		return interfaces.BulkCloseResult{}, newError(ErrInvalidInput, "symbol is required")
 	}
-	accountType, ok := request["account_type"].(string)
-	if !ok {
-		return errors.New("missing or invalid account_type")
+	return s.CloseTradesByGroup(userID, accountType, accountID, symbol, "")
+}
+
+// CloseTradesByDirection closes every open BUY or every open SELL trade on
+// the account. A non-empty accountType must match the account's.
+func (s *tradeService) CloseTradesByDirection(userID, accountType, accountID string, tradeType models.TradeType) (interfaces.BulkCloseResult, error) {
+	if tradeType == "" {
+		return interfaces.BulkCloseResult{}, newError(ErrInvalidInput, "trade type is required")
 	}
-	_, err := s.RequestBalance(userID, accountID, accountType)
-	return err
+	return s.CloseTradesByGroup(userID, accountType, accountID, "", tradeType)
 }
 
-func (s *tradeService) RequestBalance(userID, accountID, accountType string) (float64, error) {
+// CloseTradesByGroup closes every open trade on an account that matches the
+// given symbol and/or direction. Empty filters match everything, and an empty
+// accountType accepts the account whatever its type.
+func (s *tradeService) CloseTradesByGroup(userID, accountType, accountID, symbol string, tradeType models.TradeType) (interfaces.BulkCloseResult, error) {
+	ctx := context.Background()
+
 	userObjID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return 0, errors.New("invalid user ID")
+		return interfaces.BulkCloseResult{}, newError(ErrInvalidInput, "invalid user ID")
 	}
-
 	accountObjID, err := primitive.ObjectIDFromHex(accountID)
 	if err != nil {
-		return 0, errors.New("invalid account ID")
-	}
-
-	user, err := s.userRepo.GetUserByID(userObjID)
-	if err != nil {
-		return 0, errors.New("failed to fetch user")
+		return interfaces.BulkCloseResult{}, newError(ErrInvalidInput, "invalid account ID")
 	}
-
-	if user == nil {
-		return 0, errors.New("user not found")
+	if tradeType != "" && tradeType != models.TradeTypeBuy && tradeType != models.TradeTypeSell {
+		return interfaces.BulkCloseResult{}, newError(ErrInvalidInput, "invalid trade type")
 	}
 
-	account, err := s.accountRepo.GetAccountByID(accountObjID)
-	if err != nil {
-		return 0, errors.New("failed to fetch account")
+	account, err := s.accountRepo.GetAccountByID(ctx, accountObjID)
+	if err != nil || account == nil || account.UserID != userObjID {
+		return interfaces.BulkCloseResult{}, newError(ErrNotFound, "account not found or does not belong to user")
 	}
-
-	if account == nil || account.UserID != userObjID {
-		return 0, errors.New("account not found or does not belong to user")
+	if accountType != "" && !models.SameAccountType(account.AccountType, accountType) {
+		return interfaces.BulkCloseResult{}, newError(ErrInvalidInput, "account type mismatch: expected %s, got %s", account.AccountType, accountType)
 	}
 
-	if account.AccountType != accountType {
-		return 0, fmt.Errorf("account type mismatch: expected %s, got %s", account.AccountType, accountType)
+	// Trades store the broker symbol name; accept the display name as well.
+	if symbol != "" {
+		symbols, err := s.symbolRepo.GetAllSymbols(ctx)
+		if err != nil {
+			return interfaces.BulkCloseResult{}, errors.New("failed to fetch symbols")
+		}
+		if sym := models.ResolveSymbol(symbols, symbol); sym != nil {
+			symbol = sym.SymbolName
+		}
 	}
 
-	balanceRequest := map[string]interface{}{
-		"type":         "balance_request",
-		"account_name": accountObjID,
-		"user_id":      userID,
-		"account_id":   accountID,
-		"account_type": accountType,
-		"wallet_id":    account.WalletID,
-		"timestamp":    time.Now().Unix(),
+	trades, err := s.tradeRepo.GetTradesByUserID(ctx, userObjID, "")
+	if err != nil {
+		return interfaces.BulkCloseResult{}, err
 	}
 
-	if err := s.sendToMT5(balanceRequest); err != nil {
-		return 0, fmt.Errorf("failed to send balance request: %v", err)
+	var targets []*models.TradeHistory
+	for _, trade := range trades {
+		if trade.AccountID != accountObjID || trade.Status != string(models.TradeStatusOpen) {
+			continue
+		}
+		if symbol != "" && trade.Symbol != symbol {
+			continue
+		}
+		if tradeType != "" && trade.TradeType != tradeType {
+			continue
+		}
+		targets = append(targets, trade)
+	}
+
+	result := interfaces.BulkCloseResult{
+		Requested: len(targets),
+		Results:   make([]interfaces.CloseResult, len(targets)),
+	}
+	var wg sync.WaitGroup
+	for i, trade := range targets {
+		wg.Add(1)
+		go func(i int, trade *models.TradeHistory) {
+			defer wg.Done()
+			res := interfaces.CloseResult{TradeID: trade.ID.Hex()}
+			response, err := s.CloseTrade(trade.ID.Hex(), userID)
+			switch {
+			case err != nil:
+				res.Error = err.Error()
+			case response.Status != "SUCCESS":
+				// MT5 answered but refused, e.g. because the market is closed.
+				res.Status = response.Status
+				res.Error = fmt.Sprintf("MT5 did not close the trade: %s", response.Status)
+			default:
+				res.Status = response.Status
+			}
+			result.Results[i] = res
+		}(i, trade)
 	}
+	wg.Wait()
 
-	select {
-	case response := <-s.balanceChan:
-		if response.UserID != userID || response.AccountID != accountID || response.AccountType != accountType {
-			return 0, errors.New("invalid balance response")
+	for _, res := range result.Results {
+		if res.Error == "" {
+			result.Closed++
+		} else {
+			result.Failed++
 		}
-		return response.Balance, nil
-	case <-time.After(10 * time.Second):
-		return 0, errors.New("timeout waiting for balance response")
 	}
+	return result, nil
 }
 
-func (s *tradeService) CloseTrade(tradeID, userID, accountType, accountID string) (interfaces.TradeResponse, error) {
+// CancelPendingOrder withdraws a pending order. User-to-user orders rest in the
+// local book and are cancelled here with their margin refunded; platform
+// orders are working at MT5, which is asked to delete them, and are only
+// cancelled here once MT5 confirms.
+func (s *tradeService) CancelPendingOrder(tradeID, userID string) (interfaces.TradeResponse, error) {
+	ctx := context.Background()
+
 	tradeObjID, err := primitive.ObjectIDFromHex(tradeID)
 	if err != nil {
-		return interfaces.TradeResponse{}, errors.New("invalid trade ID")
+		return interfaces.TradeResponse{}, ErrInvalidTradeID
 	}
 	userObjID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return interfaces.TradeResponse{}, errors.New("invalid user ID")
-	}
-	accountObjID, err := primitive.ObjectIDFromHex(accountID)
-	if err != nil {
-		return interfaces.TradeResponse{}, errors.New("invalid account ID")
+		return interfaces.TradeResponse{}, newError(ErrInvalidInput, "invalid user ID")
 	}
-	trade, err := s.tradeRepo.GetTradeByID(tradeObjID)
+	trade, err := s.tradeRepo.GetTradeByID(ctx, tradeObjID)
 	if err != nil {
 		return interfaces.TradeResponse{}, err
 	}
 	if trade == nil {
-		return interfaces.TradeResponse{}, errors.New("trade not found")
+		return interfaces.TradeResponse{}, ErrTradeNotFound
 	}
-	if trade.UserID != userObjID || trade.AccountID != accountObjID {
-		return interfaces.TradeResponse{}, errors.New("trade belongs to another user or account")
+	if trade.UserID != userObjID {
+		return interfaces.TradeResponse{}, ErrTradeForbidden
 	}
-	if trade.AccountType != accountType {
-		return interfaces.TradeResponse{}, fmt.Errorf("trade is not associated with %s account", accountType)
+	if trade.Status != string(models.TradeStatusPending) {
+		return interfaces.TradeResponse{}, newError(ErrConflict, "trade %s is not a pending order", tradeID)
 	}
 
-	account, err := s.accountRepo.GetAccountByID(accountObjID)
-	if err != nil || account == nil {
-		return interfaces.TradeResponse{}, errors.New("account not found")
+	var mt5Status string
+	if trade.ExecutionType != models.ExecutionTypeUserToUser {
+		response, err := s.cancelAtMT5(ctx, trade, models.CloseReasonManual)
+		if err != nil {
+			return interfaces.TradeResponse{}, err
+		}
+		if !isCancelConfirmation(response.Status) {
+			return interfaces.TradeResponse{}, newError(ErrConflict, "MT5 did not cancel pending order %s: %s", tradeID, response.Status)
+		}
+		mt5Status = response.Status
 	}
 
-	closeRequest := map[string]interface{}{
-		"type":         "close_trade_request",
-		"trade_id":     tradeID,
-		"user_id":      userID,
-		"account_id":   accountID,
-		"account_type": accountType,
-		"wallet_id":    account.WalletID, // Include wallet ID
-		"timestamp":    time.Now().Unix(),
+	cancelled, err := s.tradeRepo.CancelPendingTrade(ctx, trade.ID)
+	if err != nil {
+		return interfaces.TradeResponse{}, err
 	}
-
-	responseChan := make(chan interfaces.TradeResponse, 1)
-	s.tradeResponseMu.Lock()
-	s.tradeResponseChans[tradeID] = responseChan
-	s.tradeResponseMu.Unlock()
-
-	defer func() {
-		s.tradeResponseMu.Lock()
-		delete(s.tradeResponseChans, tradeID)
-		close(responseChan)
-		s.tradeResponseMu.Unlock()
-	}()
-
-	if err := s.sendToMT5(closeRequest); err != nil {
-		return interfaces.TradeResponse{}, fmt.Errorf("failed to send close trade request: %v", err)
+	if !cancelled {
+		return interfaces.TradeResponse{}, newError(ErrConflict, "pending order %s was filled or cancelled concurrently", tradeID)
 	}
+	// A partial fill since the order was read has reduced the volume to refund.
+	if current, err := s.tradeRepo.GetTradeByID(ctx, trade.ID); err == nil && current != nil {
+		trade = current
+	}
+	s.removeFromBook(trade)
+	s.refundTrade(ctx, trade.AccountID, trade.Margin())
 
-	select {
-	case response := <-responseChan:
-		if response.TradeID != tradeID {
-			return interfaces.TradeResponse{}, errors.New("received response for wrong trade ID")
-		}
-		return response, nil
-	case <-time.After(30 * time.Second):
-		return interfaces.TradeResponse{}, errors.New("timeout waiting for MT5 close trade response")
+	trade.Status = string(models.TradeStatusCancelled)
+	metadata := map[string]interface{}{
+		"trade_id":   tradeID,
+		"account_id": trade.AccountID.Hex(),
+		"symbol":     trade.Symbol,
+		"volume":     trade.Volume,
+		"refunded":   trade.Margin(),
+	}
+	if mt5Status != "" {
+		metadata["mt5_status"] = mt5Status
+	}
+	if err := s.logService.LogAction(trade.UserID, "PendingOrderCancelled", "Pending order cancelled", "", metadata); err != nil {
+		log.Printf("error: %v", err)
 	}
+	s.hub.BroadcastTrade(trade)
+
+	return interfaces.TradeResponse{
+		TradeID:     tradeID,
+		UserID:      userID,
+		AccountType: trade.AccountType,
+		AccountID:   trade.AccountID.Hex(),
+		Status:      string(models.TradeStatusCancelled),
+		Timestamp:   float64(time.Now().Unix()),
+	}, nil
 }
 
+// StreamTrades accepts the account type in any casing; the stream is keyed
+// and requested from MT5 under the lowercase form.
 func (s *tradeService) StreamTrades(userID, accountType string) (chan models.OrderStreamResponse, error) {
-	if accountType != "DEMO" && accountType != "REAL" {
+	if !models.IsTradingAccountType(accountType) {
 		return nil, errors.New("invalid account type")
 	}
+	accountType = models.NormalizeAccountType(accountType)
 
 	userObjID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return nil, errors.New("invalid user ID")
 	}
-	user, err := s.userRepo.GetUserByID(userObjID)
+	user, err := s.userRepo.GetUserByID(context.Background(), userObjID)
 	if err != nil || user == nil {
 		return nil, errors.New("user not found")
 	}
 
 	streamKey := userID + ":" + accountType
 	ctx, cancel := context.WithCancel(context.Background())
-	streamChan := make(chan models.OrderStreamResponse, 256)
+	stream := &orderStream{ctx: ctx, cancel: cancel, ch: make(chan models.OrderStreamResponse, 256)}
+	stream.touch()
+
+	s.ordersResponseMu.Lock()
+	if previous, exists := s.orderStreams[streamKey]; exists {
+		previous.cancel()
+	}
+	s.orderStreams[streamKey] = stream
+	s.ordersResponseMu.Unlock()
+
+	go s.watchOrderStream(streamKey, stream)
+
+	if err := s.sendToMT5(orderStreamRequest(userID, accountType)); err != nil {
+		stream.cancel()
+		return nil, fmt.Errorf("failed to send order stream request: %v", err)
+	}
+
+	return stream.ch, nil
+}
+
+// orderStream is one open StreamTrades subscription. lastSeen is the last
+// time the client proved it was still there, in Unix nanoseconds.
+type orderStream struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	ch       chan models.OrderStreamResponse
+	lastSeen atomic.Int64
+}
+
+func (o *orderStream) touch() {
+	o.lastSeen.Store(time.Now().UnixNano())
+}
+
+func (o *orderStream) idleFor() time.Duration {
+	return time.Since(time.Unix(0, o.lastSeen.Load()))
+}
 
+// watchOrderStream ends the stream when it is stopped or replaced, when it
+// reaches its maximum lifetime, or when the client has gone quiet for longer
+// than the idle timeout. A zero idle timeout disables the idle check.
+func (s *tradeService) watchOrderStream(streamKey string, stream *orderStream) {
+	lifetime := time.NewTimer(s.streamLifetime)
+	defer lifetime.Stop()
+
+	var idleCheck <-chan time.Time
+	if s.streamIdleTimeout > 0 {
+		ticker := time.NewTicker(max(s.streamIdleTimeout/4, time.Second))
+		defer ticker.Stop()
+		idleCheck = ticker.C
+	}
+
+	for {
+		select {
+		case <-stream.ctx.Done():
+			s.endOrderStream(streamKey, stream)
+			return
+		case <-lifetime.C:
+			log.Printf("Order stream %s reached its maximum lifetime of %s", streamKey, s.streamLifetime)
+			s.endOrderStream(streamKey, stream)
+			return
+		case <-idleCheck:
+			if idle := stream.idleFor(); idle > s.streamIdleTimeout {
+				log.Printf("Order stream %s idle for %s, closing", streamKey, idle.Round(time.Second))
+				s.endOrderStream(streamKey, stream)
+				return
+			}
+		}
+	}
+}
+
+// endOrderStream frees the stream's slot, unless a newer stream already took
+// it, and closes its channel. Only the stream's watcher calls it. Responses
+// are only sent to streams in the map, under the same lock, so nothing sends
+// on the channel once it is closed.
+func (s *tradeService) endOrderStream(streamKey string, stream *orderStream) {
+	s.ordersResponseMu.Lock()
+	if s.orderStreams[streamKey] == stream {
+		delete(s.orderStreams, streamKey)
+	}
+	s.ordersResponseMu.Unlock()
+
+	stream.cancel()
+	close(stream.ch)
+}
+
+// TouchStream records that the client behind a stream is still connected,
+// postponing its idle expiry.
+func (s *tradeService) TouchStream(userID, accountType string) {
+	streamKey := userID + ":" + models.NormalizeAccountType(accountType)
 	s.ordersResponseMu.Lock()
-	s.streamCtx[streamKey] = cancel
-	s.ordersResponseChans[streamKey] = streamChan
+	stream, exists := s.orderStreams[streamKey]
 	s.ordersResponseMu.Unlock()
+	if exists {
+		stream.touch()
+	}
+}
 
-	streamRequest := map[string]interface{}{
+func orderStreamRequest(userID, accountType string) map[string]interface{} {
+	return map[string]interface{}{
 		"type":         "order_stream_request",
 		"user_id":      userID,
 		"account_type": accountType,
 		"timestamp":    time.Now().Unix(),
 	}
+}
 
-	if err := s.sendToMT5(streamRequest); err != nil {
-		s.ordersResponseMu.Lock()
-		delete(s.streamCtx, streamKey)
-		delete(s.ordersResponseChans, streamKey)
-		s.ordersResponseMu.Unlock()
-		close(streamChan)
-		return nil, fmt.Errorf("failed to send order stream request: %v", err)
+// resumeOrderStreams re-sends the request of every stream still open.
+func (s *tradeService) resumeOrderStreams() {
+	s.ordersResponseMu.Lock()
+	streamKeys := make([]string, 0, len(s.orderStreams))
+	for streamKey := range s.orderStreams {
+		streamKeys = append(streamKeys, streamKey)
 	}
+	s.ordersResponseMu.Unlock()
 
-	go func() {
-		select {
-		case <-ctx.Done():
-		case <-time.After(24 * time.Hour):
-		}
-		s.ordersResponseMu.Lock()
-		if cancel, exists := s.streamCtx[streamKey]; exists {
-			cancel()
-			delete(s.streamCtx, streamKey)
-			delete(s.ordersResponseChans, streamKey)
+	for _, streamKey := range streamKeys {
+		userID, accountType, _ := strings.Cut(streamKey, ":")
+		if err := s.sendToMT5(orderStreamRequest(userID, accountType)); err != nil {
+			log.Printf("Failed to resume order stream %s after MT5 reconnect: %v", streamKey, err)
+			continue
 		}
-		s.ordersResponseMu.Unlock()
-		close(streamChan)
-	}()
-
-	return streamChan, nil
+		log.Printf("Resumed order stream %s after MT5 reconnect", streamKey)
+	}
 }
 
 func (s *tradeService) StopStream(userID, accountType string) error {
-	streamKey := userID + ":" + accountType
-	s.tradeResponseMu.Lock()
-	defer s.tradeResponseMu.Unlock()
+	streamKey := userID + ":" + models.NormalizeAccountType(accountType)
+	s.ordersResponseMu.Lock()
+	defer s.ordersResponseMu.Unlock()
 
-	if cancel, exists := s.streamCtx[streamKey]; exists {
-		cancel()
-		delete(s.streamCtx, streamKey)
+	if stream, exists := s.orderStreams[streamKey]; exists {
+		stream.cancel()
+		delete(s.orderStreams, streamKey)
 		return nil
 	}
 	return fmt.Errorf("no active stream found for user %s and account type %s", userID, accountType)
 }
 
+// orphanResponse raises an alert for a response MT5 sent about a trade the
+// platform has no record of. The returned error wraps ErrUnknownTrade so the
+// socket server keeps the raw message as an orphan dead letter.
+func (s *tradeService) orphanResponse(messageType string, response interfaces.TradeResponse) error {
+	metadata := map[string]interface{}{
+		"message_type":     messageType,
+		"trade_id":         response.TradeID,
+		"user_id":          response.UserID,
+		"account_type":     response.AccountType,
+		"account_id":       response.AccountID,
+		"status":           response.Status,
+		"matched_trade_id": response.MatchedTradeID,
+	}
+	log.Printf("MT5 sent a %s for unknown trade %q", messageType, response.TradeID)
+	if err := s.logService.LogAction(primitive.NilObjectID, "MT5OrphanResponse", "MT5 responded for a trade unknown to the platform", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+	s.events.Publish(models.WebhookEventMT5OrphanResponse, metadata)
+	return fmt.Errorf("%w: %s %q", interfaces.ErrUnknownTrade, messageType, response.TradeID)
+}
+
 func (s *tradeService) HandleCloseTradeResponse(response interfaces.TradeResponse) error {
+	ctx := context.Background()
+
 	if response.Status != "SUCCESS" {
+		// The trade stays open; tell the caller waiting on the close at once
+		// rather than leaving it to time out.
+		s.notifyTradeResponse(response)
 		return fmt.Errorf("MT5 failed to close trade: %s", response.Status)
 	}
 	tradeID, err := primitive.ObjectIDFromHex(response.TradeID)
 	if err != nil {
-		return errors.New("invalid trade ID")
+		return s.orphanResponse("close_trade_response", response)
 	}
-	trade, err := s.tradeRepo.GetTradeByID(tradeID)
+	trade, err := s.tradeRepo.GetTradeByID(ctx, tradeID)
 	if err != nil {
 		return err
 	}
 	if trade == nil {
-		return errors.New("trade not found")
+		return s.orphanResponse("close_trade_response", response)
 	}
-	if trade.AccountType != response.AccountType {
+	if !models.SameAccountType(trade.AccountType, response.AccountType) {
 		return fmt.Errorf("trade account type mismatch: expected %s, got %s", trade.AccountType, response.AccountType)
 	}
 
-	account, err := s.accountRepo.GetAccountByID(trade.AccountID)
-	if err != nil || account == nil {
-		return errors.New("account not found")
+	// MT5 may redeliver close responses; only the first one settles the trade.
+	if trade.Status == string(models.TradeStatusClosed) {
+		log.Printf("Ignoring duplicate close response for trade %s", response.TradeID)
+		s.notifyTradeResponse(response)
+		return nil
+	}
+
+	if !canTransition(models.TradeStatus(trade.Status), models.TradeStatusClosed) {
+		err := transitionError(trade, models.TradeStatusClosed)
+		log.Printf("Rejected close trade response: %v", err)
+		return err
 	}
 
 	trade.Status = string(models.TradeStatusClosed)
@@ -812,56 +2177,153 @@ func (s *tradeService) HandleCloseTradeResponse(response interfaces.TradeRespons
 	nanos := int64((response.Timestamp - float64(secs)) * 1e9)
 	*trade.CloseTime = time.Unix(secs, nanos)
 	trade.ClosePrice = response.ClosePrice
-	trade.CloseReason = response.CloseReason
+	trade.CloseReason = models.ParseCloseReason(response.CloseReason)
+	// The platform's commission was charged when the order was placed and
+	// stays on the trade; MT5's own commission is recorded but not charged.
+	trade.MT5Commission = response.Commission
+	trade.Swap = response.Swap
 
-	profit := (response.ClosePrice - trade.EntryPrice) * trade.Volume
+	rawProfit := (response.ClosePrice - trade.EntryPrice) * trade.Volume
 	if trade.TradeType == models.TradeTypeSell {
-		profit = -profit
-	}
-
-	margin := trade.Volume * trade.EntryPrice / float64(trade.Leverage)
-	account.Balance += profit + margin
-	if err := s.accountRepo.UpdateAccount(account); err != nil {
-		log.Printf("Failed to update account balance: %v", err)
+		rawProfit = -rawProfit
 	}
+	profit, profitCurrency := s.profitInAccountCurrency(ctx, trade, rawProfit)
+	trade.Profit = profit
 
-	err = s.tradeRepo.SaveTrade(trade)
+	closed, err := s.tradeRepo.MarkTradeClosed(ctx, trade)
 	if err != nil {
 		return err
 	}
+	if !closed {
+		log.Printf("Ignoring close response for trade %s: it is no longer open", response.TradeID)
+		s.notifyTradeResponse(response)
+		return nil
+	}
+
+	if err := s.accountRepo.AdjustBalance(ctx, trade.AccountID, profit+trade.Swap+trade.Margin()); err != nil {
+		log.Printf("Failed to update account balance: %v", err)
+	}
+	s.recordCloseOutcome(ctx, trade, profit+trade.Swap)
 
 	metadata := map[string]interface{}{
-		"trade_id":     response.TradeID,
-		"account_id":   trade.AccountID.Hex(),
-		"account_type": response.AccountType,
-		"close_price":  response.ClosePrice,
-		"close_reason": response.CloseReason,
+		"trade_id":       response.TradeID,
+		"account_id":     trade.AccountID.Hex(),
+		"account_type":   response.AccountType,
+		"close_price":    response.ClosePrice,
+		"close_reason":   trade.CloseReason,
+		"mt5_reason":     response.CloseReason,
+		"profit":         profit,
+		"commission":     trade.Commission,
+		"mt5_commission": trade.MT5Commission,
+		"swap":           trade.Swap,
+	}
+	if profitCurrency != "" {
+		metadata["raw_profit"] = rawProfit
+		metadata["profit_currency"] = profitCurrency
 	}
 	if err := s.logService.LogAction(trade.UserID, "TradeResponse", "Trade closed", "", metadata); err != nil {
 		log.Printf("error: %v", err)
 	}
+	s.events.Publish(models.WebhookEventTradeClosed, trade)
+
+	s.notifyTradeResponse(response)
+
+	s.hub.BroadcastTrade(trade)
+	return nil
+}
+
+// profitInAccountCurrency converts a trade's price-difference profit, which is
+// in the symbol's profit currency, to the currency of the trade's account. It
+// also returns the currency converted from, empty when no conversion applied.
+// MT5 has already closed the position by now, so if the conversion fails the
+// unconverted profit is credited and the failure is audited for an admin to
+// correct.
+func (s *tradeService) profitInAccountCurrency(ctx context.Context, trade *models.TradeHistory, profit float64) (float64, string) {
+	if s.currencyService == nil || profit == 0 {
+		return profit, ""
+	}
+	symbol, err := s.symbolByName(ctx, trade.Symbol)
+	if err != nil || symbol.ProfitCurrency == "" {
+		return profit, ""
+	}
+	account, err := s.accountRepo.GetAccountByID(ctx, trade.AccountID)
+	if err == nil && account == nil {
+		err = ErrAccountNotFound
+	}
+	if err == nil {
+		var converted float64
+		if converted, err = s.currencyService.Convert(ctx, profit, symbol.ProfitCurrency, account.Currency); err == nil {
+			return converted, symbol.ProfitCurrency
+		}
+	}
+
+	log.Printf("Failed to convert profit of trade %s from %s: %v", trade.ID.Hex(), symbol.ProfitCurrency, err)
+	metadata := map[string]interface{}{
+		"trade_id":        trade.ID.Hex(),
+		"account_id":      trade.AccountID.Hex(),
+		"profit":          profit,
+		"profit_currency": symbol.ProfitCurrency,
+		"error":           err.Error(),
+	}
+	if err := s.logService.LogAction(trade.UserID, "ProfitConversionFailed", "Trade profit credited without currency conversion", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+	return profit, ""
+}
 
+// awaitTradeResponse registers a channel for the MT5 reply to tradeID. The
+// returned release unregisters it once the caller stops waiting. The channel
+// is never closed, so a late notifyTradeResponse cannot panic on it.
+func (s *tradeService) awaitTradeResponse(tradeID string) (chan interfaces.TradeResponse, func()) {
+	ch := make(chan interfaces.TradeResponse, s.tradeResponseBuffer)
 	s.tradeResponseMu.Lock()
-	if ch, exists := s.tradeResponseChans[response.TradeID]; exists {
-		select {
-		case ch <- response:
-		default:
-			log.Printf("Close trade response channel for trade %s is full or closed", response.TradeID)
+	s.tradeResponseChans[tradeID] = ch
+	s.tradeResponseMu.Unlock()
+
+	return ch, func() {
+		s.tradeResponseMu.Lock()
+		if s.tradeResponseChans[tradeID] == ch {
+			delete(s.tradeResponseChans, tradeID)
 		}
+		s.tradeResponseMu.Unlock()
 	}
+}
+
+// notifyTradeResponse hands a response to the caller waiting on it, if any.
+// A full channel means the waiter has not read yet but almost certainly is
+// about to, so the send waits briefly instead of dropping the response.
+func (s *tradeService) notifyTradeResponse(response interfaces.TradeResponse) {
+	s.tradeResponseMu.Lock()
+	ch, exists := s.tradeResponseChans[response.TradeID]
 	s.tradeResponseMu.Unlock()
+	if !exists {
+		return
+	}
 
-	s.hub.BroadcastTrade(trade)
-	return nil
+	select {
+	case ch <- response:
+		return
+	default:
+	}
+
+	timer := time.NewTimer(s.responseSendTimeout)
+	defer timer.Stop()
+	select {
+	case ch <- response:
+	case <-timer.C:
+		log.Printf("Dropped response for trade %s: nobody read it within %s", response.TradeID, s.responseSendTimeout)
+	}
 }
 
 func (s *tradeService) HandleOrderStreamResponse(response models.OrderStreamResponse) error {
+	ctx := context.Background()
+
 	for _, trade := range response.Trades {
-		if trade.AccountType != response.AccountType {
+		if !models.SameAccountType(trade.AccountType, response.AccountType) {
 			continue
 		}
 
-		existingTrade, err := s.tradeRepo.GetTradeByID(trade.ID)
+		existingTrade, err := s.tradeRepo.GetTradeByID(ctx, trade.ID)
 		if err != nil {
 			continue
 		}
@@ -882,7 +2344,6 @@ func (s *tradeService) HandleOrderStreamResponse(response models.OrderStreamResp
 			TakeProfit:     trade.TakeProfit,
 			OpenTime:       openTime,
 			CloseTime:      nil,
-			CloseReason:    "",
 			Status:         trade.Status,
 			MatchedTradeID: "",
 			Expiration:     nil,
@@ -890,19 +2351,38 @@ func (s *tradeService) HandleOrderStreamResponse(response models.OrderStreamResp
 		}
 
 		if existingTrade == nil {
-			if err = s.tradeRepo.SaveTrade(&trade); err != nil {
-				continue
-			}
-		} else {
-			existingTrade.Status = trade.Status
-			existingTrade.AccountType = trade.AccountType
-			existingTrade.AccountID = trade.AccountID
-			existingTrade.Volume = trade.Volume
-			if err = s.tradeRepo.SaveTrade(existingTrade); err != nil {
+			if err = s.tradeRepo.SaveTrade(ctx, &trade); err != nil {
 				continue
 			}
+			s.hub.BroadcastTrade(&trade)
+			continue
+		}
+
+		// The stream only lists live positions, so it lags a close the
+		// platform has just settled. A finished trade is never reopened.
+		if isTerminal(models.TradeStatus(existingTrade.Status)) {
+			continue
+		}
+		// Streams repeat the same positions every time; only real changes
+		// are written and broadcast.
+		if existingTrade.Status == trade.Status &&
+			models.SameAccountType(existingTrade.AccountType, trade.AccountType) &&
+			existingTrade.AccountID == trade.AccountID &&
+			existingTrade.Volume == trade.Volume {
+			continue
+		}
+		if !canTransition(models.TradeStatus(existingTrade.Status), models.TradeStatus(trade.Status)) {
+			log.Printf("Rejected order stream update: %v", transitionError(existingTrade, models.TradeStatus(trade.Status)))
+			continue
+		}
+		existingTrade.Status = trade.Status
+		existingTrade.AccountType = trade.AccountType
+		existingTrade.AccountID = trade.AccountID
+		existingTrade.Volume = trade.Volume
+		if err = s.tradeRepo.SaveTrade(ctx, existingTrade); err != nil {
+			continue
 		}
-		s.hub.BroadcastTrade(&trade)
+		s.hub.BroadcastTrade(existingTrade)
 	}
 
 	metadata := map[string]interface{}{
@@ -915,10 +2395,10 @@ func (s *tradeService) HandleOrderStreamResponse(response models.OrderStreamResp
 	}
 
 	s.ordersResponseMu.Lock()
-	streamKey := response.UserID.Hex() + ":" + response.AccountType
-	if ch, exists := s.ordersResponseChans[streamKey]; exists {
+	streamKey := response.UserID.Hex() + ":" + models.NormalizeAccountType(response.AccountType)
+	if stream, exists := s.orderStreams[streamKey]; exists {
 		select {
-		case ch <- response:
+		case stream.ch <- response:
 		default:
 			log.Printf("Stream channel for %s is full or closed", streamKey)
 		}
@@ -944,20 +2424,20 @@ func (s *tradeService) ModifyTrade(ctx context.Context, userID, tradeID, account
 		return interfaces.TradeResponse{}, errors.New("invalid account ID")
 	}
 
-	user, err := s.userRepo.GetUserByID(userObjID)
+	user, err := s.userRepo.GetUserByID(ctx, userObjID)
 	if err != nil || user == nil {
 		return interfaces.TradeResponse{}, errors.New("user not found")
 	}
 
-	account, err := s.accountRepo.GetAccountByID(accountObjID)
+	account, err := s.accountRepo.GetAccountByID(ctx, accountObjID)
 	if err != nil || account == nil || account.UserID != userObjID {
 		return interfaces.TradeResponse{}, errors.New("account not found or does not belong to user")
 	}
-	if account.AccountType != accountType {
+	if !models.SameAccountType(account.AccountType, accountType) {
 		return interfaces.TradeResponse{}, fmt.Errorf("account type mismatch: expected %s, got %s", account.AccountType, accountType)
 	}
 
-	trade, err := s.tradeRepo.GetTradeByID(tradeObjID)
+	trade, err := s.tradeRepo.GetTradeByID(ctx, tradeObjID)
 	if err != nil {
 		return interfaces.TradeResponse{}, err
 	}
@@ -980,6 +2460,39 @@ func (s *tradeService) ModifyTrade(ctx context.Context, userID, tradeID, account
 			return interfaces.TradeResponse{}, errors.New("invalid volume")
 		}
 	}
+	if entryPrice > 0 && (trade.StopLoss > 0 || trade.TakeProfit > 0) {
+		symbol, err := s.symbolByName(ctx, trade.Symbol)
+		if err != nil {
+			return interfaces.TradeResponse{}, err
+		}
+		if err := checkStopsLevel(symbol, entryPrice, trade.StopLoss, trade.TakeProfit); err != nil {
+			return interfaces.TradeResponse{}, err
+		}
+	}
+
+	// The order holds margin for its volume at its entry price, so changing
+	// either moves it. An increase is reserved before MT5 is asked, so any
+	// change MT5 accepts is already paid for; a decrease is refunded once MT5
+	// confirms. Commission is a per-order fee and does not change.
+	newVolume, newPrice := trade.Volume, trade.EntryPrice
+	if volume > 0 {
+		newVolume = volume
+	}
+	if entryPrice > 0 {
+		newPrice = entryPrice
+	}
+	marginDelta := trade.MarginFor(newVolume, newPrice) - trade.Margin()
+	reserved := 0.0
+	if marginDelta > 0 {
+		debited, err := s.accountRepo.DebitBalance(ctx, account.ID, marginDelta)
+		if err != nil {
+			return interfaces.TradeResponse{}, err
+		}
+		if !debited {
+			return interfaces.TradeResponse{}, newError(ErrInsufficientBalance, "insufficient balance for %.2f additional margin", marginDelta)
+		}
+		reserved = marginDelta
+	}
 
 	request := map[string]interface{}{
 		"type":         "modify_trade_request",
@@ -992,37 +2505,57 @@ func (s *tradeService) ModifyTrade(ctx context.Context, userID, tradeID, account
 		"volume":       volume,
 	}
 
-	responseChan := make(chan interfaces.TradeResponse, 1)
-	s.tradeResponseMu.Lock()
-	s.tradeResponseChans[tradeID] = responseChan
-	s.tradeResponseMu.Unlock()
-	defer func() {
-		s.tradeResponseMu.Lock()
-		delete(s.tradeResponseChans, tradeID)
-		close(responseChan)
-		s.tradeResponseMu.Unlock()
-	}()
+	responseChan, release := s.awaitTradeResponse(tradeID)
+	defer release()
+	done := s.expectControlReply(tradeID, time.Now().Add(mt5ModifyTimeout))
+	defer done()
 
-	if err := s.sendToMT5(request); err != nil {
-		return interfaces.TradeResponse{}, fmt.Errorf("failed to send modify request: %v", err)
+	sentAt := time.Now()
+	if err := s.requestMT5(request); err != nil {
+		s.refundTrade(ctx, account.ID, reserved)
+		return interfaces.TradeResponse{}, fmt.Errorf("failed to send modify request: %w", err)
 	}
 
 	select {
 	case response := <-responseChan:
-		if response.Status == "MODIFIED" {
-			if entryPrice > 0 {
-				trade.EntryPrice = entryPrice
-			}
-			if volume > 0 {
-				trade.Volume = volume
+		s.observeMT5(mt5RequestModify, time.Since(sentAt), false)
+		if response.Status != "MODIFIED" {
+			s.refundTrade(ctx, account.ID, reserved)
+			return response, nil
+		}
+
+		trade.EntryPrice = newPrice
+		trade.Volume = newVolume
+		err := s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+			if err := s.tradeRepo.SaveTrade(ctx, trade); err != nil {
+				return err
 			}
-			if err := s.tradeRepo.SaveTrade(trade); err != nil {
-				log.Printf("Failed to save modified trade: %v", err)
+			if marginDelta < 0 {
+				return s.accountRepo.AdjustBalance(ctx, account.ID, -marginDelta)
 			}
-			s.logService.LogAction(userObjID, "ModifyTrade", fmt.Sprintf("Modified trade %s: entry_price=%f, volume=%f", tradeID, entryPrice, volume), "", nil)
+			return nil
+		})
+		if err != nil {
+			// The stored trade keeps its old terms, so it must keep its old
+			// margin too.
+			log.Printf("Failed to save modified trade %s: %v", tradeID, err)
+			s.refundTrade(ctx, account.ID, reserved)
+			return response, nil
+		}
+
+		metadata := map[string]interface{}{
+			"trade_id":     tradeID,
+			"entry_price":  newPrice,
+			"volume":       newVolume,
+			"margin_delta": marginDelta,
+		}
+		if err := s.logService.LogAction(userObjID, "ModifyTrade", fmt.Sprintf("Modified trade %s: entry_price=%f, volume=%f", tradeID, entryPrice, volume), "", metadata); err != nil {
+			log.Printf("error: %v", err)
 		}
 		return response, nil
-	case <-time.After(10 * time.Second):
-		return interfaces.TradeResponse{}, errors.New("timeout waiting for modify response")
+	case <-time.After(mt5ModifyTimeout):
+		s.observeMT5(mt5RequestModify, mt5ModifyTimeout, true)
+		s.refundTrade(ctx, account.ID, reserved)
+		return interfaces.TradeResponse{}, newError(ErrTimeout, "timeout waiting for modify response")
 	}
 }
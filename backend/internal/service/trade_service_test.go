@@ -0,0 +1,597 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/interfaces"
+	"github.com/mehrbod2002/fxtrader/internal/clock"
+	"github.com/mehrbod2002/fxtrader/internal/config"
+	"github.com/mehrbod2002/fxtrader/internal/models"
+	"github.com/mehrbod2002/fxtrader/internal/repository/memory"
+	"github.com/mehrbod2002/fxtrader/internal/socket"
+	"github.com/mehrbod2002/fxtrader/internal/ws"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type nopPublisher struct{}
+
+func (nopPublisher) Publish(string, interface{}) {}
+
+// tradeFixture is a trade service wired to in-memory repositories and an
+// in-process MT5 bridge, with one funded demo account and EURUSD quoted at
+// 1.1/1.1002.
+type tradeFixture struct {
+	service   *tradeService
+	trades    *memory.TradeRepository
+	accounts  *memory.AccountRepository
+	symbols   *memory.SymbolRepository
+	transport *socket.MemoryTransport
+	hub       *ws.Hub
+	user      *models.User
+	account   *models.Account
+}
+
+func newTradeFixture(t *testing.T, balance float64) *tradeFixture {
+	t.Helper()
+	ctx := context.Background()
+
+	cfg := &config.Config{
+		MT5ResponseBuffer:   16,
+		TradeResponseBuffer: 16,
+		LogRetryQueueSize:   16,
+		StreamLifetime:      time.Minute,
+	}
+	f := &tradeFixture{
+		trades:    memory.NewTradeRepository(),
+		accounts:  memory.NewAccountRepository(),
+		symbols:   memory.NewSymbolRepository(),
+		transport: socket.NewMemoryTransport(),
+		hub:       ws.NewHub(),
+	}
+	go f.hub.Run()
+
+	symbol := &models.Symbol{
+		SymbolName:    "EURUSD",
+		Leverage:      100,
+		MinLot:        0.01,
+		MaxLot:        100,
+		Digits:        5,
+		TickSize:      0.00001,
+		IsTradingOpen: true,
+	}
+	if err := f.symbols.SaveSymbol(ctx, symbol); err != nil {
+		t.Fatalf("save symbol: %v", err)
+	}
+	f.hub.BroadcastPrice(&models.PriceData{Symbol: "EURUSD", Bid: 1.1, Ask: 1.1002})
+
+	users := memory.NewUserRepository()
+	f.user = &models.User{ID: primitive.NewObjectID(), Username: "trader"}
+	if err := users.SaveUser(ctx, f.user); err != nil {
+		t.Fatalf("save user: %v", err)
+	}
+	f.account = &models.Account{
+		ID:          primitive.NewObjectID(),
+		UserID:      f.user.ID,
+		AccountName: "main",
+		AccountType: models.AccountTypeDemo,
+	}
+	if err := f.accounts.SaveAccount(ctx, f.account); err != nil {
+		t.Fatalf("save account: %v", err)
+	}
+	if err := f.accounts.AdjustBalance(ctx, f.account.ID, balance); err != nil {
+		t.Fatalf("fund account: %v", err)
+	}
+
+	logService := NewLogService(memory.NewLogRepository(), cfg)
+	svc, err := NewTradeService(f.trades, f.symbols, users, f.accounts, logService, f.hub, f.transport,
+		nil, nopPublisher{}, memory.NewTransactor(), nil, clock.New(time.UTC), cfg)
+	if err != nil {
+		t.Fatalf("new trade service: %v", err)
+	}
+	f.service = svc.(*tradeService)
+	if err := f.transport.Start(svc); err != nil {
+		t.Fatalf("start transport: %v", err)
+	}
+	return f
+}
+
+func (f *tradeFixture) balance(t *testing.T) float64 {
+	t.Helper()
+	account, err := f.accounts.GetAccountByID(context.Background(), f.account.ID)
+	if err != nil || account == nil {
+		t.Fatalf("get account: %v", err)
+	}
+	return account.Balance
+}
+
+// openTrade stores an OPEN EURUSD position on the fixture's account.
+func (f *tradeFixture) openTrade(t *testing.T, tradeType models.TradeType, volume, entryPrice float64) *models.TradeHistory {
+	t.Helper()
+	return f.openTradeIn(t, "EURUSD", tradeType, volume, entryPrice)
+}
+
+func (f *tradeFixture) openTradeIn(t *testing.T, symbol string, tradeType models.TradeType, volume, entryPrice float64) *models.TradeHistory {
+	t.Helper()
+	trade := &models.TradeHistory{
+		ID:            primitive.NewObjectID(),
+		UserID:        f.user.ID,
+		AccountID:     f.account.ID,
+		Symbol:        symbol,
+		TradeType:     tradeType,
+		OrderType:     "MARKET",
+		Leverage:      100,
+		MarginRate:    0.01,
+		Volume:        volume,
+		FilledVolume:  volume,
+		EntryPrice:    entryPrice,
+		OpenTime:      time.Now(),
+		Status:        string(models.TradeStatusOpen),
+		AccountType:   f.account.AccountType,
+		ExecutionType: models.ExecutionTypePlatform,
+	}
+	if err := f.trades.SaveTrade(context.Background(), trade); err != nil {
+		t.Fatalf("save trade: %v", err)
+	}
+	return trade
+}
+
+func (f *tradeFixture) storedTrade(t *testing.T, id primitive.ObjectID) *models.TradeHistory {
+	t.Helper()
+	trade, err := f.trades.GetTradeByID(context.Background(), id)
+	if err != nil || trade == nil {
+		t.Fatalf("get trade %s: %v", id.Hex(), err)
+	}
+	return trade
+}
+
+func assertBalance(t *testing.T, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 1e-9 {
+		t.Fatalf("balance = %v, want %v", got, want)
+	}
+}
+
+func TestHandleCloseTradeResponseSettlesOnce(t *testing.T) {
+	f := newTradeFixture(t, 1000)
+	trade := f.openTrade(t, models.TradeTypeBuy, 2, 1.1)
+
+	response := interfaces.TradeResponse{
+		TradeID:     trade.ID.Hex(),
+		UserID:      f.user.ID.Hex(),
+		AccountID:   f.account.ID.Hex(),
+		AccountType: f.account.AccountType,
+		Status:      "SUCCESS",
+		ClosePrice:  1.2,
+		CloseReason: "CLIENT",
+		Timestamp:   float64(time.Now().Unix()),
+	}
+	// Profit of (1.2-1.1)*2 plus the 2*1.1*0.01 margin held by the trade.
+	want := 1000 + 0.2 + 0.022
+
+	for i := 0; i < 2; i++ {
+		if err := f.service.HandleCloseTradeResponse(response); err != nil {
+			t.Fatalf("delivery %d: %v", i+1, err)
+		}
+		assertBalance(t, f.balance(t), want)
+	}
+
+	closed, err := f.trades.GetTradeByID(context.Background(), trade.ID)
+	if err != nil {
+		t.Fatalf("get trade: %v", err)
+	}
+	if closed.Status != string(models.TradeStatusClosed) {
+		t.Fatalf("status = %s, want CLOSED", closed.Status)
+	}
+}
+
+func TestPlaceTrade(t *testing.T) {
+	timeout := mt5ResponseTimeout
+	mt5ResponseTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { mt5ResponseTimeout = timeout })
+
+	// One lot bought at the 1.1002 ask with 1:100 leverage.
+	const margin = 1.1002 * 0.01
+
+	tests := []struct {
+		name        string
+		prime       func(*socket.MemoryTransport)
+		wantErr     bool
+		wantStatus  models.TradeStatus
+		wantReason  models.CloseReason
+		wantBalance float64
+	}{
+		{
+			name:        "matched",
+			prime:       func(tr *socket.MemoryTransport) { tr.PrimeTradeResponse("MATCHED", 0) },
+			wantStatus:  models.TradeStatusOpen,
+			wantBalance: 1000 - margin,
+		},
+		{
+			name:        "rejected",
+			prime:       func(tr *socket.MemoryTransport) { tr.PrimeTradeResponse("REJECTED", 0) },
+			wantErr:     true,
+			wantStatus:  models.TradeStatusClosed,
+			wantReason:  models.CloseReasonBrokerReject,
+			wantBalance: 1000,
+		},
+		{
+			name:        "timeout",
+			prime:       func(*socket.MemoryTransport) {},
+			wantErr:     true,
+			wantStatus:  models.TradeStatusClosed,
+			wantReason:  models.CloseReasonTimeout,
+			wantBalance: 1000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTradeFixture(t, 1000)
+			tt.prime(f.transport)
+
+			_, _, err := f.service.PlaceTrade(f.user.ID.Hex(), f.account.AccountName, "EURUSD", f.account.AccountType,
+				models.TradeTypeBuy, "MARKET", 100, 1, 0, 0, 0, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PlaceTrade error = %v, want error %v", err, tt.wantErr)
+			}
+
+			sent := f.transport.Sent()
+			if len(sent) != 1 {
+				t.Fatalf("sent %d requests to MT5, want 1", len(sent))
+			}
+			tradeID, _ := primitive.ObjectIDFromHex(sent[0]["trade_id"].(string))
+			trade := f.storedTrade(t, tradeID)
+			if trade.Status != string(tt.wantStatus) {
+				t.Errorf("status = %s, want %s", trade.Status, tt.wantStatus)
+			}
+			if trade.CloseReason != tt.wantReason {
+				t.Errorf("close reason = %q, want %q", trade.CloseReason, tt.wantReason)
+			}
+			assertBalance(t, f.balance(t), tt.wantBalance)
+		})
+	}
+}
+
+func TestTradeLifecycle(t *testing.T) {
+	f := newTradeFixture(t, 1000)
+	f.transport.PrimeTradeResponse("MATCHED", 0)
+
+	trade, _, err := f.service.PlaceTrade(f.user.ID.Hex(), f.account.AccountName, "EURUSD", f.account.AccountType,
+		models.TradeTypeBuy, "MARKET", 100, 1, 0, 0, 0, nil)
+	if err != nil {
+		t.Fatalf("PlaceTrade: %v", err)
+	}
+	if trade.EntryPrice != 1.1002 {
+		t.Fatalf("entry price = %v, want the 1.1002 ask", trade.EntryPrice)
+	}
+
+	f.transport.PrimeCloseResponse("SUCCESS", 1.1102, "CLIENT")
+	if _, err := f.service.CloseTrade(trade.ID.Hex(), f.user.ID.Hex()); err != nil {
+		t.Fatalf("CloseTrade: %v", err)
+	}
+
+	closed := f.storedTrade(t, trade.ID)
+	if closed.Status != string(models.TradeStatusClosed) {
+		t.Fatalf("status = %s, want CLOSED", closed.Status)
+	}
+	// The 0.01 move on one lot is credited and the margin released.
+	assertBalance(t, f.balance(t), 1000.01)
+}
+
+func TestCloseTradePersistsCloseDetails(t *testing.T) {
+	tests := []struct {
+		mt5Reason  string
+		closePrice float64
+		wantReason models.CloseReason
+	}{
+		{"SL", 1.095, models.CloseReasonStopLoss},
+		{"TP", 1.12, models.CloseReasonTakeProfit},
+		{"CLIENT", 1.101, models.CloseReasonManual},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mt5Reason, func(t *testing.T) {
+			f := newTradeFixture(t, 1000)
+			trade := f.openTrade(t, models.TradeTypeBuy, 1, 1.1)
+
+			f.transport.PrimeCloseResponse("SUCCESS", tt.closePrice, tt.mt5Reason)
+			if _, err := f.service.CloseTrade(trade.ID.Hex(), f.user.ID.Hex()); err != nil {
+				t.Fatalf("CloseTrade: %v", err)
+			}
+
+			closed := f.storedTrade(t, trade.ID)
+			if closed.ClosePrice != tt.closePrice {
+				t.Errorf("close price = %v, want %v", closed.ClosePrice, tt.closePrice)
+			}
+			if closed.CloseReason != tt.wantReason {
+				t.Errorf("close reason = %q, want %q", closed.CloseReason, tt.wantReason)
+			}
+			if closed.CloseTime == nil {
+				t.Error("close time was not stored")
+			}
+		})
+	}
+}
+
+func TestStreamTradesAcceptsLowercaseAccountType(t *testing.T) {
+	f := newTradeFixture(t, 0)
+	accounts := NewAccountService(f.accounts)
+	account := &models.Account{UserID: f.user.ID, AccountName: "live", AccountType: "real"}
+	if err := accounts.CreateAccount(context.Background(), account); err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+
+	for _, accountType := range []string{account.AccountType, "REAL"} {
+		stream, err := f.service.StreamTrades(f.user.ID.Hex(), accountType)
+		if err != nil {
+			t.Fatalf("StreamTrades(%q): %v", accountType, err)
+		}
+		if stream == nil {
+			t.Fatalf("StreamTrades(%q) returned no channel", accountType)
+		}
+	}
+	t.Cleanup(func() { _ = f.service.StopStream(f.user.ID.Hex(), account.AccountType) })
+
+	sent := f.transport.Sent()
+	if len(sent) != 2 {
+		t.Fatalf("sent %d stream requests, want 2", len(sent))
+	}
+	for _, request := range sent {
+		if got := request["account_type"]; got != models.AccountTypeReal {
+			t.Errorf("stream request account_type = %v, want %s", got, models.AccountTypeReal)
+		}
+	}
+}
+
+func TestCloseTradesBySymbolAndDirection(t *testing.T) {
+	f := newTradeFixture(t, 1000)
+	eurBuy := f.openTrade(t, models.TradeTypeBuy, 1, 1.1)
+	eurSell := f.openTrade(t, models.TradeTypeSell, 1, 1.1)
+	gbpBuy := f.openTradeIn(t, "GBPUSD", models.TradeTypeBuy, 1, 1.3)
+	userID, accountID := f.user.ID.Hex(), f.account.ID.Hex()
+
+	if _, err := f.service.CloseTradesBySymbol(userID, models.AccountTypeReal, accountID, "EURUSD"); err == nil {
+		t.Fatal("closing with the wrong account type succeeded")
+	}
+
+	f.transport.PrimeCloseResponse("SUCCESS", 1.1, "CLIENT")
+	f.transport.PrimeCloseResponse("SUCCESS", 1.1, "CLIENT")
+	result, err := f.service.CloseTradesBySymbol(userID, "DEMO", accountID, "EURUSD")
+	if err != nil {
+		t.Fatalf("CloseTradesBySymbol: %v", err)
+	}
+	if result.Requested != 2 || result.Closed != 2 {
+		t.Fatalf("by symbol: requested %d, closed %d; want 2 and 2", result.Requested, result.Closed)
+	}
+
+	f.transport.PrimeCloseResponse("SUCCESS", 1.3, "CLIENT")
+	result, err = f.service.CloseTradesByDirection(userID, f.account.AccountType, accountID, models.TradeTypeBuy)
+	if err != nil {
+		t.Fatalf("CloseTradesByDirection: %v", err)
+	}
+	if result.Requested != 1 || result.Closed != 1 || result.Results[0].TradeID != gbpBuy.ID.Hex() {
+		t.Fatalf("by direction: %+v, want only %s closed", result, gbpBuy.ID.Hex())
+	}
+
+	for _, trade := range []*models.TradeHistory{eurBuy, eurSell, gbpBuy} {
+		if status := f.storedTrade(t, trade.ID).Status; status != string(models.TradeStatusClosed) {
+			t.Errorf("%s %s is %s, want CLOSED", trade.Symbol, trade.TradeType, status)
+		}
+	}
+
+	rejected := f.openTrade(t, models.TradeTypeSell, 1, 1.1)
+	f.transport.PrimeCloseResponse("FAILED", 0, "")
+	result, err = f.service.CloseTradesByDirection(userID, "", accountID, models.TradeTypeSell)
+	if err != nil {
+		t.Fatalf("CloseTradesByDirection: %v", err)
+	}
+	if result.Requested != 1 || result.Closed != 0 || result.Failed != 1 || result.Results[0].Error == "" {
+		t.Fatalf("rejected close: %+v, want it counted as failed", result)
+	}
+	if status := f.storedTrade(t, rejected.ID).Status; status != string(models.TradeStatusOpen) {
+		t.Errorf("rejected trade is %s, want OPEN", status)
+	}
+}
+
+func TestCancelPendingOrderAtMT5(t *testing.T) {
+	timeout := mt5ResponseTimeout
+	mt5ResponseTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { mt5ResponseTimeout = timeout })
+
+	// A market order MT5 has accepted but not yet filled holds its margin at
+	// the 1.1002 ask until MT5 confirms the cancel.
+	const margin = 1.1002 * 0.01
+
+	tests := []struct {
+		name        string
+		reply       string
+		wantErr     bool
+		wantStatus  models.TradeStatus
+		wantBalance float64
+	}{
+		{"confirmed", "CANCELED", false, models.TradeStatusCancelled, 1000},
+		{"refused", "REJECTED", true, models.TradeStatusPending, 1000 - margin},
+		{"unanswered", "", true, models.TradeStatusPending, 1000 - margin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTradeFixture(t, 1000)
+			f.transport.PrimeTradeResponse("PENDING", 0)
+			order, _, err := f.service.PlaceTrade(f.user.ID.Hex(), f.account.AccountName, "EURUSD", f.account.AccountType,
+				models.TradeTypeBuy, "MARKET", 100, 1, 0, 0, 0, nil)
+			if err != nil {
+				t.Fatalf("PlaceTrade: %v", err)
+			}
+			if status := f.storedTrade(t, order.ID).Status; status != string(models.TradeStatusPending) {
+				t.Fatalf("placed order is %s, want PENDING", status)
+			}
+
+			if tt.reply != "" {
+				f.transport.PrimeTradeResponse(tt.reply, 0)
+			}
+			_, err = f.service.CancelPendingOrder(order.ID.Hex(), f.user.ID.Hex())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CancelPendingOrder error = %v, want error %v", err, tt.wantErr)
+			}
+
+			sent := f.transport.Sent()
+			if got := sent[len(sent)-1]["type"]; got != "cancel_order_request" {
+				t.Fatalf("last request to MT5 was %v, want cancel_order_request", got)
+			}
+			if status := f.storedTrade(t, order.ID).Status; status != string(tt.wantStatus) {
+				t.Errorf("status = %s, want %s", status, tt.wantStatus)
+			}
+			assertBalance(t, f.balance(t), tt.wantBalance)
+		})
+	}
+}
+
+func TestCloseChargesCommissionOnce(t *testing.T) {
+	f := newTradeFixture(t, 1000)
+	f.service.demoCommissionRate = 1
+	symbols, _ := f.symbols.GetAllSymbols(context.Background())
+	symbols[0].CommissionFee = 0.5
+	if err := f.symbols.UpdateSymbol(context.Background(), symbols[0].ID, symbols[0]); err != nil {
+		t.Fatalf("update symbol: %v", err)
+	}
+
+	f.transport.PrimeTradeResponse("MATCHED", 0)
+	trade, _, err := f.service.PlaceTrade(f.user.ID.Hex(), f.account.AccountName, "EURUSD", f.account.AccountType,
+		models.TradeTypeBuy, "MARKET", 100, 1, 0, 0, 0, nil)
+	if err != nil {
+		t.Fatalf("PlaceTrade: %v", err)
+	}
+	assertBalance(t, f.balance(t), 1000-0.5-1.1002*0.01)
+
+	err = f.service.HandleCloseTradeResponse(interfaces.TradeResponse{
+		TradeID:     trade.ID.Hex(),
+		UserID:      f.user.ID.Hex(),
+		AccountID:   f.account.ID.Hex(),
+		AccountType: f.account.AccountType,
+		Status:      "SUCCESS",
+		ClosePrice:  1.1102,
+		CloseReason: "CLIENT",
+		Commission:  -0.3,
+		Timestamp:   float64(time.Now().Unix()),
+	})
+	if err != nil {
+		t.Fatalf("HandleCloseTradeResponse: %v", err)
+	}
+
+	// The 0.01 profit and the margin come back; the 0.5 fee stays charged and
+	// MT5's commission is not taken a second time.
+	assertBalance(t, f.balance(t), 1000-0.5+0.01)
+	closed := f.storedTrade(t, trade.ID)
+	if closed.Commission != -0.5 || closed.MT5Commission != -0.3 {
+		t.Fatalf("commission = %v, mt5 commission = %v; want -0.5 and -0.3", closed.Commission, closed.MT5Commission)
+	}
+}
+
+func TestTradeErrorKinds(t *testing.T) {
+	f := newTradeFixture(t, 1)
+	userID, accountID := f.user.ID.Hex(), f.account.ID.Hex()
+	place := func(userID, account, symbol string, volume float64) error {
+		_, _, err := f.service.PlaceTrade(userID, account, symbol, f.account.AccountType,
+			models.TradeTypeBuy, "MARKET", 100, volume, 0, 0, 0, nil)
+		return err
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"invalid user", place("nope", f.account.AccountName, "EURUSD", 1), ErrInvalidInput},
+		{"unknown account", place(userID, "other", "EURUSD", 1), ErrNotFound},
+		{"unknown symbol", place(userID, f.account.AccountName, "XYZ", 1), ErrNotFound},
+		{"volume below the minimum lot", place(userID, f.account.AccountName, "EURUSD", 0.001), ErrInvalidInput},
+		{"insufficient balance", place(userID, f.account.AccountName, "EURUSD", 100), ErrInsufficientBalance},
+		{"close unknown trade", func() error {
+			_, err := f.service.CloseTrade(primitive.NewObjectID().Hex(), userID)
+			return err
+		}(), ErrNotFound},
+		{"group close on another's account", func() error {
+			_, err := f.service.CloseTradesBySymbol(primitive.NewObjectID().Hex(), "", accountID, "EURUSD")
+			return err
+		}(), ErrNotFound},
+		{"group close without symbol", func() error {
+			_, err := f.service.CloseTradesBySymbol(userID, "", accountID, "")
+			return err
+		}(), ErrInvalidInput},
+	}
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.err, tt.want)
+		}
+	}
+}
+
+func TestMT5BreakerCycle(t *testing.T) {
+	f := newTradeFixture(t, 1000)
+	clk := clock.NewManual(time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC))
+	f.service.mt5Breaker = newMT5Breaker(f.service.logService, clk,
+		&config.Config{MT5BreakerThreshold: 2, MT5BreakerCooldown: time.Minute})
+	trade := f.openTrade(t, models.TradeTypeBuy, 1, 1.1)
+	other := f.openTrade(t, models.TradeTypeBuy, 1, 1.1)
+	userID := f.user.ID.Hex()
+
+	state := func() string { return f.service.MT5Metrics().Circuit.State }
+	closeTrade := func(trade *models.TradeHistory) error {
+		_, err := f.service.CloseTrade(trade.ID.Hex(), userID)
+		return err
+	}
+
+	// Closed: failures are counted until the threshold opens the circuit.
+	f.transport.FailSends(errors.New("bridge down"))
+	for i := 0; i < 2; i++ {
+		if state() != interfaces.MT5CircuitClosed {
+			t.Fatalf("circuit %s after %d failures, want closed", state(), i)
+		}
+		if err := closeTrade(trade); err == nil {
+			t.Fatal("close succeeded while sends fail")
+		}
+	}
+	if state() != interfaces.MT5CircuitOpen {
+		t.Fatalf("circuit %s after 2 failures, want open", state())
+	}
+
+	// Open: requests fail at once for the whole cooldown.
+	f.transport.FailSends(nil)
+	clk.Advance(59 * time.Second)
+	if err := closeTrade(trade); !errors.Is(err, ErrMT5Unavailable) {
+		t.Fatalf("close during cooldown: got %v, want ErrMT5Unavailable", err)
+	}
+	if len(f.transport.Sent()) != 0 {
+		t.Fatal("a request reached MT5 while the circuit was open")
+	}
+
+	// Half-open: after the cooldown one probe goes through and the rest wait.
+	clk.Advance(time.Second)
+	f.transport.SetDelay(50 * time.Millisecond)
+	f.transport.PrimeCloseResponse("SUCCESS", 1.1, "CLIENT")
+	probe := make(chan error, 1)
+	go func() { probe <- closeTrade(trade) }()
+	for len(f.transport.Sent()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if state() != interfaces.MT5CircuitHalfOpen {
+		t.Fatalf("circuit %s while probing, want half-open", state())
+	}
+	if err := closeTrade(other); !errors.Is(err, ErrMT5Unavailable) {
+		t.Fatalf("second request while probing: got %v, want ErrMT5Unavailable", err)
+	}
+
+	// The probe's reply closes the circuit again.
+	if err := <-probe; err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if state() != interfaces.MT5CircuitClosed {
+		t.Fatalf("circuit %s after a successful probe, want closed", state())
+	}
+}
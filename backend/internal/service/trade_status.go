@@ -0,0 +1,48 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+)
+
+// tradeTransitions lists the statuses a trade may move to from each state.
+// CLOSED, EXPIRED and CANCELLED are terminal. A REQUOTED market order goes
+// back to PENDING when confirmed and to CANCELLED when the requote lapses.
+var tradeTransitions = map[models.TradeStatus][]models.TradeStatus{
+	models.TradeStatusPending: {
+		models.TradeStatusOpen,
+		models.TradeStatusClosed,
+		models.TradeStatusExpired,
+		models.TradeStatusCancelled,
+		models.TradeStatusRequoted,
+	},
+	models.TradeStatusRequoted: {
+		models.TradeStatusPending,
+		models.TradeStatusCancelled,
+	},
+	models.TradeStatusOpen: {
+		models.TradeStatusClosed,
+	},
+}
+
+func canTransition(from, to models.TradeStatus) bool {
+	if from == to {
+		return true
+	}
+	for _, next := range tradeTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// isTerminal reports whether a trade in status can no longer change.
+func isTerminal(status models.TradeStatus) bool {
+	return len(tradeTransitions[status]) == 0
+}
+
+func transitionError(trade *models.TradeHistory, to models.TradeStatus) error {
+	return fmt.Errorf("invalid status transition for trade %s: %s -> %s", trade.ID.Hex(), trade.Status, to)
+}
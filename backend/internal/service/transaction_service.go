@@ -1,39 +1,52 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"log"
 	"time"
 
+	"github.com/mehrbod2002/fxtrader/interfaces"
 	"github.com/mehrbod2002/fxtrader/internal/models"
 	"github.com/mehrbod2002/fxtrader/internal/repository"
+	"github.com/mehrbod2002/fxtrader/internal/ws"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type TransactionService interface {
-	CreateTransaction(userID string, transaction *models.Transaction) error
-	GetTransactionByID(id string) (*models.Transaction, error)
-	GetTransactionsByUserID(userID string) ([]*models.Transaction, error)
-	GetAllTransactions() ([]*models.Transaction, error)
-	ApproveTransaction(id string, reason string, adminComment string) error
-	DenyTransaction(id string, reason string, adminComment string) error
+	CreateTransaction(ctx context.Context, userID string, transaction *models.Transaction) error
+	GetTransactionByID(ctx context.Context, id string) (*models.Transaction, error)
+	GetTransactionsByUserID(ctx context.Context, userID string) ([]*models.Transaction, error)
+	GetAllTransactions(ctx context.Context) ([]*models.Transaction, error)
+	ApproveTransaction(ctx context.Context, id string, reason string, adminComment string) error
+	DenyTransaction(ctx context.Context, id string, reason string, adminComment string) error
 }
 
 type transactionService struct {
 	transactionRepo repository.TransactionRepository
 	logService      LogService
 	userInfoRepo    repository.UserRepository
+	currencyService CurrencyService
+	hub             *ws.Hub
+	events          interfaces.EventPublisher
+	transactor      repository.Transactor
 }
 
-func NewTransactionService(transactionRepo repository.TransactionRepository, logService LogService, userInfoRepo repository.UserRepository) TransactionService {
+func NewTransactionService(transactionRepo repository.TransactionRepository, logService LogService, userInfoRepo repository.UserRepository, currencyService CurrencyService, hub *ws.Hub, events interfaces.EventPublisher, transactor repository.Transactor) TransactionService {
 	return &transactionService{
 		transactionRepo: transactionRepo,
 		logService:      logService,
 		userInfoRepo:    userInfoRepo,
+		currencyService: currencyService,
+		hub:             hub,
+		events:          events,
+		transactor:      transactor,
 	}
 }
 
-func (s *transactionService) CreateTransaction(userID string, transaction *models.Transaction) error {
+func (s *transactionService) CreateTransaction(ctx context.Context, userID string, transaction *models.Transaction) error {
 	if transaction.TransactionType != models.TransactionTypeDeposit && transaction.TransactionType != models.TransactionTypeWithdrawal {
 		return errors.New("invalid transaction type")
 	}
@@ -49,8 +62,12 @@ func (s *transactionService) CreateTransaction(userID string, transaction *model
 
 	transaction.UserID = userID
 	transaction.Status = models.TransactionStatusPending
+	transaction.Currency = normalizeCurrency(transaction.Currency)
+	if transaction.Currency == "" {
+		transaction.Currency = s.currencyService.BaseCurrency()
+	}
 
-	err := s.transactionRepo.SaveTransaction(transaction)
+	err := s.transactionRepo.SaveTransaction(ctx, transaction)
 	if err != nil {
 		return err
 	}
@@ -68,33 +85,33 @@ func (s *transactionService) CreateTransaction(userID string, transaction *model
 	return nil
 }
 
-func (s *transactionService) GetTransactionByID(id string) (*models.Transaction, error) {
+func (s *transactionService) GetTransactionByID(ctx context.Context, id string) (*models.Transaction, error) {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, errors.New("invalid transaction ID")
 	}
-	return s.transactionRepo.GetTransactionByID(objID)
+	return s.transactionRepo.GetTransactionByID(ctx, objID)
 }
 
-func (s *transactionService) GetTransactionsByUserID(userID string) ([]*models.Transaction, error) {
+func (s *transactionService) GetTransactionsByUserID(ctx context.Context, userID string) ([]*models.Transaction, error) {
 	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return nil, errors.New("invalid user ID")
 	}
-	return s.transactionRepo.GetTransactionsByUserID(objID)
+	return s.transactionRepo.GetTransactionsByUserID(ctx, objID)
 }
 
-func (s *transactionService) GetAllTransactions() ([]*models.Transaction, error) {
-	return s.transactionRepo.GetAllTransactions()
+func (s *transactionService) GetAllTransactions(ctx context.Context) ([]*models.Transaction, error) {
+	return s.transactionRepo.GetAllTransactions(ctx)
 }
 
-func (s *transactionService) ApproveTransaction(id string, reason string, adminComment string) error {
+func (s *transactionService) ApproveTransaction(ctx context.Context, id string, reason string, adminComment string) error {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return errors.New("invalid transaction ID")
 	}
 
-	transaction, err := s.transactionRepo.GetTransactionByID(objID)
+	transaction, err := s.transactionRepo.GetTransactionByID(ctx, objID)
 	if err != nil {
 		return err
 	}
@@ -106,33 +123,60 @@ func (s *transactionService) ApproveTransaction(id string, reason string, adminC
 		return errors.New("transaction already reviewed")
 	}
 
-	responseTime := time.Now()
-	transaction.Status = models.TransactionStatusApproved
-	transaction.ResponseTime = &responseTime
-	transaction.Reason = reason
-	transaction.AdminComment = adminComment
-
-	err = s.transactionRepo.UpdateTransaction(objID, transaction)
+	// Main balances are held in the base currency, so convert before crediting or debiting.
+	amount, err := s.currencyService.Convert(ctx, transaction.Amount, transaction.Currency, s.currencyService.BaseCurrency())
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to convert transaction amount: %v", err)
 	}
 
 	userID, err := primitive.ObjectIDFromHex(transaction.UserID)
 	if err != nil {
 		return errors.New("invalid user ID")
 	}
-	switch transaction.TransactionType {
-	case models.TransactionTypeDeposit:
-		err = s.userInfoRepo.AddBalance(userID, transaction.Amount)
+
+	responseTime := time.Now()
+	transaction.Status = models.TransactionStatusApproved
+	transaction.ResponseTime = &responseTime
+	transaction.Reason = reason
+	transaction.AdminComment = adminComment
+	transaction.ConvertedAmount = amount
+
+	// The balance change must not be abandoned halfway because the admin's
+	// request went away, so only the deadline carries over from here on.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	// The status flip and the balance change commit together, so a failed
+	// balance update leaves the transaction pending instead of approved.
+	err = s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		reviewed, err := s.transactionRepo.ReviewTransaction(ctx, objID, transaction)
 		if err != nil {
-			return errors.New("failed to add deposit to balance: " + err.Error())
+			return err
 		}
-	case models.TransactionTypeWithdrawal:
-		err = s.userInfoRepo.SubtractBalance(userID, transaction.Amount)
-		if err != nil {
-			return errors.New("failed to subtract withdrawal from balance: " + err.Error())
+		if !reviewed {
+			return errors.New("transaction already reviewed")
 		}
+
+		switch transaction.TransactionType {
+		case models.TransactionTypeDeposit:
+			if err := s.userInfoRepo.AddBalance(ctx, userID, amount); err != nil {
+				return errors.New("failed to add deposit to balance: " + err.Error())
+			}
+		case models.TransactionTypeWithdrawal:
+			if err := s.userInfoRepo.SubtractBalance(ctx, userID, amount); err != nil {
+				if errors.Is(err, repository.ErrInsufficientBalance) {
+					return newError(ErrInsufficientBalance, "insufficient balance for a withdrawal of %.2f", amount)
+				}
+				return errors.New("failed to subtract withdrawal from balance: " + err.Error())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
+	s.broadcastBalance(ctx, userID)
+	s.events.Publish(models.WebhookEventTransactionApproved, transaction)
 
 	metadata := map[string]interface{}{
 		"transaction_id":   id,
@@ -141,6 +185,8 @@ func (s *transactionService) ApproveTransaction(id string, reason string, adminC
 		"admin_comment":    adminComment,
 		"transaction_type": transaction.TransactionType,
 		"amount":           transaction.Amount,
+		"currency":         transaction.Currency,
+		"converted_amount": amount,
 	}
 	action := "Transaction approved"
 	switch transaction.TransactionType {
@@ -157,13 +203,13 @@ func (s *transactionService) ApproveTransaction(id string, reason string, adminC
 	return nil
 }
 
-func (s *transactionService) DenyTransaction(id string, reason string, adminComment string) error {
+func (s *transactionService) DenyTransaction(ctx context.Context, id string, reason string, adminComment string) error {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return errors.New("invalid transaction ID")
 	}
 
-	transaction, err := s.transactionRepo.GetTransactionByID(objID)
+	transaction, err := s.transactionRepo.GetTransactionByID(ctx, objID)
 	if err != nil {
 		return err
 	}
@@ -181,10 +227,13 @@ func (s *transactionService) DenyTransaction(id string, reason string, adminComm
 	transaction.Reason = reason
 	transaction.AdminComment = adminComment
 
-	err = s.transactionRepo.UpdateTransaction(objID, transaction)
+	reviewed, err := s.transactionRepo.ReviewTransaction(ctx, objID, transaction)
 	if err != nil {
 		return err
 	}
+	if !reviewed {
+		return errors.New("transaction already reviewed")
+	}
 
 	metadata := map[string]interface{}{
 		"transaction_id":   id,
@@ -208,3 +257,22 @@ func (s *transactionService) DenyTransaction(id string, reason string, adminComm
 
 	return nil
 }
+
+// broadcastBalance pushes the user's main balance to their connected clients
+// so an approval shows up without a refresh.
+func (s *transactionService) broadcastBalance(ctx context.Context, userID primitive.ObjectID) {
+	if s.hub == nil {
+		return
+	}
+	user, err := s.userInfoRepo.GetUserByID(ctx, userID)
+	if err != nil || user == nil {
+		log.Printf("Failed to load balance for user %s: %v", userID.Hex(), err)
+		return
+	}
+	s.hub.BroadcastBalance(&models.BalanceData{
+		UserID:      userID.Hex(),
+		AccountType: models.BalanceAccountMain,
+		Balance:     user.Balance,
+		Timestamp:   time.Now().Unix(),
+	})
+}
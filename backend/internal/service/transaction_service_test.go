@@ -0,0 +1,98 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/internal/config"
+	"github.com/mehrbod2002/fxtrader/internal/models"
+	"github.com/mehrbod2002/fxtrader/internal/repository/memory"
+	"github.com/mehrbod2002/fxtrader/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type nopPublisher struct{}
+
+func (nopPublisher) Publish(string, interface{}) {}
+
+func ExampleTransactionService() {
+	ctx := context.Background()
+	users := memory.NewUserRepository()
+	transactions := memory.NewTransactionRepository()
+	logService := service.NewLogService(memory.NewLogRepository(), &config.Config{LogRetryQueueSize: 16})
+	currency := service.NewCurrencyService(nil, nil, "USD", time.Hour)
+	transactionService := service.NewTransactionService(transactions, logService, users, currency, nil, nopPublisher{}, memory.NewTransactor())
+
+	user := &models.User{ID: primitive.NewObjectID(), Username: "trader"}
+	_ = users.SaveUser(ctx, user)
+
+	deposit := &models.Transaction{
+		TransactionType: models.TransactionTypeDeposit,
+		PaymentMethod:   models.PaymentMethodCardToCard,
+		Amount:          250,
+	}
+	_ = transactionService.CreateTransaction(ctx, user.ID.Hex(), deposit)
+	fmt.Println("deposit:", deposit.Status, deposit.Currency)
+
+	err := transactionService.ApproveTransaction(ctx, deposit.ID.Hex(), "received", "")
+	fmt.Println("approve:", err)
+	err = transactionService.ApproveTransaction(ctx, deposit.ID.Hex(), "received", "")
+	fmt.Println("approve again:", err)
+
+	withdrawal := &models.Transaction{
+		TransactionType: models.TransactionTypeWithdrawal,
+		PaymentMethod:   models.PaymentMethodCardToCard,
+		Amount:          100,
+	}
+	_ = transactionService.CreateTransaction(ctx, user.ID.Hex(), withdrawal)
+	err = transactionService.DenyTransaction(ctx, withdrawal.ID.Hex(), "card mismatch", "")
+	fmt.Println("deny:", err)
+
+	stored, _ := users.GetUserByID(ctx, user.ID)
+	reviewed, _ := transactionService.GetTransactionByID(ctx, withdrawal.ID.Hex())
+	fmt.Printf("balance %.2f, withdrawal %s\n", stored.Balance, reviewed.Status)
+	// Output:
+	// deposit: PENDING USD
+	// approve: <nil>
+	// approve again: transaction already reviewed
+	// deny: <nil>
+	// balance 250.00, withdrawal REJECTED
+}
+
+func TestApproveWithdrawalOverBalanceFails(t *testing.T) {
+	ctx := context.Background()
+	users := memory.NewUserRepository()
+	logService := service.NewLogService(memory.NewLogRepository(), &config.Config{LogRetryQueueSize: 16})
+	currency := service.NewCurrencyService(nil, nil, "USD", time.Hour)
+	transactionService := service.NewTransactionService(memory.NewTransactionRepository(), logService, users, currency, nil, nopPublisher{}, memory.NewTransactor())
+
+	user := &models.User{ID: primitive.NewObjectID(), Username: "trader"}
+	if err := users.SaveUser(ctx, user); err != nil {
+		t.Fatalf("save user: %v", err)
+	}
+	if err := users.AddBalance(ctx, user.ID, 50); err != nil {
+		t.Fatalf("fund user: %v", err)
+	}
+
+	withdrawal := &models.Transaction{
+		TransactionType: models.TransactionTypeWithdrawal,
+		PaymentMethod:   models.PaymentMethodCardToCard,
+		Amount:          80,
+	}
+	if err := transactionService.CreateTransaction(ctx, user.ID.Hex(), withdrawal); err != nil {
+		t.Fatalf("create withdrawal: %v", err)
+	}
+	err := transactionService.ApproveTransaction(ctx, withdrawal.ID.Hex(), "", "")
+	if !errors.Is(err, service.ErrInsufficientBalance) {
+		t.Fatalf("approving a withdrawal larger than the balance: got %v, want insufficient balance", err)
+	}
+
+	stored, _ := users.GetUserByID(ctx, user.ID)
+	if stored.Balance != 50 {
+		t.Fatalf("balance = %v, want it untouched at 50", stored.Balance)
+	}
+}
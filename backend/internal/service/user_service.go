@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -14,28 +15,31 @@ import (
 )
 
 type UserService interface {
-	SignupUser(user *models.User) error
-	EditUser(user *models.User) error
-	GetUser(id string) (*models.User, error)
-	GetUserByTelegramID(telegramID string) (*models.User, error)
-	GetUsersByLeaderStatus(isLeader bool) ([]*models.User, error)
-	GetAllUsers() ([]*models.User, error)
-	UpdateUser(user *models.User) error
-	GetUserByReferralCode(code string) (*models.User, error)
-	GetUsersReferredBy(code string, page, limit int64) ([]*models.User, int64, error)
-	GetAllReferrals(page, limit int64) ([]*models.User, int64, error)
-	ActiveUser(userID primitive.ObjectID, active bool) error
+	SignupUser(ctx context.Context, user *models.User) error
+	EditUser(ctx context.Context, user *models.User) error
+	GetUser(ctx context.Context, id string) (*models.User, error)
+	GetUserByTelegramID(ctx context.Context, telegramID string) (*models.User, error)
+	GetAllUsers(ctx context.Context) ([]*models.User, error)
+	GetUsers(ctx context.Context, filter models.UserFilter, page, limit int64) ([]*models.User, int64, error)
+	SearchUsers(ctx context.Context, query string, filter models.UserFilter, page, limit int64) ([]*models.User, int64, error)
+	CountUsers(ctx context.Context, filter models.UserFilter) (int64, error)
+	UpdateUser(ctx context.Context, user *models.User) error
+	GetUserByReferralCode(ctx context.Context, code string) (*models.User, error)
+	GetUsersReferredBy(ctx context.Context, code string, page, limit int64) ([]*models.User, int64, error)
+	GetAllReferrals(ctx context.Context, page, limit int64) ([]*models.User, int64, error)
+	ActiveUser(ctx context.Context, userID primitive.ObjectID, active bool) error
 }
 
 type AccountService interface {
-	CreateAccount(account *models.Account) error
-	GetAccount(id string) (*models.Account, error)
-	GetAccountsByUserID(userID string) ([]*models.Account, error)
-	DeleteAccount(accountID, userID primitive.ObjectID) error
+	CreateAccount(ctx context.Context, account *models.Account) error
+	GetAccount(ctx context.Context, id string) (*models.Account, error)
+	GetAccountsByUserID(ctx context.Context, userID string) ([]*models.Account, error)
+	DeleteAccount(ctx context.Context, accountID, userID primitive.ObjectID) error
+	SetRiskLimits(ctx context.Context, accountID, userID primitive.ObjectID, limits models.AccountRiskLimits) error
 }
 
 type TransferService interface {
-	TransferBalance(userID primitive.ObjectID, sourceID, destID string, amount float64, sourceType, destType string) error
+	TransferBalance(ctx context.Context, userID primitive.ObjectID, sourceID, destID string, amount float64, sourceType, destType string) error
 }
 
 type userService struct {
@@ -47,8 +51,10 @@ type accountService struct {
 }
 
 type transferService struct {
-	userRepo    repository.UserRepository
-	accountRepo repository.AccountRepository
+	userRepo        repository.UserRepository
+	accountRepo     repository.AccountRepository
+	transactionRepo repository.TransactionRepository
+	transactor      repository.Transactor
 }
 
 func NewUserService(userRepo repository.UserRepository) UserService {
@@ -59,23 +65,31 @@ func NewAccountService(accountRepo repository.AccountRepository) AccountService
 	return &accountService{accountRepo: accountRepo}
 }
 
-func NewTransferService(userRepo repository.UserRepository, accountRepo repository.AccountRepository) TransferService {
-	return &transferService{userRepo: userRepo, accountRepo: accountRepo}
+func NewTransferService(userRepo repository.UserRepository, accountRepo repository.AccountRepository, transactionRepo repository.TransactionRepository, transactor repository.Transactor) TransferService {
+	return &transferService{userRepo: userRepo, accountRepo: accountRepo, transactionRepo: transactionRepo, transactor: transactor}
+}
+
+func (s *userService) GetUserByReferralCode(ctx context.Context, code string) (*models.User, error) {
+	return s.userRepo.GetUserByReferralCode(ctx, code)
 }
 
-func (s *userService) GetUserByReferralCode(code string) (*models.User, error) {
-	return s.userRepo.GetUserByReferralCode(code)
+func (s *userService) GetUsers(ctx context.Context, filter models.UserFilter, page, limit int64) ([]*models.User, int64, error) {
+	return s.userRepo.GetUsers(ctx, filter, page, limit)
 }
 
-func (s *userService) GetUsersByLeaderStatus(isLeader bool) ([]*models.User, error) {
-	return s.userRepo.GetUsersByLeaderStatus(isLeader)
+func (s *userService) SearchUsers(ctx context.Context, query string, filter models.UserFilter, page, limit int64) ([]*models.User, int64, error) {
+	return s.userRepo.SearchUsers(ctx, query, filter, page, limit)
 }
 
-func (s *userService) UpdateUser(user *models.User) error {
-	return s.userRepo.UpdateUser(user)
+func (s *userService) CountUsers(ctx context.Context, filter models.UserFilter) (int64, error) {
+	return s.userRepo.CountUsers(ctx, filter)
 }
 
-func (s *userService) SignupUser(user *models.User) error {
+func (s *userService) UpdateUser(ctx context.Context, user *models.User) error {
+	return s.userRepo.UpdateUser(ctx, user)
+}
+
+func (s *userService) SignupUser(ctx context.Context, user *models.User) error {
 	if user.ID.IsZero() {
 		user.ID = primitive.NewObjectID()
 		user.RegistrationDate = time.Now().Format(time.RFC3339)
@@ -84,109 +98,125 @@ func (s *userService) SignupUser(user *models.User) error {
 		user.Balance = 0.0
 		user.Bonus = 0.0
 	}
-	return s.userRepo.SaveUser(user)
+	return s.userRepo.SaveUser(ctx, user)
 }
 
-func (s *userService) ActiveUser(userID primitive.ObjectID, active bool) error {
-	return s.userRepo.ActiveUser(userID, active)
+func (s *userService) ActiveUser(ctx context.Context, userID primitive.ObjectID, active bool) error {
+	return s.userRepo.ActiveUser(ctx, userID, active)
 }
 
-func (s *userService) EditUser(user *models.User) error {
-	return s.userRepo.EditUser(user)
+func (s *userService) EditUser(ctx context.Context, user *models.User) error {
+	return s.userRepo.EditUser(ctx, user)
 }
 
-func (s *userService) GetUser(id string) (*models.User, error) {
+func (s *userService) GetUser(ctx context.Context, id string) (*models.User, error) {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, err
 	}
-	return s.userRepo.GetUserByID(objID)
+	return s.userRepo.GetUserByID(ctx, objID)
 }
 
-func (s *userService) GetUserByTelegramID(telegramID string) (*models.User, error) {
-	return s.userRepo.GetUserByTelegramID(telegramID)
+func (s *userService) GetUserByTelegramID(ctx context.Context, telegramID string) (*models.User, error) {
+	return s.userRepo.GetUserByTelegramID(ctx, telegramID)
 }
-func (s *userService) GetAllUsers() ([]*models.User, error) {
-	return s.userRepo.GetAllUsers()
+func (s *userService) GetAllUsers(ctx context.Context) ([]*models.User, error) {
+	return s.userRepo.GetAllUsers(ctx)
 }
 
-func (s *userService) GetUsersReferredBy(code string, page, limit int64) ([]*models.User, int64, error) {
-	return s.userRepo.GetUsersReferredBy(code, page, limit)
+func (s *userService) GetUsersReferredBy(ctx context.Context, code string, page, limit int64) ([]*models.User, int64, error) {
+	return s.userRepo.GetUsersReferredBy(ctx, code, page, limit)
 }
 
-func (s *userService) GetAllReferrals(page, limit int64) ([]*models.User, int64, error) {
-	return s.userRepo.GetAllReferrals(page, limit)
+func (s *userService) GetAllReferrals(ctx context.Context, page, limit int64) ([]*models.User, int64, error) {
+	return s.userRepo.GetAllReferrals(ctx, page, limit)
 }
 
-func (s *accountService) CreateAccount(account *models.Account) error {
+func (s *accountService) CreateAccount(ctx context.Context, account *models.Account) error {
 	if account.ID.IsZero() {
 		account.ID = primitive.NewObjectID()
 		account.RegistrationDate = time.Now().Format(time.RFC3339)
 		account.IsActive = false
 	}
-	if account.AccountType != "demo" && account.AccountType != "real" {
-		return fmt.Errorf("invalid account type: %s", account.AccountType)
+	account.AccountType = models.NormalizeAccountType(account.AccountType)
+	if !models.IsTradingAccountType(account.AccountType) {
+		return newError(ErrInvalidInput, "invalid account type: %s", account.AccountType)
 	}
-	return s.accountRepo.SaveAccount(account)
+	return s.accountRepo.SaveAccount(ctx, account)
 }
 
-func (s *accountService) GetAccount(id string) (*models.Account, error) {
+func (s *accountService) GetAccount(ctx context.Context, id string) (*models.Account, error) {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return nil, err
 	}
-	return s.accountRepo.GetAccountByID(objID)
+	return s.accountRepo.GetAccountByID(ctx, objID)
 }
 
-func (s *accountService) GetAccountsByUserID(userID string) ([]*models.Account, error) {
+func (s *accountService) GetAccountsByUserID(ctx context.Context, userID string) ([]*models.Account, error) {
 	objID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
 		return nil, err
 	}
-	return s.accountRepo.GetAccountsByUserID(objID)
+	return s.accountRepo.GetAccountsByUserID(ctx, objID)
+}
+
+func (s *accountService) DeleteAccount(ctx context.Context, accountID, userID primitive.ObjectID) error {
+	err := s.accountRepo.DeleteAccount(ctx, accountID, userID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrAccountNotFound
+	}
+	return err
 }
 
-func (s *accountService) DeleteAccount(accountID, userID primitive.ObjectID) error {
-	return s.accountRepo.DeleteAccount(accountID, userID)
+func (s *accountService) SetRiskLimits(ctx context.Context, accountID, userID primitive.ObjectID, limits models.AccountRiskLimits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+	err := s.accountRepo.SetRiskLimits(ctx, accountID, userID, limits)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrAccountNotFound
+	}
+	return err
 }
 
-func (s *transferService) TransferBalance(userID primitive.ObjectID, sourceID, destID string, amount float64, sourceType, destType string) error {
+func (s *transferService) TransferBalance(ctx context.Context, userID primitive.ObjectID, sourceID, destID string, amount float64, sourceType, destType string) error {
 	if amount <= 0 {
-		return fmt.Errorf("amount must be positive")
+		return newError(ErrInvalidInput, "amount must be positive")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	// Both legs of the transfer are written separately, so a dropped request
+	// must not cancel the second one.
+	sourceType = models.NormalizeAccountType(sourceType)
+	destType = models.NormalizeAccountType(destType)
 
-	session, err := s.userRepo.Collection().Database().Client().StartSession()
-	if err != nil {
-		return fmt.Errorf("failed to start session: %w", err)
-	}
-	defer session.EndSession(ctx)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
 
-	callback := func(sessionContext mongo.SessionContext) (interface{}, error) {
+	return s.transactor.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
 		var sourceUser *models.User
 		var sourceAccount *models.Account
 		var sourceBalance *float64
 
-		if sourceType == "main" {
-			sourceUser, err = s.userRepo.GetUserByID(userID)
+		if sourceType == models.AccountTypeMain {
+			sourceUser, err = s.userRepo.GetUserByID(ctx, userID)
 			if err != nil || sourceUser == nil {
-				return nil, fmt.Errorf("source user not found")
+				return newError(ErrNotFound, "source user not found")
 			}
 			sourceBalance = &sourceUser.Balance
 		} else {
-			sourceAccount, err = s.accountRepo.GetAccountByName(sourceID, userID)
+			sourceAccount, err = s.accountRepo.GetAccountByName(ctx, sourceID, userID)
 			if err != nil || sourceAccount == nil {
-				return nil, fmt.Errorf("source account not found")
+				return newError(ErrNotFound, "source account not found")
 			}
-			if sourceAccount.AccountType != sourceType {
-				return nil, fmt.Errorf("source account type mismatch: expected %s, got %s", sourceType, sourceAccount.AccountType)
+			if !models.SameAccountType(sourceAccount.AccountType, sourceType) {
+				return newError(ErrInvalidInput, "source account type mismatch: expected %s, got %s", sourceType, sourceAccount.AccountType)
 			}
 			sourceBalance = &sourceAccount.Balance
-			sourceUser, err = s.userRepo.GetUserByID(sourceAccount.UserID)
+			sourceUser, err = s.userRepo.GetUserByID(ctx, sourceAccount.UserID)
 			if err != nil || sourceUser == nil {
-				return nil, fmt.Errorf("source user not found")
+				return newError(ErrNotFound, "source user not found")
 			}
 		}
 
@@ -194,65 +224,85 @@ func (s *transferService) TransferBalance(userID primitive.ObjectID, sourceID, d
 		var destAccount *models.Account
 		var destBalance *float64
 
-		if destType == "main" {
-			destUser, err = s.userRepo.GetUserByID(userID)
+		if destType == models.AccountTypeMain {
+			destUser, err = s.userRepo.GetUserByID(ctx, userID)
 			if err != nil || destUser == nil {
-				return nil, fmt.Errorf("destination user not found")
+				return newError(ErrNotFound, "destination user not found")
 			}
 			destBalance = &destUser.Balance
 		} else {
-			destAccount, err = s.accountRepo.GetAccountByName(destID, userID)
+			destAccount, err = s.accountRepo.GetAccountByName(ctx, destID, userID)
 			if err != nil || destAccount == nil {
-				return nil, fmt.Errorf("destination account not found")
+				return newError(ErrNotFound, "destination account not found")
 			}
-			if destAccount.AccountType != destType {
-				return nil, fmt.Errorf("destination account type mismatch: expected %s, got %s", destType, destAccount.AccountType)
+			if !models.SameAccountType(destAccount.AccountType, destType) {
+				return newError(ErrInvalidInput, "destination account type mismatch: expected %s, got %s", destType, destAccount.AccountType)
 			}
 			destBalance = &destAccount.Balance
-			destUser, err = s.userRepo.GetUserByID(userID)
+			destUser, err = s.userRepo.GetUserByID(ctx, userID)
 			if err != nil || destUser == nil {
-				return nil, fmt.Errorf("destination user not found")
+				return newError(ErrNotFound, "destination user not found")
 			}
 		}
 
 		if sourceUser.ID != destUser.ID {
-			return nil, fmt.Errorf("transfers must be within the same user")
+			return newError(ErrForbidden, "transfers must be within the same user")
 		}
 
-		if (sourceType == "demo" && destType == "real") || (sourceType == "real" && destType == "demo") {
-			return nil, fmt.Errorf("cannot transfer between demo and real balances")
+		if (sourceType == models.AccountTypeDemo && destType == models.AccountTypeReal) || (sourceType == models.AccountTypeReal && destType == models.AccountTypeDemo) {
+			return newError(ErrForbidden, "cannot transfer between demo and real balances")
 		}
 
 		if *sourceBalance < amount {
-			return nil, fmt.Errorf("insufficient balance in source account")
+			return newError(ErrInsufficientBalance, "insufficient balance in source account")
 		}
 
 		*sourceBalance -= amount
 		*destBalance += amount
 
-		if sourceType == "main" {
-			if err := s.userRepo.UpdateUser(sourceUser); err != nil {
-				return nil, fmt.Errorf("failed to update source user: %w", err)
+		if sourceType == models.AccountTypeMain {
+			if err := s.userRepo.UpdateUser(ctx, sourceUser); err != nil {
+				return fmt.Errorf("failed to update source user: %w", err)
 			}
 		} else {
-			if err := s.accountRepo.UpdateAccount(sourceAccount); err != nil {
-				return nil, fmt.Errorf("failed to update source account: %w", err)
+			if err := s.accountRepo.UpdateAccount(ctx, sourceAccount); err != nil {
+				return fmt.Errorf("failed to update source account: %w", err)
 			}
 		}
 
-		if destType == "main" {
-			if err := s.userRepo.UpdateUser(destUser); err != nil {
-				return nil, fmt.Errorf("failed to update destination user: %w", err)
+		if destType == models.AccountTypeMain {
+			if err := s.userRepo.UpdateUser(ctx, destUser); err != nil {
+				return fmt.Errorf("failed to update destination user: %w", err)
 			}
 		} else {
-			if err := s.accountRepo.UpdateAccount(destAccount); err != nil {
-				return nil, fmt.Errorf("failed to update destination account: %w", err)
+			if err := s.accountRepo.UpdateAccount(ctx, destAccount); err != nil {
+				return fmt.Errorf("failed to update destination account: %w", err)
 			}
 		}
 
-		return nil, nil
-	}
+		now := time.Now()
+		record := &models.Transaction{
+			UserID:          userID.Hex(),
+			TelegramID:      sourceUser.TelegramID,
+			TransactionType: models.TransactionTypeTransfer,
+			Amount:          amount,
+			Status:          models.TransactionStatusApproved,
+			ResponseTime:    &now,
+			FromAccount:     transferEndpoint(sourceType, sourceID),
+			ToAccount:       transferEndpoint(destType, destID),
+		}
+		if err := s.transactionRepo.SaveTransaction(ctx, record); err != nil {
+			return fmt.Errorf("failed to record transfer: %w", err)
+		}
 
-	_, err = session.WithTransaction(ctx, callback)
-	return err
+		return nil
+	})
+}
+
+// transferEndpoint names one side of a transfer for the transaction history.
+func transferEndpoint(accountType, accountName string) string {
+	if accountType == models.AccountTypeMain {
+		return models.AccountTypeMain
+	}
+	return accountType + ":" + accountName
 }
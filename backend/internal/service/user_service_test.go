@@ -0,0 +1,46 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mehrbod2002/fxtrader/internal/models"
+	"github.com/mehrbod2002/fxtrader/internal/repository/memory"
+	"github.com/mehrbod2002/fxtrader/internal/service"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func ExampleTransferService() {
+	ctx := context.Background()
+	users := memory.NewUserRepository()
+	accounts := memory.NewAccountRepository()
+	transactions := memory.NewTransactionRepository()
+	transfers := service.NewTransferService(users, accounts, transactions, memory.NewTransactor())
+
+	user := &models.User{ID: primitive.NewObjectID(), Username: "trader"}
+	_ = users.SaveUser(ctx, user)
+	_ = users.AddBalance(ctx, user.ID, 500)
+	account := &models.Account{ID: primitive.NewObjectID(), UserID: user.ID, AccountName: "live", AccountType: models.AccountTypeReal}
+	_ = accounts.SaveAccount(ctx, account)
+
+	err := transfers.TransferBalance(ctx, user.ID, "", "live", 200, models.AccountTypeMain, "REAL")
+	fmt.Println("transfer:", err)
+
+	err = transfers.TransferBalance(ctx, user.ID, "live", "", 1000, models.AccountTypeReal, models.AccountTypeMain)
+	fmt.Println("overdraw:", err)
+
+	stored, _ := users.GetUserByID(ctx, user.ID)
+	live, _ := accounts.GetAccountByID(ctx, account.ID)
+	fmt.Printf("main %.2f, live %.2f\n", stored.Balance, live.Balance)
+
+	history, _ := transactions.GetTransactionsByUserID(ctx, user.ID)
+	for _, record := range history {
+		fmt.Println(record.TransactionType, record.FromAccount, "->", record.ToAccount, record.Amount)
+	}
+	// Output:
+	// transfer: <nil>
+	// overdraw: insufficient balance in source account
+	// main 300.00, live 200.00
+	// TRANSFER main -> real:live 200
+}
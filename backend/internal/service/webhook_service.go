@@ -0,0 +1,267 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mehrbod2002/fxtrader/internal/config"
+	"github.com/mehrbod2002/fxtrader/internal/models"
+	"github.com/mehrbod2002/fxtrader/internal/repository"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Headers sent with every webhook. The signature is the hex HMAC-SHA256 of
+// "<timestamp>.<body>" under the shared secret, prefixed with "sha256=".
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	webhookWorkers     = 4
+	webhookTimeout     = 10 * time.Second
+	webhookBaseBackoff = 10 * time.Second
+	webhookMaxBackoff  = 30 * time.Minute
+)
+
+type WebhookService interface {
+	Publish(event string, data interface{})
+	GetDeliveries(ctx context.Context, status models.WebhookDeliveryStatus, page, limit int) ([]*models.WebhookDelivery, int64, error)
+	RetryDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error)
+}
+
+type webhookService struct {
+	repo        repository.WebhookRepository
+	logService  LogService
+	urls        []string
+	secret      []byte
+	maxAttempts int
+	client      *http.Client
+	queue       chan *models.WebhookDelivery
+}
+
+// NewWebhookService starts the delivery workers and requeues deliveries that
+// were still pending when the server last stopped. With no subscriber URLs
+// configured, Publish does nothing.
+func NewWebhookService(repo repository.WebhookRepository, logService LogService, cfg *config.Config) WebhookService {
+	s := &webhookService{
+		repo:        repo,
+		logService:  logService,
+		urls:        cfg.WebhookURLs,
+		secret:      []byte(cfg.WebhookSecret),
+		maxAttempts: cfg.WebhookMaxAttempts,
+		client:      &http.Client{Timeout: webhookTimeout},
+		queue:       make(chan *models.WebhookDelivery, cfg.WebhookQueueSize),
+	}
+	for i := 0; i < webhookWorkers; i++ {
+		go s.work()
+	}
+	go s.resume(cap(s.queue))
+	return s
+}
+
+// Publish records one delivery of the event per subscriber and queues them.
+// It never blocks the caller on the network.
+func (s *webhookService) Publish(event string, data interface{}) {
+	if len(s.urls) == 0 {
+		return
+	}
+	ctx := context.Background()
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":         uuid.New().String(),
+		"event":      event,
+		"created_at": time.Now().UTC(),
+		"data":       data,
+	})
+	if err != nil {
+		log.Printf("Failed to encode webhook %s: %v", event, err)
+		return
+	}
+
+	for _, url := range s.urls {
+		delivery := &models.WebhookDelivery{
+			Event:   event,
+			URL:     url,
+			Payload: string(payload),
+			Status:  models.WebhookDeliveryPending,
+		}
+		if err := s.repo.SaveDelivery(ctx, delivery); err != nil {
+			log.Printf("Failed to record webhook %s for %s: %v", event, url, err)
+			continue
+		}
+		s.enqueue(delivery)
+	}
+}
+
+func (s *webhookService) GetDeliveries(ctx context.Context, status models.WebhookDeliveryStatus, page, limit int) ([]*models.WebhookDelivery, int64, error) {
+	return s.repo.GetDeliveries(ctx, status, page, limit)
+}
+
+// RetryDelivery gives a failed delivery a fresh set of attempts.
+func (s *webhookService) RetryDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, newError(ErrInvalidInput, "invalid delivery ID")
+	}
+	delivery, err := s.repo.GetDeliveryByID(ctx, objID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery == nil {
+		return nil, newError(ErrNotFound, "delivery not found")
+	}
+	if delivery.Status != models.WebhookDeliveryFailed {
+		return nil, newError(ErrConflict, "only failed deliveries can be retried")
+	}
+
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = nil
+	if err := s.repo.UpdateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+	s.enqueue(delivery)
+	return delivery, nil
+}
+
+func (s *webhookService) enqueue(delivery *models.WebhookDelivery) {
+	select {
+	case s.queue <- delivery:
+	default:
+		s.fail(delivery, "delivery queue full")
+	}
+}
+
+func (s *webhookService) work() {
+	for delivery := range s.queue {
+		s.attempt(delivery)
+	}
+}
+
+// resume requeues pending deliveries left over from a previous run, honouring
+// any retry that was already scheduled.
+func (s *webhookService) resume(limit int) {
+	pending, _, err := s.repo.GetDeliveries(context.Background(), models.WebhookDeliveryPending, 1, limit)
+	if err != nil {
+		log.Printf("Failed to load pending webhooks: %v", err)
+		return
+	}
+	for _, delivery := range pending {
+		s.schedule(delivery)
+	}
+}
+
+func (s *webhookService) schedule(delivery *models.WebhookDelivery) {
+	if delivery.NextAttemptAt == nil {
+		s.enqueue(delivery)
+		return
+	}
+	time.AfterFunc(time.Until(*delivery.NextAttemptAt), func() { s.enqueue(delivery) })
+}
+
+func (s *webhookService) attempt(delivery *models.WebhookDelivery) {
+	ctx := context.Background()
+
+	delivery.Attempts++
+	statusCode, err := s.post(delivery)
+	delivery.LastStatusCode = statusCode
+	if err == nil {
+		now := time.Now()
+		delivery.Status = models.WebhookDeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+		delivery.LastError = ""
+		if err := s.repo.UpdateDelivery(ctx, delivery); err != nil {
+			log.Printf("Failed to record webhook delivery %s: %v", delivery.ID.Hex(), err)
+		}
+		return
+	}
+
+	if delivery.Attempts >= s.maxAttempts {
+		s.fail(delivery, err.Error())
+		return
+	}
+	delivery.LastError = err.Error()
+	next := time.Now().Add(webhookBackoff(delivery.Attempts))
+	delivery.NextAttemptAt = &next
+	if err := s.repo.UpdateDelivery(ctx, delivery); err != nil {
+		log.Printf("Failed to record webhook delivery %s: %v", delivery.ID.Hex(), err)
+	}
+	s.schedule(delivery)
+}
+
+// post sends the delivery once. Any 2xx answer counts as delivered.
+func (s *webhookService) post(delivery *models.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID.Hex())
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+s.sign(timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("subscriber returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (s *webhookService) sign(timestamp, payload string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// fail gives up on the delivery and leaves it for an admin to retry.
+func (s *webhookService) fail(delivery *models.WebhookDelivery, reason string) {
+	delivery.Status = models.WebhookDeliveryFailed
+	delivery.LastError = reason
+	delivery.NextAttemptAt = nil
+	if err := s.repo.UpdateDelivery(context.Background(), delivery); err != nil {
+		log.Printf("Failed to record webhook delivery %s: %v", delivery.ID.Hex(), err)
+	}
+
+	metadata := map[string]interface{}{
+		"delivery_id": delivery.ID.Hex(),
+		"event":       delivery.Event,
+		"url":         delivery.URL,
+		"attempts":    delivery.Attempts,
+		"last_error":  reason,
+	}
+	if err := s.logService.LogAction(primitive.NilObjectID, "WebhookFailed", "Webhook delivery failed", "", metadata); err != nil {
+		log.Printf("error: %v", err)
+	}
+}
+
+// webhookBackoff doubles the wait after each failed attempt, up to a cap.
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookBaseBackoff
+	for i := 1; i < attempts && delay < webhookMaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookMaxBackoff)
+}
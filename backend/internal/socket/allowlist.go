@@ -0,0 +1,75 @@
+package socket
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Allowlist restricts which peers may open an MT5 bridge connection. An empty
+// list on either dimension leaves that dimension unrestricted.
+type Allowlist struct {
+	origins  map[string]struct{}
+	networks []*net.IPNet
+}
+
+// NewAllowlist accepts exact origins (scheme://host[:port]) and IPs or CIDR ranges.
+func NewAllowlist(origins, ips []string) (*Allowlist, error) {
+	a := &Allowlist{origins: make(map[string]struct{}, len(origins))}
+	for _, origin := range origins {
+		a.origins[strings.ToLower(strings.TrimSuffix(origin, "/"))] = struct{}{}
+	}
+	for _, entry := range ips {
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			a.networks = append(a.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowlist entry %q", entry)
+		}
+		a.networks = append(a.networks, network)
+	}
+	return a, nil
+}
+
+func (a *Allowlist) Empty() bool {
+	return len(a.origins) == 0 && len(a.networks) == 0
+}
+
+// Allows checks the request's remote address and, when present, its Origin
+// header. Forwarding headers are ignored because the bridge connects directly.
+func (a *Allowlist) Allows(r *http.Request) bool {
+	if len(a.networks) > 0 {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		ip := net.ParseIP(host)
+		if ip == nil || !a.containsIP(ip) {
+			return false
+		}
+	}
+	if len(a.origins) > 0 {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if _, ok := a.origins[strings.ToLower(origin)]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (a *Allowlist) containsIP(ip net.IP) bool {
+	for _, network := range a.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package socket
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mehrbod2002/fxtrader/interfaces"
+)
+
+var _ interfaces.MT5Transport = (*WebSocketServer)(nil)
+var _ interfaces.MT5Transport = (*MemoryTransport)(nil)
+
+// MemoryTransport is an in-process stand-in for the MT5 bridge. Responses are
+// primed per request type and replayed in order; a request with nothing primed
+// gets no reply, which lets callers exercise their timeout paths.
+type MemoryTransport struct {
+	mu             sync.Mutex
+	tradeService   interfaces.TradeService
+	tradeResponses []interfaces.TradeResponse
+	closeResponses []interfaces.TradeResponse
+	sent           []map[string]interface{}
+	delay          time.Duration
+	sendErr        error
+}
+
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{}
+}
+
+// Start binds the transport to the service that receives its responses.
+func (t *MemoryTransport) Start(tradeService interfaces.TradeService) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tradeService = tradeService
+	return nil
+}
+
+// PrimeTradeResponse queues the reply to the next trade request, e.g. MATCHED,
+// PENDING or REJECTED.
+func (t *MemoryTransport) PrimeTradeResponse(status string, matchedVolume float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tradeResponses = append(t.tradeResponses, interfaces.TradeResponse{Status: status, MatchedVolume: matchedVolume})
+}
+
+// PrimeCloseResponse queues the reply to the next close request.
+func (t *MemoryTransport) PrimeCloseResponse(status string, closePrice float64, closeReason string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closeResponses = append(t.closeResponses, interfaces.TradeResponse{Status: status, ClosePrice: closePrice, CloseReason: closeReason})
+}
+
+// SetDelay holds every reply back for d before delivering it.
+func (t *MemoryTransport) SetDelay(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.delay = d
+}
+
+// FailSends makes every send return err, simulating a missing MT5 connection.
+// Pass nil to restore normal behaviour.
+func (t *MemoryTransport) FailSends(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sendErr = err
+}
+
+// Sent returns a copy of every request handed to the transport.
+func (t *MemoryTransport) Sent() []map[string]interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sent := make([]map[string]interface{}, len(t.sent))
+	copy(sent, t.sent)
+	return sent
+}
+
+func (t *MemoryTransport) SendTradeRequest(request map[string]interface{}) error {
+	response, ok, err := t.record(request, &t.tradeResponses)
+	if err != nil || !ok {
+		return err
+	}
+	t.deliver(func(ts interfaces.TradeService) error { return ts.HandleTradeResponse(response) })
+	return nil
+}
+
+func (t *MemoryTransport) SendCloseTradeRequest(request map[string]interface{}) error {
+	response, ok, err := t.record(request, &t.closeResponses)
+	if err != nil || !ok {
+		return err
+	}
+	t.deliver(func(ts interfaces.TradeService) error { return ts.HandleCloseTradeResponse(response) })
+	return nil
+}
+
+func (t *MemoryTransport) SendOrderStreamRequest(request map[string]interface{}) error {
+	_, _, err := t.record(request, nil)
+	return err
+}
+
+func (t *MemoryTransport) SendBalanceRequest(request map[string]interface{}) error {
+	_, _, err := t.record(request, nil)
+	return err
+}
+
+// record stores the request and pops the next primed response from queue,
+// filling in the identifiers the real bridge would echo back.
+func (t *MemoryTransport) record(request map[string]interface{}, queue *[]interfaces.TradeResponse) (interfaces.TradeResponse, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.sendErr != nil {
+		return interfaces.TradeResponse{}, false, t.sendErr
+	}
+	t.sent = append(t.sent, request)
+	if queue == nil || len(*queue) == 0 {
+		return interfaces.TradeResponse{}, false, nil
+	}
+
+	response := (*queue)[0]
+	*queue = (*queue)[1:]
+	response.TradeID, _ = request["trade_id"].(string)
+	response.UserID, _ = request["user_id"].(string)
+	response.AccountID, _ = request["account_id"].(string)
+	response.AccountType, _ = request["account_type"].(string)
+	response.Timestamp = float64(time.Now().Unix())
+	return response, true, nil
+}
+
+func (t *MemoryTransport) deliver(handle func(interfaces.TradeService) error) {
+	t.mu.Lock()
+	tradeService, delay := t.tradeService, t.delay
+	t.mu.Unlock()
+	if tradeService == nil {
+		return
+	}
+
+	go func() {
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		_ = handle(tradeService)
+	}()
+}
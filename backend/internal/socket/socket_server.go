@@ -3,6 +3,7 @@ package socket
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -42,6 +43,10 @@ type WebSocketServer struct {
 	cancel       context.CancelFunc
 	upgrader     websocket.Upgrader
 	accountRepo  repository.AccountRepository
+	tlsCertFile  string
+	tlsKeyFile   string
+	allowlist    *Allowlist
+	deadLetters  repository.DeadLetterRepository
 }
 
 type Client struct {
@@ -51,21 +56,75 @@ type Client struct {
 	writeMu    sync.Mutex
 }
 
-func NewWebSocketServer(listenPort int, accountInfo repository.AccountRepository) (*WebSocketServer, error) {
+func NewWebSocketServer(listenPort int, accountInfo repository.AccountRepository, enableCompression bool) (*WebSocketServer, error) {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &WebSocketServer{
+	s := &WebSocketServer{
 		listenAddr: fmt.Sprintf(":%d", listenPort),
 		handlers:   make(map[string]HandlerFunc),
 		clients:    make(map[string]*Client),
 		ctx:        ctx,
 		cancel:     cancel,
 		upgrader: websocket.Upgrader{
-			ReadBufferSize:  8192,
-			WriteBufferSize: 8192,
-			CheckOrigin:     func(r *http.Request) bool { return true },
+			ReadBufferSize:    8192,
+			WriteBufferSize:   8192,
+			EnableCompression: enableCompression,
 		},
 		accountRepo: accountInfo,
-	}, nil
+	}
+	s.upgrader.CheckOrigin = s.checkOrigin
+	return s, nil
+}
+
+// SetAllowlist limits bridge connections to the given peers.
+func (s *WebSocketServer) SetAllowlist(allowlist *Allowlist) {
+	s.allowlist = allowlist
+}
+
+// SetDeadLetterRepository stores messages that fail processing instead of only logging them.
+func (s *WebSocketServer) SetDeadLetterRepository(repo repository.DeadLetterRepository) {
+	s.deadLetters = repo
+}
+
+func (s *WebSocketServer) deadLetter(clientID string, message []byte, procErr error) {
+	if s.deadLetters == nil {
+		return
+	}
+	letter := &models.DeadLetter{
+		ClientID: clientID,
+		Raw:      string(message),
+		Error:    procErr.Error(),
+	}
+	var envelope struct {
+		Type    string `json:"type"`
+		TradeID string `json:"trade_id"`
+	}
+	if json.Unmarshal(message, &envelope) == nil {
+		letter.MessageType = envelope.Type
+		letter.TradeID = envelope.TradeID
+	}
+	if errors.Is(procErr, interfaces.ErrUnknownTrade) {
+		letter.Reason = models.DeadLetterReasonOrphan
+	}
+	if err := s.deadLetters.SaveDeadLetter(context.Background(), letter); err != nil {
+		log.Printf("Failed to store dead letter from %s: %v", clientID, err)
+	}
+}
+
+func (s *WebSocketServer) checkOrigin(r *http.Request) bool {
+	if s.allowlist == nil || s.allowlist.Empty() {
+		return true
+	}
+	if !s.allowlist.Allows(r) {
+		log.Printf("Rejected MT5 connection from %s (origin %q)", r.RemoteAddr, r.Header.Get("Origin"))
+		return false
+	}
+	return true
+}
+
+// UseTLS makes Start serve wss:// with the given certificate and key.
+func (s *WebSocketServer) UseTLS(certFile, keyFile string) {
+	s.tlsCertFile = certFile
+	s.tlsKeyFile = keyFile
 }
 
 func (s *WebSocketServer) RegisterHandler(msgType string, handler HandlerFunc) {
@@ -97,7 +156,15 @@ func (s *WebSocketServer) Start(tradeService interfaces.TradeService) error {
 	})
 
 	go func() {
-		if err := http.ListenAndServe(s.listenAddr, nil); err != nil {
+		var err error
+		if s.tlsCertFile != "" {
+			log.Printf("MT5 socket server listening on wss://%s/ws", s.listenAddr)
+			err = http.ListenAndServeTLS(s.listenAddr, s.tlsCertFile, s.tlsKeyFile, nil)
+		} else {
+			log.Printf("MT5 socket server listening on ws://%s/ws without TLS; use only for local development", s.listenAddr)
+			err = http.ListenAndServe(s.listenAddr, nil)
+		}
+		if err != nil {
 			log.Printf("WebSocket server failed: %v", err)
 		}
 	}()
@@ -148,7 +215,7 @@ func (s *WebSocketServer) handleBalanceRequest(msg map[string]interface{}, clien
 		return err
 	}
 
-	account, err := s.accountRepo.GetAccountByName(accountName, userObjID)
+	account, err := s.accountRepo.GetAccountByName(context.Background(), accountName, userObjID)
 	if err != nil {
 		errorResponse := map[string]interface{}{
 			"type":      "error",
@@ -224,8 +291,7 @@ func (s *WebSocketServer) handleBalanceResponse(msg map[string]interface{}, clie
 	if err := json.Unmarshal(data, &response); err != nil {
 		return fmt.Errorf("failed to unmarshal balance response: %v", err)
 	}
-	log.Printf("Forwarding balance response to TradeService: %+v", response)
-	return nil
+	return s.tradeService.HandleBalanceResponse(response)
 }
 
 func (s *WebSocketServer) addClient(clientID string, conn *websocket.Conn, cancelPing context.CancelFunc) {
@@ -388,6 +454,7 @@ func (s *WebSocketServer) handleConnection(conn *websocket.Conn, ctx context.Con
 
 			if err := s.processMessage(message, conn, &tempClientID); err != nil {
 				log.Printf("Error processing message from %s: %v", tempClientID, err)
+				s.deadLetter(tempClientID, message, err)
 			}
 		}
 	}
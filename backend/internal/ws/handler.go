@@ -1,10 +1,14 @@
 package ws
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -29,6 +33,12 @@ var Upgrader = websocket.Upgrader{
 	},
 }
 
+// SetCompression toggles permessage-deflate negotiation for client connections.
+// Compression is only used when the client offers it in the handshake.
+func SetCompression(enabled bool) {
+	Upgrader.EnableCompression = enabled
+}
+
 type WebSocketHandler struct {
 	hub            *Hub
 	tradeService   interfaces.TradeService
@@ -55,10 +65,30 @@ func (h *WebSocketHandler) HandleConnection(c *gin.Context) {
 	go h.writePump(client)
 }
 
+// tradeStream identifies a StreamTrades subscription opened by a client.
+type tradeStream struct {
+	userID      string
+	accountType string
+}
+
 func (h *WebSocketHandler) readPump(client *models.Client) {
+	var streams []tradeStream
 	defer func() {
+		// The client is gone, so nothing will drain its trade streams.
+		for _, stream := range streams {
+			if err := h.tradeService.StopStream(stream.userID, stream.accountType); err != nil {
+				log.Printf("Failed to stop trade stream for client %s: %v", client.ID, err)
+			}
+		}
 		h.hub.UnregisterClient(client)
 	}()
+	// Pongs and messages show the client is alive, which keeps its trade
+	// streams from expiring as idle.
+	touchStreams := func() {
+		for _, stream := range streams {
+			h.tradeService.TouchStream(stream.userID, stream.accountType)
+		}
+	}
 
 	client.Conn.SetReadLimit(maxMessageSize)
 	if err := client.Conn.SetReadDeadline(time.Now().Add(pongWait)); err != nil {
@@ -68,21 +98,27 @@ func (h *WebSocketHandler) readPump(client *models.Client) {
 		if err := client.Conn.SetReadDeadline(time.Now().Add(pongWait)); err != nil {
 			return err
 		}
+		touchStreams()
 		return nil
 	})
 
 	for {
 		_, message, err := client.Conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				log.Printf("Client %s missed pong deadline, disconnecting", client.ID)
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket read error: %v", err)
 			}
 			break
 		}
+		touchStreams()
 
 		var socketMsg struct {
 			Action      string `json:"action"`
 			Symbol      string `json:"symbol"`
+			Policy      string `json:"policy"`
 			AccountType string `json:"account_type"`
 			UserID      string `json:"user_id"`
 		}
@@ -97,7 +133,20 @@ func (h *WebSocketHandler) readPump(client *models.Client) {
 
 		switch socketMsg.Action {
 		case "subscribe":
+			policy, ok := models.ParseBackpressurePolicy(socketMsg.Policy)
+			if !ok {
+				response := models.ErrorResponse{Error: "Invalid policy; expected coalesce or drop-oldest"}
+				if err := client.Conn.WriteJSON(response); err != nil {
+					log.Printf("Error sending error response: %v", err)
+				}
+				continue
+			}
+			client.Prices.SetPolicy(socketMsg.Symbol, policy)
 			client.Subscribe(socketMsg.Symbol)
+			// Send the last known tick so the client doesn't wait for the next one.
+			if price, ok := h.hub.LastPrice(socketMsg.Symbol); ok {
+				client.Prices.Push(price)
+			}
 			var symbols []string
 			for symbol := range client.Symbols {
 				symbols = append(symbols, symbol)
@@ -112,14 +161,14 @@ func (h *WebSocketHandler) readPump(client *models.Client) {
 			}
 
 		case "subscribe_trades":
-			if socketMsg.AccountType != "DEMO" && socketMsg.AccountType != "REAL" {
+			if !models.IsTradingAccountType(socketMsg.AccountType) {
 				response := models.ErrorResponse{Error: "Invalid account type"}
 				if err := client.Conn.WriteJSON(response); err != nil {
 					log.Printf("Error sending error response: %v", err)
 				}
 				continue
 			}
-			user, err := h.userRepository.GetUserByTelegramID(socketMsg.UserID)
+			user, err := h.userRepository.GetUserByTelegramID(context.Background(), socketMsg.UserID)
 			if err != nil {
 				response := models.ErrorResponse{Error: "Invalid user ID"}
 				if err := client.Conn.WriteJSON(response); err != nil {
@@ -128,19 +177,27 @@ func (h *WebSocketHandler) readPump(client *models.Client) {
 				continue
 			}
 
-			subscriptionKey := socketMsg.UserID + ":" + socketMsg.AccountType
+			// The hub routes trade and balance updates by internal user ID and
+			// lowercase account type.
+			accountType := models.NormalizeAccountType(socketMsg.AccountType)
+			subscriptionKey := user.ID.Hex() + ":" + accountType
 			client.Subscribe(subscriptionKey)
+			client.Subscribe(user.ID.Hex() + ":" + models.BalanceAccountMain)
 
-			streamChan, err := h.tradeService.StreamTrades(user.ID.Hex(), socketMsg.AccountType)
+			streamChan, err := h.tradeService.StreamTrades(user.ID.Hex(), accountType)
 			if err != nil {
 				response := models.ErrorResponse{Error: fmt.Sprintf("Failed to start trade stream: %v", err)}
 				if err := client.Conn.WriteJSON(response); err != nil {
 					log.Printf("Error sending error response: %v", err)
 				}
 				client.Unsubscribe(subscriptionKey)
+				client.Unsubscribe(user.ID.Hex() + ":" + models.BalanceAccountMain)
 				continue
 			}
 
+			streams = append(streams, tradeStream{userID: user.ID.Hex(), accountType: accountType})
+			h.sendOpenPositions(client, user.ID.Hex(), accountType)
+
 			go func() {
 				for response := range streamChan {
 					select {
@@ -153,9 +210,9 @@ func (h *WebSocketHandler) readPump(client *models.Client) {
 
 			response := models.SubscriptionResponse{
 				Status:      "success",
-				Message:     fmt.Sprintf("Subscribed to trade stream for user %s (%s)", socketMsg.UserID, socketMsg.AccountType),
+				Message:     fmt.Sprintf("Subscribed to trade stream for user %s (%s)", socketMsg.UserID, accountType),
 				UserID:      socketMsg.UserID,
-				AccountType: socketMsg.AccountType,
+				AccountType: accountType,
 			}
 			if err := client.Conn.WriteJSON(response); err != nil {
 				continue
@@ -164,7 +221,7 @@ func (h *WebSocketHandler) readPump(client *models.Client) {
 			if err := client.Conn.WriteJSON(map[string]string{
 				"status":       "trade_stream_started",
 				"user_id":      socketMsg.UserID,
-				"account_type": socketMsg.AccountType,
+				"account_type": accountType,
 			}); err != nil {
 				continue
 			}
@@ -193,6 +250,30 @@ func (h *WebSocketHandler) readPump(client *models.Client) {
 	}
 }
 
+// sendOpenPositions pushes the current open and pending trades for an account
+// type so a new subscriber starts from a full snapshot.
+func (h *WebSocketHandler) sendOpenPositions(client *models.Client, userID, accountType string) {
+	trades, err := h.tradeService.GetTradesByUserID(context.Background(), userID, accountType)
+	if err != nil {
+		log.Printf("Failed to load open positions for %s: %v", userID, err)
+		return
+	}
+	for _, trade := range trades {
+		if !strings.EqualFold(trade.AccountType, accountType) {
+			continue
+		}
+		if trade.Status != string(models.TradeStatusOpen) && trade.Status != string(models.TradeStatusPending) {
+			continue
+		}
+		select {
+		case client.SendTrade <- trade:
+		default:
+			log.Printf("Client %s trade buffer full, skipping snapshot", client.ID)
+			return
+		}
+	}
+}
+
 func (h *WebSocketHandler) writePump(client *models.Client) {
 	ticker := time.NewTicker(pingPeriod)
 	defer func() {
@@ -202,18 +283,14 @@ func (h *WebSocketHandler) writePump(client *models.Client) {
 
 	for {
 		select {
-		case price, ok := <-client.Send:
-			if err := client.Conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
-				return
-			}
-			if !ok {
-				if err := client.Conn.WriteMessage(websocket.CloseMessage, []byte{}); err != nil {
-					log.Printf("Error sending close message: %v", err)
+		case <-client.Prices.Ready():
+			for _, price := range client.Prices.Drain() {
+				if err := client.Conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
+					return
+				}
+				if err := client.Conn.WriteJSON(price); err != nil {
+					return
 				}
-				return
-			}
-			if err := client.Conn.WriteJSON(price); err != nil {
-				return
 			}
 
 		case trade, ok := <-client.SendTrade:
@@ -258,6 +335,14 @@ func (h *WebSocketHandler) writePump(client *models.Client) {
 				return
 			}
 
+		case notice := <-client.SendNotice:
+			if err := client.Conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
+				return
+			}
+			if err := client.Conn.WriteJSON(notice); err != nil {
+				return
+			}
+
 		case <-ticker.C:
 			if err := client.Conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
 				continue
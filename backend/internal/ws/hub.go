@@ -17,7 +17,10 @@ type Hub struct {
 	balanceBroadcast     chan *models.BalanceData
 	tradeBroadcast       chan *models.TradeHistory
 	orderStreamBroadcast chan models.OrderStreamResponse
+	noticeBroadcast      chan *models.Announcement
 	mu                   sync.RWMutex
+	lastPrices           map[string]*models.PriceData
+	lastPricesMu         sync.RWMutex
 }
 
 func NewHub() *Hub {
@@ -29,6 +32,8 @@ func NewHub() *Hub {
 		tradeBroadcast:       make(chan *models.TradeHistory),
 		balanceBroadcast:     make(chan *models.BalanceData),
 		orderStreamBroadcast: make(chan models.OrderStreamResponse, 256),
+		noticeBroadcast:      make(chan *models.Announcement, 16),
+		lastPrices:           make(map[string]*models.PriceData),
 	}
 }
 
@@ -50,18 +55,14 @@ func (h *Hub) Run() {
 			h.mu.RLock()
 			for _, client := range h.clients {
 				if client.IsSubscribed(price.Symbol) {
-					select {
-					case client.Send <- price:
-					default:
-						log.Printf("Client %s buffer full, skipping price message", client.ID)
-					}
+					client.Prices.Push(price)
 				}
 			}
 			h.mu.RUnlock()
 		case trade := <-h.tradeBroadcast:
 			h.mu.RLock()
 			for _, client := range h.clients {
-				subscriptionKey := trade.UserID.Hex() + ":" + trade.AccountType
+				subscriptionKey := trade.UserID.Hex() + ":" + models.NormalizeAccountType(trade.AccountType)
 				if client.IsSubscribed(subscriptionKey) {
 					select {
 					case client.SendTrade <- trade:
@@ -87,7 +88,7 @@ func (h *Hub) Run() {
 		case orderStream := <-h.orderStreamBroadcast:
 			h.mu.RLock()
 			for _, client := range h.clients {
-				subscriptionKey := orderStream.UserID.Hex() + ":" + orderStream.AccountType
+				subscriptionKey := orderStream.UserID.Hex() + ":" + models.NormalizeAccountType(orderStream.AccountType)
 				if client.IsSubscribed(subscriptionKey) {
 					select {
 					case client.SendOrders <- orderStream:
@@ -97,6 +98,19 @@ func (h *Hub) Run() {
 				}
 			}
 			h.mu.RUnlock()
+		case notice := <-h.noticeBroadcast:
+			h.mu.RLock()
+			for _, client := range h.clients {
+				if notice.Symbol != "" && !client.IsSubscribed(notice.Symbol) {
+					continue
+				}
+				select {
+				case client.SendNotice <- notice:
+				default:
+					log.Printf("Client %s announcement buffer full, skipping announcement", client.ID)
+				}
+			}
+			h.mu.RUnlock()
 		}
 	}
 }
@@ -113,9 +127,20 @@ func (h *Hub) UnregisterClient(client *models.Client) {
 }
 
 func (h *Hub) BroadcastPrice(data *models.PriceData) {
+	h.lastPricesMu.Lock()
+	h.lastPrices[data.Symbol] = data
+	h.lastPricesMu.Unlock()
 	h.broadcast <- data
 }
 
+// LastPrice returns the most recent tick broadcast for symbol.
+func (h *Hub) LastPrice(symbol string) (*models.PriceData, bool) {
+	h.lastPricesMu.RLock()
+	defer h.lastPricesMu.RUnlock()
+	price, ok := h.lastPrices[symbol]
+	return price, ok
+}
+
 func (h *Hub) BroadcastTrade(trade *models.TradeHistory) {
 	h.tradeBroadcast <- trade
 }
@@ -128,6 +153,10 @@ func (h *Hub) BroadcastOrderStream(orderStream models.OrderStreamResponse) {
 	h.orderStreamBroadcast <- orderStream
 }
 
+func (h *Hub) BroadcastAnnouncement(notice *models.Announcement) {
+	h.noticeBroadcast <- notice
+}
+
 func (h *Hub) GetClientCount() int {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
@@ -0,0 +1,85 @@
+package ws
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Stream prices over server-sent events
+// @Description Fallback for clients that cannot hold a WebSocket: streams the same price ticks as /ws as text/event-stream "price" events, plus "announcement" events
+// @Tags Prices
+// @Produce text/event-stream
+// @Param symbols query string true "Comma-separated symbols, e.g. EURUSD,XAUUSD"
+// @Success 200 {object} models.PriceData
+// @Failure 400 {object} map[string]string "No symbols requested"
+// @Router /prices/stream [get]
+func (h *WebSocketHandler) HandlePriceStream(c *gin.Context) {
+	var symbols []string
+	for _, symbol := range strings.Split(c.Query("symbols"), ",") {
+		if symbol = strings.TrimSpace(symbol); symbol != "" {
+			symbols = append(symbols, symbol)
+		}
+	}
+	if len(symbols) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "symbols query parameter is required"})
+		return
+	}
+
+	client := h.hub.RegisterClient(nil)
+	defer h.hub.UnregisterClient(client)
+	for _, symbol := range symbols {
+		client.Subscribe(symbol)
+		if price, ok := h.hub.LastPrice(symbol); ok {
+			client.Prices.Push(price)
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	// Comment lines keep proxies from closing an idle stream.
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-client.Prices.Ready():
+			for _, price := range client.Prices.Drain() {
+				data, err := json.Marshal(price)
+				if err != nil {
+					continue
+				}
+				if _, err := fmt.Fprintf(c.Writer, "event: price\ndata: %s\n\n", data); err != nil {
+					return
+				}
+			}
+			c.Writer.Flush()
+		case notice := <-client.SendNotice:
+			data, err := json.Marshal(notice)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "event: announcement\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
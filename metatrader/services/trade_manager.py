@@ -631,6 +631,27 @@ class TradeManager:
                 return
         await self.send_trade_response(trade_id, trade_code, user_id, "FAILED 20", "", ws, error="Trade not found")
 
+    async def handle_cancel_order_request(self, json_data: dict, ws):
+        trade_id = json_data.get("trade_id", "")
+        trade_code = json_data.get("trade_code", 0)
+        user_id = json_data.get("user_id", "")
+        account_type = json_data.get("account_type", "")
+        for trade in self.trade_repository.pool[:]:
+            if trade.trade_id == trade_id and trade.user_id == user_id and trade.account_type == account_type:
+                if trade.ticket:
+                    for order in self.mt5_client.get_orders():
+                        if order.ticket == trade.ticket:
+                            if not self.mt5_client.close_order(order.ticket, order.symbol, order.volume_current, order.type):
+                                await self.send_trade_response(trade_id, trade_code, user_id, "FAILED 21", "", ws, error="Failed to cancel order")
+                                return
+                            break
+                self.trade_repository.remove_from_pool(trade)
+                self.remove_trade_from_redis(trade)
+                break
+        # An order that is no longer in the pool is not resting here either, so
+        # the backend may release it; a fill would already have been reported.
+        await self.send_trade_response(trade_id, trade_code, user_id, "CANCELLED", "", ws)
+
     def process_tick(self):
         current_time = int(self.get_timestamp())
         for trade in self.trade_repository.pool:
@@ -89,6 +89,8 @@ class WebSocketClient:
                             user_id, account_type, self.websocket))
                     elif msg_type == "modify_trade_request":
                         await self.trade_manager.handle_modify_trade_request(json_data, self.websocket)
+                    elif msg_type == "cancel_order_request":
+                        await self.trade_manager.handle_cancel_order_request(json_data, self.websocket)
                     elif msg_type == "ping":
                         pong = {"type": "pong", "timestamp": float(
                             self.trade_manager.get_timestamp())}